@@ -9,4 +9,9 @@ var (
 	ErrCDRURLCannotBeEmpty = errors.New("base CDR URL cannot be empty")
 	ErrEmptyResult         = errors.New("empty result")
 	ErrMissingAcceptHeader = errors.New("missing accept header")
+
+	ErrMissingClient          = errors.New("missing client")
+	ErrUnsupportedFHIRVersion = errors.New("unsupported FHIR version")
+	ErrMissingResourceType    = errors.New("missing resourceType")
+	ErrUnresolvedReference    = errors.New("unresolved reference")
 )
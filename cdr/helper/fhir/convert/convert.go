@@ -0,0 +1,256 @@
+// Package convert maps CDR resources between FHIR STU3 and R4
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	ErrUnsupportedResource = errors.New("unsupported resource type for conversion")
+	ErrEmptyResource       = errors.New("empty resource")
+)
+
+// SupportedResourceTypes lists the resource types which can be converted
+var SupportedResourceTypes = []string{
+	"Patient",
+	"Practitioner",
+	"Organization",
+	"Observation",
+	"Subscription",
+	"Device",
+	"AuditEvent",
+}
+
+// Loss describes a field which could not be fully represented in the target version
+type Loss struct {
+	// Path is the location of the field in the source resource, e.g. Observation.related[1]
+	Path   string
+	Reason string
+}
+
+func (l Loss) String() string {
+	return l.Path + ": " + l.Reason
+}
+
+// Converter converts resources between STU3 and R4
+type Converter struct {
+	maSTU3 *jsonformat.Marshaller
+	umSTU3 *jsonformat.Unmarshaller
+	maR4   *jsonformat.Marshaller
+	umR4   *jsonformat.Unmarshaller
+}
+
+// NewConverter returns a Converter using timeZone for date and time values
+func NewConverter(timeZone string) (*Converter, error) {
+	maSTU3, err := jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+	if err != nil {
+		return nil, fmt.Errorf("convert.NewConverter create FHIR STU3 marshaller: %w", err)
+	}
+	umSTU3, err := jsonformat.NewUnmarshaller(timeZone, fhirversion.STU3)
+	if err != nil {
+		return nil, fmt.Errorf("convert.NewConverter create FHIR STU3 unmarshaller (timezone=[%s]): %w", timeZone, err)
+	}
+	maR4, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		return nil, fmt.Errorf("convert.NewConverter create FHIR R4 marshaller: %w", err)
+	}
+	umR4, err := jsonformat.NewUnmarshaller(timeZone, fhirversion.R4)
+	if err != nil {
+		return nil, fmt.Errorf("convert.NewConverter create FHIR R4 unmarshaller (timezone=[%s]): %w", timeZone, err)
+	}
+	return &Converter{maSTU3: maSTU3, umSTU3: umSTU3, maR4: maR4, umR4: umR4}, nil
+}
+
+// ToR4 converts a STU3 resource to R4. Fields which have no R4 equivalent are
+// dropped and reported in the returned Loss list
+func (c *Converter) ToR4(resource *stu3pb.ContainedResource) (*r4pb.ContainedResource, []Loss, error) {
+	if resource == nil {
+		return nil, nil, ErrEmptyResource
+	}
+	data, err := c.maSTU3.Marshal(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, losses, err := c.convertJSON(data, stu3ToR4Rules, (&r4pb.ContainedResource{}).ProtoReflect().Descriptor())
+	if err != nil {
+		return nil, losses, err
+	}
+	contained, err := c.umR4.UnmarshalR4(out)
+	if err != nil {
+		return nil, losses, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	return contained, losses, nil
+}
+
+// ToSTU3 converts a R4 resource to STU3. Fields which have no STU3 equivalent are
+// dropped and reported in the returned Loss list
+func (c *Converter) ToSTU3(resource *r4pb.ContainedResource) (*stu3pb.ContainedResource, []Loss, error) {
+	if resource == nil {
+		return nil, nil, ErrEmptyResource
+	}
+	data, err := c.maR4.Marshal(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, losses, err := c.convertJSON(data, r4ToSTU3Rules, (&stu3pb.ContainedResource{}).ProtoReflect().Descriptor())
+	if err != nil {
+		return nil, losses, err
+	}
+	contained, err := c.umSTU3.UnmarshalR3(out)
+	if err != nil {
+		return nil, losses, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	return contained, losses, nil
+}
+
+// ToVersion converts a STU3 or R4 contained resource to the requested version.
+// Resources already in the requested version are returned as is
+func (c *Converter) ToVersion(resource interface{}, version fhirversion.Version) (interface{}, []Loss, error) {
+	switch r := resource.(type) {
+	case *stu3pb.ContainedResource:
+		if version == fhirversion.STU3 {
+			return r, nil, nil
+		}
+		return c.ToR4(r)
+	case *r4pb.ContainedResource:
+		if version == fhirversion.R4 {
+			return r, nil, nil
+		}
+		return c.ToSTU3(r)
+	}
+	return nil, nil, fmt.Errorf("%w: %T", ErrUnsupportedResource, resource)
+}
+
+func (c *Converter) convertJSON(data []byte, rules map[string][]rule, contained protoreflect.MessageDescriptor) ([]byte, []Loss, error) {
+	var resource map[string]interface{}
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, nil, err
+	}
+	resourceType, _ := resource["resourceType"].(string)
+	resourceRules, ok := rules[resourceType]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedResource, resourceType)
+	}
+	l := &lossRecorder{}
+	for _, r := range resourceRules {
+		r(resource, l)
+	}
+	target := resourceDescriptor(contained, resourceType)
+	if target == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedResource, resourceType)
+	}
+	prune(resource, target, resourceType, l)
+	out, err := json.Marshal(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(l.losses, func(i, j int) bool {
+		return l.losses[i].Path < l.losses[j].Path
+	})
+	return out, l.losses, nil
+}
+
+type lossRecorder struct {
+	losses []Loss
+}
+
+func (l *lossRecorder) add(path, reason string) {
+	l.losses = append(l.losses, Loss{Path: path, Reason: reason})
+}
+
+// resourceDescriptor finds the message descriptor of resourceType in a ContainedResource
+func resourceDescriptor(contained protoreflect.MessageDescriptor, resourceType string) protoreflect.MessageDescriptor {
+	fields := contained.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		md := fields.Get(i).Message()
+		if md != nil && string(md.Name()) == resourceType {
+			return md
+		}
+	}
+	return nil
+}
+
+// prune removes all fields from obj which are not present in md, recursing into
+// nested elements. Removed fields are recorded as losses
+func prune(obj map[string]interface{}, md protoreflect.MessageDescriptor, path string, l *lossRecorder) {
+	for key, value := range obj {
+		if key == "resourceType" {
+			continue
+		}
+		name := strings.TrimPrefix(key, "_")
+		fd, choice := fieldByJSONName(md, name)
+		if fd == nil {
+			l.add(path+"."+key, "not supported in target version")
+			delete(obj, key)
+			continue
+		}
+		if fd.Message() == nil {
+			continue
+		}
+		child := fd.Message()
+		if choice != nil {
+			child = choice.Message()
+		}
+		if child == nil || child.FullName().Name() == "Any" || child.Name() == "ContainedResource" {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			prune(v, child, path+"."+key, l)
+		case []interface{}:
+			for i, e := range v {
+				if m, ok := e.(map[string]interface{}); ok {
+					prune(m, child, fmt.Sprintf("%s.%s[%d]", path, key, i), l)
+				}
+			}
+		}
+	}
+}
+
+// fieldByJSONName looks up a field by its JSON name. For choice elements such as
+// valueQuantity the selected choice field is returned as well
+func fieldByJSONName(md protoreflect.MessageDescriptor, name string) (protoreflect.FieldDescriptor, protoreflect.FieldDescriptor) {
+	if fd := md.Fields().ByJSONName(name); fd != nil {
+		return fd, nil
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() == nil || !isChoice(fd.Message()) || !strings.HasPrefix(name, fd.JSONName()) {
+			continue
+		}
+		suffix := strings.TrimPrefix(name, fd.JSONName())
+		if suffix == "" {
+			continue
+		}
+		suffix = strings.ToLower(suffix[:1]) + suffix[1:]
+		if choice := fd.Message().Fields().ByJSONName(suffix); choice != nil {
+			return fd, choice
+		}
+	}
+	return nil, nil
+}
+
+// isChoice reports whether md represents a FHIR choice type, i.e. all its fields are part of a oneof
+func isChoice(md protoreflect.MessageDescriptor) bool {
+	fields := md.Fields()
+	if fields.Len() == 0 {
+		return false
+	}
+	for i := 0; i < fields.Len(); i++ {
+		if fields.Get(i).ContainingOneof() == nil {
+			return false
+		}
+	}
+	return true
+}
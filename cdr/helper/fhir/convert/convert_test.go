@@ -0,0 +1,187 @@
+package convert_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/convert"
+	"github.com/stretchr/testify/assert"
+)
+
+const timeZone = "Europe/Amsterdam"
+
+func paths(losses []convert.Loss) []string {
+	var p []string
+	for _, l := range losses {
+		p = append(p, l.Path)
+	}
+	return p
+}
+
+func TestObservationToR4(t *testing.T) {
+	c, err := convert.NewConverter(timeZone)
+	if !assert.Nil(t, err) {
+		return
+	}
+	um, _ := jsonformat.NewUnmarshaller(timeZone, fhirversion.STU3)
+	stu3, err := um.UnmarshalR3([]byte(`{
+  "resourceType": "Observation",
+  "id": "obs1",
+  "status": "final",
+  "code": {"coding": [{"system": "http://loinc.org", "code": "29463-7"}]},
+  "subject": {"reference": "Patient/p1"},
+  "context": {"reference": "Encounter/e1"},
+  "valueQuantity": {"value": 72.5, "unit": "kg", "system": "http://unitsofmeasure.org", "code": "kg"},
+  "interpretation": {"text": "normal"},
+  "comment": "measured after breakfast",
+  "related": [
+    {"type": "has-member", "target": {"reference": "Observation/o2"}},
+    {"type": "replaces", "target": {"reference": "Observation/o3"}}
+  ]
+}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	r4, losses, err := c.ToR4(stu3)
+	if !assert.Nil(t, err) {
+		return
+	}
+	obs := r4.GetObservation()
+	if !assert.NotNil(t, obs) {
+		return
+	}
+	assert.Equal(t, "obs1", obs.Id.Value)
+	assert.Equal(t, "e1", obs.Encounter.GetEncounterId().GetValue())
+	assert.Equal(t, "72.5", obs.Value.GetQuantity().Value.Value)
+	assert.Len(t, obs.Interpretation, 1)
+	if assert.Len(t, obs.Note, 1) {
+		assert.Equal(t, "measured after breakfast", obs.Note[0].Text.Value)
+	}
+	assert.Len(t, obs.HasMember, 1)
+	assert.Equal(t, []string{"Observation.related[1]"}, paths(losses))
+
+	back, losses, err := c.ToSTU3(r4)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, losses, 0)
+	assert.Equal(t, "measured after breakfast", back.GetObservation().Comment.Value)
+	assert.Equal(t, "e1", back.GetObservation().Context.GetEncounterId().GetValue())
+}
+
+func TestDeviceToSTU3(t *testing.T) {
+	c, err := convert.NewConverter(timeZone)
+	if !assert.Nil(t, err) {
+		return
+	}
+	um, _ := jsonformat.NewUnmarshaller(timeZone, fhirversion.R4)
+	r4, err := um.UnmarshalR4([]byte(`{
+  "resourceType": "Device",
+  "id": "dev1",
+  "status": "active",
+  "udiCarrier": [{"deviceIdentifier": "00844588003288", "carrierHRF": "(01)00844588003288"}],
+  "deviceName": [
+    {"name": "FlowMeter", "type": "udi-label-name"},
+    {"name": "Flow", "type": "user-friendly-name"}
+  ],
+  "serialNumber": "SN-1",
+  "modelNumber": "FM-2000",
+  "version": [{"value": "1.2.3"}]
+}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	stu3, losses, err := c.ToSTU3(r4)
+	if !assert.Nil(t, err) {
+		return
+	}
+	device := stu3.GetDevice()
+	if !assert.NotNil(t, device) {
+		return
+	}
+	assert.Equal(t, "00844588003288", device.Udi.DeviceIdentifier.Value)
+	assert.Equal(t, "FlowMeter", device.Udi.Name.Value)
+	assert.Equal(t, "FM-2000", device.Model.Value)
+	assert.Equal(t, "1.2.3", device.Version.Value)
+	assert.Equal(t, []string{"Device.deviceName[1]", "Device.serialNumber"}, paths(losses))
+
+	again, losses, err := c.ToR4(stu3)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, losses, 0)
+	assert.Equal(t, "FM-2000", again.GetDevice().ModelNumber.Value)
+	assert.Equal(t, "1.2.3", again.GetDevice().Version[0].Value.Value)
+}
+
+func TestAuditEventRoundTrip(t *testing.T) {
+	c, err := convert.NewConverter(timeZone)
+	if !assert.Nil(t, err) {
+		return
+	}
+	um, _ := jsonformat.NewUnmarshaller(timeZone, fhirversion.STU3)
+	stu3, err := um.UnmarshalR3([]byte(`{
+  "resourceType": "AuditEvent",
+  "type": {"system": "http://dicom.nema.org/resources/ontology/DCM", "code": "110100"},
+  "recorded": "2021-01-01T10:00:00Z",
+  "agent": [{"userId": {"value": "ron"}, "requestor": true}],
+  "source": {"identifier": {"value": "hospital"}},
+  "entity": [{"reference": {"reference": "Patient/p1"}, "detail": [{"type": "note", "value": "aGVsbG8="}]}]
+}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	r4, losses, err := c.ToR4(stu3)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, losses, 0)
+	event := r4.GetAuditEvent()
+	assert.Equal(t, "ron", event.Agent[0].Who.Identifier.Value.Value)
+	assert.Equal(t, "hospital", event.Source.Observer.Identifier.Value.Value)
+	assert.Equal(t, "p1", event.Entity[0].What.GetPatientId().GetValue())
+	assert.Equal(t, []byte("hello"), event.Entity[0].Detail[0].Value.GetBase64Binary().Value)
+
+	back, _, err := c.ToSTU3(r4)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "ron", back.GetAuditEvent().Agent[0].UserId.Value.Value)
+	assert.Equal(t, "hospital", back.GetAuditEvent().Source.Identifier.Value.Value)
+}
+
+func TestPatientLossyAndUnsupported(t *testing.T) {
+	c, err := convert.NewConverter(timeZone)
+	if !assert.Nil(t, err) {
+		return
+	}
+	um, _ := jsonformat.NewUnmarshaller(timeZone, fhirversion.STU3)
+	stu3, err := um.UnmarshalR3([]byte(`{
+  "resourceType": "Patient",
+  "id": "p1",
+  "name": [{"family": "Swanson", "given": ["Ron"]}],
+  "animal": {"species": {"text": "dog"}}
+}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	r4, losses, err := c.ToR4(stu3)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "Swanson", r4.GetPatient().Name[0].Family.Value)
+	assert.Equal(t, []string{"Patient.animal"}, paths(losses))
+
+	encounter, err := um.UnmarshalR3([]byte(`{"resourceType": "Encounter", "status": "planned"}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, _, err = c.ToR4(encounter)
+	assert.True(t, errors.Is(err, convert.ErrUnsupportedResource))
+
+	same, _, err := c.ToVersion(stu3, fhirversion.STU3)
+	assert.Nil(t, err)
+	assert.Equal(t, stu3, same)
+}
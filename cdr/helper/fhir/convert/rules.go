@@ -0,0 +1,399 @@
+package convert
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// rule rewrites a resource in JSON form before it is pruned against the target version
+type rule func(resource map[string]interface{}, l *lossRecorder)
+
+var stu3ToR4Rules = map[string][]rule{
+	"Patient":      {},
+	"Practitioner": {},
+	"Organization": {},
+	"Subscription": {},
+	"Observation": {
+		observationContextToEncounter,
+		observationInterpretationToList,
+		observationCommentToNote,
+		observationRelatedToR4,
+	},
+	"Device": {
+		deviceUDIToCarrier,
+		rename("model", "modelNumber"),
+		deviceVersionToList,
+	},
+	"AuditEvent": {
+		auditEventAgentsToR4,
+		auditEventSourceToR4,
+		auditEventEntitiesToR4,
+	},
+}
+
+var r4ToSTU3Rules = map[string][]rule{
+	"Patient":      {},
+	"Practitioner": {},
+	"Organization": {},
+	"Subscription": {},
+	"Observation": {
+		rename("encounter", "context"),
+		observationInterpretationToSingle,
+		observationNoteToComment,
+		observationRelatedToSTU3,
+	},
+	"Device": {
+		deviceCarrierToUDI,
+		rename("modelNumber", "model"),
+		deviceVersionToString,
+	},
+	"AuditEvent": {
+		auditEventAgentsToSTU3,
+		auditEventSourceToSTU3,
+		auditEventEntitiesToSTU3,
+	},
+}
+
+func rename(from, to string) rule {
+	return func(resource map[string]interface{}, _ *lossRecorder) {
+		for _, prefix := range []string{"", "_"} {
+			if v, ok := resource[prefix+from]; ok {
+				resource[prefix+to] = v
+				delete(resource, prefix+from)
+			}
+		}
+	}
+}
+
+func asList(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		return t
+	case nil:
+		return nil
+	}
+	return []interface{}{v}
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// firstOnly keeps the first element of a list, reporting the others as lost
+func firstOnly(obj map[string]interface{}, key, path string, l *lossRecorder) {
+	list, ok := obj[key].([]interface{})
+	if !ok {
+		return
+	}
+	if len(list) == 0 {
+		delete(obj, key)
+		return
+	}
+	for i := 1; i < len(list); i++ {
+		l.add(fmt.Sprintf("%s.%s[%d]", path, key, i), "target version allows a single value")
+	}
+	obj[key] = list[0]
+}
+
+func observationContextToEncounter(resource map[string]interface{}, l *lossRecorder) {
+	ctx := asMap(resource["context"])
+	if ctx == nil {
+		return
+	}
+	delete(resource, "context")
+	if ref, _ := ctx["reference"].(string); ref != "" && !strings.HasPrefix(ref, "Encounter/") {
+		l.add("Observation.context", "only Encounter references are supported")
+		return
+	}
+	resource["encounter"] = ctx
+}
+
+func observationInterpretationToList(resource map[string]interface{}, _ *lossRecorder) {
+	if v, ok := resource["interpretation"]; ok {
+		resource["interpretation"] = asList(v)
+	}
+	for _, c := range asList(resource["component"]) {
+		if component := asMap(c); component != nil {
+			if v, ok := component["interpretation"]; ok {
+				component["interpretation"] = asList(v)
+			}
+		}
+	}
+}
+
+func observationInterpretationToSingle(resource map[string]interface{}, l *lossRecorder) {
+	firstOnly(resource, "interpretation", "Observation", l)
+	for i, c := range asList(resource["component"]) {
+		if component := asMap(c); component != nil {
+			firstOnly(component, "interpretation", fmt.Sprintf("Observation.component[%d]", i), l)
+		}
+	}
+}
+
+func observationCommentToNote(resource map[string]interface{}, _ *lossRecorder) {
+	comment, ok := resource["comment"].(string)
+	delete(resource, "comment")
+	if !ok || comment == "" {
+		return
+	}
+	resource["note"] = append(asList(resource["note"]), map[string]interface{}{"text": comment})
+}
+
+func observationNoteToComment(resource map[string]interface{}, l *lossRecorder) {
+	notes := asList(resource["note"])
+	delete(resource, "note")
+	for i, n := range notes {
+		note := asMap(n)
+		if i > 0 || note == nil {
+			l.add(fmt.Sprintf("Observation.note[%d]", i), "target version allows a single comment")
+			continue
+		}
+		if text, ok := note["text"].(string); ok {
+			resource["comment"] = text
+		}
+		for key := range note {
+			if key != "text" {
+				l.add(fmt.Sprintf("Observation.note[%d].%s", i, key), "not supported in target version")
+			}
+		}
+	}
+}
+
+func observationRelatedToR4(resource map[string]interface{}, l *lossRecorder) {
+	related := asList(resource["related"])
+	delete(resource, "related")
+	for i, r := range related {
+		rel := asMap(r)
+		if rel == nil {
+			continue
+		}
+		relType, _ := rel["type"].(string)
+		switch relType {
+		case "has-member":
+			resource["hasMember"] = append(asList(resource["hasMember"]), rel["target"])
+		case "derived-from":
+			resource["derivedFrom"] = append(asList(resource["derivedFrom"]), rel["target"])
+		default:
+			l.add(fmt.Sprintf("Observation.related[%d]", i), "relationship type '"+relType+"' has no R4 equivalent")
+		}
+	}
+}
+
+func observationRelatedToSTU3(resource map[string]interface{}, _ *lossRecorder) {
+	var related []interface{}
+	for _, kind := range []struct{ key, relType string }{{"hasMember", "has-member"}, {"derivedFrom", "derived-from"}} {
+		for _, target := range asList(resource[kind.key]) {
+			related = append(related, map[string]interface{}{"type": kind.relType, "target": target})
+		}
+		delete(resource, kind.key)
+	}
+	if len(related) > 0 {
+		resource["related"] = related
+	}
+}
+
+const udiLabelName = "udi-label-name"
+
+func deviceUDIToCarrier(resource map[string]interface{}, _ *lossRecorder) {
+	udi := asMap(resource["udi"])
+	delete(resource, "udi")
+	if udi == nil {
+		return
+	}
+	if name, ok := udi["name"].(string); ok && name != "" {
+		resource["deviceName"] = []interface{}{map[string]interface{}{"name": name, "type": udiLabelName}}
+	}
+	delete(udi, "name")
+	resource["udiCarrier"] = []interface{}{udi}
+}
+
+func deviceCarrierToUDI(resource map[string]interface{}, l *lossRecorder) {
+	firstOnly(resource, "udiCarrier", "Device", l)
+	if udi := asMap(resource["udiCarrier"]); udi != nil {
+		resource["udi"] = udi
+	}
+	delete(resource, "udiCarrier")
+	names := asList(resource["deviceName"])
+	delete(resource, "deviceName")
+	for i, n := range names {
+		name := asMap(n)
+		if name != nil && name["type"] == udiLabelName && resource["udi"] != nil {
+			asMap(resource["udi"])["name"] = name["name"]
+			continue
+		}
+		l.add(fmt.Sprintf("Device.deviceName[%d]", i), "not supported in target version")
+	}
+}
+
+func deviceVersionToList(resource map[string]interface{}, _ *lossRecorder) {
+	if version, ok := resource["version"].(string); ok {
+		resource["version"] = []interface{}{map[string]interface{}{"value": version}}
+	}
+}
+
+func deviceVersionToString(resource map[string]interface{}, l *lossRecorder) {
+	versions := asList(resource["version"])
+	delete(resource, "version")
+	for i, v := range versions {
+		version := asMap(v)
+		if i > 0 || version == nil {
+			l.add(fmt.Sprintf("Device.version[%d]", i), "target version allows a single version")
+			continue
+		}
+		resource["version"] = version["value"]
+		for key := range version {
+			if key != "value" {
+				l.add(fmt.Sprintf("Device.version[%d].%s", i, key), "not supported in target version")
+			}
+		}
+	}
+}
+
+func auditEventAgentsToR4(resource map[string]interface{}, l *lossRecorder) {
+	for i, a := range asList(resource["agent"]) {
+		agent := asMap(a)
+		if agent == nil {
+			continue
+		}
+		who := asMap(agent["reference"])
+		delete(agent, "reference")
+		if userID := asMap(agent["userId"]); userID != nil {
+			if who == nil {
+				who = map[string]interface{}{}
+			}
+			if _, ok := who["identifier"]; ok {
+				l.add(fmt.Sprintf("AuditEvent.agent[%d].userId", i), "conflicts with reference identifier")
+			} else {
+				who["identifier"] = userID
+			}
+			delete(agent, "userId")
+		}
+		if who != nil {
+			agent["who"] = who
+		}
+	}
+}
+
+func auditEventAgentsToSTU3(resource map[string]interface{}, _ *lossRecorder) {
+	for _, a := range asList(resource["agent"]) {
+		agent := asMap(a)
+		if agent == nil {
+			continue
+		}
+		who := asMap(agent["who"])
+		delete(agent, "who")
+		if who == nil {
+			continue
+		}
+		if identifier := asMap(who["identifier"]); identifier != nil {
+			agent["userId"] = identifier
+			delete(who, "identifier")
+		}
+		if len(who) > 0 {
+			agent["reference"] = who
+		}
+	}
+}
+
+func auditEventSourceToR4(resource map[string]interface{}, _ *lossRecorder) {
+	source := asMap(resource["source"])
+	if source == nil {
+		return
+	}
+	if identifier := asMap(source["identifier"]); identifier != nil {
+		source["observer"] = map[string]interface{}{"identifier": identifier}
+		delete(source, "identifier")
+	}
+}
+
+func auditEventSourceToSTU3(resource map[string]interface{}, l *lossRecorder) {
+	source := asMap(resource["source"])
+	if source == nil {
+		return
+	}
+	observer := asMap(source["observer"])
+	delete(source, "observer")
+	if observer == nil {
+		return
+	}
+	if identifier := asMap(observer["identifier"]); identifier != nil {
+		source["identifier"] = identifier
+		return
+	}
+	// STU3 requires an identifier, so fall back to the reference or display value
+	value, _ := observer["reference"].(string)
+	if value == "" {
+		value, _ = observer["display"].(string)
+	}
+	source["identifier"] = map[string]interface{}{"value": value}
+	l.add("AuditEvent.source.observer", "reference converted to identifier value")
+}
+
+func auditEventEntitiesToR4(resource map[string]interface{}, l *lossRecorder) {
+	for i, e := range asList(resource["entity"]) {
+		entity := asMap(e)
+		if entity == nil {
+			continue
+		}
+		what := asMap(entity["reference"])
+		delete(entity, "reference")
+		if identifier := asMap(entity["identifier"]); identifier != nil {
+			if what == nil {
+				what = map[string]interface{}{}
+			}
+			if _, ok := what["identifier"]; ok {
+				l.add(fmt.Sprintf("AuditEvent.entity[%d].identifier", i), "conflicts with reference identifier")
+			} else {
+				what["identifier"] = identifier
+			}
+			delete(entity, "identifier")
+		}
+		if what != nil {
+			entity["what"] = what
+		}
+		for _, d := range asList(entity["detail"]) {
+			if detail := asMap(d); detail != nil {
+				if value, ok := detail["value"]; ok {
+					detail["valueBase64Binary"] = value
+					delete(detail, "value")
+				}
+			}
+		}
+	}
+}
+
+func auditEventEntitiesToSTU3(resource map[string]interface{}, _ *lossRecorder) {
+	for _, e := range asList(resource["entity"]) {
+		entity := asMap(e)
+		if entity == nil {
+			continue
+		}
+		what := asMap(entity["what"])
+		delete(entity, "what")
+		if what != nil {
+			if identifier := asMap(what["identifier"]); identifier != nil {
+				entity["identifier"] = identifier
+				delete(what, "identifier")
+			}
+			if len(what) > 0 {
+				entity["reference"] = what
+			}
+		}
+		for _, d := range asList(entity["detail"]) {
+			detail := asMap(d)
+			if detail == nil {
+				continue
+			}
+			if value, ok := detail["valueBase64Binary"]; ok {
+				detail["value"] = value
+				delete(detail, "valueBase64Binary")
+			}
+			if value, ok := detail["valueString"].(string); ok {
+				detail["value"] = base64.StdEncoding.EncodeToString([]byte(value))
+				delete(detail, "valueString")
+			}
+		}
+	}
+}
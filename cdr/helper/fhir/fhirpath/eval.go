@@ -0,0 +1,488 @@
+package fhirpath
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// variables are the well known environment variables besides %resource and %context
+var variables = map[string]string{
+	"ucum":  "http://unitsofmeasure.org",
+	"sct":   "http://snomed.info/sct",
+	"loinc": "http://loinc.org",
+}
+
+type environment struct {
+	resource Node
+	context  Node
+	index    []Node
+	total    []Node
+}
+
+func (env *environment) eval(e expr, input []Node) ([]Node, error) {
+	switch e := e.(type) {
+	case *literalExpr:
+		return e.values, nil
+	case *identifierExpr:
+		switch e.name {
+		case "$this":
+			return input, nil
+		case "$index":
+			return env.index, nil
+		case "$total":
+			return env.total, nil
+		}
+		return navigate(input, e.name), nil
+	case *variableExpr:
+		return env.variable(e.name)
+	case *memberExpr:
+		target, err := env.eval(e.target, input)
+		if err != nil {
+			return nil, err
+		}
+		return navigate(target, e.name), nil
+	case *callExpr:
+		target := input
+		if e.target != nil {
+			var err error
+			if target, err = env.eval(e.target, input); err != nil {
+				return nil, err
+			}
+		}
+		return env.call(e, target, input)
+	case *indexExpr:
+		target, err := env.eval(e.target, input)
+		if err != nil {
+			return nil, err
+		}
+		index, err := env.eval(e.index, input)
+		if err != nil {
+			return nil, err
+		}
+		i, ok, err := singletonNumber(index)
+		if err != nil || !ok {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(target) {
+			return nil, nil
+		}
+		return target[int(i) : int(i)+1], nil
+	case *unaryExpr:
+		operand, err := env.eval(e.operand, input)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "+" {
+			return operand, nil
+		}
+		value, ok, err := singletonNumber(operand)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []Node{number(-value)}, nil
+	case *typeExpr:
+		operand, err := env.eval(e.operand, input)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "is" {
+			if len(operand) == 0 {
+				return nil, nil
+			}
+			if len(operand) > 1 {
+				return nil, fmt.Errorf("'is' requires a single item, got %d", len(operand))
+			}
+			return []Node{boolean(isType(operand[0], e.typeName))}, nil
+		}
+		return ofType(operand, e.typeName), nil
+	case *binaryExpr:
+		return env.binary(e, input)
+	}
+	return nil, fmt.Errorf("unsupported expression %T", e)
+}
+
+func (env *environment) variable(name string) ([]Node, error) {
+	switch name {
+	case "resource", "rootResource":
+		return []Node{env.resource}, nil
+	case "context":
+		return []Node{env.context}, nil
+	}
+	if value, ok := variables[name]; ok {
+		return []Node{str(value)}, nil
+	}
+	if strings.HasPrefix(name, "vs-") {
+		return []Node{str("http://hl7.org/fhir/ValueSet/" + strings.TrimPrefix(name, "vs-"))}, nil
+	}
+	if strings.HasPrefix(name, "ext-") {
+		return []Node{str("http://hl7.org/fhir/StructureDefinition/" + strings.TrimPrefix(name, "ext-"))}, nil
+	}
+	return nil, fmt.Errorf("unknown variable %%%s", name)
+}
+
+// navigate returns the children called name of each item. A capitalized
+// name matching the type of an item selects the item itself, e.g. Patient
+func navigate(input []Node, name string) []Node {
+	var result []Node
+	for _, n := range input {
+		if n.Type == name && unicode.IsUpper(rune(name[0])) {
+			result = append(result, n)
+			continue
+		}
+		result = append(result, n.Children(name)...)
+	}
+	return result
+}
+
+func (env *environment) binary(e *binaryExpr, input []Node) ([]Node, error) {
+	left, err := env.eval(e.left, input)
+	if err != nil {
+		return nil, err
+	}
+	right, err := env.eval(e.right, input)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "and", "or", "xor", "implies":
+		return logic(e.op, left, right)
+	case "|":
+		return distinct(append(append([]Node{}, left...), right...)), nil
+	case "=", "!=":
+		if len(left) == 0 || len(right) == 0 {
+			return nil, nil
+		}
+		equal := len(left) == len(right)
+		for i := 0; equal && i < len(left); i++ {
+			equal = equals(left[i], right[i])
+		}
+		return []Node{boolean(equal == (e.op == "="))}, nil
+	case "~", "!~":
+		equivalent := len(left) == len(right)
+		for i := 0; equivalent && i < len(left); i++ {
+			found := false
+			for j := 0; !found && j < len(right); j++ {
+				found = equivalentTo(left[i], right[j])
+			}
+			equivalent = found
+		}
+		return []Node{boolean(equivalent == (e.op == "~"))}, nil
+	case "in", "contains":
+		item, collection := left, right
+		if e.op == "contains" {
+			item, collection = right, left
+		}
+		if len(item) == 0 {
+			return nil, nil
+		}
+		if len(item) > 1 {
+			return nil, fmt.Errorf("'%s' requires a single item, got %d", e.op, len(item))
+		}
+		for _, n := range collection {
+			if equals(item[0], n) {
+				return []Node{boolean(true)}, nil
+			}
+		}
+		return []Node{boolean(false)}, nil
+	case "<", ">", "<=", ">=":
+		if len(left) == 0 || len(right) == 0 {
+			return nil, nil
+		}
+		if len(left) > 1 || len(right) > 1 {
+			return nil, fmt.Errorf("'%s' requires single items", e.op)
+		}
+		cmp, err := compare(left[0], right[0])
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case "<":
+			return []Node{boolean(cmp < 0)}, nil
+		case ">":
+			return []Node{boolean(cmp > 0)}, nil
+		case "<=":
+			return []Node{boolean(cmp <= 0)}, nil
+		}
+		return []Node{boolean(cmp >= 0)}, nil
+	case "&":
+		l, _, err := singletonString(left)
+		if err != nil {
+			return nil, err
+		}
+		r, _, err := singletonString(right)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{str(l + r)}, nil
+	}
+	return arithmetic(e.op, left, right)
+}
+
+func logic(op string, left, right []Node) ([]Node, error) {
+	l, lKnown, err := singletonBoolean(left)
+	if err != nil {
+		return nil, err
+	}
+	r, rKnown, err := singletonBoolean(right)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "and":
+		if (lKnown && !l) || (rKnown && !r) {
+			return []Node{boolean(false)}, nil
+		}
+		if lKnown && rKnown {
+			return []Node{boolean(true)}, nil
+		}
+	case "or":
+		if (lKnown && l) || (rKnown && r) {
+			return []Node{boolean(true)}, nil
+		}
+		if lKnown && rKnown {
+			return []Node{boolean(false)}, nil
+		}
+	case "xor":
+		if lKnown && rKnown {
+			return []Node{boolean(l != r)}, nil
+		}
+	case "implies":
+		if lKnown && !l {
+			return []Node{boolean(true)}, nil
+		}
+		if rKnown && r {
+			return []Node{boolean(true)}, nil
+		}
+		if lKnown && rKnown {
+			return []Node{boolean(false)}, nil
+		}
+	}
+	return nil, nil
+}
+
+func arithmetic(op string, left, right []Node) ([]Node, error) {
+	if len(left) == 0 || len(right) == 0 {
+		return nil, nil
+	}
+	if len(left) > 1 || len(right) > 1 {
+		return nil, fmt.Errorf("'%s' requires single items", op)
+	}
+	if op == "+" {
+		l, lok := left[0].Value.(string)
+		r, rok := right[0].Value.(string)
+		if lok && rok {
+			return []Node{str(l + r)}, nil
+		}
+	}
+	l, lok := left[0].Value.(float64)
+	r, rok := right[0].Value.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("'%s' is not supported for %v and %v", op, left[0].Value, right[0].Value)
+	}
+	switch op {
+	case "+":
+		return []Node{number(l + r)}, nil
+	case "-":
+		return []Node{number(l - r)}, nil
+	case "*":
+		return []Node{number(l * r)}, nil
+	}
+	if r == 0 {
+		return nil, nil
+	}
+	switch op {
+	case "/":
+		return []Node{number(l / r)}, nil
+	case "div":
+		return []Node{number(math.Trunc(l / r))}, nil
+	case "mod":
+		return []Node{number(math.Mod(l, r))}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator '%s'", op)
+}
+
+func equals(a, b Node) bool {
+	switch av := a.Value.(type) {
+	case map[string]interface{}:
+		bv, ok := b.Value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if a.Type == "Quantity" || b.Type == "Quantity" {
+			return av["value"] == bv["value"] && av["code"] == bv["code"]
+		}
+		return reflect.DeepEqual(av, bv)
+	default:
+		return a.Value == b.Value
+	}
+}
+
+func equivalentTo(a, b Node) bool {
+	as, aok := a.Value.(string)
+	bs, bok := b.Value.(string)
+	if aok && bok {
+		normalize := func(s string) string {
+			return strings.ToLower(strings.Join(strings.Fields(s), " "))
+		}
+		return normalize(as) == normalize(bs)
+	}
+	return equals(a, b)
+}
+
+func compare(a, b Node) (int, error) {
+	switch av := a.Value.(type) {
+	case float64:
+		if bv, ok := b.Value.(float64); ok {
+			switch {
+			case av < bv:
+				return -1, nil
+			case av > bv:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if bv, ok := b.Value.(string); ok {
+			return strings.Compare(av, bv), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %v with %v", a.Value, b.Value)
+}
+
+func distinct(nodes []Node) []Node {
+	var result []Node
+	for _, n := range nodes {
+		found := false
+		for _, r := range result {
+			if found = equals(n, r); found {
+				break
+			}
+		}
+		if !found {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// systemTypes maps the FHIRPath System types to the FHIR types they cover
+var systemTypes = map[string][]string{
+	"Boolean":  {"boolean"},
+	"String":   {"string", "code", "id", "uri", "url", "canonical", "oid", "uuid", "markdown", "base64Binary"},
+	"Integer":  {"integer", "unsignedInt", "positiveInt"},
+	"Decimal":  {"decimal"},
+	"Date":     {"date"},
+	"DateTime": {"dateTime", "instant"},
+	"Time":     {"time"},
+}
+
+func isType(n Node, typeName string) bool {
+	typeName = strings.TrimPrefix(strings.TrimPrefix(typeName, "FHIR."), "System.")
+	if n.Type == typeName {
+		return true
+	}
+	if object, ok := n.Value.(map[string]interface{}); ok {
+		_, isResource := object["resourceType"]
+		return isResource && (typeName == "Resource" || typeName == "DomainResource")
+	}
+	for _, t := range systemTypes[typeName] {
+		if n.Type == t {
+			return true
+		}
+	}
+	if n.Type != "" {
+		return false
+	}
+	switch n.Value.(type) {
+	case bool:
+		return typeName == "Boolean" || typeName == "boolean"
+	case string:
+		return typeName == "String" || typeName == "string"
+	case float64:
+		return typeName == "Decimal" || typeName == "decimal" || typeName == "Integer" || typeName == "integer"
+	}
+	return false
+}
+
+func ofType(nodes []Node, typeName string) []Node {
+	var result []Node
+	for _, n := range nodes {
+		if isType(n, typeName) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// singletonBoolean applies the FHIRPath singleton evaluation of collections:
+// an empty collection is unknown and a single non boolean item is true
+func singletonBoolean(nodes []Node) (value, known bool, err error) {
+	switch len(nodes) {
+	case 0:
+		return false, false, nil
+	case 1:
+		if b, ok := nodes[0].Value.(bool); ok {
+			return b, true, nil
+		}
+		return true, true, nil
+	}
+	return false, false, fmt.Errorf("expected a single boolean, got %d items", len(nodes))
+}
+
+func singletonNumber(nodes []Node) (float64, bool, error) {
+	if len(nodes) == 0 {
+		return 0, false, nil
+	}
+	if len(nodes) > 1 {
+		return 0, false, fmt.Errorf("expected a single number, got %d items", len(nodes))
+	}
+	value, ok := nodes[0].Value.(float64)
+	if !ok {
+		return 0, false, fmt.Errorf("expected a number, got %v", nodes[0].Value)
+	}
+	return value, true, nil
+}
+
+func singletonString(nodes []Node) (string, bool, error) {
+	if len(nodes) == 0 {
+		return "", false, nil
+	}
+	if len(nodes) > 1 {
+		return "", false, fmt.Errorf("expected a single string, got %d items", len(nodes))
+	}
+	switch v := nodes[0].Value.(type) {
+	case string:
+		return v, true, nil
+	case float64, bool:
+		return fmt.Sprint(v), true, nil
+	}
+	return "", false, fmt.Errorf("expected a string, got %v", nodes[0].Value)
+}
+
+func boolean(value bool) Node {
+	return Node{Value: value, Type: "boolean"}
+}
+
+func number(value float64) Node {
+	if value == math.Trunc(value) {
+		return Node{Value: value, Type: "integer"}
+	}
+	return Node{Value: value, Type: "decimal"}
+}
+
+func str(value string) Node {
+	return Node{Value: value, Type: "string"}
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
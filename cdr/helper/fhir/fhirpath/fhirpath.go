@@ -0,0 +1,297 @@
+// Package fhirpath evaluates FHIRPath expressions against CDR resources
+//
+// Resources are evaluated in their FHIR JSON representation, so the same
+// expression works for STU3 and R4 ContainedResource protos as well as for
+// raw JSON. Numbers are returned as float64, dates and times as strings.
+package fhirpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+var (
+	ErrInvalidExpression = errors.New("invalid FHIRPath expression")
+	ErrEvaluation        = errors.New("FHIRPath evaluation failed")
+	ErrInvalidResource   = errors.New("invalid resource")
+)
+
+// Node is a single item of a FHIRPath collection
+type Node struct {
+	// Value is a map[string]interface{} for complex types, or a string, float64 or bool
+	Value interface{}
+	// Type is the FHIR type when known, e.g. Patient, Quantity or dateTime
+	Type string
+	// Location is the path to the item in the resource, e.g. Patient.name[0].given[1]
+	Location string
+}
+
+// Expression is a compiled FHIRPath expression
+type Expression struct {
+	source string
+	tree   expr
+}
+
+// Compile parses a FHIRPath expression
+func Compile(expression string) (*Expression, error) {
+	tree, err := parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{source: expression, tree: tree}, nil
+}
+
+// MustCompile is like Compile but panics when the expression is invalid
+func MustCompile(expression string) *Expression {
+	e, err := Compile(expression)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the expression against a resource in FHIR JSON format
+func (e *Expression) Evaluate(resource []byte) ([]interface{}, error) {
+	root, err := Parse(resource)
+	if err != nil {
+		return nil, err
+	}
+	return e.values(root, root)
+}
+
+// EvaluateR4 evaluates the expression against an R4 resource
+func (e *Expression) EvaluateR4(resource *r4pb.ContainedResource) ([]interface{}, error) {
+	root, err := FromR4(resource)
+	if err != nil {
+		return nil, err
+	}
+	return e.values(root, root)
+}
+
+// EvaluateSTU3 evaluates the expression against an STU3 resource
+func (e *Expression) EvaluateSTU3(resource *stu3pb.ContainedResource) ([]interface{}, error) {
+	root, err := FromSTU3(resource)
+	if err != nil {
+		return nil, err
+	}
+	return e.values(root, root)
+}
+
+// EvaluateNode evaluates the expression with context as the input collection
+// and resource as %resource. Use this to evaluate invariants on an element
+func (e *Expression) EvaluateNode(resource, context Node) ([]Node, error) {
+	env := &environment{
+		resource: resource,
+		context:  context,
+	}
+	result, err := env.eval(e.tree, []Node{context})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrEvaluation, e.source, err)
+	}
+	return result, nil
+}
+
+// IsTrue evaluates the expression as a boolean. An empty result is reported as false
+func (e *Expression) IsTrue(resource, context Node) (bool, error) {
+	result, err := e.EvaluateNode(resource, context)
+	if err != nil {
+		return false, err
+	}
+	value, known, err := singletonBoolean(result)
+	return known && value, err
+}
+
+func (e *Expression) values(resource, context Node) ([]interface{}, error) {
+	result, err := e.EvaluateNode(resource, context)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(result))
+	for _, n := range result {
+		values = append(values, n.Value)
+	}
+	return values, nil
+}
+
+// EvaluateR4 compiles and evaluates expression against an R4 resource
+func EvaluateR4(resource *r4pb.ContainedResource, expression string) ([]interface{}, error) {
+	e, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.EvaluateR4(resource)
+}
+
+// EvaluateSTU3 compiles and evaluates expression against an STU3 resource
+func EvaluateSTU3(resource *stu3pb.ContainedResource, expression string) ([]interface{}, error) {
+	e, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.EvaluateSTU3(resource)
+}
+
+// Parse returns the root Node of a resource in FHIR JSON format
+func Parse(resource []byte) (Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(resource))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return Node{}, fmt.Errorf("%w: %v", ErrInvalidResource, err)
+	}
+	object, ok := normalize(value).(map[string]interface{})
+	if !ok {
+		return Node{}, fmt.Errorf("%w: expected a JSON object", ErrInvalidResource)
+	}
+	resourceType, _ := object["resourceType"].(string)
+	if resourceType == "" {
+		return Node{}, fmt.Errorf("%w: missing resourceType", ErrInvalidResource)
+	}
+	return Node{Value: object, Type: resourceType, Location: resourceType}, nil
+}
+
+// FromR4 returns the root Node of an R4 resource
+func FromR4(resource *r4pb.ContainedResource) (Node, error) {
+	ma, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		return Node{}, err
+	}
+	data, err := ma.Marshal(resource)
+	if err != nil {
+		return Node{}, fmt.Errorf("%w: %v", ErrInvalidResource, err)
+	}
+	return Parse(data)
+}
+
+// FromSTU3 returns the root Node of an STU3 resource
+func FromSTU3(resource *stu3pb.ContainedResource) (Node, error) {
+	ma, err := jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+	if err != nil {
+		return Node{}, err
+	}
+	data, err := ma.Marshal(resource)
+	if err != nil {
+		return Node{}, fmt.Errorf("%w: %v", ErrInvalidResource, err)
+	}
+	return Parse(data)
+}
+
+// normalize converts json.Number values to float64
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	}
+	return value
+}
+
+// choiceTypes maps the type suffix of choice elements such as valueQuantity to the FHIR type
+var choiceTypes = map[string]string{}
+
+func init() {
+	for _, t := range []string{"boolean", "integer", "string", "decimal", "uri", "url", "canonical",
+		"base64Binary", "instant", "date", "dateTime", "time", "code", "oid", "id", "markdown",
+		"unsignedInt", "positiveInt", "uuid"} {
+		choiceTypes[strings.ToUpper(t[:1])+t[1:]] = t
+	}
+	for _, t := range []string{"Address", "Age", "Annotation", "Attachment", "CodeableConcept", "Coding",
+		"ContactDetail", "ContactPoint", "Count", "Distance", "Dosage", "Duration", "Expression",
+		"HumanName", "Identifier", "Meta", "Money", "Period", "Quantity", "Range", "Ratio",
+		"Reference", "SampledData", "Signature", "Timing", "TriggerDefinition", "UsageContext"} {
+		choiceTypes[t] = t
+	}
+}
+
+// Children returns the child elements called name. Choice elements such as
+// value[x] are found by their base name, e.g. value
+func (n Node) Children(name string) []Node {
+	object, ok := n.Value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	name = strings.TrimSuffix(name, "[x]")
+	if value, ok := object[name]; ok {
+		return expand(value, "", n.Location+"."+name)
+	}
+	for key, value := range object {
+		if len(key) <= len(name) || !strings.HasPrefix(key, name) || !unicode.IsUpper(rune(key[len(name)])) {
+			continue
+		}
+		if typ, ok := choiceTypes[key[len(name):]]; ok {
+			return expand(value, typ, n.Location+"."+name)
+		}
+	}
+	return nil
+}
+
+// AllChildren returns all child elements in document order of the keys
+func (n Node) AllChildren() []Node {
+	object, ok := n.Value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var children []Node
+	for _, key := range sortedKeys(object) {
+		if key == "resourceType" || strings.HasPrefix(key, "_") {
+			continue
+		}
+		children = append(children, n.Children(key)...)
+	}
+	return children
+}
+
+// HasValue reports whether n is a primitive with a value, as opposed to
+// a primitive which only carries extensions
+func (n Node) HasValue() bool {
+	switch n.Value.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+func expand(value interface{}, typ, location string) []Node {
+	items, isList := value.([]interface{})
+	if !isList {
+		return []Node{node(value, typ, location)}
+	}
+	nodes := make([]Node, 0, len(items))
+	for i, item := range items {
+		if item == nil {
+			continue
+		}
+		nodes = append(nodes, node(item, typ, fmt.Sprintf("%s[%d]", location, i)))
+	}
+	return nodes
+}
+
+func node(value interface{}, typ, location string) Node {
+	if object, ok := value.(map[string]interface{}); ok {
+		if resourceType, ok := object["resourceType"].(string); ok {
+			typ = resourceType
+		}
+	}
+	return Node{Value: value, Type: typ, Location: location}
+}
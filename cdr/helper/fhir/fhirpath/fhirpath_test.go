@@ -0,0 +1,102 @@
+package fhirpath_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/fhirpath"
+	"github.com/stretchr/testify/assert"
+)
+
+const observation = `{
+  "resourceType": "Observation",
+  "id": "bp",
+  "status": "final",
+  "category": [{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/observation-category", "code": "vital-signs"}]}],
+  "code": {"coding": [{"system": "http://loinc.org", "code": "85354-9", "display": "Blood pressure panel"}]},
+  "subject": {"reference": "Patient/p1"},
+  "effectiveDateTime": "2021-03-04T10:00:00Z",
+  "component": [
+    {"code": {"coding": [{"system": "http://loinc.org", "code": "8480-6"}]}, "valueQuantity": {"value": 120, "unit": "mm[Hg]", "system": "http://unitsofmeasure.org", "code": "mm[Hg]"}},
+    {"code": {"coding": [{"system": "http://loinc.org", "code": "8462-4"}]}, "valueQuantity": {"value": 80.5, "unit": "mm[Hg]", "system": "http://unitsofmeasure.org", "code": "mm[Hg]"}}
+  ]
+}`
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   []interface{}
+	}{
+		{"Observation.status", []interface{}{"final"}},
+		{"status = 'final'", []interface{}{true}},
+		{"component.count()", []interface{}{float64(2)}},
+		{"component.value.value", []interface{}{float64(120), 80.5}},
+		{"component.where(code.coding.code = '8462-4').value.ofType(Quantity).value", []interface{}{80.5}},
+		{"component.value.value.where($this > 100)", []interface{}{float64(120)}},
+		{"component[1].value.code", []interface{}{"mm[Hg]"}},
+		{"component.all(value.system = %ucum)", []interface{}{true}},
+		{"code.coding.where(system = %loinc).exists() and subject.reference.startsWith('Patient/')", []interface{}{true}},
+		{"effective.exists() implies effective >= @2021-01-01", []interface{}{true}},
+		{"issued.exists() or valueString.exists()", []interface{}{false}},
+		{"valueString.empty() xor true", []interface{}{false}},
+		{"iif(status in ('final' | 'amended'), 'done', 'busy')", []interface{}{"done"}},
+		{"code.coding.display.lower().contains('pressure')", []interface{}{true}},
+		{"(component.value.value.first() + 2) * 2 div 3", []interface{}{float64(81)}},
+		{"id & '-' & status", []interface{}{"bp-final"}},
+		{"Patient.name", []interface{}{}},
+		{"status ~ 'FINAL'", []interface{}{true}},
+		{"`status`.matches('^fin')", []interface{}{true}},
+		{"category.coding.code | code.coding.code", []interface{}{"vital-signs", "85354-9"}},
+		{"effective is DateTime and effective.is(FHIR.dateTime).not().not()", []interface{}{true}},
+		{"{}.empty()", []interface{}{true}},
+		{"component.value.children().count() > 6", []interface{}{true}},
+	}
+	for _, test := range tests {
+		e, err := fhirpath.Compile(test.expression)
+		if !assert.Nil(t, err, test.expression) {
+			continue
+		}
+		result, err := e.Evaluate([]byte(observation))
+		if !assert.Nil(t, err, test.expression) {
+			continue
+		}
+		assert.Equal(t, test.expected, result, test.expression)
+	}
+}
+
+func TestEvaluateR4(t *testing.T) {
+	um, _ := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	resource, err := um.UnmarshalR4([]byte(`{"resourceType":"Patient","id":"p1","active":true,"name":[{"family":"Swanson","given":["Ron","Ulysses"]}]}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	result, err := fhirpath.EvaluateR4(resource, "Patient.name.given.last()")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []interface{}{"Ulysses"}, result)
+
+	result, err = fhirpath.EvaluateR4(resource, "active and name.family.exists()")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []interface{}{true}, result)
+}
+
+func TestErrors(t *testing.T) {
+	for _, expression := range []string{"name.", "where(", "'open", "a b", "name[0"} {
+		_, err := fhirpath.Compile(expression)
+		assert.True(t, errors.Is(err, fhirpath.ErrInvalidExpression), expression)
+	}
+	e := fhirpath.MustCompile("component.value.value > 1")
+	_, err := e.Evaluate([]byte(observation))
+	assert.True(t, errors.Is(err, fhirpath.ErrEvaluation))
+
+	_, err = fhirpath.MustCompile("unknown()").Evaluate([]byte(observation))
+	assert.True(t, errors.Is(err, fhirpath.ErrEvaluation))
+
+	_, err = e.Evaluate([]byte(`{"id":"no-type"}`))
+	assert.True(t, errors.Is(err, fhirpath.ErrInvalidResource))
+}
@@ -0,0 +1,482 @@
+package fhirpath
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (env *environment) call(e *callExpr, target, input []Node) ([]Node, error) {
+	// arg evaluates a regular argument relative to the input of the invocation
+	arg := func(i int) ([]Node, error) {
+		if i >= len(e.args) {
+			return nil, fmt.Errorf("%s() expects at least %d arguments", e.name, i+1)
+		}
+		return env.eval(e.args[i], input)
+	}
+	stringArg := func(i int) (string, error) {
+		nodes, err := arg(i)
+		if err != nil {
+			return "", err
+		}
+		value, _, err := singletonString(nodes)
+		return value, err
+	}
+	numberArg := func(i int) (int, error) {
+		nodes, err := arg(i)
+		if err != nil {
+			return 0, err
+		}
+		value, _, err := singletonNumber(nodes)
+		return int(value), err
+	}
+	stringTarget := func() (string, bool, error) {
+		return singletonString(target)
+	}
+	// lambda returns an argument which is evaluated for each item of the target
+	lambda := func() (expr, error) {
+		if len(e.args) != 1 {
+			return nil, fmt.Errorf("%s() expects one argument", e.name)
+		}
+		return e.args[0], nil
+	}
+
+	switch e.name {
+	case "empty":
+		return []Node{boolean(len(target) == 0)}, nil
+	case "exists":
+		if len(e.args) > 0 {
+			matches, err := env.where(e.args[0], target)
+			if err != nil {
+				return nil, err
+			}
+			return []Node{boolean(len(matches) > 0)}, nil
+		}
+		return []Node{boolean(len(target) > 0)}, nil
+	case "all":
+		criterion, err := lambda()
+		if err != nil {
+			return nil, err
+		}
+		matches, err := env.where(criterion, target)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{boolean(len(matches) == len(target))}, nil
+	case "allTrue", "anyTrue", "allFalse", "anyFalse":
+		want := strings.HasSuffix(e.name, "True")
+		count := 0
+		for _, n := range target {
+			if b, ok := n.Value.(bool); ok && b == want {
+				count++
+			}
+		}
+		if strings.HasPrefix(e.name, "all") {
+			return []Node{boolean(count == len(target))}, nil
+		}
+		return []Node{boolean(count > 0)}, nil
+	case "count":
+		return []Node{number(float64(len(target)))}, nil
+	case "distinct":
+		return distinct(target), nil
+	case "isDistinct":
+		return []Node{boolean(len(distinct(target)) == len(target))}, nil
+	case "where":
+		criterion, err := lambda()
+		if err != nil {
+			return nil, err
+		}
+		return env.where(criterion, target)
+	case "select":
+		projection, err := lambda()
+		if err != nil {
+			return nil, err
+		}
+		return env.selectEach(projection, target)
+	case "repeat":
+		projection, err := lambda()
+		if err != nil {
+			return nil, err
+		}
+		var result []Node
+		next := target
+		for len(next) > 0 {
+			found, err := env.selectEach(projection, next)
+			if err != nil {
+				return nil, err
+			}
+			next = nil
+			for _, n := range found {
+				if len(distinct(append(append([]Node{}, result...), n))) > len(result) {
+					result = append(result, n)
+					next = append(next, n)
+				}
+			}
+		}
+		return result, nil
+	case "ofType", "is", "as":
+		arg, err := lambda()
+		if err != nil {
+			return nil, err
+		}
+		typeName := typeNameOf(arg)
+		if typeName == "" {
+			return nil, fmt.Errorf("%s() expects a type name", e.name)
+		}
+		if e.name != "is" {
+			return ofType(target, typeName), nil
+		}
+		if len(target) != 1 {
+			return nil, nil
+		}
+		return []Node{boolean(isType(target[0], typeName))}, nil
+	case "first":
+		return subset(target, 0, 1), nil
+	case "last":
+		return subset(target, len(target)-1, len(target)), nil
+	case "tail":
+		return subset(target, 1, len(target)), nil
+	case "skip":
+		n, err := numberArg(0)
+		if err != nil {
+			return nil, err
+		}
+		return subset(target, n, len(target)), nil
+	case "take":
+		n, err := numberArg(0)
+		if err != nil {
+			return nil, err
+		}
+		return subset(target, 0, n), nil
+	case "single":
+		if len(target) > 1 {
+			return nil, fmt.Errorf("single() found %d items", len(target))
+		}
+		return target, nil
+	case "union", "combine", "intersect", "exclude", "subsetOf", "supersetOf":
+		other, err := arg(0)
+		if err != nil {
+			return nil, err
+		}
+		return setOperation(e.name, target, other), nil
+	case "not":
+		value, known, err := singletonBoolean(target)
+		if err != nil || !known {
+			return nil, err
+		}
+		return []Node{boolean(!value)}, nil
+	case "iif":
+		if len(e.args) < 2 {
+			return nil, fmt.Errorf("iif() expects at least two arguments")
+		}
+		criterion, err := env.eval(e.args[0], target)
+		if err != nil {
+			return nil, err
+		}
+		value, known, err := singletonBoolean(criterion)
+		if err != nil {
+			return nil, err
+		}
+		if known && value {
+			return env.eval(e.args[1], target)
+		}
+		if len(e.args) > 2 {
+			return env.eval(e.args[2], target)
+		}
+		return nil, nil
+	case "hasValue":
+		return []Node{boolean(len(target) == 1 && target[0].HasValue())}, nil
+	case "children":
+		var result []Node
+		for _, n := range target {
+			result = append(result, n.AllChildren()...)
+		}
+		return result, nil
+	case "descendants":
+		var result []Node
+		next := target
+		for len(next) > 0 {
+			var children []Node
+			for _, n := range next {
+				children = append(children, n.AllChildren()...)
+			}
+			result = append(result, children...)
+			next = children
+		}
+		return result, nil
+	case "extension":
+		url, err := stringArg(0)
+		if err != nil {
+			return nil, err
+		}
+		var result []Node
+		for _, ext := range navigate(target, "extension") {
+			if object, ok := ext.Value.(map[string]interface{}); ok && object["url"] == url {
+				result = append(result, ext)
+			}
+		}
+		return result, nil
+	case "trace":
+		return target, nil
+	case "htmlChecks", "conformsTo", "memberOf":
+		// narrative, profile and terminology checks need external services, so they are assumed to pass
+		if len(target) == 0 {
+			return nil, nil
+		}
+		return []Node{boolean(true)}, nil
+	case "resolve":
+		return env.resolve(target), nil
+	case "today":
+		return []Node{{Value: time.Now().Format("2006-01-02"), Type: "date"}}, nil
+	case "now":
+		return []Node{{Value: time.Now().Format(time.RFC3339), Type: "dateTime"}}, nil
+	case "toString":
+		value, ok, err := stringTarget()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []Node{str(value)}, nil
+	case "toInteger", "toDecimal":
+		value, ok, err := stringTarget()
+		if err != nil || !ok {
+			return nil, err
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || (e.name == "toInteger" && f != math.Trunc(f)) {
+			return nil, nil
+		}
+		return []Node{number(f)}, nil
+	case "toBoolean":
+		value, ok, err := stringTarget()
+		if err != nil || !ok {
+			return nil, err
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, nil
+		}
+		return []Node{boolean(b)}, nil
+	case "length", "upper", "lower", "trim", "startsWith", "endsWith", "contains", "indexOf",
+		"substring", "replace", "matches", "replaceMatches", "split":
+		value, ok, err := stringTarget()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return stringFunction(e.name, value, stringArg, numberArg)
+	case "join":
+		separator := ""
+		if len(e.args) > 0 {
+			var err error
+			if separator, err = stringArg(0); err != nil {
+				return nil, err
+			}
+		}
+		var parts []string
+		for _, n := range target {
+			parts = append(parts, fmt.Sprint(n.Value))
+		}
+		return []Node{str(strings.Join(parts, separator))}, nil
+	}
+	return nil, fmt.Errorf("unsupported function %s()", e.name)
+}
+
+func stringFunction(name, value string, stringArg func(int) (string, error), numberArg func(int) (int, error)) ([]Node, error) {
+	switch name {
+	case "length":
+		return []Node{number(float64(len([]rune(value))))}, nil
+	case "upper":
+		return []Node{str(strings.ToUpper(value))}, nil
+	case "lower":
+		return []Node{str(strings.ToLower(value))}, nil
+	case "trim":
+		return []Node{str(strings.TrimSpace(value))}, nil
+	case "substring":
+		start, err := numberArg(0)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(value)
+		if start < 0 || start >= len(runes) {
+			return nil, nil
+		}
+		end := len(runes)
+		if length, err := numberArg(1); err == nil && start+length < end {
+			end = start + length
+		}
+		return []Node{str(string(runes[start:end]))}, nil
+	}
+	s, err := stringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "startsWith":
+		return []Node{boolean(strings.HasPrefix(value, s))}, nil
+	case "endsWith":
+		return []Node{boolean(strings.HasSuffix(value, s))}, nil
+	case "contains":
+		return []Node{boolean(strings.Contains(value, s))}, nil
+	case "indexOf":
+		return []Node{number(float64(strings.Index(value, s)))}, nil
+	case "split":
+		var result []Node
+		for _, part := range strings.Split(value, s) {
+			result = append(result, str(part))
+		}
+		return result, nil
+	case "matches", "replaceMatches":
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression '%s': %w", s, err)
+		}
+		if name == "matches" {
+			return []Node{boolean(re.MatchString(value))}, nil
+		}
+		replacement, err := stringArg(1)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{str(re.ReplaceAllString(value, replacement))}, nil
+	}
+	replacement, err := stringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	return []Node{str(strings.ReplaceAll(value, s, replacement))}, nil
+}
+
+// where returns the items of target for which criterion evaluates to true
+func (env *environment) where(criterion expr, target []Node) ([]Node, error) {
+	var result []Node
+	err := env.each(criterion, target, func(item Node, value []Node) error {
+		b, known, err := singletonBoolean(value)
+		if err != nil {
+			return err
+		}
+		if known && b {
+			result = append(result, item)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (env *environment) selectEach(projection expr, target []Node) ([]Node, error) {
+	var result []Node
+	err := env.each(projection, target, func(item Node, value []Node) error {
+		result = append(result, value...)
+		return nil
+	})
+	return result, err
+}
+
+func (env *environment) each(e expr, target []Node, fn func(item Node, value []Node) error) error {
+	index, total := env.index, env.total
+	defer func() {
+		env.index, env.total = index, total
+	}()
+	for i, item := range target {
+		env.index = []Node{number(float64(i))}
+		value, err := env.eval(e, []Node{item})
+		if err != nil {
+			return err
+		}
+		if err := fn(item, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve finds contained resources and Bundle entries referenced by target
+func (env *environment) resolve(target []Node) []Node {
+	candidates := env.resource.Children("contained")
+	for _, entry := range env.resource.Children("entry") {
+		candidates = append(candidates, entry.Children("resource")...)
+	}
+	var result []Node
+	for _, n := range target {
+		reference, ok := n.Value.(string)
+		if !ok {
+			if object, isObject := n.Value.(map[string]interface{}); isObject {
+				reference, _ = object["reference"].(string)
+			}
+		}
+		for _, c := range candidates {
+			object, _ := c.Value.(map[string]interface{})
+			id, _ := object["id"].(string)
+			if reference == "#"+id || strings.HasSuffix(reference, c.Type+"/"+id) {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+// typeNameOf returns the type name of a type specifier argument such as FHIR.Quantity
+func typeNameOf(e expr) string {
+	switch e := e.(type) {
+	case *identifierExpr:
+		return e.name
+	case *memberExpr:
+		if namespace := typeNameOf(e.target); namespace != "" {
+			return namespace + "." + e.name
+		}
+	}
+	return ""
+}
+
+func subset(nodes []Node, start, end int) []Node {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(nodes) {
+		end = len(nodes)
+	}
+	if start >= end {
+		return nil
+	}
+	return nodes[start:end]
+}
+
+func contains(nodes []Node, n Node) bool {
+	for _, item := range nodes {
+		if equals(item, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func setOperation(name string, target, other []Node) []Node {
+	switch name {
+	case "union":
+		return distinct(append(append([]Node{}, target...), other...))
+	case "combine":
+		return append(append([]Node{}, target...), other...)
+	case "subsetOf", "supersetOf":
+		set, superset := target, other
+		if name == "supersetOf" {
+			set, superset = other, target
+		}
+		for _, n := range set {
+			if !contains(superset, n) {
+				return []Node{boolean(false)}
+			}
+		}
+		return []Node{boolean(true)}
+	}
+	var result []Node
+	for _, n := range target {
+		if contains(other, n) == (name == "intersect") {
+			result = append(result, n)
+		}
+	}
+	if name == "intersect" {
+		return distinct(result)
+	}
+	return result
+}
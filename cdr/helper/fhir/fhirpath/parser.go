@@ -0,0 +1,450 @@
+package fhirpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenDateTime
+	tokenVariable
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	quote bool // identifier was delimited with backticks
+	pos   int
+}
+
+var operators = []string{"!=", "!~", "<=", ">=", ".", "[", "]", "(", ")", "{", "}", ",", "=", "~", "<", ">", "|", "+", "-", "*", "/", "&"}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := i
+			for i += 2; i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/'); i++ {
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated comment at %d", ErrInvalidExpression, start)
+			}
+			i += 2
+		case r == '\'' || r == '`':
+			text, next, err := unquote(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			if r == '`' {
+				tokens = append(tokens, token{kind: tokenIdentifier, text: text, quote: true, pos: i})
+			} else {
+				tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			}
+			i = next
+		case r == '@':
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune("-:T.+Z", runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenDateTime, text: string(runes[start+1 : i]), pos: start})
+		case r == '%':
+			start := i
+			i++
+			if i < len(runes) && (runes[i] == '`' || runes[i] == '\'') {
+				text, next, err := unquote(runes, i)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, token{kind: tokenVariable, text: text, pos: start})
+				i = next
+				continue
+			}
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenVariable, text: string(runes[start+1 : i]), pos: start})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			if i+1 < len(runes) && runes[i] == '.' && unicode.IsDigit(runes[i+1]) {
+				i++
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: string(runes[start:i]), pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("%w: unexpected '%c' at %d", ErrInvalidExpression, r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func unquote(runes []rune, start int) (string, int, error) {
+	quote := runes[start]
+	var sb strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		r := runes[i]
+		if r == quote {
+			return sb.String(), i + 1, nil
+		}
+		if r != '\\' || i+1 >= len(runes) {
+			sb.WriteRune(r)
+			continue
+		}
+		i++
+		switch runes[i] {
+		case 'n':
+			sb.WriteRune('\n')
+		case 'r':
+			sb.WriteRune('\r')
+		case 't':
+			sb.WriteRune('\t')
+		case 'f':
+			sb.WriteRune('\f')
+		case 'u':
+			if i+4 < len(runes) {
+				if code, err := strconv.ParseUint(string(runes[i+1:i+5]), 16, 32); err == nil {
+					sb.WriteRune(rune(code))
+					i += 4
+					continue
+				}
+			}
+			sb.WriteRune('u')
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+	return "", 0, fmt.Errorf("%w: unterminated string at %d", ErrInvalidExpression, start)
+}
+
+// expr is a node of the parsed expression tree
+type expr interface{}
+
+type (
+	literalExpr struct {
+		values []Node
+	}
+	identifierExpr struct {
+		name string
+	}
+	variableExpr struct {
+		name string
+	}
+	memberExpr struct {
+		target expr
+		name   string
+	}
+	callExpr struct {
+		target expr // nil when the function is invoked on the input collection
+		name   string
+		args   []expr
+	}
+	indexExpr struct {
+		target expr
+		index  expr
+	}
+	binaryExpr struct {
+		op          string
+		left, right expr
+	}
+	unaryExpr struct {
+		op      string
+		operand expr
+	}
+	typeExpr struct {
+		op       string // is or as
+		operand  expr
+		typeName string
+	}
+)
+
+// binary operator precedence, from loosest to tightest binding
+var precedence = [][]string{
+	{"implies"},
+	{"or", "xor"},
+	{"and"},
+	{"in", "contains"},
+	{"=", "~", "!=", "!~"},
+	{"<", ">", "<=", ">="},
+	{"|"},
+	{"is", "as"},
+	{"+", "-", "&"},
+	{"*", "/", "div", "mod"},
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(src string) (expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected '%s' at %d", ErrInvalidExpression, t.text, t.pos)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(t token, ops ...string) bool {
+	if t.quote || (t.kind != tokenOperator && t.kind != tokenIdentifier) {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokenOperator || t.text != op {
+		return fmt.Errorf("%w: expected '%s' at %d", ErrInvalidExpression, op, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseBinary(level int) (expr, error) {
+	if level == len(precedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(p.peek(), precedence[level]...) {
+		op := p.next().text
+		if op == "is" || op == "as" {
+			typeName, err := p.parseTypeSpecifier()
+			if err != nil {
+				return nil, err
+			}
+			left = &typeExpr{op: op, operand: left, typeName: typeName}
+			continue
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTypeSpecifier() (string, error) {
+	t := p.next()
+	if t.kind != tokenIdentifier {
+		return "", fmt.Errorf("%w: expected type name at %d", ErrInvalidExpression, t.pos)
+	}
+	name := t.text
+	for p.isOperator(p.peek(), ".") && p.tokens[p.pos+1].kind == tokenIdentifier {
+		p.next()
+		name += "." + p.next().text
+	}
+	return name, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if t := p.peek(); t.kind == tokenOperator && (t.text == "-" || t.text == "+") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: t.text, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (expr, error) {
+	e, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case p.isOperator(t, ".") && t.kind == tokenOperator:
+			p.next()
+			name := p.next()
+			if name.kind != tokenIdentifier {
+				return nil, fmt.Errorf("%w: expected identifier at %d", ErrInvalidExpression, name.pos)
+			}
+			if p.isOperator(p.peek(), "(") && p.peek().kind == tokenOperator {
+				args, err := p.parseArguments()
+				if err != nil {
+					return nil, err
+				}
+				e = &callExpr{target: e, name: name.text, args: args}
+				continue
+			}
+			e = &memberExpr{target: e, name: name.text}
+		case p.isOperator(t, "[") && t.kind == tokenOperator:
+			p.next()
+			index, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			e = &indexExpr{target: e, index: index}
+		default:
+			return e, nil
+		}
+	}
+}
+
+func (p *parser) parseArguments() ([]expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []expr
+	if t := p.peek(); t.kind == tokenOperator && t.text == ")" {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		t := p.next()
+		if t.kind == tokenOperator && t.text == ")" {
+			return args, nil
+		}
+		if t.kind != tokenOperator || t.text != "," {
+			return nil, fmt.Errorf("%w: expected ',' or ')' at %d", ErrInvalidExpression, t.pos)
+		}
+	}
+}
+
+func (p *parser) parseTerm() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return &literalExpr{values: []Node{{Value: t.text, Type: "string"}}}, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number '%s'", ErrInvalidExpression, t.text)
+		}
+		typ := "integer"
+		if strings.Contains(t.text, ".") {
+			typ = "decimal"
+		}
+		// a quantity literal such as 4 'mg' or 3 days
+		if u := p.peek(); u.kind == tokenString {
+			p.next()
+			return &literalExpr{values: []Node{{Value: map[string]interface{}{"value": value, "code": u.text}, Type: "Quantity"}}}, nil
+		}
+		return &literalExpr{values: []Node{{Value: value, Type: typ}}}, nil
+	case tokenDateTime:
+		typ := "dateTime"
+		if strings.HasPrefix(t.text, "T") {
+			return &literalExpr{values: []Node{{Value: t.text[1:], Type: "time"}}}, nil
+		}
+		if !strings.Contains(t.text, "T") {
+			typ = "date"
+		}
+		return &literalExpr{values: []Node{{Value: t.text, Type: typ}}}, nil
+	case tokenVariable:
+		return &variableExpr{name: t.text}, nil
+	case tokenIdentifier:
+		if !t.quote {
+			switch t.text {
+			case "true", "false":
+				return &literalExpr{values: []Node{{Value: t.text == "true", Type: "boolean"}}}, nil
+			}
+		}
+		if next := p.peek(); next.kind == tokenOperator && next.text == "(" && !t.quote {
+			args, err := p.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callExpr{name: t.text, args: args}, nil
+		}
+		return &identifierExpr{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			e, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		case "{":
+			if err := p.expect("}"); err != nil {
+				return nil, err
+			}
+			return &literalExpr{}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unexpected '%s' at %d", ErrInvalidExpression, t.text, t.pos)
+}
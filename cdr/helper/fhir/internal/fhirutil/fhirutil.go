@@ -0,0 +1,93 @@
+// Package fhirutil contains version independent helpers for the FHIR resource builders
+package fhirutil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	ErrMissingRequiredElement = errors.New("missing required element")
+	ErrInvalidCode            = errors.New("invalid code")
+	ErrInvalidUnit            = errors.New("invalid UCUM unit")
+)
+
+// SetCode sets the enum value of a FHIR code message such as Observation_StatusCode
+// from its FHIR code, e.g. "entered-in-error"
+func SetCode(msg proto.Message, code string) error {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName("value")
+	if fd == nil || fd.Enum() == nil {
+		return fmt.Errorf("%w: %s is not a code type", ErrInvalidCode, m.Descriptor().FullName())
+	}
+	name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(code))
+	value := fd.Enum().Values().ByName(protoreflect.Name(name))
+	if value == nil || value.Number() == 0 {
+		return fmt.Errorf("%w: '%s' for %s", ErrInvalidCode, code, m.Descriptor().Name())
+	}
+	m.Set(fd, protoreflect.ValueOfEnum(value.Number()))
+	return nil
+}
+
+// Require returns an error listing the missing elements of resourceType, or nil
+func Require(resourceType string, missing ...string) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s.%s", ErrMissingRequiredElement, resourceType, strings.Join(missing, ", "+resourceType+"."))
+}
+
+// Missing collects the names for which present is false
+type Missing []string
+
+// Check records name when present is false
+func (m *Missing) Check(present bool, name string) {
+	if !present {
+		*m = append(*m, name)
+	}
+}
+
+// ValidUCUM performs a syntactic check of a UCUM unit code such as "mm[Hg]" or "kg/m2"
+func ValidUCUM(code string) bool {
+	if code == "" {
+		return false
+	}
+	depth := map[rune]int{}
+	pairs := map[rune]rune{']': '[', '}': '{', ')': '('}
+	for _, r := range code {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+		switch r {
+		case '[', '{', '(':
+			depth[r]++
+		case ']', '}', ')':
+			depth[pairs[r]]--
+			if depth[pairs[r]] < 0 {
+				return false
+			}
+		}
+	}
+	for _, d := range depth {
+		if d != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// TimeZone returns the UTC offset of at in the form accepted by the FHIR JSON marshaller
+func TimeZone(at time.Time) string {
+	return at.Format("Z07:00")
+}
+
+// FormatDecimal renders value in the shortest decimal representation
+func FormatDecimal(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
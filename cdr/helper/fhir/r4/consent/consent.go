@@ -0,0 +1,116 @@
+// Package consent contains a builder for FHIR R4 Consent resources
+package consent
+
+import (
+	"time"
+
+	r4pbcon "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/consent_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+const (
+	// ScopeSystem is the system of the consent scopes: adr, research, patient-privacy and treatment
+	ScopeSystem = "http://terminology.hl7.org/CodeSystem/consentscope"
+	// CategorySystem is the LOINC based system of the consent categories
+	CategorySystem = r4.LOINCSystem
+)
+
+type WithFunc func(resource *r4pbcon.Consent) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. draft, proposed, active or rejected
+func WithStatus(status string) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.Status = &r4pbcon.Consent_StatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithScope sets the scope. Use ScopeSystem for the standard scopes
+func WithScope(system, code, display string) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.Scope = r4.NewCodeableConcept(system, code, display)
+		return nil
+	}
+}
+
+func WithCategory(system, code, display string) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.Category = append(resource.Category, r4.NewCodeableConcept(system, code, display))
+		return nil
+	}
+}
+
+// WithPatient sets the reference to the patient, e.g. Patient/123
+func WithPatient(reference string) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.Patient = r4.NewReference(reference)
+		return nil
+	}
+}
+
+func WithDateTime(at time.Time) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.DateTime = r4.NewDateTime(at)
+		return nil
+	}
+}
+
+// WithOrganization adds a reference to the custodian organization
+func WithOrganization(reference string) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.Organization = append(resource.Organization, r4.NewReference(reference))
+		return nil
+	}
+}
+
+func WithPolicyRule(system, code, display string) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		resource.PolicyRule = r4.NewCodeableConcept(system, code, display)
+		return nil
+	}
+}
+
+// WithProvision sets the base rule, provisionType is deny or permit. A zero end
+// leaves the period open ended
+func WithProvision(provisionType string, start, end time.Time) WithFunc {
+	return func(resource *r4pbcon.Consent) error {
+		provision := &r4pbcon.Consent_Provision{Type: &r4pbcon.Consent_Provision_TypeCode{}}
+		if err := fhirutil.SetCode(provision.Type, provisionType); err != nil {
+			return err
+		}
+		if !start.IsZero() {
+			provision.Period = r4.NewPeriod(start, end)
+		}
+		resource.Provision = provision
+		return nil
+	}
+}
+
+// NewConsent creates a FHIR Consent proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status, scope or category are missing
+func NewConsent(options ...WithFunc) (*r4pbcon.Consent, error) {
+	resource := &r4pbcon.Consent{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.Scope != nil, "scope")
+	missing.Check(len(resource.Category) > 0, "category")
+	if err := fhirutil.Require("Consent", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
@@ -0,0 +1,35 @@
+package consent_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/consent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConsent(t *testing.T) {
+	c, err := consent.NewConsent(
+		consent.WithStatus("active"),
+		consent.WithScope(consent.ScopeSystem, "patient-privacy", "Privacy Consent"),
+		consent.WithCategory(consent.CategorySystem, "59284-0", "Patient Consent"),
+		consent.WithPatient("Patient/p1"),
+		consent.WithDateTime(time.Now()),
+		consent.WithOrganization("Organization/o1"),
+		consent.WithPolicyRule("http://terminology.hl7.org/CodeSystem/v3-ActCode", "OPTIN", "opt-in"),
+		consent.WithProvision("permit", time.Now(), time.Time{}),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	_, err = ma.MarshalResource(c)
+	assert.Nil(t, err)
+
+	_, err = consent.NewConsent(consent.WithStatus("active"))
+	assert.ErrorIs(t, err, r4.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "Consent.scope, Consent.category")
+}
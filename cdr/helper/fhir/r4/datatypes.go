@@ -0,0 +1,120 @@
+package r4
+
+import (
+	"fmt"
+	"time"
+
+	r4dt "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	identifierhelper "github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/identifier"
+)
+
+const (
+	LOINCSystem = "http://loinc.org"
+	UCUMSystem  = "http://unitsofmeasure.org"
+	DICOMSystem = "http://dicom.nema.org/resources/ontology/DCM"
+)
+
+// Errors returned by the resource builders
+var (
+	ErrMissingRequiredElement = fhirutil.ErrMissingRequiredElement
+	ErrInvalidCode            = fhirutil.ErrInvalidCode
+	ErrInvalidUnit            = fhirutil.ErrInvalidUnit
+)
+
+// NewReference returns a Reference to a relative or absolute resource URL, e.g. Patient/123
+func NewReference(reference string) *r4dt.Reference {
+	return &r4dt.Reference{
+		Reference: &r4dt.Reference_Uri{Uri: &r4dt.String{Value: reference}},
+	}
+}
+
+// NewCoding returns a Coding, display is optional
+func NewCoding(system, code, display string) *r4dt.Coding {
+	coding := &r4dt.Coding{
+		System: &r4dt.Uri{Value: system},
+		Code:   &r4dt.Code{Value: code},
+	}
+	if display != "" {
+		coding.Display = &r4dt.String{Value: display}
+	}
+	return coding
+}
+
+// NewCodeableConcept returns a CodeableConcept with a single Coding
+func NewCodeableConcept(system, code, display string) *r4dt.CodeableConcept {
+	return &r4dt.CodeableConcept{
+		Coding: []*r4dt.Coding{NewCoding(system, code, display)},
+	}
+}
+
+// NewIdentifier returns an Identifier
+func NewIdentifier(system, value, use string) *r4dt.Identifier {
+	return &r4dt.Identifier{
+		System: &r4dt.Uri{Value: system},
+		Value:  &r4dt.String{Value: value},
+		Use:    identifierhelper.StringToUse(use),
+	}
+}
+
+// NewHumanName returns a HumanName
+func NewHumanName(text, family string, given []string) *r4dt.HumanName {
+	var givenList []*r4dt.String
+	for _, g := range given {
+		givenList = append(givenList, &r4dt.String{Value: g})
+	}
+	return &r4dt.HumanName{
+		Text:   &r4dt.String{Value: text},
+		Given:  givenList,
+		Family: &r4dt.String{Value: family},
+	}
+}
+
+// NewDateTime returns a DateTime with second precision
+func NewDateTime(at time.Time) *r4dt.DateTime {
+	return &r4dt.DateTime{
+		ValueUs:   at.UnixNano() / 1000,
+		Timezone:  fhirutil.TimeZone(at),
+		Precision: r4dt.DateTime_SECOND,
+	}
+}
+
+// NewDate returns a Date with day precision
+func NewDate(at time.Time) *r4dt.Date {
+	return &r4dt.Date{
+		ValueUs:   time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location()).UnixNano() / 1000,
+		Timezone:  fhirutil.TimeZone(at),
+		Precision: r4dt.Date_DAY,
+	}
+}
+
+// NewInstant returns an Instant with microsecond precision
+func NewInstant(at time.Time) *r4dt.Instant {
+	return &r4dt.Instant{
+		ValueUs:   at.UnixNano() / 1000,
+		Timezone:  fhirutil.TimeZone(at),
+		Precision: r4dt.Instant_MICROSECOND,
+	}
+}
+
+// NewPeriod returns a Period. A zero end time leaves the Period open ended
+func NewPeriod(start, end time.Time) *r4dt.Period {
+	period := &r4dt.Period{Start: NewDateTime(start)}
+	if !end.IsZero() {
+		period.End = NewDateTime(end)
+	}
+	return period
+}
+
+// NewUCUMQuantity returns a Quantity expressed in the UCUM unit code
+func NewUCUMQuantity(value float64, unit string) (*r4dt.Quantity, error) {
+	if !fhirutil.ValidUCUM(unit) {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidUnit, unit)
+	}
+	return &r4dt.Quantity{
+		Value:  &r4dt.Decimal{Value: fhirutil.FormatDecimal(value)},
+		Unit:   &r4dt.String{Value: unit},
+		System: &r4dt.Uri{Value: UCUMSystem},
+		Code:   &r4dt.Code{Value: unit},
+	}, nil
+}
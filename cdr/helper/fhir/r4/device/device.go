@@ -0,0 +1,119 @@
+// Package device contains a builder for FHIR R4 Device resources
+package device
+
+import (
+	r4dt "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pbdev "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/device_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+type WithFunc func(resource *r4pbdev.Device) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status: active, inactive, entered-in-error or unknown
+func WithStatus(status string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.Status = &r4pbdev.Device_StatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithUDI adds a UDI carrier with the device identifier and human readable form
+func WithUDI(deviceIdentifier, carrierHRF string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		udi := &r4pbdev.Device_UdiCarrier{
+			DeviceIdentifier: &r4dt.String{Value: deviceIdentifier},
+		}
+		if carrierHRF != "" {
+			udi.CarrierHrf = &r4dt.String{Value: carrierHRF}
+		}
+		resource.UdiCarrier = append(resource.UdiCarrier, udi)
+		return nil
+	}
+}
+
+// WithDeviceName adds a name, nameType is e.g. udi-label-name, manufacturer-name or model-name
+func WithDeviceName(name, nameType string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		deviceName := &r4pbdev.Device_DeviceName{
+			Name: &r4dt.String{Value: name},
+			Type: &r4pbdev.Device_DeviceName_TypeCode{},
+		}
+		if err := fhirutil.SetCode(deviceName.Type, nameType); err != nil {
+			return err
+		}
+		resource.DeviceName = append(resource.DeviceName, deviceName)
+		return nil
+	}
+}
+
+func WithManufacturer(manufacturer string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.Manufacturer = &r4dt.String{Value: manufacturer}
+		return nil
+	}
+}
+
+func WithModelNumber(model string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.ModelNumber = &r4dt.String{Value: model}
+		return nil
+	}
+}
+
+func WithSerialNumber(serial string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.SerialNumber = &r4dt.String{Value: serial}
+		return nil
+	}
+}
+
+func WithType(system, code, display string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.Type = r4.NewCodeableConcept(system, code, display)
+		return nil
+	}
+}
+
+// WithPatient sets the reference to the patient the device is affixed to
+func WithPatient(reference string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.Patient = r4.NewReference(reference)
+		return nil
+	}
+}
+
+// WithOwner sets the reference to the organization responsible for the device
+func WithOwner(reference string) WithFunc {
+	return func(resource *r4pbdev.Device) error {
+		resource.Owner = r4.NewReference(reference)
+		return nil
+	}
+}
+
+// NewDevice creates a FHIR Device proto resource
+// The WithFunc option methods should be used to build the structure
+func NewDevice(options ...WithFunc) (*r4pbdev.Device, error) {
+	resource := &r4pbdev.Device{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	for _, udi := range resource.UdiCarrier {
+		missing.Check(udi.DeviceIdentifier.GetValue() != "", "udiCarrier.deviceIdentifier")
+	}
+	if err := fhirutil.Require("Device", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
@@ -0,0 +1,35 @@
+package device_test
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDevice(t *testing.T) {
+	d, err := device.NewDevice(
+		device.WithIdentifier("https://example.com/serial", "SN-1", "official"),
+		device.WithStatus("active"),
+		device.WithUDI("00844588003288", "(01)00844588003288"),
+		device.WithDeviceName("FlowMeter", "udi-label-name"),
+		device.WithManufacturer("Philips"),
+		device.WithModelNumber("FM-2000"),
+		device.WithSerialNumber("SN-1"),
+		device.WithPatient("Patient/p1"),
+		device.WithOwner("Organization/o1"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	_, err = ma.MarshalResource(d)
+	assert.Nil(t, err)
+	assert.Equal(t, "FM-2000", d.ModelNumber.Value)
+
+	_, err = device.NewDevice(device.WithUDI("", ""))
+	assert.ErrorIs(t, err, r4.ErrMissingRequiredElement)
+}
@@ -0,0 +1,98 @@
+// Package devicerequest contains a builder for FHIR R4 DeviceRequest resources
+package devicerequest
+
+import (
+	"time"
+
+	r4pbdr "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/device_request_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+type WithFunc func(resource *r4pbdr.DeviceRequest) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. draft, active or completed
+func WithStatus(status string) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.Status = &r4pbdr.DeviceRequest_StatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithIntent sets the intent, e.g. proposal, plan or order
+func WithIntent(intent string) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.Intent = &r4pbdr.DeviceRequest_IntentCode{}
+		return fhirutil.SetCode(resource.Intent, intent)
+	}
+}
+
+// WithCodeReference sets the requested device by reference, e.g. Device/123
+func WithCodeReference(reference string) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.Code = &r4pbdr.DeviceRequest_CodeX{
+			Choice: &r4pbdr.DeviceRequest_CodeX_Reference{Reference: r4.NewReference(reference)},
+		}
+		return nil
+	}
+}
+
+// WithCodeConcept sets the requested device by code
+func WithCodeConcept(system, code, display string) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.Code = &r4pbdr.DeviceRequest_CodeX{
+			Choice: &r4pbdr.DeviceRequest_CodeX_CodeableConcept{CodeableConcept: r4.NewCodeableConcept(system, code, display)},
+		}
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.Subject = r4.NewReference(reference)
+		return nil
+	}
+}
+
+func WithRequester(reference string) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.Requester = r4.NewReference(reference)
+		return nil
+	}
+}
+
+func WithAuthoredOn(at time.Time) WithFunc {
+	return func(resource *r4pbdr.DeviceRequest) error {
+		resource.AuthoredOn = r4.NewDateTime(at)
+		return nil
+	}
+}
+
+// NewDeviceRequest creates a FHIR DeviceRequest proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when intent, code or subject are missing
+func NewDeviceRequest(options ...WithFunc) (*r4pbdr.DeviceRequest, error) {
+	resource := &r4pbdr.DeviceRequest{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Intent != nil, "intent")
+	missing.Check(resource.Code != nil, "code")
+	missing.Check(resource.Subject != nil, "subject")
+	if err := fhirutil.Require("DeviceRequest", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
@@ -0,0 +1,35 @@
+package devicerequest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/devicerequest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDeviceRequest(t *testing.T) {
+	dr, err := devicerequest.NewDeviceRequest(
+		devicerequest.WithStatus("active"),
+		devicerequest.WithIntent("order"),
+		devicerequest.WithCodeReference("Device/d1"),
+		devicerequest.WithSubject("Patient/p1"),
+		devicerequest.WithRequester("Practitioner/pr1"),
+		devicerequest.WithAuthoredOn(time.Now()),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	_, err = ma.MarshalResource(dr)
+	assert.Nil(t, err)
+
+	_, err = devicerequest.NewDeviceRequest(
+		devicerequest.WithCodeConcept("http://snomed.info/sct", "706172005", "Ventilator"),
+	)
+	assert.ErrorIs(t, err, r4.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "DeviceRequest.intent, DeviceRequest.subject")
+}
@@ -0,0 +1,118 @@
+// Package diagnosticreport contains a builder for FHIR R4 DiagnosticReport resources
+package diagnosticreport
+
+import (
+	"time"
+
+	r4dt "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pbdr "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/diagnostic_report_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+type WithFunc func(resource *r4pbdr.DiagnosticReport) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. registered, partial, preliminary or final
+func WithStatus(status string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Status = &r4pbdr.DiagnosticReport_StatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithCode adds a coding to the report code
+func WithCode(system, code, display string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		if resource.Code == nil {
+			resource.Code = &r4dt.CodeableConcept{}
+		}
+		resource.Code.Coding = append(resource.Code.Coding, r4.NewCoding(system, code, display))
+		return nil
+	}
+}
+
+// WithLOINCCode adds a LOINC coding to the report code
+func WithLOINCCode(code, display string) WithFunc {
+	return WithCode(r4.LOINCSystem, code, display)
+}
+
+func WithCategory(system, code, display string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Category = append(resource.Category, r4.NewCodeableConcept(system, code, display))
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Subject = r4.NewReference(reference)
+		return nil
+	}
+}
+
+func WithEffectiveDateTime(at time.Time) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Effective = &r4pbdr.DiagnosticReport_EffectiveX{
+			Choice: &r4pbdr.DiagnosticReport_EffectiveX_DateTime{DateTime: r4.NewDateTime(at)},
+		}
+		return nil
+	}
+}
+
+func WithIssued(at time.Time) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Issued = r4.NewInstant(at)
+		return nil
+	}
+}
+
+// WithResult adds a reference to a result Observation, e.g. Observation/123
+func WithResult(reference string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Result = append(resource.Result, r4.NewReference(reference))
+		return nil
+	}
+}
+
+// WithImagingStudy adds a reference to an ImagingStudy
+func WithImagingStudy(reference string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.ImagingStudy = append(resource.ImagingStudy, r4.NewReference(reference))
+		return nil
+	}
+}
+
+func WithConclusion(conclusion string) WithFunc {
+	return func(resource *r4pbdr.DiagnosticReport) error {
+		resource.Conclusion = &r4dt.String{Value: conclusion}
+		return nil
+	}
+}
+
+// NewDiagnosticReport creates a FHIR DiagnosticReport proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status or code are missing
+func NewDiagnosticReport(options ...WithFunc) (*r4pbdr.DiagnosticReport, error) {
+	resource := &r4pbdr.DiagnosticReport{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.Code != nil, "code")
+	if err := fhirutil.Require("DiagnosticReport", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
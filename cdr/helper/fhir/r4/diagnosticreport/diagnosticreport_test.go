@@ -0,0 +1,36 @@
+package diagnosticreport_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/diagnosticreport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDiagnosticReport(t *testing.T) {
+	dr, err := diagnosticreport.NewDiagnosticReport(
+		diagnosticreport.WithStatus("final"),
+		diagnosticreport.WithLOINCCode("58410-2", "CBC panel"),
+		diagnosticreport.WithSubject("Patient/p1"),
+		diagnosticreport.WithEffectiveDateTime(time.Now()),
+		diagnosticreport.WithIssued(time.Now()),
+		diagnosticreport.WithResult("Observation/o1"),
+		diagnosticreport.WithImagingStudy("ImagingStudy/i1"),
+		diagnosticreport.WithConclusion("normal"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	_, err = ma.MarshalResource(dr)
+	assert.Nil(t, err)
+
+	_, err = diagnosticreport.NewDiagnosticReport(diagnosticreport.WithStatus("unknown-status"))
+	assert.ErrorIs(t, err, r4.ErrInvalidCode)
+	_, err = diagnosticreport.NewDiagnosticReport(diagnosticreport.WithStatus("final"))
+	assert.ErrorIs(t, err, r4.ErrMissingRequiredElement)
+}
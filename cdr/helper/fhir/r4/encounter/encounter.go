@@ -0,0 +1,100 @@
+// Package encounter contains a builder for FHIR R4 Encounter resources
+package encounter
+
+import (
+	"time"
+
+	r4pbenc "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/encounter_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+const (
+	// ClassSystem is the system of the HL7 v3 ActCode encounter classes, e.g. AMB or IMP
+	ClassSystem = "http://terminology.hl7.org/CodeSystem/v3-ActCode"
+)
+
+type WithFunc func(resource *r4pbenc.Encounter) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. planned, arrived, in-progress or finished
+func WithStatus(status string) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.Status = &r4pbenc.Encounter_StatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithClass sets the encounter class. Use ClassSystem for the standard classes
+func WithClass(system, code, display string) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.ClassValue = r4.NewCoding(system, code, display)
+		return nil
+	}
+}
+
+func WithType(system, code, display string) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.Type = append(resource.Type, r4.NewCodeableConcept(system, code, display))
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.Subject = r4.NewReference(reference)
+		return nil
+	}
+}
+
+// WithParticipant adds a reference to an individual involved, e.g. Practitioner/123
+func WithParticipant(reference string) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.Participant = append(resource.Participant, &r4pbenc.Encounter_Participant{
+			Individual: r4.NewReference(reference),
+		})
+		return nil
+	}
+}
+
+// WithPeriod sets the start and end time. A zero end leaves the Encounter open ended
+func WithPeriod(start, end time.Time) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.Period = r4.NewPeriod(start, end)
+		return nil
+	}
+}
+
+func WithServiceProvider(reference string) WithFunc {
+	return func(resource *r4pbenc.Encounter) error {
+		resource.ServiceProvider = r4.NewReference(reference)
+		return nil
+	}
+}
+
+// NewEncounter creates a FHIR Encounter proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status or class are missing
+func NewEncounter(options ...WithFunc) (*r4pbenc.Encounter, error) {
+	resource := &r4pbenc.Encounter{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.ClassValue != nil, "class")
+	if err := fhirutil.Require("Encounter", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
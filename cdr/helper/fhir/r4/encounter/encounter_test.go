@@ -0,0 +1,35 @@
+package encounter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/encounter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEncounter(t *testing.T) {
+	e, err := encounter.NewEncounter(
+		encounter.WithStatus("in-progress"),
+		encounter.WithClass(encounter.ClassSystem, "AMB", "ambulatory"),
+		encounter.WithSubject("Patient/p1"),
+		encounter.WithParticipant("Practitioner/pr1"),
+		encounter.WithPeriod(time.Now().Add(-time.Hour), time.Time{}),
+		encounter.WithServiceProvider("Organization/o1"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	data, err := ma.MarshalResource(e)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Contains(t, string(data), `"status":"in-progress"`)
+
+	_, err = encounter.NewEncounter(encounter.WithStatus("finished"))
+	assert.ErrorIs(t, err, r4.ErrMissingRequiredElement)
+}
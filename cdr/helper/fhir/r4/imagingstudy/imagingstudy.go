@@ -0,0 +1,138 @@
+// Package imagingstudy contains a builder for FHIR R4 ImagingStudy resources
+package imagingstudy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	r4dt "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pbis "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/imaging_study_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+// Instance describes a single SOP instance of a series
+type Instance struct {
+	UID         string
+	SOPClassUID string
+	Number      uint32
+}
+
+type WithFunc func(resource *r4pbis.ImagingStudy) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbis.ImagingStudy) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStudyInstanceUID adds the DICOM Study Instance UID as identifier
+func WithStudyInstanceUID(uid string) WithFunc {
+	return func(resource *r4pbis.ImagingStudy) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier("urn:dicom:uid", "urn:oid:"+strings.TrimPrefix(uid, "urn:oid:"), "official"))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. registered, available or cancelled
+func WithStatus(status string) WithFunc {
+	return func(resource *r4pbis.ImagingStudy) error {
+		resource.Status = &r4pbis.ImagingStudy_StatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *r4pbis.ImagingStudy) error {
+		resource.Subject = r4.NewReference(reference)
+		return nil
+	}
+}
+
+func WithStarted(at time.Time) WithFunc {
+	return func(resource *r4pbis.ImagingStudy) error {
+		resource.Started = r4.NewDateTime(at)
+		return nil
+	}
+}
+
+func WithDescription(description string) WithFunc {
+	return func(resource *r4pbis.ImagingStudy) error {
+		resource.Description = &r4dt.String{Value: description}
+		return nil
+	}
+}
+
+// WithSeries adds a series with the DICOM modality code, e.g. CT or MR, and its instances.
+// The study modality list and instance counts are updated accordingly
+func WithSeries(uid string, number uint32, modality string, instances ...Instance) WithFunc {
+	return func(resource *r4pbis.ImagingStudy) error {
+		series := &r4pbis.ImagingStudy_Series{
+			Uid:      &r4dt.Id{Value: uid},
+			Number:   &r4dt.UnsignedInt{Value: number},
+			Modality: r4.NewCoding(r4.DICOMSystem, modality, ""),
+		}
+		for _, i := range instances {
+			instance := &r4pbis.ImagingStudy_Series_Instance{
+				Uid:      &r4dt.Id{Value: i.UID},
+				SopClass: r4.NewCoding("urn:ietf:rfc:3986", "urn:oid:"+strings.TrimPrefix(i.SOPClassUID, "urn:oid:"), ""),
+			}
+			if i.SOPClassUID == "" {
+				instance.SopClass = nil
+			}
+			if i.Number > 0 {
+				instance.Number = &r4dt.UnsignedInt{Value: i.Number}
+			}
+			series.Instance = append(series.Instance, instance)
+		}
+		series.NumberOfInstances = &r4dt.UnsignedInt{Value: uint32(len(series.Instance))}
+		resource.Series = append(resource.Series, series)
+
+		known := false
+		for _, m := range resource.Modality {
+			known = known || m.Code.GetValue() == modality
+		}
+		if !known && modality != "" {
+			resource.Modality = append(resource.Modality, r4.NewCoding(r4.DICOMSystem, modality, ""))
+		}
+		total := uint32(0)
+		for _, s := range resource.Series {
+			total += s.NumberOfInstances.GetValue()
+		}
+		resource.NumberOfSeries = &r4dt.UnsignedInt{Value: uint32(len(resource.Series))}
+		resource.NumberOfInstances = &r4dt.UnsignedInt{Value: total}
+		return nil
+	}
+}
+
+// NewImagingStudy creates a FHIR ImagingStudy proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status or subject are missing, or when a
+// series or instance lacks its UID, modality or SOP class
+func NewImagingStudy(options ...WithFunc) (*r4pbis.ImagingStudy, error) {
+	resource := &r4pbis.ImagingStudy{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.Subject != nil, "subject")
+	for i, s := range resource.Series {
+		missing.Check(s.Uid.GetValue() != "", fmt.Sprintf("series[%d].uid", i))
+		missing.Check(s.Modality.GetCode().GetValue() != "", fmt.Sprintf("series[%d].modality", i))
+		for j, instance := range s.Instance {
+			missing.Check(instance.Uid.GetValue() != "", fmt.Sprintf("series[%d].instance[%d].uid", i, j))
+			missing.Check(instance.SopClass != nil, fmt.Sprintf("series[%d].instance[%d].sopClass", i, j))
+		}
+	}
+	if err := fhirutil.Require("ImagingStudy", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
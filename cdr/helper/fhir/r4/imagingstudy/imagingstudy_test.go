@@ -0,0 +1,44 @@
+package imagingstudy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/imagingstudy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewImagingStudy(t *testing.T) {
+	is, err := imagingstudy.NewImagingStudy(
+		imagingstudy.WithStatus("available"),
+		imagingstudy.WithStudyInstanceUID("1.2.840.113619.2.55.3"),
+		imagingstudy.WithSubject("Patient/p1"),
+		imagingstudy.WithStarted(time.Now()),
+		imagingstudy.WithDescription("CT chest"),
+		imagingstudy.WithSeries("1.2.840.113619.2.55.3.1", 1, "CT",
+			imagingstudy.Instance{UID: "1.2.840.113619.2.55.3.1.1", SOPClassUID: "1.2.840.10008.5.1.4.1.1.2", Number: 1},
+			imagingstudy.Instance{UID: "1.2.840.113619.2.55.3.1.2", SOPClassUID: "1.2.840.10008.5.1.4.1.1.2", Number: 2},
+		),
+		imagingstudy.WithSeries("1.2.840.113619.2.55.3.2", 2, "CT"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, uint32(2), is.NumberOfSeries.Value)
+	assert.Equal(t, uint32(2), is.NumberOfInstances.Value)
+	assert.Len(t, is.Modality, 1)
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	_, err = ma.MarshalResource(is)
+	assert.Nil(t, err)
+
+	_, err = imagingstudy.NewImagingStudy(
+		imagingstudy.WithStatus("available"),
+		imagingstudy.WithSubject("Patient/p1"),
+		imagingstudy.WithSeries("1.2.3", 1, "MR", imagingstudy.Instance{UID: "1.2.3.4"}),
+	)
+	assert.ErrorIs(t, err, r4.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "ImagingStudy.series[0].instance[0].sopClass")
+}
@@ -0,0 +1,150 @@
+// Package observation contains a builder for FHIR R4 Observation resources
+package observation
+
+import (
+	"time"
+
+	r4dt "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pbobs "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+const (
+	// CategorySystem is the system of the standard observation categories, e.g. vital-signs
+	CategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+)
+
+type WithFunc func(resource *r4pbobs.Observation) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. registered, preliminary, final or amended
+func WithStatus(status string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Status = &r4pbobs.Observation_StatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithCode adds a coding to the Observation code
+func WithCode(system, code, display string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		if resource.Code == nil {
+			resource.Code = &r4dt.CodeableConcept{}
+		}
+		resource.Code.Coding = append(resource.Code.Coding, r4.NewCoding(system, code, display))
+		return nil
+	}
+}
+
+// WithLOINCCode adds a LOINC coding to the Observation code
+func WithLOINCCode(code, display string) WithFunc {
+	return WithCode(r4.LOINCSystem, code, display)
+}
+
+// WithCategory adds a category. Use CategorySystem for the standard categories
+func WithCategory(system, code, display string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Category = append(resource.Category, r4.NewCodeableConcept(system, code, display))
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Subject = r4.NewReference(reference)
+		return nil
+	}
+}
+
+// WithDevice sets the reference to the device which made the observation
+func WithDevice(reference string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Device = r4.NewReference(reference)
+		return nil
+	}
+}
+
+func WithEffectiveDateTime(at time.Time) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Effective = &r4pbobs.Observation_EffectiveX{
+			Choice: &r4pbobs.Observation_EffectiveX_DateTime{DateTime: r4.NewDateTime(at)},
+		}
+		return nil
+	}
+}
+
+func WithIssued(at time.Time) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Issued = r4.NewInstant(at)
+		return nil
+	}
+}
+
+// WithUCUMQuantity sets the value to a Quantity in the given UCUM unit, e.g. kg or mm[Hg]
+func WithUCUMQuantity(value float64, unit string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		quantity, err := r4.NewUCUMQuantity(value, unit)
+		if err != nil {
+			return err
+		}
+		resource.Value = &r4pbobs.Observation_ValueX{
+			Choice: &r4pbobs.Observation_ValueX_Quantity{Quantity: quantity},
+		}
+		return nil
+	}
+}
+
+func WithValueString(value string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		resource.Value = &r4pbobs.Observation_ValueX{
+			Choice: &r4pbobs.Observation_ValueX_StringValue{StringValue: &r4dt.String{Value: value}},
+		}
+		return nil
+	}
+}
+
+// WithLOINCComponent adds a component with a LOINC code and UCUM quantity,
+// e.g. the systolic and diastolic values of a blood pressure panel
+func WithLOINCComponent(code, display string, value float64, unit string) WithFunc {
+	return func(resource *r4pbobs.Observation) error {
+		quantity, err := r4.NewUCUMQuantity(value, unit)
+		if err != nil {
+			return err
+		}
+		resource.Component = append(resource.Component, &r4pbobs.Observation_Component{
+			Code: r4.NewCodeableConcept(r4.LOINCSystem, code, display),
+			Value: &r4pbobs.Observation_Component_ValueX{
+				Choice: &r4pbobs.Observation_Component_ValueX_Quantity{Quantity: quantity},
+			},
+		})
+		return nil
+	}
+}
+
+// NewObservation creates a FHIR Observation proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status or code are missing
+func NewObservation(options ...WithFunc) (*r4pbobs.Observation, error) {
+	resource := &r4pbobs.Observation{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.Code != nil, "code")
+	if err := fhirutil.Require("Observation", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
@@ -0,0 +1,55 @@
+package observation_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/observation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewObservation(t *testing.T) {
+	obs, err := observation.NewObservation(
+		observation.WithStatus("final"),
+		observation.WithCategory(observation.CategorySystem, "vital-signs", "Vital Signs"),
+		observation.WithLOINCCode("85354-9", "Blood pressure panel"),
+		observation.WithSubject("Patient/p1"),
+		observation.WithEffectiveDateTime(time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)),
+		observation.WithLOINCComponent("8480-6", "Systolic blood pressure", 120, "mm[Hg]"),
+		observation.WithLOINCComponent("8462-4", "Diastolic blood pressure", 80.5, "mm[Hg]"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Len(t, obs.Component, 2) {
+		return
+	}
+	assert.Equal(t, "80.5", obs.Component[1].Value.GetQuantity().Value.Value)
+	assert.Equal(t, r4.UCUMSystem, obs.Component[1].Value.GetQuantity().System.Value)
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	data, err := ma.MarshalResource(obs)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Contains(t, string(data), `"system":"http://loinc.org"`)
+
+	weight, err := observation.NewObservation(
+		observation.WithStatus("entered-in-error"),
+		observation.WithLOINCCode("29463-7", "Body weight"),
+		observation.WithUCUMQuantity(72.3, "kg"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "72.3", weight.Value.GetQuantity().Value.Value)
+
+	_, err = observation.NewObservation(observation.WithUCUMQuantity(1, "mm[Hg"))
+	assert.ErrorIs(t, err, r4.ErrInvalidUnit)
+
+	_, err = observation.NewObservation(observation.WithSubject("Patient/p1"))
+	assert.ErrorIs(t, err, r4.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "Observation.status, Observation.code")
+}
@@ -0,0 +1,70 @@
+// Package patient contains a builder for FHIR R4 Patient resources
+package patient
+
+import (
+	"time"
+
+	r4dt "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pbpat "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+)
+
+type WithFunc func(resource *r4pbpat.Patient) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *r4pbpat.Patient) error {
+		resource.Identifier = append(resource.Identifier, r4.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+func WithName(text, family string, given []string) WithFunc {
+	return func(resource *r4pbpat.Patient) error {
+		resource.Name = append(resource.Name, r4.NewHumanName(text, family, given))
+		return nil
+	}
+}
+
+// WithGender sets the administrative gender: male, female, other or unknown
+func WithGender(gender string) WithFunc {
+	return func(resource *r4pbpat.Patient) error {
+		resource.Gender = &r4pbpat.Patient_GenderCode{}
+		return fhirutil.SetCode(resource.Gender, gender)
+	}
+}
+
+func WithBirthDate(at time.Time) WithFunc {
+	return func(resource *r4pbpat.Patient) error {
+		resource.BirthDate = r4.NewDate(at)
+		return nil
+	}
+}
+
+func WithActive(active bool) WithFunc {
+	return func(resource *r4pbpat.Patient) error {
+		resource.Active = &r4dt.Boolean{Value: active}
+		return nil
+	}
+}
+
+// WithManagingOrganization sets the reference to the custodian organization, e.g. Organization/123
+func WithManagingOrganization(reference string) WithFunc {
+	return func(resource *r4pbpat.Patient) error {
+		resource.ManagingOrganization = r4.NewReference(reference)
+		return nil
+	}
+}
+
+// NewPatient creates a FHIR Patient proto resource
+// The WithFunc option methods should be used to build the structure
+func NewPatient(options ...WithFunc) (*r4pbpat.Patient, error) {
+	resource := &r4pbpat.Patient{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	return resource, nil
+}
@@ -0,0 +1,36 @@
+package patient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/r4/patient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPatient(t *testing.T) {
+	p, err := patient.NewPatient(
+		patient.WithIdentifier("https://example.com/mrn", "12345", "usual"),
+		patient.WithName("Ron Swanson", "Swanson", []string{"Ron"}),
+		patient.WithGender("male"),
+		patient.WithBirthDate(time.Date(1970, 5, 6, 0, 0, 0, 0, time.UTC)),
+		patient.WithActive(true),
+		patient.WithManagingOrganization("Organization/pawnee"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	ma, _ := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	data, err := ma.MarshalResource(p)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Contains(t, string(data), `"birthDate":"1970-05-06"`)
+	assert.Contains(t, string(data), `"gender":"male"`)
+
+	_, err = patient.NewPatient(patient.WithGender("robot"))
+	assert.ErrorIs(t, err, r4.ErrInvalidCode)
+}
@@ -0,0 +1,484 @@
+// Package receiver implements an endpoint for CDR rest-hook Subscription notifications
+package receiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+const (
+	// AnyResource registers a callback for all resource types without a specific callback
+	AnyResource = "*"
+
+	handshake = "handshake"
+	heartbeat = "heartbeat"
+
+	defaultMaxBodySize = 10 * 1024 * 1024
+	defaultDedupSize   = 1024
+	defaultDedupTTL    = time.Hour
+)
+
+var (
+	ErrUnsupportedFHIRVersion = errors.New("unsupported FHIR version")
+	ErrInvalidHeader          = errors.New("invalid header, expected 'Name: value'")
+	ErrUnauthorized           = errors.New("unauthorized")
+)
+
+// R4Func is called for every R4 resource received
+type R4Func func(ctx context.Context, resource *r4pb.ContainedResource) error
+
+// STU3Func is called for every STU3 resource received
+type STU3Func func(ctx context.Context, resource *stu3pb.ContainedResource) error
+
+// DeleteFunc is called when CDR reports a deleted resource on the delete endpoint
+type DeleteFunc func(ctx context.Context, resourceType, id string) error
+
+// EventFunc is called for handshake and heartbeat notifications
+type EventFunc func(ctx context.Context) error
+
+// Config configures a Receiver
+type Config struct {
+	// FHIRVersion of the subscribed store. Defaults to R4
+	FHIRVersion fhirversion.Version
+	TimeZone    string
+	// Headers which must be present on every notification, in the same
+	// 'Name: value' format as passed to the Subscription WithHeaders helper
+	Headers []string
+	// MaxBodySize limits the accepted payload size in bytes
+	MaxBodySize int64
+	// DedupSize is the number of recent notifications remembered for deduplication
+	DedupSize int
+	// DedupTTL is how long a delivered notification is remembered
+	DedupTTL time.Duration
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+// Receiver is a http.Handler for CDR Subscription rest-hook notifications
+type Receiver struct {
+	config  Config
+	headers []header
+	um      *jsonformat.Unmarshaller
+	dedup   *dedup
+
+	mu          sync.RWMutex
+	r4          map[string]R4Func
+	stu3        map[string]STU3Func
+	onDelete    DeleteFunc
+	onHandshake EventFunc
+	onHeartbeat EventFunc
+}
+
+// New returns a Receiver for the given configuration
+func New(config Config) (*Receiver, error) {
+	if config.FHIRVersion == "" {
+		config.FHIRVersion = fhirversion.R4
+	}
+	if config.FHIRVersion != fhirversion.R4 && config.FHIRVersion != fhirversion.STU3 {
+		return nil, fmt.Errorf("receiver: %w: %v", ErrUnsupportedFHIRVersion, config.FHIRVersion)
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+	if config.DedupSize <= 0 {
+		config.DedupSize = defaultDedupSize
+	}
+	if config.DedupTTL <= 0 {
+		config.DedupTTL = defaultDedupTTL
+	}
+	um, err := jsonformat.NewUnmarshaller(config.TimeZone, config.FHIRVersion)
+	if err != nil {
+		return nil, fmt.Errorf("receiver: create FHIR unmarshaller (timezone=[%s]): %w", config.TimeZone, err)
+	}
+	r := &Receiver{
+		config: config,
+		um:     um,
+		dedup:  newDedup(config.DedupSize, config.DedupTTL),
+		r4:     make(map[string]R4Func),
+		stu3:   make(map[string]STU3Func),
+	}
+	for _, h := range config.Headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("receiver: %w: %s", ErrInvalidHeader, h)
+		}
+		r.headers = append(r.headers, header{name: strings.TrimSpace(parts[0]), value: strings.TrimSpace(parts[1])})
+	}
+	return r, nil
+}
+
+// OnR4 registers fn for R4 resources of resourceType. Use AnyResource as a fallback
+func (r *Receiver) OnR4(resourceType string, fn R4Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.r4[resourceType] = fn
+}
+
+// OnSTU3 registers fn for STU3 resources of resourceType. Use AnyResource as a fallback
+func (r *Receiver) OnSTU3(resourceType string, fn STU3Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stu3[resourceType] = fn
+}
+
+// OnDelete registers fn for notifications received on the delete endpoint
+func (r *Receiver) OnDelete(fn DeleteFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onDelete = fn
+}
+
+// OnHandshake registers fn for handshake notifications
+func (r *Receiver) OnHandshake(fn EventFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onHandshake = fn
+}
+
+// OnHeartbeat registers fn for heartbeat notifications
+func (r *Receiver) OnHeartbeat(fn EventFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onHeartbeat = fn
+}
+
+// ServeHTTP handles notifications sent to the Subscription endpoint
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, ok := r.accept(w, req)
+	if !ok {
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		// Payload-less notification, used by CDR to verify the endpoint
+		r.respond(w, r.callEvent(req.Context(), handshake))
+		return
+	}
+	r.respond(w, r.dispatch(req.Context(), body))
+}
+
+// DeleteHandler returns the handler for the endpoint configured with WithDeleteEndpoint.
+// The deleted resource is taken from the payload when present, otherwise from the
+// trailing [type]/[id] segments of the request path
+func (r *Receiver) DeleteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, ok := r.accept(w, req)
+		if !ok {
+			return
+		}
+		var resource struct {
+			ResourceType string `json:"resourceType"`
+			ID           string `json:"id"`
+		}
+		_ = json.Unmarshal(body, &resource)
+		if resource.ResourceType == "" || resource.ID == "" {
+			segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+			if len(segments) >= 2 {
+				resource.ResourceType, resource.ID = segments[len(segments)-2], segments[len(segments)-1]
+			}
+		}
+		if resource.ResourceType == "" || resource.ID == "" {
+			http.Error(w, "missing resource type or id", http.StatusBadRequest)
+			return
+		}
+		r.respond(w, r.deleted(req.Context(), resource.ResourceType, resource.ID))
+	})
+}
+
+// accept checks method, authentication headers and reads the body
+func (r *Receiver) accept(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodDelete:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	for _, h := range r.headers {
+		if subtle.ConstantTimeCompare([]byte(req.Header.Get(h.name)), []byte(h.value)) != 1 {
+			http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+			return nil, false
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, r.config.MaxBodySize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if int64(len(body)) > r.config.MaxBodySize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	return body, true
+}
+
+func (r *Receiver) respond(w http.ResponseWriter, err error) {
+	var badRequest *decodeError
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusOK)
+	case errors.As(err, &badRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		// Have CDR redeliver the notification
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return "decode notification: " + e.err.Error()
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+type notificationBundle struct {
+	ResourceType string `json:"resourceType"`
+	Type         string `json:"type"`
+	Entry        []struct {
+		FullURL  string          `json:"fullUrl"`
+		Resource json.RawMessage `json:"resource"`
+		Request  struct {
+			Method string `json:"method"`
+			URL    string `json:"url"`
+		} `json:"request"`
+	} `json:"entry"`
+}
+
+type resourceHeader struct {
+	ResourceType string `json:"resourceType"`
+	ID           string `json:"id"`
+	Meta         struct {
+		VersionID string `json:"versionId"`
+	} `json:"meta"`
+	// Parameters (R4 backport) and SubscriptionStatus notification types
+	Type      string `json:"type"`
+	Parameter []struct {
+		Name      string `json:"name"`
+		ValueCode string `json:"valueCode"`
+	} `json:"parameter"`
+}
+
+func (h resourceHeader) notificationType() string {
+	switch h.ResourceType {
+	case "SubscriptionStatus":
+		return h.Type
+	case "Parameters":
+		for _, p := range h.Parameter {
+			if p.Name == "type" {
+				return p.ValueCode
+			}
+		}
+	}
+	return ""
+}
+
+func (r *Receiver) dispatch(ctx context.Context, body []byte) error {
+	var head resourceHeader
+	if err := json.Unmarshal(body, &head); err != nil {
+		return &decodeError{err}
+	}
+	if head.ResourceType != "Bundle" {
+		return r.deliver(ctx, head, body)
+	}
+	var bundle notificationBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return &decodeError{err}
+	}
+	for i, entry := range bundle.Entry {
+		if len(entry.Resource) == 0 {
+			if entry.Request.Method == http.MethodDelete {
+				if err := r.deleteFromURL(ctx, entry.Request.URL); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		var entryHead resourceHeader
+		if err := json.Unmarshal(entry.Resource, &entryHead); err != nil {
+			return &decodeError{err}
+		}
+		if i == 0 {
+			switch entryHead.notificationType() {
+			case handshake:
+				return r.callEvent(ctx, handshake)
+			case heartbeat:
+				return r.callEvent(ctx, heartbeat)
+			case "event-notification", "query-event":
+				continue
+			}
+		}
+		if err := r.deliver(ctx, entryHead, entry.Resource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Receiver) deleteFromURL(ctx context.Context, url string) error {
+	segments := strings.Split(strings.Trim(url, "/"), "/")
+	if len(segments) < 2 {
+		return nil
+	}
+	return r.deleted(ctx, segments[len(segments)-2], segments[len(segments)-1])
+}
+
+func (r *Receiver) deleted(ctx context.Context, resourceType, id string) error {
+	key := "DELETE " + resourceType + "/" + id
+	if !r.dedup.reserve(key) {
+		return nil
+	}
+	r.mu.RLock()
+	fn := r.onDelete
+	r.mu.RUnlock()
+	if fn != nil {
+		if err := fn(ctx, resourceType, id); err != nil {
+			r.dedup.release(key)
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver decodes a single resource and calls the registered callback, unless
+// the same resource version was delivered before
+func (r *Receiver) deliver(ctx context.Context, head resourceHeader, data []byte) error {
+	key := head.ResourceType + "/" + head.ID + "/_history/" + head.Meta.VersionID
+	if head.ID == "" || head.Meta.VersionID == "" {
+		sum := sha256.Sum256(data)
+		key = head.ResourceType + "#" + hex.EncodeToString(sum[:])
+	}
+	if !r.dedup.reserve(key) {
+		return nil
+	}
+	var err error
+	switch r.config.FHIRVersion {
+	case fhirversion.STU3:
+		err = r.deliverSTU3(ctx, head.ResourceType, data)
+	default:
+		err = r.deliverR4(ctx, head.ResourceType, data)
+	}
+	if err != nil {
+		r.dedup.release(key)
+		return err
+	}
+	return nil
+}
+
+func (r *Receiver) deliverR4(ctx context.Context, resourceType string, data []byte) error {
+	r.mu.RLock()
+	fn, ok := r.r4[resourceType]
+	if !ok {
+		fn = r.r4[AnyResource]
+	}
+	r.mu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	contained, err := r.um.UnmarshalR4(data)
+	if err != nil {
+		return &decodeError{err}
+	}
+	return fn(ctx, contained)
+}
+
+func (r *Receiver) deliverSTU3(ctx context.Context, resourceType string, data []byte) error {
+	r.mu.RLock()
+	fn, ok := r.stu3[resourceType]
+	if !ok {
+		fn = r.stu3[AnyResource]
+	}
+	r.mu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	contained, err := r.um.UnmarshalR3(data)
+	if err != nil {
+		return &decodeError{err}
+	}
+	return fn(ctx, contained)
+}
+
+func (r *Receiver) callEvent(ctx context.Context, event string) error {
+	r.mu.RLock()
+	fn := r.onHandshake
+	if event == heartbeat {
+		fn = r.onHeartbeat
+	}
+	r.mu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	return fn(ctx)
+}
+
+// dedup remembers recently delivered notifications, including those being delivered
+type dedup struct {
+	mu     sync.Mutex
+	size   int
+	ttl    time.Duration
+	now    func() time.Time
+	seenAt map[string]time.Time
+	order  []string
+}
+
+func newDedup(size int, ttl time.Duration) *dedup {
+	return &dedup{size: size, ttl: ttl, now: time.Now, seenAt: make(map[string]time.Time)}
+}
+
+// reserve records key and reports whether it was not seen recently, in which case
+// the caller delivers the notification and releases the key when that fails
+func (d *dedup) reserve(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if at, ok := d.seenAt[key]; ok && d.now().Sub(at) < d.ttl {
+		return false
+	}
+	if _, ok := d.seenAt[key]; !ok {
+		d.order = append(d.order, key)
+	}
+	d.seenAt[key] = d.now()
+	for len(d.order) > d.size {
+		delete(d.seenAt, d.order[0])
+		d.order = d.order[1:]
+	}
+	return true
+}
+
+// release forgets key so a redelivery is delivered again
+func (d *dedup) release(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.seenAt[key]; !ok {
+		return
+	}
+	delete(d.seenAt, key)
+	for i, k := range d.order {
+		if k == key {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			break
+		}
+	}
+}
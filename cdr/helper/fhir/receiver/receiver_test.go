@@ -0,0 +1,172 @@
+package receiver_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/receiver"
+	"github.com/stretchr/testify/assert"
+)
+
+const authHeader = "Authorization: Bearer cm9uOnN3YW5zb24="
+
+func post(h http.Handler, path, body string, auth bool) int {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if auth {
+		req.Header.Set("Authorization", "Bearer cm9uOnN3YW5zb24=")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestReceiverR4(t *testing.T) {
+	r, err := receiver.New(receiver.Config{
+		Headers:  []string{authHeader},
+		TimeZone: "Europe/Amsterdam",
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	var patients, others, handshakes int
+	r.OnR4("Patient", func(ctx context.Context, resource *r4pb.ContainedResource) error {
+		assert.Equal(t, "Swanson", resource.GetPatient().Name[0].Family.Value)
+		patients++
+		return nil
+	})
+	r.OnR4(receiver.AnyResource, func(ctx context.Context, resource *r4pb.ContainedResource) error {
+		others++
+		return nil
+	})
+	r.OnHandshake(func(ctx context.Context) error {
+		handshakes++
+		return nil
+	})
+
+	patient := `{"resourceType":"Patient","id":"p1","meta":{"versionId":"1"},"name":[{"family":"Swanson"}]}`
+
+	assert.Equal(t, http.StatusUnauthorized, post(r, "/notification", patient, false))
+	assert.Equal(t, http.StatusOK, post(r, "/notification", "", true))
+	assert.Equal(t, 1, handshakes)
+
+	assert.Equal(t, http.StatusOK, post(r, "/notification/Patient/p1", patient, true))
+	assert.Equal(t, http.StatusOK, post(r, "/notification/Patient/p1", patient, true))
+	assert.Equal(t, 1, patients, "redelivery should be deduplicated")
+
+	assert.Equal(t, http.StatusOK, post(r, "/notification", `{"resourceType":"Organization","id":"o1","meta":{"versionId":"3"}}`, true))
+	assert.Equal(t, 1, others)
+
+	assert.Equal(t, http.StatusBadRequest, post(r, "/notification", `{"resourceType":"Patient","active":"maybe"}`, true))
+}
+
+func TestReceiverConcurrentRedelivery(t *testing.T) {
+	r, err := receiver.New(receiver.Config{Headers: []string{authHeader}})
+	if !assert.Nil(t, err) {
+		return
+	}
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.OnR4("Patient", func(ctx context.Context, resource *r4pb.ContainedResource) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	patient := `{"resourceType":"Patient","id":"p1","meta":{"versionId":"1"}}`
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.Equal(t, http.StatusInternalServerError, post(r, "/notification", patient, true))
+	}()
+	<-started
+	// A redelivery while the first delivery is running is not delivered twice
+	assert.Equal(t, http.StatusOK, post(r, "/notification", patient, true))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	close(release)
+	wg.Wait()
+
+	// The failed delivery released the key, so the next redelivery is delivered
+	assert.Equal(t, http.StatusOK, post(r, "/notification", patient, true))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestReceiverBundle(t *testing.T) {
+	r, err := receiver.New(receiver.Config{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	var heartbeats, observations int
+	var deleted []string
+	r.OnHeartbeat(func(ctx context.Context) error {
+		heartbeats++
+		return nil
+	})
+	r.OnR4("Observation", func(ctx context.Context, resource *r4pb.ContainedResource) error {
+		observations++
+		if observations == 1 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+	r.OnDelete(func(ctx context.Context, resourceType, id string) error {
+		deleted = append(deleted, resourceType+"/"+id)
+		return nil
+	})
+
+	heartbeat := `{"resourceType":"Bundle","type":"history","entry":[{"resource":{"resourceType":"Parameters","parameter":[{"name":"type","valueCode":"heartbeat"}]}}]}`
+	assert.Equal(t, http.StatusOK, post(r, "/", heartbeat, false))
+	assert.Equal(t, 1, heartbeats)
+
+	event := `{"resourceType":"Bundle","type":"history","entry":[
+  {"resource":{"resourceType":"Parameters","parameter":[{"name":"type","valueCode":"event-notification"}]}},
+  {"resource":{"resourceType":"Observation","id":"o1","meta":{"versionId":"2"},"status":"final","code":{"text":"x"}}},
+  {"request":{"method":"DELETE","url":"Observation/o0"}}
+]}`
+	assert.Equal(t, http.StatusInternalServerError, post(r, "/", event, false))
+	assert.Equal(t, http.StatusOK, post(r, "/", event, false))
+	assert.Equal(t, 2, observations, "failed delivery must not be deduplicated")
+	assert.Equal(t, []string{"Observation/o0"}, deleted)
+
+	assert.Equal(t, http.StatusOK, post(r.DeleteHandler(), "/delete/Patient/p9", "", false))
+	assert.Equal(t, http.StatusOK, post(r.DeleteHandler(), "/delete/Patient/p9", "", false))
+	assert.Equal(t, []string{"Observation/o0", "Patient/p9"}, deleted)
+	assert.Equal(t, http.StatusBadRequest, post(r.DeleteHandler(), "/", "", false))
+}
+
+func TestReceiverSTU3(t *testing.T) {
+	_, err := receiver.New(receiver.Config{Headers: []string{"nocolon"}})
+	assert.True(t, errors.Is(err, receiver.ErrInvalidHeader))
+	_, err = receiver.New(receiver.Config{FHIRVersion: "DSTU2"})
+	assert.True(t, errors.Is(err, receiver.ErrUnsupportedFHIRVersion))
+
+	r, err := receiver.New(receiver.Config{FHIRVersion: fhirversion.STU3})
+	if !assert.Nil(t, err) {
+		return
+	}
+	var got string
+	r.OnSTU3("Patient", func(ctx context.Context, resource *stu3pb.ContainedResource) error {
+		got = resource.GetPatient().Id.Value
+		return nil
+	})
+	assert.Equal(t, http.StatusOK, post(r, "/", `{"resourceType":"Patient","id":"p2"}`, false))
+	assert.Equal(t, "p2", got)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
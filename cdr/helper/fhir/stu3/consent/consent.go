@@ -0,0 +1,101 @@
+// Package consent contains a builder for FHIR STU3 Consent resources
+package consent
+
+import (
+	"time"
+
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+const (
+	// CategorySystem is the LOINC based system of the consent categories
+	CategorySystem = stu3.LOINCSystem
+)
+
+type WithFunc func(resource *stu3pb.Consent) error
+
+// WithIdentifier sets the identifier. STU3 allows only one
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.Identifier = stu3.NewIdentifier(system, value, use)
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. draft, proposed, active or rejected
+func WithStatus(status string) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.Status = &stu3cd.ConsentStateCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+func WithCategory(system, code, display string) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.Category = append(resource.Category, stu3.NewCodeableConcept(system, code, display))
+		return nil
+	}
+}
+
+// WithPatient sets the reference to the patient, e.g. Patient/123
+func WithPatient(reference string) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.Patient = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// WithPeriod sets the period the consent applies to. A zero end leaves the period open ended
+func WithPeriod(start, end time.Time) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.Period = stu3.NewPeriod(start, end)
+		return nil
+	}
+}
+
+func WithDateTime(at time.Time) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.DateTime = stu3.NewDateTime(at)
+		return nil
+	}
+}
+
+// WithOrganization adds a reference to the custodian organization
+func WithOrganization(reference string) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.Organization = append(resource.Organization, stu3.NewReference(reference))
+		return nil
+	}
+}
+
+// WithPolicyRule sets the URI of the policy the consent is based on
+func WithPolicyRule(uri string) WithFunc {
+	return func(resource *stu3pb.Consent) error {
+		resource.PolicyRule = &stu3dt.Uri{Value: uri}
+		return nil
+	}
+}
+
+// NewConsent creates a FHIR Consent proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status or patient are missing
+func NewConsent(options ...WithFunc) (*stu3pb.Consent, error) {
+	resource := &stu3pb.Consent{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.Patient != nil, "patient")
+	if err := fhirutil.Require("Consent", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
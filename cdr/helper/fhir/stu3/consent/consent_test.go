@@ -0,0 +1,36 @@
+package consent_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/consent"
+	"github.com/stretchr/testify/assert"
+)
+
+var ma, _ = jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+
+func TestNewConsent(t *testing.T) {
+	c, err := consent.NewConsent(
+		consent.WithIdentifier("https://example.com/consent", "c1", "official"),
+		consent.WithStatus("active"),
+		consent.WithCategory(consent.CategorySystem, "59284-0", "Patient Consent"),
+		consent.WithPatient("Patient/p1"),
+		consent.WithPeriod(time.Now(), time.Time{}),
+		consent.WithDateTime(time.Now()),
+		consent.WithOrganization("Organization/o1"),
+		consent.WithPolicyRule("http://hl7.org/fhir/ConsentPolicy/opt-in"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = ma.MarshalResource(c)
+	assert.Nil(t, err)
+
+	_, err = consent.NewConsent(consent.WithStatus("active"))
+	assert.ErrorIs(t, err, stu3.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "Consent.patient")
+}
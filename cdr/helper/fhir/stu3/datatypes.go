@@ -0,0 +1,120 @@
+package stu3
+
+import (
+	"fmt"
+	"time"
+
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	identifierhelper "github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/identifier"
+)
+
+const (
+	LOINCSystem = "http://loinc.org"
+	UCUMSystem  = "http://unitsofmeasure.org"
+	DICOMSystem = "http://dicom.nema.org/resources/ontology/DCM"
+)
+
+// Errors returned by the resource builders
+var (
+	ErrMissingRequiredElement = fhirutil.ErrMissingRequiredElement
+	ErrInvalidCode            = fhirutil.ErrInvalidCode
+	ErrInvalidUnit            = fhirutil.ErrInvalidUnit
+)
+
+// NewReference returns a Reference to a relative or absolute resource URL, e.g. Patient/123
+func NewReference(reference string) *stu3dt.Reference {
+	return &stu3dt.Reference{
+		Reference: &stu3dt.Reference_Uri{Uri: &stu3dt.String{Value: reference}},
+	}
+}
+
+// NewCoding returns a Coding, display is optional
+func NewCoding(system, code, display string) *stu3dt.Coding {
+	coding := &stu3dt.Coding{
+		System: &stu3dt.Uri{Value: system},
+		Code:   &stu3dt.Code{Value: code},
+	}
+	if display != "" {
+		coding.Display = &stu3dt.String{Value: display}
+	}
+	return coding
+}
+
+// NewCodeableConcept returns a CodeableConcept with a single Coding
+func NewCodeableConcept(system, code, display string) *stu3dt.CodeableConcept {
+	return &stu3dt.CodeableConcept{
+		Coding: []*stu3dt.Coding{NewCoding(system, code, display)},
+	}
+}
+
+// NewIdentifier returns an Identifier
+func NewIdentifier(system, value, use string) *stu3dt.Identifier {
+	return &stu3dt.Identifier{
+		System: &stu3dt.Uri{Value: system},
+		Value:  &stu3dt.String{Value: value},
+		Use:    identifierhelper.StringToUse(use),
+	}
+}
+
+// NewHumanName returns a HumanName
+func NewHumanName(text, family string, given []string) *stu3dt.HumanName {
+	var givenList []*stu3dt.String
+	for _, g := range given {
+		givenList = append(givenList, &stu3dt.String{Value: g})
+	}
+	return &stu3dt.HumanName{
+		Text:   &stu3dt.String{Value: text},
+		Given:  givenList,
+		Family: &stu3dt.String{Value: family},
+	}
+}
+
+// NewDateTime returns a DateTime with second precision
+func NewDateTime(at time.Time) *stu3dt.DateTime {
+	return &stu3dt.DateTime{
+		ValueUs:   at.UnixNano() / 1000,
+		Timezone:  fhirutil.TimeZone(at),
+		Precision: stu3dt.DateTime_SECOND,
+	}
+}
+
+// NewDate returns a Date with day precision
+func NewDate(at time.Time) *stu3dt.Date {
+	return &stu3dt.Date{
+		ValueUs:   time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location()).UnixNano() / 1000,
+		Timezone:  fhirutil.TimeZone(at),
+		Precision: stu3dt.Date_DAY,
+	}
+}
+
+// NewInstant returns an Instant with microsecond precision
+func NewInstant(at time.Time) *stu3dt.Instant {
+	return &stu3dt.Instant{
+		ValueUs:   at.UnixNano() / 1000,
+		Timezone:  fhirutil.TimeZone(at),
+		Precision: stu3dt.Instant_MICROSECOND,
+	}
+}
+
+// NewPeriod returns a Period. A zero end time leaves the Period open ended
+func NewPeriod(start, end time.Time) *stu3dt.Period {
+	period := &stu3dt.Period{Start: NewDateTime(start)}
+	if !end.IsZero() {
+		period.End = NewDateTime(end)
+	}
+	return period
+}
+
+// NewUCUMQuantity returns a Quantity expressed in the UCUM unit code
+func NewUCUMQuantity(value float64, unit string) (*stu3dt.Quantity, error) {
+	if !fhirutil.ValidUCUM(unit) {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidUnit, unit)
+	}
+	return &stu3dt.Quantity{
+		Value:  &stu3dt.Decimal{Value: fhirutil.FormatDecimal(value)},
+		Unit:   &stu3dt.String{Value: unit},
+		System: &stu3dt.Uri{Value: UCUMSystem},
+		Code:   &stu3dt.Code{Value: unit},
+	}, nil
+}
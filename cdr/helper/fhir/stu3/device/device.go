@@ -0,0 +1,105 @@
+// Package device contains a builder for FHIR STU3 Device resources
+package device
+
+import (
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+type WithFunc func(resource *stu3pb.Device) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Identifier = append(resource.Identifier, stu3.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status: active, inactive, entered-in-error or unknown
+func WithStatus(status string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Status = &stu3cd.FHIRDeviceStatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithUDI sets the UDI with the device identifier and human readable form
+func WithUDI(deviceIdentifier, carrierHRF string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		udi := &stu3pb.Device_Udi{
+			DeviceIdentifier: &stu3dt.String{Value: deviceIdentifier},
+		}
+		if carrierHRF != "" {
+			udi.CarrierHrf = &stu3dt.String{Value: carrierHRF}
+		}
+		resource.Udi = udi
+		return nil
+	}
+}
+
+func WithManufacturer(manufacturer string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Manufacturer = &stu3dt.String{Value: manufacturer}
+		return nil
+	}
+}
+
+func WithModel(model string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Model = &stu3dt.String{Value: model}
+		return nil
+	}
+}
+
+func WithVersion(version string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Version = &stu3dt.String{Value: version}
+		return nil
+	}
+}
+
+func WithType(system, code, display string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Type = stu3.NewCodeableConcept(system, code, display)
+		return nil
+	}
+}
+
+// WithPatient sets the reference to the patient the device is affixed to
+func WithPatient(reference string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Patient = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// WithOwner sets the reference to the organization responsible for the device
+func WithOwner(reference string) WithFunc {
+	return func(resource *stu3pb.Device) error {
+		resource.Owner = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// NewDevice creates a FHIR Device proto resource
+// The WithFunc option methods should be used to build the structure
+func NewDevice(options ...WithFunc) (*stu3pb.Device, error) {
+	resource := &stu3pb.Device{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	if resource.Udi != nil {
+		missing.Check(resource.Udi.DeviceIdentifier.GetValue() != "", "udi.deviceIdentifier")
+	}
+	if err := fhirutil.Require("Device", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
@@ -0,0 +1,34 @@
+package device_test
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/device"
+	"github.com/stretchr/testify/assert"
+)
+
+var ma, _ = jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+
+func TestNewDevice(t *testing.T) {
+	d, err := device.NewDevice(
+		device.WithIdentifier("https://example.com/serial", "SN-1", "official"),
+		device.WithStatus("active"),
+		device.WithUDI("00844588003288", "(01)00844588003288"),
+		device.WithManufacturer("Philips"),
+		device.WithModel("FM-2000"),
+		device.WithVersion("1.2.3"),
+		device.WithPatient("Patient/p1"),
+		device.WithOwner("Organization/o1"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = ma.MarshalResource(d)
+	assert.Nil(t, err)
+
+	_, err = device.NewDevice(device.WithUDI("", "(01)"))
+	assert.ErrorIs(t, err, stu3.ErrMissingRequiredElement)
+}
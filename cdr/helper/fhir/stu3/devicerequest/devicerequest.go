@@ -0,0 +1,109 @@
+// Package devicerequest contains a builder for FHIR STU3 DeviceRequest resources
+package devicerequest
+
+import (
+	"time"
+
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+const (
+	// IntentSystem is the system of the request intents, e.g. proposal, plan or order
+	IntentSystem = "http://hl7.org/fhir/request-intent"
+)
+
+type WithFunc func(resource *stu3pb.DeviceRequest) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		resource.Identifier = append(resource.Identifier, stu3.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. draft, active or completed
+func WithStatus(status string) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		resource.Status = &stu3cd.RequestStatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithIntent sets the intent, e.g. proposal, plan or order
+func WithIntent(intent string) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		// STU3 binds intent to a CodeableConcept, validate it against the request-intent codes
+		if err := fhirutil.SetCode(&stu3cd.RequestIntentCode{}, intent); err != nil {
+			return err
+		}
+		resource.Intent = stu3.NewCodeableConcept(IntentSystem, intent, "")
+		return nil
+	}
+}
+
+// WithCodeReference sets the requested device by reference, e.g. Device/123
+func WithCodeReference(reference string) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		resource.Code = &stu3pb.DeviceRequest_Code{
+			Code: &stu3pb.DeviceRequest_Code_Reference{Reference: stu3.NewReference(reference)},
+		}
+		return nil
+	}
+}
+
+// WithCodeConcept sets the requested device by code
+func WithCodeConcept(system, code, display string) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		resource.Code = &stu3pb.DeviceRequest_Code{
+			Code: &stu3pb.DeviceRequest_Code_CodeableConcept{CodeableConcept: stu3.NewCodeableConcept(system, code, display)},
+		}
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		resource.Subject = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// WithRequester sets the reference to the requesting agent, e.g. Practitioner/123
+func WithRequester(reference string) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		resource.Requester = &stu3pb.DeviceRequest_Requester{Agent: stu3.NewReference(reference)}
+		return nil
+	}
+}
+
+func WithAuthoredOn(at time.Time) WithFunc {
+	return func(resource *stu3pb.DeviceRequest) error {
+		resource.AuthoredOn = stu3.NewDateTime(at)
+		return nil
+	}
+}
+
+// NewDeviceRequest creates a FHIR DeviceRequest proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when intent, code or subject are missing
+func NewDeviceRequest(options ...WithFunc) (*stu3pb.DeviceRequest, error) {
+	resource := &stu3pb.DeviceRequest{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Intent != nil, "intent")
+	missing.Check(resource.Code != nil, "code")
+	missing.Check(resource.Subject != nil, "subject")
+	if err := fhirutil.Require("DeviceRequest", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
@@ -0,0 +1,36 @@
+package devicerequest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/devicerequest"
+	"github.com/stretchr/testify/assert"
+)
+
+var ma, _ = jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+
+func TestNewDeviceRequest(t *testing.T) {
+	dr, err := devicerequest.NewDeviceRequest(
+		devicerequest.WithStatus("active"),
+		devicerequest.WithIntent("order"),
+		devicerequest.WithCodeReference("Device/d1"),
+		devicerequest.WithSubject("Patient/p1"),
+		devicerequest.WithRequester("Practitioner/pr1"),
+		devicerequest.WithAuthoredOn(time.Now()),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, devicerequest.IntentSystem, dr.Intent.Coding[0].System.Value)
+	_, err = ma.MarshalResource(dr)
+	assert.Nil(t, err)
+
+	_, err = devicerequest.NewDeviceRequest(devicerequest.WithIntent("wish"))
+	assert.ErrorIs(t, err, stu3.ErrInvalidCode)
+	_, err = devicerequest.NewDeviceRequest(devicerequest.WithIntent("plan"))
+	assert.ErrorIs(t, err, stu3.ErrMissingRequiredElement)
+}
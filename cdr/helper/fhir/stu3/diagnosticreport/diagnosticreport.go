@@ -0,0 +1,120 @@
+// Package diagnosticreport contains a builder for FHIR STU3 DiagnosticReport resources
+package diagnosticreport
+
+import (
+	"time"
+
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+type WithFunc func(resource *stu3pb.DiagnosticReport) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Identifier = append(resource.Identifier, stu3.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. registered, partial, preliminary or final
+func WithStatus(status string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Status = &stu3cd.DiagnosticReportStatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithCode adds a coding to the report code
+func WithCode(system, code, display string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		if resource.Code == nil {
+			resource.Code = &stu3dt.CodeableConcept{}
+		}
+		resource.Code.Coding = append(resource.Code.Coding, stu3.NewCoding(system, code, display))
+		return nil
+	}
+}
+
+// WithLOINCCode adds a LOINC coding to the report code
+func WithLOINCCode(code, display string) WithFunc {
+	return WithCode(stu3.LOINCSystem, code, display)
+}
+
+// WithCategory sets the category. STU3 allows only one
+func WithCategory(system, code, display string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Category = stu3.NewCodeableConcept(system, code, display)
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Subject = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+func WithEffectiveDateTime(at time.Time) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Effective = &stu3pb.DiagnosticReport_Effective{
+			Effective: &stu3pb.DiagnosticReport_Effective_DateTime{DateTime: stu3.NewDateTime(at)},
+		}
+		return nil
+	}
+}
+
+func WithIssued(at time.Time) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Issued = stu3.NewInstant(at)
+		return nil
+	}
+}
+
+// WithResult adds a reference to a result Observation, e.g. Observation/123
+func WithResult(reference string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Result = append(resource.Result, stu3.NewReference(reference))
+		return nil
+	}
+}
+
+// WithImagingStudy adds a reference to an ImagingStudy
+func WithImagingStudy(reference string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.ImagingStudy = append(resource.ImagingStudy, stu3.NewReference(reference))
+		return nil
+	}
+}
+
+func WithConclusion(conclusion string) WithFunc {
+	return func(resource *stu3pb.DiagnosticReport) error {
+		resource.Conclusion = &stu3dt.String{Value: conclusion}
+		return nil
+	}
+}
+
+// NewDiagnosticReport creates a FHIR DiagnosticReport proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status or code are missing
+func NewDiagnosticReport(options ...WithFunc) (*stu3pb.DiagnosticReport, error) {
+	resource := &stu3pb.DiagnosticReport{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.Code != nil, "code")
+	if err := fhirutil.Require("DiagnosticReport", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
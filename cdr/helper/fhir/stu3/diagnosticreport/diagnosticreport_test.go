@@ -0,0 +1,36 @@
+package diagnosticreport_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/diagnosticreport"
+	"github.com/stretchr/testify/assert"
+)
+
+var ma, _ = jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+
+func TestNewDiagnosticReport(t *testing.T) {
+	dr, err := diagnosticreport.NewDiagnosticReport(
+		diagnosticreport.WithStatus("final"),
+		diagnosticreport.WithLOINCCode("58410-2", "CBC panel"),
+		diagnosticreport.WithCategory("http://hl7.org/fhir/v2/0074", "HM", "Hematology"),
+		diagnosticreport.WithSubject("Patient/p1"),
+		diagnosticreport.WithEffectiveDateTime(time.Now()),
+		diagnosticreport.WithIssued(time.Now()),
+		diagnosticreport.WithResult("Observation/o1"),
+		diagnosticreport.WithConclusion("normal"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = ma.MarshalResource(dr)
+	assert.Nil(t, err)
+
+	_, err = diagnosticreport.NewDiagnosticReport(diagnosticreport.WithLOINCCode("58410-2", ""))
+	assert.ErrorIs(t, err, stu3.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "DiagnosticReport.status")
+}
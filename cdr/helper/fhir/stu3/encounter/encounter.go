@@ -0,0 +1,100 @@
+// Package encounter contains a builder for FHIR STU3 Encounter resources
+package encounter
+
+import (
+	"time"
+
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+const (
+	// ClassSystem is the system of the HL7 v3 ActCode encounter classes, e.g. AMB or IMP
+	ClassSystem = "http://hl7.org/fhir/v3/ActCode"
+)
+
+type WithFunc func(resource *stu3pb.Encounter) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.Identifier = append(resource.Identifier, stu3.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. planned, arrived, in-progress or finished
+func WithStatus(status string) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.Status = &stu3cd.EncounterStatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithClass sets the encounter class. Use ClassSystem for the standard classes
+func WithClass(system, code, display string) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.ClassValue = stu3.NewCoding(system, code, display)
+		return nil
+	}
+}
+
+func WithType(system, code, display string) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.Type = append(resource.Type, stu3.NewCodeableConcept(system, code, display))
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.Subject = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// WithParticipant adds a reference to an individual involved, e.g. Practitioner/123
+func WithParticipant(reference string) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.Participant = append(resource.Participant, &stu3pb.Encounter_Participant{
+			Individual: stu3.NewReference(reference),
+		})
+		return nil
+	}
+}
+
+// WithPeriod sets the start and end time. A zero end leaves the Encounter open ended
+func WithPeriod(start, end time.Time) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.Period = stu3.NewPeriod(start, end)
+		return nil
+	}
+}
+
+func WithServiceProvider(reference string) WithFunc {
+	return func(resource *stu3pb.Encounter) error {
+		resource.ServiceProvider = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// NewEncounter creates a FHIR Encounter proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status is missing
+func NewEncounter(options ...WithFunc) (*stu3pb.Encounter, error) {
+	resource := &stu3pb.Encounter{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	if err := fhirutil.Require("Encounter", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
@@ -0,0 +1,33 @@
+package encounter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/encounter"
+	"github.com/stretchr/testify/assert"
+)
+
+var ma, _ = jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+
+func TestNewEncounter(t *testing.T) {
+	e, err := encounter.NewEncounter(
+		encounter.WithStatus("in-progress"),
+		encounter.WithClass(encounter.ClassSystem, "AMB", "ambulatory"),
+		encounter.WithSubject("Patient/p1"),
+		encounter.WithParticipant("Practitioner/pr1"),
+		encounter.WithPeriod(time.Now().Add(-time.Hour), time.Now()),
+		encounter.WithServiceProvider("Organization/o1"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = ma.MarshalResource(e)
+	assert.Nil(t, err)
+
+	_, err = encounter.NewEncounter(encounter.WithSubject("Patient/p1"))
+	assert.ErrorIs(t, err, stu3.ErrMissingRequiredElement)
+}
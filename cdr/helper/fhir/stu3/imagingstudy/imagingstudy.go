@@ -0,0 +1,143 @@
+// Package imagingstudy contains a builder for FHIR STU3 ImagingStudy resources
+package imagingstudy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+// Instance describes a single SOP instance of a series
+type Instance struct {
+	UID         string
+	SOPClassUID string
+	Number      uint32
+}
+
+type WithFunc func(resource *stu3pb.ImagingStudy) error
+
+func oid(uid string) *stu3dt.Oid {
+	if uid == "" {
+		return nil
+	}
+	return &stu3dt.Oid{Value: "urn:oid:" + strings.TrimPrefix(uid, "urn:oid:")}
+}
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.ImagingStudy) error {
+		resource.Identifier = append(resource.Identifier, stu3.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStudyInstanceUID sets the DICOM Study Instance UID
+func WithStudyInstanceUID(uid string) WithFunc {
+	return func(resource *stu3pb.ImagingStudy) error {
+		resource.Uid = oid(uid)
+		return nil
+	}
+}
+
+// WithAvailability sets the availability: online, offline, nearline or unavailable
+func WithAvailability(availability string) WithFunc {
+	return func(resource *stu3pb.ImagingStudy) error {
+		resource.Availability = &stu3cd.InstanceAvailabilityCode{}
+		return fhirutil.SetCode(resource.Availability, availability)
+	}
+}
+
+// WithPatient sets the reference to the patient, e.g. Patient/123
+func WithPatient(reference string) WithFunc {
+	return func(resource *stu3pb.ImagingStudy) error {
+		resource.Patient = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+func WithStarted(at time.Time) WithFunc {
+	return func(resource *stu3pb.ImagingStudy) error {
+		resource.Started = stu3.NewDateTime(at)
+		return nil
+	}
+}
+
+func WithDescription(description string) WithFunc {
+	return func(resource *stu3pb.ImagingStudy) error {
+		resource.Description = &stu3dt.String{Value: description}
+		return nil
+	}
+}
+
+// WithSeries adds a series with the DICOM modality code, e.g. CT or MR, and its instances.
+// The study modality list and instance counts are updated accordingly
+func WithSeries(uid string, number uint32, modality string, instances ...Instance) WithFunc {
+	return func(resource *stu3pb.ImagingStudy) error {
+		series := &stu3pb.ImagingStudy_Series{
+			Uid:      oid(uid),
+			Number:   &stu3dt.UnsignedInt{Value: number},
+			Modality: stu3.NewCoding(stu3.DICOMSystem, modality, ""),
+		}
+		for _, i := range instances {
+			instance := &stu3pb.ImagingStudy_Series_Instance{
+				Uid:      oid(i.UID),
+				SopClass: oid(i.SOPClassUID),
+			}
+			if i.Number > 0 {
+				instance.Number = &stu3dt.UnsignedInt{Value: i.Number}
+			}
+			series.Instance = append(series.Instance, instance)
+		}
+		series.NumberOfInstances = &stu3dt.UnsignedInt{Value: uint32(len(series.Instance))}
+		resource.Series = append(resource.Series, series)
+
+		known := false
+		for _, m := range resource.ModalityList {
+			known = known || m.Code.GetValue() == modality
+		}
+		if !known && modality != "" {
+			resource.ModalityList = append(resource.ModalityList, stu3.NewCoding(stu3.DICOMSystem, modality, ""))
+		}
+		total := uint32(0)
+		for _, s := range resource.Series {
+			total += s.NumberOfInstances.GetValue()
+		}
+		resource.NumberOfSeries = &stu3dt.UnsignedInt{Value: uint32(len(resource.Series))}
+		resource.NumberOfInstances = &stu3dt.UnsignedInt{Value: total}
+		return nil
+	}
+}
+
+// NewImagingStudy creates a FHIR ImagingStudy proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when uid or patient are missing, or when a
+// series or instance lacks its UID, modality or SOP class
+func NewImagingStudy(options ...WithFunc) (*stu3pb.ImagingStudy, error) {
+	resource := &stu3pb.ImagingStudy{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Uid != nil, "uid")
+	missing.Check(resource.Patient != nil, "patient")
+	for i, s := range resource.Series {
+		missing.Check(s.Uid != nil, fmt.Sprintf("series[%d].uid", i))
+		missing.Check(s.Modality.GetCode().GetValue() != "", fmt.Sprintf("series[%d].modality", i))
+		for j, instance := range s.Instance {
+			missing.Check(instance.Uid != nil, fmt.Sprintf("series[%d].instance[%d].uid", i, j))
+			missing.Check(instance.SopClass != nil, fmt.Sprintf("series[%d].instance[%d].sopClass", i, j))
+		}
+	}
+	if err := fhirutil.Require("ImagingStudy", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
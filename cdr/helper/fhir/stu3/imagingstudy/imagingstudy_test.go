@@ -0,0 +1,37 @@
+package imagingstudy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/imagingstudy"
+	"github.com/stretchr/testify/assert"
+)
+
+var ma, _ = jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+
+func TestNewImagingStudy(t *testing.T) {
+	is, err := imagingstudy.NewImagingStudy(
+		imagingstudy.WithStudyInstanceUID("1.2.840.113619.2.55.3"),
+		imagingstudy.WithAvailability("online"),
+		imagingstudy.WithPatient("Patient/p1"),
+		imagingstudy.WithStarted(time.Now()),
+		imagingstudy.WithSeries("1.2.840.113619.2.55.3.1", 1, "CT",
+			imagingstudy.Instance{UID: "1.2.840.113619.2.55.3.1.1", SOPClassUID: "1.2.840.10008.5.1.4.1.1.2", Number: 1},
+		),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "urn:oid:1.2.840.113619.2.55.3", is.Uid.Value)
+	assert.Equal(t, uint32(1), is.NumberOfInstances.Value)
+	_, err = ma.MarshalResource(is)
+	assert.Nil(t, err)
+
+	_, err = imagingstudy.NewImagingStudy(imagingstudy.WithPatient("Patient/p1"))
+	assert.ErrorIs(t, err, stu3.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "ImagingStudy.uid")
+}
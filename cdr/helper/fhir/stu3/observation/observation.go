@@ -0,0 +1,151 @@
+// Package observation contains a builder for FHIR STU3 Observation resources
+package observation
+
+import (
+	"time"
+
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+const (
+	// CategorySystem is the system of the standard observation categories, e.g. vital-signs
+	CategorySystem = "http://hl7.org/fhir/observation-category"
+)
+
+type WithFunc func(resource *stu3pb.Observation) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Identifier = append(resource.Identifier, stu3.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+// WithStatus sets the status, e.g. registered, preliminary, final or amended
+func WithStatus(status string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Status = &stu3cd.ObservationStatusCode{}
+		return fhirutil.SetCode(resource.Status, status)
+	}
+}
+
+// WithCode adds a coding to the Observation code
+func WithCode(system, code, display string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		if resource.Code == nil {
+			resource.Code = &stu3dt.CodeableConcept{}
+		}
+		resource.Code.Coding = append(resource.Code.Coding, stu3.NewCoding(system, code, display))
+		return nil
+	}
+}
+
+// WithLOINCCode adds a LOINC coding to the Observation code
+func WithLOINCCode(code, display string) WithFunc {
+	return WithCode(stu3.LOINCSystem, code, display)
+}
+
+// WithCategory adds a category. Use CategorySystem for the standard categories
+func WithCategory(system, code, display string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Category = append(resource.Category, stu3.NewCodeableConcept(system, code, display))
+		return nil
+	}
+}
+
+// WithSubject sets the reference to the subject, e.g. Patient/123
+func WithSubject(reference string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Subject = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// WithDevice sets the reference to the device which made the observation
+func WithDevice(reference string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Device = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+func WithEffectiveDateTime(at time.Time) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Effective = &stu3pb.Observation_Effective{
+			Effective: &stu3pb.Observation_Effective_DateTime{DateTime: stu3.NewDateTime(at)},
+		}
+		return nil
+	}
+}
+
+func WithIssued(at time.Time) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Issued = stu3.NewInstant(at)
+		return nil
+	}
+}
+
+// WithUCUMQuantity sets the value to a Quantity in the given UCUM unit, e.g. kg or mm[Hg]
+func WithUCUMQuantity(value float64, unit string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		quantity, err := stu3.NewUCUMQuantity(value, unit)
+		if err != nil {
+			return err
+		}
+		resource.Value = &stu3pb.Observation_Value{
+			Value: &stu3pb.Observation_Value_Quantity{Quantity: quantity},
+		}
+		return nil
+	}
+}
+
+func WithValueString(value string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		resource.Value = &stu3pb.Observation_Value{
+			Value: &stu3pb.Observation_Value_StringValue{StringValue: &stu3dt.String{Value: value}},
+		}
+		return nil
+	}
+}
+
+// WithLOINCComponent adds a component with a LOINC code and UCUM quantity,
+// e.g. the systolic and diastolic values of a blood pressure panel
+func WithLOINCComponent(code, display string, value float64, unit string) WithFunc {
+	return func(resource *stu3pb.Observation) error {
+		quantity, err := stu3.NewUCUMQuantity(value, unit)
+		if err != nil {
+			return err
+		}
+		resource.Component = append(resource.Component, &stu3pb.Observation_Component{
+			Code: stu3.NewCodeableConcept(stu3.LOINCSystem, code, display),
+			Value: &stu3pb.Observation_Component_Value{
+				Value: &stu3pb.Observation_Component_Value_Quantity{Quantity: quantity},
+			},
+		})
+		return nil
+	}
+}
+
+// NewObservation creates a FHIR Observation proto resource
+// The WithFunc option methods should be used to build the structure.
+// An error is returned when status or code are missing
+func NewObservation(options ...WithFunc) (*stu3pb.Observation, error) {
+	resource := &stu3pb.Observation{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	var missing fhirutil.Missing
+	missing.Check(resource.Status != nil, "status")
+	missing.Check(resource.Code != nil, "code")
+	if err := fhirutil.Require("Observation", missing...); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
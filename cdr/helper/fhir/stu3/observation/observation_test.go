@@ -0,0 +1,42 @@
+package observation_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3/observation"
+	"github.com/stretchr/testify/assert"
+)
+
+var ma, _ = jsonformat.NewMarshaller(false, "", "", fhirversion.STU3)
+
+func TestNewObservation(t *testing.T) {
+	obs, err := observation.NewObservation(
+		observation.WithStatus("final"),
+		observation.WithCategory(observation.CategorySystem, "vital-signs", "Vital Signs"),
+		observation.WithLOINCCode("85354-9", "Blood pressure panel"),
+		observation.WithSubject("Patient/p1"),
+		observation.WithEffectiveDateTime(time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)),
+		observation.WithLOINCComponent("8480-6", "Systolic blood pressure", 120, "mm[Hg]"),
+		observation.WithLOINCComponent("8462-4", "Diastolic blood pressure", 80, "mm[Hg]"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Len(t, obs.Component, 2) {
+		return
+	}
+	assert.Equal(t, "mm[Hg]", obs.Component[0].Value.GetQuantity().Code.Value)
+	_, err = ma.MarshalResource(obs)
+	assert.Nil(t, err)
+
+	_, err = observation.NewObservation(observation.WithUCUMQuantity(1, "k g"))
+	assert.ErrorIs(t, err, stu3.ErrInvalidUnit)
+
+	_, err = observation.NewObservation(observation.WithStatus("final"))
+	assert.ErrorIs(t, err, stu3.ErrMissingRequiredElement)
+	assert.Contains(t, err.Error(), "Observation.code")
+}
@@ -0,0 +1,71 @@
+// Package patient contains a builder for FHIR STU3 Patient resources
+package patient
+
+import (
+	"time"
+
+	stu3cd "github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/internal/fhirutil"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+type WithFunc func(resource *stu3pb.Patient) error
+
+func WithIdentifier(system, value, use string) WithFunc {
+	return func(resource *stu3pb.Patient) error {
+		resource.Identifier = append(resource.Identifier, stu3.NewIdentifier(system, value, use))
+		return nil
+	}
+}
+
+func WithName(text, family string, given []string) WithFunc {
+	return func(resource *stu3pb.Patient) error {
+		resource.Name = append(resource.Name, stu3.NewHumanName(text, family, given))
+		return nil
+	}
+}
+
+// WithGender sets the administrative gender: male, female, other or unknown
+func WithGender(gender string) WithFunc {
+	return func(resource *stu3pb.Patient) error {
+		resource.Gender = &stu3cd.AdministrativeGenderCode{}
+		return fhirutil.SetCode(resource.Gender, gender)
+	}
+}
+
+func WithBirthDate(at time.Time) WithFunc {
+	return func(resource *stu3pb.Patient) error {
+		resource.BirthDate = stu3.NewDate(at)
+		return nil
+	}
+}
+
+func WithActive(active bool) WithFunc {
+	return func(resource *stu3pb.Patient) error {
+		resource.Active = &stu3dt.Boolean{Value: active}
+		return nil
+	}
+}
+
+// WithManagingOrganization sets the reference to the custodian organization, e.g. Organization/123
+func WithManagingOrganization(reference string) WithFunc {
+	return func(resource *stu3pb.Patient) error {
+		resource.ManagingOrganization = stu3.NewReference(reference)
+		return nil
+	}
+}
+
+// NewPatient creates a FHIR Patient proto resource
+// The WithFunc option methods should be used to build the structure
+func NewPatient(options ...WithFunc) (*stu3pb.Patient, error) {
+	resource := &stu3pb.Patient{}
+
+	for _, w := range options {
+		if err := w(resource); err != nil {
+			return nil, err
+		}
+	}
+	return resource, nil
+}
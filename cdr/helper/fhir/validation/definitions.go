@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"strings"
+)
+
+type structureDefinition struct {
+	ResourceType   string `json:"resourceType"`
+	URL            string `json:"url"`
+	Type           string `json:"type"`
+	BaseDefinition string `json:"baseDefinition"`
+	Snapshot       *struct {
+		Element []elementDefinition `json:"element"`
+	} `json:"snapshot"`
+	Differential *struct {
+		Element []elementDefinition `json:"element"`
+	} `json:"differential"`
+}
+
+type elementDefinition struct {
+	ID         string       `json:"id"`
+	Path       string       `json:"path"`
+	SliceName  string       `json:"sliceName"`
+	Min        *int         `json:"min"`
+	Max        string       `json:"max"`
+	Type       []typeRef    `json:"type"`
+	Binding    *binding     `json:"binding"`
+	Constraint []constraint `json:"constraint"`
+}
+
+type typeRef struct {
+	Code string `json:"code"`
+}
+
+type binding struct {
+	Strength string `json:"strength"`
+	// ValueSet is the R4 canonical of the value set
+	ValueSet string `json:"valueSet"`
+	// ValueSetReference and ValueSetURI are the STU3 alternatives
+	ValueSetReference *struct {
+		Reference string `json:"reference"`
+	} `json:"valueSetReference"`
+	ValueSetURI string `json:"valueSetUri"`
+}
+
+func (b binding) url() string {
+	url := b.ValueSet
+	if url == "" && b.ValueSetReference != nil {
+		url = b.ValueSetReference.Reference
+	}
+	if url == "" {
+		url = b.ValueSetURI
+	}
+	return canonical(url)
+}
+
+type constraint struct {
+	Key        string `json:"key"`
+	Severity   string `json:"severity"`
+	Human      string `json:"human"`
+	Expression string `json:"expression"`
+}
+
+// sliced reports whether the element describes a slice, which is not validated
+func (e elementDefinition) sliced() bool {
+	return e.SliceName != "" || strings.Contains(e.ID, ":")
+}
+
+// merge applies a differential element on top of its base element
+func (e elementDefinition) merge(diff elementDefinition) elementDefinition {
+	if diff.Min != nil {
+		e.Min = diff.Min
+	}
+	if diff.Max != "" {
+		e.Max = diff.Max
+	}
+	if len(diff.Type) > 0 {
+		e.Type = diff.Type
+	}
+	if diff.Binding != nil {
+		e.Binding = diff.Binding
+	}
+	e.Constraint = append(append([]constraint{}, e.Constraint...), diff.Constraint...)
+	return e
+}
+
+type valueSetDefinition struct {
+	ResourceType string `json:"resourceType"`
+	URL          string `json:"url"`
+	Compose      *struct {
+		Include []include `json:"include"`
+		Exclude []include `json:"exclude"`
+	} `json:"compose"`
+	Expansion *struct {
+		Contains []expansionContains `json:"contains"`
+	} `json:"expansion"`
+}
+
+type include struct {
+	System   string   `json:"system"`
+	ValueSet []string `json:"valueSet"`
+	Concept  []struct {
+		Code string `json:"code"`
+	} `json:"concept"`
+	Filter []interface{} `json:"filter"`
+}
+
+type expansionContains struct {
+	System   string              `json:"system"`
+	Code     string              `json:"code"`
+	Contains []expansionContains `json:"contains"`
+}
+
+type codeSystemDefinition struct {
+	ResourceType string          `json:"resourceType"`
+	URL          string          `json:"url"`
+	Concept      []systemConcept `json:"concept"`
+}
+
+type systemConcept struct {
+	Code    string          `json:"code"`
+	Concept []systemConcept `json:"concept"`
+}
+
+func (c systemConcept) codes(add func(code string)) {
+	add(c.Code)
+	for _, child := range c.Concept {
+		child.codes(add)
+	}
+}
+
+// canonical strips the version from a canonical URL such as http://hl7.org/fhir/ValueSet/x|4.0.1
+func canonical(url string) string {
+	if i := strings.Index(url, "|"); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
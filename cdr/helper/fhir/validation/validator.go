@@ -0,0 +1,562 @@
+// Package validation validates CDR resources against FHIR profiles
+//
+// StructureDefinitions, ValueSets and CodeSystems are loaded from their FHIR
+// JSON representation. Resources are checked for element cardinality, choice
+// types, bindings to the loaded ValueSets and the FHIRPath invariants of the
+// profile. The outcome is reported as an OperationOutcome.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/fhirpath"
+	"github.com/philips-software/go-hsdp-api/internal"
+)
+
+// OperationOutcome reports the validation issues
+type OperationOutcome = internal.OperationOutcome
+
+// Issue is a single validation issue
+type Issue = internal.Issue
+
+const (
+	SeverityFatal       = "fatal"
+	SeverityError       = "error"
+	SeverityWarning     = "warning"
+	SeverityInformation = "information"
+
+	// CoreProfilePrefix is the canonical URL prefix of the base resource definitions
+	CoreProfilePrefix = "http://hl7.org/fhir/StructureDefinition/"
+)
+
+var (
+	ErrUnknownProfile      = errors.New("unknown profile")
+	ErrInvalidDefinition   = errors.New("invalid definition")
+	ErrUnsupportedResource = errors.New("unsupported definition resource")
+	errUnknownValueSet     = errors.New("value set not loaded")
+)
+
+const (
+	maxBaseDefinitionDepth  = 16
+	maxValueSetIncludeDepth = 16
+)
+
+// Validator validates resources against the loaded profiles. It is safe for concurrent use
+type Validator struct {
+	mu          sync.RWMutex
+	profiles    map[string]*structureDefinition
+	valueSets   map[string]*valueSetDefinition
+	codeSystems map[string]map[string]bool
+	expressions map[string]*fhirpath.Expression
+	compileErrs map[string]error
+}
+
+// NewValidator returns a Validator without any definitions loaded
+func NewValidator() *Validator {
+	return &Validator{
+		profiles:    make(map[string]*structureDefinition),
+		valueSets:   make(map[string]*valueSetDefinition),
+		codeSystems: make(map[string]map[string]bool),
+		expressions: make(map[string]*fhirpath.Expression),
+		compileErrs: make(map[string]error),
+	}
+}
+
+// Load adds a StructureDefinition, ValueSet or CodeSystem in FHIR JSON format.
+// A Bundle loads each of its entries
+func (v *Validator) Load(data []byte) error {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+	switch header.ResourceType {
+	case "Bundle":
+		for _, entry := range header.Entry {
+			if len(entry.Resource) == 0 {
+				continue
+			}
+			if err := v.Load(entry.Resource); err != nil && !errors.Is(err, ErrUnsupportedResource) {
+				return err
+			}
+		}
+		return nil
+	case "StructureDefinition":
+		var sd structureDefinition
+		if err := json.Unmarshal(data, &sd); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+		}
+		if sd.URL == "" || sd.Type == "" {
+			return fmt.Errorf("%w: StructureDefinition requires url and type", ErrInvalidDefinition)
+		}
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.profiles[canonical(sd.URL)] = &sd
+		for _, elements := range [][]elementDefinition{sd.elements(true), sd.elements(false)} {
+			for _, e := range elements {
+				for _, c := range e.Constraint {
+					v.compile(c.Expression)
+				}
+			}
+		}
+		return nil
+	case "ValueSet":
+		var vs valueSetDefinition
+		if err := json.Unmarshal(data, &vs); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+		}
+		if vs.URL == "" {
+			return fmt.Errorf("%w: ValueSet requires url", ErrInvalidDefinition)
+		}
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.valueSets[canonical(vs.URL)] = &vs
+		return nil
+	case "CodeSystem":
+		var cs codeSystemDefinition
+		if err := json.Unmarshal(data, &cs); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+		}
+		if cs.URL == "" {
+			return fmt.Errorf("%w: CodeSystem requires url", ErrInvalidDefinition)
+		}
+		codes := make(map[string]bool)
+		for _, c := range cs.Concept {
+			c.codes(func(code string) { codes[code] = true })
+		}
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.codeSystems[canonical(cs.URL)] = codes
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedResource, header.ResourceType)
+}
+
+func (v *Validator) compile(expression string) {
+	if expression == "" {
+		return
+	}
+	if _, ok := v.expressions[expression]; ok {
+		return
+	}
+	e, err := fhirpath.Compile(expression)
+	v.expressions[expression] = e
+	if err != nil {
+		v.compileErrs[expression] = err
+	}
+}
+
+// Validate validates a resource in FHIR JSON format. When no profiles are
+// given the resource is validated against its meta.profile entries and the
+// base definition of its type, as far as they are loaded
+func (v *Validator) Validate(resource []byte, profiles ...string) (*OperationOutcome, error) {
+	root, err := fhirpath.Parse(resource)
+	if err != nil {
+		return nil, err
+	}
+	return v.validate(root, profiles)
+}
+
+// ValidateR4 validates an R4 resource, see Validate
+func (v *Validator) ValidateR4(resource *r4pb.ContainedResource, profiles ...string) (*OperationOutcome, error) {
+	root, err := fhirpath.FromR4(resource)
+	if err != nil {
+		return nil, err
+	}
+	return v.validate(root, profiles)
+}
+
+// ValidateSTU3 validates an STU3 resource, see Validate
+func (v *Validator) ValidateSTU3(resource *stu3pb.ContainedResource, profiles ...string) (*OperationOutcome, error) {
+	root, err := fhirpath.FromSTU3(resource)
+	if err != nil {
+		return nil, err
+	}
+	return v.validate(root, profiles)
+}
+
+// HasErrors reports whether the outcome contains error or fatal issues
+func HasErrors(outcome *OperationOutcome) bool {
+	if outcome == nil {
+		return false
+	}
+	for _, i := range outcome.Issue {
+		if i.Severity == SeverityError || i.Severity == SeverityFatal {
+			return true
+		}
+	}
+	return false
+}
+
+// run holds the state of validating a resource against a single profile
+type run struct {
+	v         *Validator
+	root      fhirpath.Node
+	outcome   *OperationOutcome
+	instances map[string][]fhirpath.Node
+	reported  map[string]bool
+}
+
+func (v *Validator) validate(root fhirpath.Node, profiles []string) (*OperationOutcome, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	r := &run{
+		v:        v,
+		root:     root,
+		outcome:  &OperationOutcome{ResourceType: "OperationOutcome"},
+		reported: make(map[string]bool),
+	}
+	for _, url := range profiles {
+		if _, ok := v.profiles[canonical(url)]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, url)
+		}
+	}
+	if len(profiles) == 0 {
+		var declared []fhirpath.Node
+		for _, meta := range root.Children("meta") {
+			declared = append(declared, meta.Children("profile")...)
+		}
+		for _, p := range declared {
+			url, _ := p.Value.(string)
+			if _, ok := v.profiles[canonical(url)]; !ok {
+				r.report(SeverityInformation, "not-supported", fmt.Sprintf("Profile %s is not loaded and was not checked", url), root.Location)
+				continue
+			}
+			profiles = append(profiles, url)
+		}
+		if _, ok := v.profiles[CoreProfilePrefix+root.Type]; ok {
+			profiles = append(profiles, CoreProfilePrefix+root.Type)
+		}
+	}
+	if len(profiles) == 0 {
+		r.report(SeverityWarning, "not-supported", fmt.Sprintf("No profile loaded for %s", root.Type), root.Location)
+		return r.outcome, nil
+	}
+	for _, url := range profiles {
+		elements, sd, err := v.elements(canonical(url), 0)
+		if err != nil {
+			return nil, err
+		}
+		if sd.Type != root.Type {
+			r.report(SeverityError, "structure", fmt.Sprintf("Resource type %s does not match type %s of profile %s", root.Type, sd.Type, url), root.Location)
+			continue
+		}
+		r.instances = map[string][]fhirpath.Node{sd.Type: {root}}
+		for _, e := range elements {
+			if e.sliced() {
+				continue
+			}
+			r.check(e)
+		}
+	}
+	if len(r.outcome.Issue) == 0 {
+		r.report(SeverityInformation, "informational", "No issues detected during validation")
+	}
+	return r.outcome, nil
+}
+
+// elements returns the snapshot of a profile, or its differential applied to the base definition
+func (v *Validator) elements(url string, depth int) ([]elementDefinition, *structureDefinition, error) {
+	sd, ok := v.profiles[url]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownProfile, url)
+	}
+	if snapshot := sd.elements(true); len(snapshot) > 0 {
+		return snapshot, sd, nil
+	}
+	var base []elementDefinition
+	if _, ok := v.profiles[canonical(sd.BaseDefinition)]; ok && depth < maxBaseDefinitionDepth {
+		var err error
+		if base, _, err = v.elements(canonical(sd.BaseDefinition), depth+1); err != nil {
+			return nil, nil, err
+		}
+	}
+	merged := append([]elementDefinition{}, base...)
+	for _, diff := range sd.elements(false) {
+		found := false
+		for i := range merged {
+			if merged[i].key() == diff.key() {
+				merged[i] = merged[i].merge(diff)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, diff)
+		}
+	}
+	return merged, sd, nil
+}
+
+func (sd *structureDefinition) elements(snapshot bool) []elementDefinition {
+	if snapshot && sd.Snapshot != nil {
+		return sd.Snapshot.Element
+	}
+	if !snapshot && sd.Differential != nil {
+		return sd.Differential.Element
+	}
+	return nil
+}
+
+func (e elementDefinition) key() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return e.Path
+}
+
+// nodes returns the instances of an element path in the resource
+func (r *run) nodes(path string) []fhirpath.Node {
+	if nodes, ok := r.instances[path]; ok {
+		return nodes
+	}
+	i := strings.LastIndex(path, ".")
+	if i < 0 {
+		return nil
+	}
+	var nodes []fhirpath.Node
+	for _, parent := range r.nodes(path[:i]) {
+		nodes = append(nodes, parent.Children(path[i+1:])...)
+	}
+	r.instances[path] = nodes
+	return nodes
+}
+
+func (r *run) check(e elementDefinition) {
+	i := strings.LastIndex(e.Path, ".")
+	if i < 0 {
+		r.checkConstraints(e, r.nodes(e.Path))
+		return
+	}
+	name := e.Path[i+1:]
+	for _, parent := range r.nodes(e.Path[:i]) {
+		children := parent.Children(name)
+		location := parent.Location + "." + strings.TrimSuffix(name, "[x]")
+		if e.Min != nil && len(children) < *e.Min {
+			r.report(SeverityError, "required", fmt.Sprintf("%s: minimum required = %d, but only found %d", e.Path, *e.Min, len(children)), location)
+		}
+		if max, err := strconv.Atoi(e.Max); err == nil && len(children) > max {
+			r.report(SeverityError, "structure", fmt.Sprintf("%s: max allowed = %d, but found %d", e.Path, max, len(children)), location)
+		}
+	}
+	nodes := r.nodes(e.Path)
+	if strings.HasSuffix(e.Path, "[x]") && len(e.Type) > 0 {
+		r.checkTypes(e, nodes)
+	}
+	if e.Binding != nil {
+		r.checkBinding(e, nodes)
+	}
+	r.checkConstraints(e, nodes)
+}
+
+func (r *run) checkTypes(e elementDefinition, nodes []fhirpath.Node) {
+	var allowed []string
+	for _, t := range e.Type {
+		allowed = append(allowed, t.Code)
+	}
+	for _, n := range nodes {
+		if n.Type == "" {
+			continue
+		}
+		ok := false
+		for _, t := range allowed {
+			ok = ok || t == n.Type
+		}
+		if !ok {
+			r.report(SeverityError, "structure", fmt.Sprintf("%s: type %s is not allowed, expected %s", e.Path, n.Type, strings.Join(allowed, " | ")), n.Location)
+		}
+	}
+}
+
+func (r *run) checkBinding(e elementDefinition, nodes []fhirpath.Node) {
+	var severity string
+	switch e.Binding.Strength {
+	case "required":
+		severity = SeverityError
+	case "extensible":
+		severity = SeverityWarning
+	default:
+		return
+	}
+	url := e.Binding.url()
+	if url == "" {
+		return
+	}
+	for _, n := range nodes {
+		codes := codings(n)
+		if len(codes) == 0 {
+			continue
+		}
+		found := false
+		for _, c := range codes {
+			ok, err := r.v.inValueSet(url, c.system, c.code, 0)
+			if err != nil {
+				r.report(SeverityInformation, "not-supported", fmt.Sprintf("ValueSet %s is not loaded, binding of %s was not checked", url, e.Path))
+				return
+			}
+			found = found || ok
+		}
+		if !found {
+			r.report(severity, "code-invalid", fmt.Sprintf("%s: the value provided (%s) is not in the value set %s", e.Path, codes, url), n.Location)
+		}
+	}
+}
+
+func (r *run) checkConstraints(e elementDefinition, nodes []fhirpath.Node) {
+	for _, c := range e.Constraint {
+		if c.Expression == "" {
+			continue
+		}
+		expression := r.v.expressions[c.Expression]
+		if err := r.v.compileErrs[c.Expression]; err != nil || expression == nil {
+			r.report(SeverityInformation, "not-supported", fmt.Sprintf("Constraint %s was not checked: %v", c.Key, err))
+			continue
+		}
+		severity := SeverityError
+		if c.Severity == SeverityWarning {
+			severity = SeverityWarning
+		}
+		for _, n := range nodes {
+			result, err := expression.EvaluateNode(r.root, n)
+			if err != nil {
+				r.report(SeverityInformation, "not-supported", fmt.Sprintf("Constraint %s was not checked: %v", c.Key, err))
+				break
+			}
+			if len(result) == 1 && result[0].Value == false {
+				r.report(severity, "invariant", fmt.Sprintf("Constraint failed: %s: '%s'", c.Key, c.Human), n.Location)
+			}
+		}
+	}
+}
+
+func (r *run) report(severity, code, diagnostics string, expression ...string) {
+	key := severity + code + diagnostics + strings.Join(expression, ",")
+	if r.reported[key] {
+		return
+	}
+	r.reported[key] = true
+	r.outcome.Issue = append(r.outcome.Issue, Issue{
+		Severity:    severity,
+		Code:        code,
+		Details:     internal.Details{Text: diagnostics},
+		Diagnostics: diagnostics,
+		Expression:  expression,
+	})
+}
+
+type coding struct {
+	system, code string
+}
+
+func (c coding) String() string {
+	if c.system == "" {
+		return c.code
+	}
+	return c.system + "#" + c.code
+}
+
+// codings returns the codes of a code, Coding, CodeableConcept or Quantity element
+func codings(n fhirpath.Node) []coding {
+	switch value := n.Value.(type) {
+	case string:
+		return []coding{{code: value}}
+	case map[string]interface{}:
+		if list, ok := value["coding"].([]interface{}); ok {
+			var result []coding
+			for _, item := range list {
+				if c, ok := item.(map[string]interface{}); ok {
+					result = append(result, codingOf(c)...)
+				}
+			}
+			return result
+		}
+		return codingOf(value)
+	}
+	return nil
+}
+
+func codingOf(object map[string]interface{}) []coding {
+	code, _ := object["code"].(string)
+	if code == "" {
+		return nil
+	}
+	system, _ := object["system"].(string)
+	return []coding{{system: system, code: code}}
+}
+
+// inValueSet reports whether the code is a member of the value set
+func (v *Validator) inValueSet(url, system, code string, depth int) (bool, error) {
+	vs, ok := v.valueSets[url]
+	if !ok || depth > maxValueSetIncludeDepth {
+		return false, fmt.Errorf("%w: %s", errUnknownValueSet, url)
+	}
+	if vs.Expansion != nil && len(vs.Expansion.Contains) > 0 {
+		return expanded(vs.Expansion.Contains, system, code), nil
+	}
+	if vs.Compose == nil {
+		return false, nil
+	}
+	for _, exclude := range vs.Compose.Exclude {
+		if ok, err := v.included(exclude, system, code, depth); err != nil || ok {
+			return false, err
+		}
+	}
+	for _, include := range vs.Compose.Include {
+		if ok, err := v.included(include, system, code, depth); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (v *Validator) included(inc include, system, code string, depth int) (bool, error) {
+	if inc.System != "" && system != "" && inc.System != system {
+		return false, nil
+	}
+	for _, url := range inc.ValueSet {
+		ok, err := v.inValueSet(canonical(url), system, code, depth+1)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if len(inc.Concept) > 0 {
+		for _, c := range inc.Concept {
+			if c.Code == code {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if inc.System == "" {
+		return len(inc.ValueSet) > 0, nil
+	}
+	if codes, ok := v.codeSystems[inc.System]; ok && len(inc.Filter) == 0 {
+		return codes[code], nil
+	}
+	// the whole code system, or a filter on it, is included but the code system is not loaded
+	return true, nil
+}
+
+func expanded(contains []expansionContains, system, code string) bool {
+	for _, c := range contains {
+		if c.Code == code && (system == "" || c.System == system) {
+			return true
+		}
+		if expanded(c.Contains, system, code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package validation_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+const definitions = `{
+  "resourceType": "Bundle",
+  "type": "collection",
+  "entry": [
+    {"resource": {
+      "resourceType": "StructureDefinition",
+      "url": "http://example.com/fhir/StructureDefinition/vitals",
+      "type": "Observation",
+      "baseDefinition": "http://hl7.org/fhir/StructureDefinition/Observation",
+      "snapshot": {"element": [
+        {"id": "Observation", "path": "Observation", "min": 0, "max": "*", "constraint": [
+          {"key": "obs-6", "severity": "error", "human": "dataAbsentReason SHALL only be present if Observation.value[x] is not present", "expression": "dataAbsentReason.empty() or value.empty()"}
+        ]},
+        {"id": "Observation.status", "path": "Observation.status", "min": 1, "max": "1",
+          "binding": {"strength": "required", "valueSet": "http://hl7.org/fhir/ValueSet/observation-status|4.0.1"}},
+        {"id": "Observation.category", "path": "Observation.category", "min": 0, "max": "*",
+          "binding": {"strength": "extensible", "valueSet": "http://example.com/fhir/ValueSet/categories"}},
+        {"id": "Observation.code", "path": "Observation.code", "min": 1, "max": "1",
+          "binding": {"strength": "required", "valueSet": "http://example.com/fhir/ValueSet/vital-signs"}},
+        {"id": "Observation.subject", "path": "Observation.subject", "min": 1, "max": "1"},
+        {"id": "Observation.effective[x]", "path": "Observation.effective[x]", "min": 0, "max": "1",
+          "type": [{"code": "dateTime"}, {"code": "Period"}]},
+        {"id": "Observation.value[x]", "path": "Observation.value[x]", "min": 0, "max": "1",
+          "type": [{"code": "Quantity"}],
+          "constraint": [{"key": "vs-3", "severity": "error", "human": "UCUM units are required", "expression": "system = %ucum"}]},
+        {"id": "Observation.value[x].code", "path": "Observation.value[x].code", "min": 0, "max": "1",
+          "binding": {"strength": "required", "valueSet": "http://example.com/fhir/ValueSet/units"}},
+        {"id": "Observation.note", "path": "Observation.note", "min": 0, "max": "1"}
+      ]}
+    }},
+    {"resource": {
+      "resourceType": "StructureDefinition",
+      "url": "http://example.com/fhir/StructureDefinition/signed-vitals",
+      "type": "Observation",
+      "baseDefinition": "http://example.com/fhir/StructureDefinition/vitals",
+      "differential": {"element": [
+        {"id": "Observation.performer", "path": "Observation.performer", "min": 1, "max": "*"},
+        {"id": "Observation.status", "path": "Observation.status", "constraint": [
+          {"key": "sig-1", "severity": "warning", "human": "Signed vitals should be final", "expression": "$this = 'final'"}
+        ]}
+      ]}
+    }},
+    {"resource": {
+      "resourceType": "ValueSet",
+      "url": "http://hl7.org/fhir/ValueSet/observation-status",
+      "compose": {"include": [{"system": "http://hl7.org/fhir/observation-status", "concept": [
+        {"code": "registered"}, {"code": "preliminary"}, {"code": "final"}, {"code": "amended"}
+      ]}]}
+    }},
+    {"resource": {
+      "resourceType": "ValueSet",
+      "url": "http://example.com/fhir/ValueSet/vital-signs",
+      "expansion": {"contains": [
+        {"system": "http://loinc.org", "code": "29463-7"},
+        {"system": "http://loinc.org", "code": "8310-5"}
+      ]}
+    }},
+    {"resource": {
+      "resourceType": "ValueSet",
+      "url": "http://example.com/fhir/ValueSet/units",
+      "compose": {"include": [{"system": "http://unitsofmeasure.org"}]}
+    }},
+    {"resource": {
+      "resourceType": "CodeSystem",
+      "url": "http://unitsofmeasure.org",
+      "concept": [{"code": "kg"}, {"code": "g"}, {"code": "Cel"}]
+    }},
+    {"resource": {"resourceType": "Patient", "id": "ignored"}}
+  ]
+}`
+
+func newValidator(t *testing.T) *validation.Validator {
+	v := validation.NewValidator()
+	if !assert.Nil(t, v.Load([]byte(definitions))) {
+		t.FailNow()
+	}
+	return v
+}
+
+func issues(outcome *validation.OperationOutcome) []string {
+	var result []string
+	for _, i := range outcome.Issue {
+		result = append(result, i.Severity+" "+i.Code+" "+strings.Join(i.Expression, ","))
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	v := newValidator(t)
+
+	outcome, err := v.Validate([]byte(`{
+  "resourceType": "Observation",
+  "meta": {"profile": ["http://example.com/fhir/StructureDefinition/vitals"]},
+  "status": "final",
+  "code": {"coding": [{"system": "http://loinc.org", "code": "29463-7"}]},
+  "subject": {"reference": "Patient/p1"},
+  "effectiveDateTime": "2021-03-04T10:00:00Z",
+  "valueQuantity": {"value": 72.5, "unit": "kg", "system": "http://unitsofmeasure.org", "code": "kg"}
+}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, validation.HasErrors(outcome))
+	assert.Equal(t, []string{"information informational "}, issues(outcome))
+
+	outcome, err = v.Validate([]byte(`{
+  "resourceType": "Observation",
+  "meta": {"profile": ["http://example.com/fhir/StructureDefinition/vitals", "http://example.com/unknown"]},
+  "status": "done",
+  "category": [{"coding": [{"system": "http://example.com/categories", "code": "vital-signs"}]}],
+  "code": {"coding": [{"system": "http://loinc.org", "code": "1234-5"}]},
+  "effectivePeriod": {"start": "2021-03-04T10:00:00Z"},
+  "valueString": "heavy",
+  "dataAbsentReason": {"text": "unknown"},
+  "note": [{"text": "a"}, {"text": "b"}]
+}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, validation.HasErrors(outcome))
+	assert.Equal(t, []string{
+		"information not-supported Observation",
+		"error invariant Observation",
+		"error code-invalid Observation.status",
+		"information not-supported ",
+		"error code-invalid Observation.code",
+		"error required Observation.subject",
+		"error structure Observation.value",
+		"error structure Observation.note",
+	}, issues(outcome))
+}
+
+func TestValidateDifferential(t *testing.T) {
+	v := newValidator(t)
+
+	outcome, err := v.Validate([]byte(`{
+  "resourceType": "Observation",
+  "status": "amended",
+  "code": {"coding": [{"system": "http://loinc.org", "code": "8310-5"}]},
+  "subject": {"reference": "Patient/p1"},
+  "valueQuantity": {"value": 37, "system": "http://unitsofmeasure.org", "code": "[degF]"}
+}`), "http://example.com/fhir/StructureDefinition/signed-vitals")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"warning invariant Observation.status",
+		"error code-invalid Observation.value.code",
+		"error required Observation.performer",
+	}, issues(outcome))
+
+	_, err = v.Validate([]byte(`{"resourceType": "Observation"}`), "http://example.com/none")
+	assert.True(t, errors.Is(err, validation.ErrUnknownProfile))
+
+	outcome, err = v.Validate([]byte(`{"resourceType": "Patient"}`), "http://example.com/fhir/StructureDefinition/vitals")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"error structure Patient"}, issues(outcome))
+
+	outcome, err = v.Validate([]byte(`{"resourceType": "Patient"}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"warning not-supported Patient"}, issues(outcome))
+}
+
+func TestValidateR4(t *testing.T) {
+	v := newValidator(t)
+	um, _ := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	resource, err := um.UnmarshalR4([]byte(`{
+  "resourceType": "Observation",
+  "status": "final",
+  "code": {"coding": [{"system": "http://loinc.org", "code": "29463-7"}]},
+  "subject": {"reference": "Patient/p1"},
+  "valueQuantity": {"value": 72.5, "unit": "kg", "system": "http://unitsofmeasure.org", "code": "kg"}
+}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	outcome, err := v.ValidateR4(resource, "http://example.com/fhir/StructureDefinition/vitals")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, validation.HasErrors(outcome))
+
+	err = v.Load([]byte(`{"resourceType": "StructureDefinition"}`))
+	assert.True(t, errors.Is(err, validation.ErrInvalidDefinition))
+	err = v.Load([]byte(`{"resourceType": "Patient"}`))
+	assert.True(t, errors.Is(err, validation.ErrUnsupportedResource))
+}
@@ -0,0 +1,605 @@
+package cdr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/philips-software/go-hsdp-api/internal"
+)
+
+const (
+	defaultImportBatchSize   = 100
+	defaultImportConcurrency = 4
+	defaultImportRetries     = 3
+)
+
+// ImportOptions configures a bulk import run
+type ImportOptions struct {
+	// FHIRVersion selects the store version to import into. Defaults to R4
+	FHIRVersion fhirversion.Version
+	// BatchSize is the number of resources processed between checkpoints
+	BatchSize int
+	// Concurrency is the number of parallel upserts within a batch
+	Concurrency int
+	// Retries is the number of retries for calls failing with one of internal.StandardRetryOnCodes
+	Retries uint64
+	// CheckpointFile, when set, records progress so an interrupted import can be resumed
+	CheckpointFile string
+}
+
+// ImportError describes a resource which could not be imported
+type ImportError struct {
+	Line         int    `json:"line"`
+	ResourceType string `json:"resourceType,omitempty"`
+	ID           string `json:"id,omitempty"`
+	Err          error  `json:"-"`
+}
+
+func (e ImportError) Error() string {
+	if e.ResourceType == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d (%s/%s): %v", e.Line, e.ResourceType, e.ID, e.Err)
+}
+
+func (e ImportError) Unwrap() error {
+	return e.Err
+}
+
+// ImportReport summarizes the outcome of an import run
+type ImportReport struct {
+	Processed int
+	Succeeded int
+	Skipped   int
+	Errors    []ImportError
+}
+
+// Failed returns the number of resources which could not be imported
+func (r *ImportReport) Failed() int {
+	return len(r.Errors)
+}
+
+// Checkpoint records the progress of an import. All lines up to and including
+// Watermark have been processed, as well as the lines listed in Done. Failed lists
+// the fullUrls of entries which could not be imported
+type Checkpoint struct {
+	Watermark  int               `json:"watermark"`
+	Done       []int             `json:"done,omitempty"`
+	References map[string]string `json:"references,omitempty"`
+	Failed     []string          `json:"failed,omitempty"`
+}
+
+// Importer imports NDJSON or Bundle files into CDR
+type Importer struct {
+	client *Client
+	opts   ImportOptions
+
+	mu         sync.Mutex
+	done       map[int]bool
+	watermark  int
+	references map[string]string
+	failed     map[string]bool
+	// fullURLs are the fullUrls of the entries of the bundle being imported
+	fullURLs map[string]bool
+}
+
+type importItem struct {
+	line         int
+	fullURL      string
+	resourceType string
+	id           string
+	resource     map[string]interface{}
+	refs         []string
+}
+
+// NewImporter returns an Importer which uses client to upsert resources
+func NewImporter(client *Client, opts *ImportOptions) (*Importer, error) {
+	if client == nil {
+		return nil, ErrMissingClient
+	}
+	i := &Importer{client: client}
+	if opts != nil {
+		i.opts = *opts
+	}
+	switch i.opts.FHIRVersion {
+	case "":
+		i.opts.FHIRVersion = fhirversion.R4
+	case fhirversion.R4, fhirversion.STU3:
+	default:
+		return nil, fmt.Errorf("importer: %w: %v", ErrUnsupportedFHIRVersion, i.opts.FHIRVersion)
+	}
+	if i.opts.BatchSize <= 0 {
+		i.opts.BatchSize = defaultImportBatchSize
+	}
+	if i.opts.Concurrency <= 0 {
+		i.opts.Concurrency = defaultImportConcurrency
+	}
+	if i.opts.Retries == 0 {
+		i.opts.Retries = defaultImportRetries
+	}
+	return i, nil
+}
+
+// ImportNDJSON imports newline delimited FHIR resources read from r.
+// Lines are numbered starting at 1, blank lines included
+func (i *Importer) ImportNDJSON(ctx context.Context, r io.Reader) (*ImportReport, error) {
+	if err := i.loadCheckpoint(); err != nil {
+		return nil, err
+	}
+	report := &ImportReport{}
+	reader := bufio.NewReader(r)
+	var batch []*importItem
+	line := 0
+	for {
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return report, readErr
+		}
+		if len(data) > 0 {
+			line++
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			if item := i.prepare(report, line, "", data); item != nil {
+				batch = append(batch, item)
+			}
+		}
+		if len(batch) >= i.opts.BatchSize || (readErr == io.EOF && len(batch) > 0) {
+			if _, err := i.runBatch(ctx, report, batch, line, true); err != nil {
+				return report, err
+			}
+			batch = nil
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	return report, i.saveCheckpoint(line)
+}
+
+// ImportBundle imports the entries of the FHIR Bundle read from r. Entries are
+// numbered starting at 1. References to urn:uuid fullUrls of other entries are
+// rewritten to the server assigned resource IDs. Entries referring to entries of
+// a later batch are held back until those have been imported
+func (i *Importer) ImportBundle(ctx context.Context, r io.Reader) (*ImportReport, error) {
+	if err := i.loadCheckpoint(); err != nil {
+		return nil, err
+	}
+	var bundle internal.Bundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("importer: decode bundle: %w", err)
+	}
+	i.fullURLs = make(map[string]bool)
+	for _, entry := range bundle.Entry {
+		if entry.FullURL != "" {
+			i.fullURLs[entry.FullURL] = true
+		}
+	}
+	report := &ImportReport{}
+	var batch []*importItem
+	for n, entry := range bundle.Entry {
+		line := n + 1
+		if item := i.prepare(report, line, entry.FullURL, entry.Resource); item != nil {
+			batch = append(batch, item)
+		}
+		final := line == len(bundle.Entry)
+		if len(batch) >= i.opts.BatchSize || (final && len(batch) > 0) {
+			held, err := i.runBatch(ctx, report, batch, line, final)
+			if err != nil {
+				return report, err
+			}
+			batch = held
+		}
+	}
+	return report, i.saveCheckpoint(len(bundle.Entry))
+}
+
+// prepare decodes a single resource, returning nil when it is skipped or invalid
+func (i *Importer) prepare(report *ImportReport, line int, fullURL string, data []byte) *importItem {
+	if i.isDone(line) {
+		report.Skipped++
+		return nil
+	}
+	report.Processed++
+	var resource map[string]interface{}
+	if err := json.Unmarshal(data, &resource); err != nil {
+		report.Errors = append(report.Errors, ImportError{Line: line, Err: err})
+		i.markFailed(line, fullURL)
+		return nil
+	}
+	item := &importItem{line: line, fullURL: fullURL, resource: resource}
+	item.resourceType, _ = resource["resourceType"].(string)
+	item.id, _ = resource["id"].(string)
+	if item.resourceType == "" {
+		report.Errors = append(report.Errors, ImportError{Line: line, Err: ErrMissingResourceType})
+		i.markFailed(line, fullURL)
+		return nil
+	}
+	collectReferences(resource, &item.refs)
+	return item
+}
+
+// runBatch upserts the items in waves. An item is only sent once all urn:uuid
+// references it contains have been resolved. Items referring to an item which
+// failed are not sent. Unless final is set, the items still waiting for an entry
+// are returned to be retried with the next batch
+func (i *Importer) runBatch(ctx context.Context, report *ImportReport, batch []*importItem, lastLine int, final bool) ([]*importItem, error) {
+	var held []*importItem
+	pending := batch
+	for len(pending) > 0 {
+		var ready, waiting []*importItem
+		unresolved := 0
+		for _, item := range pending {
+			switch {
+			case i.refersToFailed(item):
+				report.Errors = append(report.Errors, ImportError{Line: item.line, ResourceType: item.resourceType, ID: item.id, Err: ErrUnresolvedReference})
+				i.markFailed(item.line, item.fullURL)
+				unresolved++
+			case i.resolvable(item):
+				ready = append(ready, item)
+			default:
+				waiting = append(waiting, item)
+			}
+		}
+		if len(ready) == 0 {
+			if unresolved > 0 {
+				pending = waiting
+				continue
+			}
+			if !final {
+				held = waiting
+				break
+			}
+			for _, item := range waiting {
+				report.Errors = append(report.Errors, ImportError{Line: item.line, ResourceType: item.resourceType, ID: item.id, Err: ErrUnresolvedReference})
+				i.markFailed(item.line, item.fullURL)
+			}
+			break
+		}
+		errs := i.upsertAll(ctx, ready)
+		if err := ctx.Err(); err != nil {
+			_ = i.saveCheckpoint(0)
+			return nil, err
+		}
+		for _, e := range errs {
+			report.Errors = append(report.Errors, e)
+		}
+		report.Succeeded += len(ready) - len(errs)
+		pending = waiting
+	}
+	sort.Slice(report.Errors, func(a, b int) bool {
+		return report.Errors[a].Line < report.Errors[b].Line
+	})
+	// Held lines are not processed yet, so the watermark stays below them
+	for _, item := range held {
+		if item.line <= lastLine {
+			lastLine = item.line - 1
+		}
+	}
+	return held, i.saveCheckpoint(lastLine)
+}
+
+// resolvable reports whether all references of item are known or point outside of the bundle
+func (i *Importer) resolvable(item *importItem) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, ref := range item.refs {
+		if _, ok := i.references[ref]; ok {
+			continue
+		}
+		if i.fullURLs[ref] && ref != item.fullURL {
+			return false
+		}
+	}
+	return true
+}
+
+// refersToFailed reports whether item references the fullUrl of an item which failed
+func (i *Importer) refersToFailed(item *importItem) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, ref := range item.refs {
+		if i.failed[ref] {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Importer) upsertAll(ctx context.Context, items []*importItem) []ImportError {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []ImportError
+	)
+	sem := make(chan struct{}, i.opts.Concurrency)
+	for _, item := range items {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return errs
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(item *importItem) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := i.upsert(ctx, item)
+			if err != nil && ctx.Err() != nil {
+				return // Not marked as done so it is retried on resume
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, ImportError{Line: item.line, ResourceType: item.resourceType, ID: item.id, Err: err})
+				mu.Unlock()
+				i.markFailed(item.line, item.fullURL)
+				return
+			}
+			i.markDone(item.line)
+		}(item)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (i *Importer) upsert(ctx context.Context, item *importItem) error {
+	i.mu.Lock()
+	rewriteReferences(item.resource, i.references)
+	i.mu.Unlock()
+	body, err := json.Marshal(item.resource)
+	if err != nil {
+		return err
+	}
+	if err := i.validate(body); err != nil {
+		return err
+	}
+	method, target := http.MethodPost, item.resourceType
+	switch {
+	case item.id != "":
+		method, target = http.MethodPut, item.resourceType+"/"+item.id
+	case conditionalQuery(item.resource) != "":
+		method, target = http.MethodPut, item.resourceType+"?"+conditionalQuery(item.resource)
+	}
+	var id string
+	err = internal.TryHTTPCall(ctx, i.opts.Retries, func() (*http.Response, error) {
+		var resp *Response
+		var err error
+		id, resp, err = i.send(ctx, method, target, body)
+		if resp == nil {
+			return nil, err
+		}
+		return resp.Response, err
+	})
+	if err != nil {
+		return err
+	}
+	if item.fullURL != "" {
+		if id == "" {
+			id = item.id
+		}
+		i.mu.Lock()
+		i.references[item.fullURL] = item.resourceType + "/" + id
+		i.mu.Unlock()
+	}
+	return nil
+}
+
+func (i *Importer) validate(body []byte) error {
+	var err error
+	switch i.opts.FHIRVersion {
+	case fhirversion.STU3:
+		_, err = i.client.OperationsSTU3.um.UnmarshalR3(body)
+	default:
+		_, err = i.client.OperationsR4.um.UnmarshalR4(body)
+	}
+	if err != nil {
+		return fmt.Errorf("FHIR validation: %w", err)
+	}
+	return nil
+}
+
+// send performs the upsert and returns the server assigned ID
+func (i *Importer) send(ctx context.Context, method, target string, body []byte) (string, *Response, error) {
+	var (
+		resp *Response
+		err  error
+		out  []byte
+	)
+	switch i.opts.FHIRVersion {
+	case fhirversion.STU3:
+		ops := i.client.OperationsSTU3
+		contained, r, e := ops.postOrPut(method, target, body, WithContext(ctx))
+		if e == nil {
+			out, _ = ops.ma.Marshal(contained)
+		}
+		resp, err = r, e
+	default:
+		ops := i.client.OperationsR4
+		contained, r, e := ops.postOrPut(method, target, body, WithContext(ctx))
+		if e == nil {
+			out, _ = ops.ma.Marshal(contained)
+		}
+		resp, err = r, e
+	}
+	if err != nil {
+		return "", resp, err
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(out, &created)
+	if created.ID == "" && resp != nil && resp.Response != nil {
+		if loc := resp.Header.Get("Location"); loc != "" {
+			created.ID = idFromLocation(loc)
+		}
+	}
+	return created.ID, resp, nil
+}
+
+func (i *Importer) isDone(line int) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return line <= i.watermark || i.done[line]
+}
+
+func (i *Importer) markDone(line int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.done[line] = true
+}
+
+// markFailed marks line as done and records fullURL so references to it are not sent
+func (i *Importer) markFailed(line int, fullURL string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.done[line] = true
+	if fullURL != "" {
+		i.failed[fullURL] = true
+	}
+}
+
+func (i *Importer) loadCheckpoint() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.done = make(map[int]bool)
+	i.references = make(map[string]string)
+	i.failed = make(map[string]bool)
+	i.fullURLs = nil
+	i.watermark = 0
+	if i.opts.CheckpointFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(i.opts.CheckpointFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("importer: read checkpoint: %w", err)
+	}
+	i.watermark = cp.Watermark
+	for _, l := range cp.Done {
+		i.done[l] = true
+	}
+	for k, v := range cp.References {
+		i.references[k] = v
+	}
+	for _, f := range cp.Failed {
+		i.failed[f] = true
+	}
+	return nil
+}
+
+// saveCheckpoint atomically writes the current progress. When lastLine is
+// positive all lines up to it have been processed
+func (i *Importer) saveCheckpoint(lastLine int) error {
+	if i.opts.CheckpointFile == "" {
+		return nil
+	}
+	i.mu.Lock()
+	if lastLine > i.watermark {
+		i.watermark = lastLine
+	}
+	cp := Checkpoint{Watermark: i.watermark, References: i.references}
+	for l := range i.done {
+		if l > i.watermark {
+			cp.Done = append(cp.Done, l)
+		}
+	}
+	sort.Ints(cp.Done)
+	for f := range i.failed {
+		cp.Failed = append(cp.Failed, f)
+	}
+	sort.Strings(cp.Failed)
+	data, err := json.Marshal(cp)
+	i.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := i.opts.CheckpointFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, i.opts.CheckpointFile)
+}
+
+// collectReferences gathers all urn:uuid references found in resource
+func collectReferences(v interface{}, refs *[]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if s, ok := e.(string); ok && k == "reference" && strings.HasPrefix(s, "urn:uuid:") {
+				*refs = append(*refs, s)
+				continue
+			}
+			collectReferences(e, refs)
+		}
+	case []interface{}:
+		for _, e := range t {
+			collectReferences(e, refs)
+		}
+	}
+}
+
+// rewriteReferences replaces resolved urn:uuid references in place
+func rewriteReferences(v interface{}, resolved map[string]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if s, ok := e.(string); ok && k == "reference" {
+				if r, ok := resolved[s]; ok {
+					t[k] = r
+				}
+				continue
+			}
+			rewriteReferences(e, resolved)
+		}
+	case []interface{}:
+		for _, e := range t {
+			rewriteReferences(e, resolved)
+		}
+	}
+}
+
+// conditionalQuery returns the identifier search used for a conditional upsert
+func conditionalQuery(resource map[string]interface{}) string {
+	identifiers, _ := resource["identifier"].([]interface{})
+	for _, e := range identifiers {
+		identifier, _ := e.(map[string]interface{})
+		system, _ := identifier["system"].(string)
+		value, _ := identifier["value"].(string)
+		if value == "" {
+			continue
+		}
+		if system == "" {
+			return "identifier=" + url.QueryEscape(value)
+		}
+		return "identifier=" + url.QueryEscape(system+"|"+value)
+	}
+	return ""
+}
+
+func idFromLocation(location string) string {
+	location = strings.TrimSuffix(location, "/")
+	if idx := strings.Index(location, "/_history/"); idx > 0 {
+		location = location[:idx]
+	}
+	return path.Base(location)
+}
@@ -0,0 +1,242 @@
+package cdr_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportNDJSON(t *testing.T) {
+	teardown := setup(t, fhirversion.R4)
+	defer teardown()
+
+	var mu sync.Mutex
+	received := make(map[string]int)
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Patient/", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, http.MethodPut, r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/fhir+json;fhirVersion=4.0")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	})
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Patient", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, http.MethodPut, r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		assert.Equal(t, "https://example.com/mrn|123", r.URL.Query().Get("identifier"))
+		mu.Lock()
+		received["conditional"]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/fhir+json;fhirVersion=4.0")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"resourceType":"Patient","id":"server-1"}`)
+	})
+
+	input := strings.Join([]string{
+		`{"resourceType":"Patient","id":"p1","active":true}`,
+		`{"resourceType":"Patient","id":"p2","active":"yes"}`,
+		``,
+		`{"resourceType":"Patient","identifier":[{"system":"https://example.com/mrn","value":"123"}]}`,
+		`{"id":"p3"}`,
+		`not json`,
+		`{"resourceType":"Patient","id":"p4"}`,
+	}, "\n")
+
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	importer, err := cdr.NewImporter(cdrClient, &cdr.ImportOptions{
+		BatchSize:      2,
+		Concurrency:    2,
+		CheckpointFile: checkpoint,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	report, err := importer.ImportNDJSON(context.Background(), strings.NewReader(input))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 6, report.Processed)
+	assert.Equal(t, 3, report.Succeeded)
+	if !assert.Equal(t, 3, report.Failed()) {
+		return
+	}
+	assert.Equal(t, 2, report.Errors[0].Line)
+	assert.Equal(t, 5, report.Errors[1].Line)
+	assert.True(t, errors.Is(report.Errors[1], cdr.ErrMissingResourceType))
+	assert.Equal(t, 6, report.Errors[2].Line)
+	assert.Equal(t, 1, received["/store/fhir/"+cdrOrgID+"/Patient/p1"])
+	assert.Equal(t, 1, received["/store/fhir/"+cdrOrgID+"/Patient/p4"])
+	assert.Equal(t, 1, received["conditional"])
+
+	data, err := os.ReadFile(checkpoint)
+	if !assert.Nil(t, err) {
+		return
+	}
+	var cp cdr.Checkpoint
+	assert.Nil(t, json.Unmarshal(data, &cp))
+	assert.Equal(t, 7, cp.Watermark)
+
+	// Resume should skip everything
+	report, err = importer.ImportNDJSON(context.Background(), strings.NewReader(input))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 6, report.Skipped)
+	assert.Equal(t, 0, report.Processed)
+	assert.Equal(t, 1, received["/store/fhir/"+cdrOrgID+"/Patient/p1"])
+}
+
+func TestImportBundleReferences(t *testing.T) {
+	teardown := setup(t, fhirversion.R4)
+	defer teardown()
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Patient", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, http.MethodPost, r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/fhir+json;fhirVersion=4.0")
+		w.Header().Set("Location", serverCDR.URL+"/store/fhir/"+cdrOrgID+"/Patient/assigned/_history/1")
+		w.WriteHeader(http.StatusCreated)
+	})
+	var observation map[string]interface{}
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Observation/o1", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &observation)
+		w.Header().Set("Content-Type", "application/fhir+json;fhirVersion=4.0")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	})
+
+	bundle := `{
+  "resourceType": "Bundle",
+  "type": "collection",
+  "entry": [
+    {
+      "fullUrl": "urn:uuid:0e2b6e3a-3e4f-4ac8-8f53-cf1a3ae7c4a1",
+      "resource": {
+        "resourceType": "Observation",
+        "id": "o1",
+        "status": "final",
+        "code": {"text": "weight"},
+        "subject": {"reference": "urn:uuid:5b6d6d0c-0c4e-4a3b-a5e0-6ba7c6b1c0d2"}
+      }
+    },
+    {
+      "fullUrl": "urn:uuid:5b6d6d0c-0c4e-4a3b-a5e0-6ba7c6b1c0d2",
+      "resource": {
+        "resourceType": "Patient",
+        "active": true
+      }
+    }
+  ]
+}`
+	// With one entry per batch the Observation refers to an entry of the next batch
+	for _, opts := range []*cdr.ImportOptions{nil, {BatchSize: 1}} {
+		observation = nil
+		importer, err := cdr.NewImporter(cdrClient, opts)
+		if !assert.Nil(t, err) {
+			return
+		}
+		report, err := importer.ImportBundle(context.Background(), strings.NewReader(bundle))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 2, report.Succeeded)
+		assert.Equal(t, 0, report.Failed())
+		if !assert.NotNil(t, observation) {
+			return
+		}
+		subject, _ := observation["subject"].(map[string]interface{})
+		assert.Equal(t, "Patient/assigned", subject["reference"])
+	}
+}
+
+func TestImportBundleFailedReference(t *testing.T) {
+	teardown := setup(t, fhirversion.R4)
+	defer teardown()
+
+	posted := 0
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/", func(w http.ResponseWriter, r *http.Request) {
+		posted++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	bundle := `{
+  "resourceType": "Bundle",
+  "type": "collection",
+  "entry": [
+    {
+      "fullUrl": "urn:uuid:0e2b6e3a-3e4f-4ac8-8f53-cf1a3ae7c4a1",
+      "resource": {
+        "resourceType": "Observation",
+        "status": "final",
+        "code": {"text": "weight"},
+        "subject": {"reference": "urn:uuid:5b6d6d0c-0c4e-4a3b-a5e0-6ba7c6b1c0d2"}
+      }
+    },
+    {
+      "fullUrl": "urn:uuid:5b6d6d0c-0c4e-4a3b-a5e0-6ba7c6b1c0d2",
+      "resource": {
+        "resourceType": "Patient",
+        "active": "yes"
+      }
+    },
+    {
+      "resource": {
+        "resourceType": "Observation",
+        "status": "final",
+        "code": {"text": "height"},
+        "derivedFrom": [{"reference": "urn:uuid:0e2b6e3a-3e4f-4ac8-8f53-cf1a3ae7c4a1"}]
+      }
+    }
+  ]
+}`
+	// The first Observation is held back until the Patient of the next batch fails
+	importer, err := cdr.NewImporter(cdrClient, &cdr.ImportOptions{BatchSize: 1})
+	if !assert.Nil(t, err) {
+		return
+	}
+	report, err := importer.ImportBundle(context.Background(), strings.NewReader(bundle))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, report.Succeeded)
+	if !assert.Equal(t, 3, report.Failed()) {
+		return
+	}
+	assert.False(t, errors.Is(report.Errors[1], cdr.ErrUnresolvedReference))
+	assert.True(t, errors.Is(report.Errors[0], cdr.ErrUnresolvedReference))
+	assert.True(t, errors.Is(report.Errors[2], cdr.ErrUnresolvedReference))
+	assert.Equal(t, 0, posted)
+}
+
+func TestNewImporterErrors(t *testing.T) {
+	_, err := cdr.NewImporter(nil, nil)
+	assert.Equal(t, cdr.ErrMissingClient, err)
+
+	teardown := setup(t, fhirversion.R4)
+	defer teardown()
+
+	_, err = cdr.NewImporter(cdrClient, &cdr.ImportOptions{FHIRVersion: "DSTU2"})
+	assert.True(t, errors.Is(err, cdr.ErrUnsupportedFHIRVersion))
+}
@@ -122,14 +122,14 @@ type PartUpload struct {
 	ETag         string `json:"eTag"`
 }
 
-func (b *BlobsService) Create(blob Blob) (*Blob, *Response, error) {
+func (b *BlobsService) Create(blob Blob, options ...OptionFunc) (*Blob, *Response, error) {
 	blob.ResourceType = "Blob"
 	blob.AutoGenerateBlobPathName = true
 	if err := b.validate.Struct(blob); err != nil {
 		return nil, nil, err
 	}
 
-	req, _ := b.NewRequest(http.MethodPost, "/Blob", blob, nil)
+	req, _ := b.NewRequest(http.MethodPost, "/Blob", blob, options...)
 	req.Header.Set("api-version", blobAPIVersion)
 
 	var created Blob
@@ -145,8 +145,8 @@ func (b *BlobsService) Create(blob Blob) (*Blob, *Response, error) {
 	return &created, resp, nil
 }
 
-func (b *BlobsService) GetByID(id string) (*Blob, *Response, error) {
-	req, err := b.NewRequest(http.MethodGet, "/Blob/"+id, nil)
+func (b *BlobsService) GetByID(id string, options ...OptionFunc) (*Blob, *Response, error) {
+	req, err := b.NewRequest(http.MethodGet, "/Blob/"+id, nil, options...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -170,9 +170,17 @@ func (b *BlobsService) GetByID(id string) (*Blob, *Response, error) {
 }
 
 func (b *BlobsService) Find(opt *GetBlobOptions, options ...OptionFunc) (*[]Blob, *Response, error) {
+	resources, _, resp, err := b.find(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &resources, resp, nil
+}
+
+func (b *BlobsService) find(opt *GetBlobOptions, options ...OptionFunc) ([]Blob, *internal.Bundle, *Response, error) {
 	req, err := b.NewRequest(http.MethodGet, "/Blob", opt, options...)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	req.Header.Set("api-version", blobAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -181,7 +189,7 @@ func (b *BlobsService) Find(opt *GetBlobOptions, options ...OptionFunc) (*[]Blob
 
 	resp, err := b.Do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
+		return nil, nil, resp, err
 	}
 	var resources []Blob
 	for _, c := range bundleResponse.Entry {
@@ -190,11 +198,40 @@ func (b *BlobsService) Find(opt *GetBlobOptions, options ...OptionFunc) (*[]Blob
 			resources = append(resources, resource)
 		}
 	}
-	return &resources, resp, err
+	return resources, &bundleResponse, resp, nil
+}
+
+// BlobIterator pages through Find results by following the next links of the bundles
+type BlobIterator struct {
+	pager *internal.Pager[Blob, OptionFunc]
+}
+
+// Iterate returns an iterator over all blobs matching opt
+func (b *BlobsService) Iterate(opt *GetBlobOptions, options ...OptionFunc) *BlobIterator {
+	return &BlobIterator{pager: internal.NewPager(func(options ...OptionFunc) ([]Blob, *internal.Bundle, error) {
+		page, bundle, _, err := b.find(opt, options...)
+		return page, bundle, err
+	}, options...)}
+}
+
+// Next advances to the next blob, fetching the next page when needed.
+// It returns false when the results are exhausted or an error occurred
+func (it *BlobIterator) Next() bool {
+	return it.pager.Next()
+}
+
+// Blob returns the current blob
+func (it *BlobIterator) Blob() Blob {
+	return it.pager.Current()
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *BlobIterator) Err() error {
+	return it.pager.Err()
 }
 
-func (b *BlobsService) Delete(blob Blob) (bool, *Response, error) {
-	req, err := b.NewRequest(http.MethodDelete, "/Blob/"+blob.ID, nil, nil)
+func (b *BlobsService) Delete(blob Blob, options ...OptionFunc) (bool, *Response, error) {
+	req, err := b.NewRequest(http.MethodDelete, "/Blob/"+blob.ID, nil, options...)
 	if err != nil {
 		return false, nil, err
 	}
@@ -209,8 +246,8 @@ func (b *BlobsService) Delete(blob Blob) (bool, *Response, error) {
 	return true, resp, nil
 }
 
-func (b *BlobsService) SetPolicy(blob Blob, policy BlobPolicy) (bool, *Response, error) {
-	req, err := b.NewRequest(http.MethodPost, "/Blob/"+blob.ID+"/$setPolicy", policy, nil)
+func (b *BlobsService) SetPolicy(blob Blob, policy BlobPolicy, options ...OptionFunc) (bool, *Response, error) {
+	req, err := b.NewRequest(http.MethodPost, "/Blob/"+blob.ID+"/$setPolicy", policy, options...)
 	if err != nil {
 		return false, nil, err
 	}
@@ -225,8 +262,8 @@ func (b *BlobsService) SetPolicy(blob Blob, policy BlobPolicy) (bool, *Response,
 	return true, resp, nil
 }
 
-func (b *BlobsService) GetPolicy(blob Blob) (*BlobPolicy, *Response, error) {
-	req, err := b.NewRequest(http.MethodGet, "/Blob/"+blob.ID+"/$getPolicy", nil, nil)
+func (b *BlobsService) GetPolicy(blob Blob, options ...OptionFunc) (*BlobPolicy, *Response, error) {
+	req, err := b.NewRequest(http.MethodGet, "/Blob/"+blob.ID+"/$getPolicy", nil, options...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -246,8 +283,8 @@ func (b *BlobsService) GetPolicy(blob Blob) (*BlobPolicy, *Response, error) {
 	return &resource, resp, nil
 }
 
-func (b *BlobsService) DeletePolicy(blob Blob) (bool, *Response, error) {
-	req, err := b.NewRequest(http.MethodDelete, "/Blob/"+blob.ID+"/$deletePolicy", nil, nil)
+func (b *BlobsService) DeletePolicy(blob Blob, options ...OptionFunc) (bool, *Response, error) {
+	req, err := b.NewRequest(http.MethodDelete, "/Blob/"+blob.ID+"/$deletePolicy", nil, options...)
 	if err != nil {
 		return false, nil, err
 	}
@@ -262,8 +299,8 @@ func (b *BlobsService) DeletePolicy(blob Blob) (bool, *Response, error) {
 	return true, resp, nil
 }
 
-func (b *BlobsService) GetAccessURL(blob Blob) (*AccessURL, *Response, error) {
-	req, err := b.NewRequest(http.MethodGet, "/Blob/"+blob.ID+"/$getAccessUrl", nil, nil)
+func (b *BlobsService) GetAccessURL(blob Blob, options ...OptionFunc) (*AccessURL, *Response, error) {
+	req, err := b.NewRequest(http.MethodGet, "/Blob/"+blob.ID+"/$getAccessUrl", nil, options...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -283,8 +320,8 @@ func (b *BlobsService) GetAccessURL(blob Blob) (*AccessURL, *Response, error) {
 	return &resource, resp, nil
 }
 
-func (b *BlobsService) CompleteUpload(blob Blob, parts BlobPartUpload) (bool, *Response, error) {
-	req, err := b.NewRequest(http.MethodPost, "/Blob/"+blob.ID+"/$completeUpload", parts, nil)
+func (b *BlobsService) CompleteUpload(blob Blob, parts BlobPartUpload, options ...OptionFunc) (bool, *Response, error) {
+	req, err := b.NewRequest(http.MethodPost, "/Blob/"+blob.ID+"/$completeUpload", parts, options...)
 	if err != nil {
 		return false, nil, err
 	}
@@ -299,8 +336,8 @@ func (b *BlobsService) CompleteUpload(blob Blob, parts BlobPartUpload) (bool, *R
 	return true, resp, nil
 }
 
-func (b *BlobsService) AbortUpload(blob Blob) (bool, *Response, error) {
-	req, err := b.NewRequest(http.MethodPost, "/Blob/"+blob.ID+"/$abortUpload", nil, nil)
+func (b *BlobsService) AbortUpload(blob Blob, options ...OptionFunc) (bool, *Response, error) {
+	req, err := b.NewRequest(http.MethodPost, "/Blob/"+blob.ID+"/$abortUpload", nil, options...)
 	if err != nil {
 		return false, nil, err
 	}
@@ -315,8 +352,8 @@ func (b *BlobsService) AbortUpload(blob Blob) (bool, *Response, error) {
 	return true, resp, nil
 }
 
-func (b *BlobsService) ListParts(blob Blob) (*BlobPartUpload, *Response, error) {
-	req, err := b.NewRequest(http.MethodGet, "/Blob/"+blob.ID+"/$listPart", nil)
+func (b *BlobsService) ListParts(blob Blob, options ...OptionFunc) (*BlobPartUpload, *Response, error) {
+	req, err := b.NewRequest(http.MethodGet, "/Blob/"+blob.ID+"/$listPart", nil, options...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -3,6 +3,7 @@ package blr
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -223,3 +224,11 @@ func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
 
 	return response, err
 }
+
+// WithContext runs the request with the provided context
+func WithContext(ctx context.Context) OptionFunc {
+	return func(req *http.Request) error {
+		*req = *req.WithContext(ctx)
+		return nil
+	}
+}
@@ -69,13 +69,13 @@ type ConfigurationsService struct {
 	validate *validator.Validate
 }
 
-func (b *ConfigurationsService) CreateBlobStorePolicy(policy BlobStorePolicy) (*BlobStorePolicy, *Response, error) {
+func (b *ConfigurationsService) CreateBlobStorePolicy(policy BlobStorePolicy, options ...OptionFunc) (*BlobStorePolicy, *Response, error) {
 	policy.ResourceType = "BlobStorePolicy"
 	if err := b.validate.Struct(policy); err != nil {
 		return nil, nil, err
 	}
 
-	req, _ := b.NewRequest(http.MethodPost, "/configuration/BlobStorePolicy", policy, nil)
+	req, _ := b.NewRequest(http.MethodPost, "/configuration/BlobStorePolicy", policy, options...)
 	req.Header.Set("api-version", blobConfigurationAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -106,9 +106,17 @@ func (b *ConfigurationsService) GetBlobStorePolicyByID(id string) (*BlobStorePol
 }
 
 func (b *ConfigurationsService) FindBlobStorePolicy(opt *GetBlobStorePolicyOptions, options ...OptionFunc) (*[]BlobStorePolicy, *Response, error) {
+	resources, _, resp, err := b.findBlobStorePolicy(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &resources, resp, nil
+}
+
+func (b *ConfigurationsService) findBlobStorePolicy(opt *GetBlobStorePolicyOptions, options ...OptionFunc) ([]BlobStorePolicy, *internal.Bundle, *Response, error) {
 	req, err := b.NewRequest(http.MethodGet, "/configuration/BlobStorePolicy", opt, options...)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	req.Header.Set("api-version", blobConfigurationAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -117,7 +125,7 @@ func (b *ConfigurationsService) FindBlobStorePolicy(opt *GetBlobStorePolicyOptio
 
 	resp, err := b.Do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
+		return nil, nil, resp, err
 	}
 	var resources []BlobStorePolicy
 	for _, c := range bundleResponse.Entry {
@@ -126,11 +134,11 @@ func (b *ConfigurationsService) FindBlobStorePolicy(opt *GetBlobStorePolicyOptio
 			resources = append(resources, resource)
 		}
 	}
-	return &resources, resp, err
+	return resources, &bundleResponse, resp, nil
 }
 
-func (b *ConfigurationsService) DeleteBlobStorePolicy(policy BlobStorePolicy) (bool, *Response, error) {
-	req, err := b.NewRequest(http.MethodDelete, "/configuration/BlobStorePolicy/"+policy.ID, nil, nil)
+func (b *ConfigurationsService) DeleteBlobStorePolicy(policy BlobStorePolicy, options ...OptionFunc) (bool, *Response, error) {
+	req, err := b.NewRequest(http.MethodDelete, "/configuration/BlobStorePolicy/"+policy.ID, nil, options...)
 	if err != nil {
 		return false, nil, err
 	}
@@ -147,13 +155,13 @@ func (b *ConfigurationsService) DeleteBlobStorePolicy(policy BlobStorePolicy) (b
 
 // Buckets
 
-func (b *ConfigurationsService) CreateBucket(bucket Bucket) (*Bucket, *Response, error) {
+func (b *ConfigurationsService) CreateBucket(bucket Bucket, options ...OptionFunc) (*Bucket, *Response, error) {
 	bucket.ResourceType = "Bucket"
 	if err := b.validate.Struct(bucket); err != nil {
 		return nil, nil, err
 	}
 
-	req, _ := b.NewRequest(http.MethodPost, "/configuration/Bucket", bucket, nil)
+	req, _ := b.NewRequest(http.MethodPost, "/configuration/Bucket", bucket, options...)
 	req.Header.Set("api-version", blobConfigurationAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -171,14 +179,14 @@ func (b *ConfigurationsService) CreateBucket(bucket Bucket) (*Bucket, *Response,
 }
 
 // UpdateBucket updates a bucket
-func (b *ConfigurationsService) UpdateBucket(bucket Bucket) (*Bucket, *Response, error) {
+func (b *ConfigurationsService) UpdateBucket(bucket Bucket, options ...OptionFunc) (*Bucket, *Response, error) {
 	bucket.ResourceType = "Bucket"
 	id := bucket.ID
 	bucket.ID = "" // Server does not like a value here
 	if err := b.validate.Struct(bucket); err != nil {
 		return nil, nil, err
 	}
-	req, _ := b.NewRequest(http.MethodPut, "/configuration/Bucket/"+id, bucket, nil)
+	req, _ := b.NewRequest(http.MethodPut, "/configuration/Bucket/"+id, bucket, options...)
 	req.Header.Set("api-version", blobConfigurationAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -191,8 +199,8 @@ func (b *ConfigurationsService) UpdateBucket(bucket Bucket) (*Bucket, *Response,
 	return &updated, resp, nil
 }
 
-func (b *ConfigurationsService) DeleteBucket(bucket Bucket) (bool, *Response, error) {
-	req, err := b.NewRequest(http.MethodDelete, "/configuration/Bucket/"+bucket.ID, nil, nil)
+func (b *ConfigurationsService) DeleteBucket(bucket Bucket, options ...OptionFunc) (bool, *Response, error) {
+	req, err := b.NewRequest(http.MethodDelete, "/configuration/Bucket/"+bucket.ID, nil, options...)
 	if err != nil {
 		return false, nil, err
 	}
@@ -207,8 +215,8 @@ func (b *ConfigurationsService) DeleteBucket(bucket Bucket) (bool, *Response, er
 	return true, resp, nil
 }
 
-func (b *ConfigurationsService) GetBucketByID(id string) (*Bucket, *Response, error) {
-	req, err := b.NewRequest(http.MethodGet, "/configuration/Bucket/"+id, nil)
+func (b *ConfigurationsService) GetBucketByID(id string, options ...OptionFunc) (*Bucket, *Response, error) {
+	req, err := b.NewRequest(http.MethodGet, "/configuration/Bucket/"+id, nil, options...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -232,9 +240,17 @@ func (b *ConfigurationsService) GetBucketByID(id string) (*Bucket, *Response, er
 }
 
 func (b *ConfigurationsService) FindBucket(opt *GetBucketOptions, options ...OptionFunc) (*[]Bucket, *Response, error) {
+	resources, _, resp, err := b.findBucket(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &resources, resp, nil
+}
+
+func (b *ConfigurationsService) findBucket(opt *GetBucketOptions, options ...OptionFunc) ([]Bucket, *internal.Bundle, *Response, error) {
 	req, err := b.NewRequest(http.MethodGet, "/configuration/Bucket", opt, options...)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	req.Header.Set("api-version", blobConfigurationAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -243,7 +259,7 @@ func (b *ConfigurationsService) FindBucket(opt *GetBucketOptions, options ...Opt
 
 	resp, err := b.Do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
+		return nil, nil, resp, err
 	}
 	var resources []Bucket
 	for _, c := range bundleResponse.Entry {
@@ -252,5 +268,5 @@ func (b *ConfigurationsService) FindBucket(opt *GetBucketOptions, options ...Opt
 			resources = append(resources, resource)
 		}
 	}
-	return &resources, resp, err
+	return resources, &bundleResponse, resp, nil
 }
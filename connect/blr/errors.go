@@ -19,4 +19,11 @@ var (
 	ErrEmptyResults                   = errors.New("empty results")
 	ErrOperationFailed                = errors.New("operation failed")
 	ErrCouldNoReadResourceAfterCreate = errors.New("could not read resource after create")
+	ErrMissingBlobID                  = errors.New("missing blob ID")
+	ErrMissingPartURL                 = errors.New("missing access URL for part")
+	ErrPartCountMismatch              = errors.New("number of parts does not match blob")
+	ErrChecksumMismatch               = errors.New("checksum mismatch")
+	ErrTransferFailed                 = errors.New("transfer failed")
+	ErrBlobModified                   = errors.New("blob was modified during download")
+	ErrInvalidRule                    = errors.New("invalid lifecycle rule")
 )
@@ -0,0 +1,219 @@
+package blr
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LifecycleAction is the action a LifecycleRule applies to matching blobs
+type LifecycleAction string
+
+const (
+	// LifecycleDelete deletes matching blobs
+	LifecycleDelete LifecycleAction = "delete"
+	// LifecycleSetPolicy sets the rule Policy on matching blobs
+	LifecycleSetPolicy LifecycleAction = "setPolicy"
+)
+
+// LifecycleRule selects blobs and applies an action to them. All set criteria must
+// match. DataType is filtered by BLR, the other criteria are evaluated locally
+type LifecycleRule struct {
+	Name     string
+	DataType string
+	// Tags lists tag values a blob must all have
+	Tags map[string]string
+	// OlderThan matches blobs created longer ago, or last updated when the creation
+	// time is unknown. Blobs without either are never matched
+	OlderThan time.Duration
+	// Match is an additional predicate
+	Match  func(Blob) bool
+	Action LifecycleAction
+	// Policy is the policy set by LifecycleSetPolicy
+	Policy *BlobPolicy
+}
+
+// LifecycleOptions configures RunLifecycle
+type LifecycleOptions struct {
+	// DryRun reports the actions without applying them
+	DryRun bool
+	// RatePerSecond limits the number of actions applied per second. Zero is unlimited
+	RatePerSecond float64
+	// MaxActions stops the run after this many actions. Zero is unlimited
+	MaxActions int
+	// PageSize is the number of blobs fetched per page
+	PageSize int
+	// Now is the reference time of OlderThan and defaults to time.Now
+	Now func() time.Time
+}
+
+// LifecycleResult is the outcome of one action on one blob
+type LifecycleResult struct {
+	Rule   string
+	Action LifecycleAction
+	Blob   Blob
+	DryRun bool
+	Err    error
+}
+
+// LifecycleReport lists the results of a RunLifecycle call in the order applied
+type LifecycleReport struct {
+	Results []LifecycleResult
+}
+
+// Failed returns the results whose action failed
+func (r *LifecycleReport) Failed() []LifecycleResult {
+	var failed []LifecycleResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Validate checks the rule can be applied. Rules without any criteria are rejected so
+// a missing field cannot delete every blob
+func (r LifecycleRule) Validate() error {
+	if r.DataType == "" && len(r.Tags) == 0 && r.OlderThan <= 0 && r.Match == nil {
+		return fmt.Errorf("rule %q: %w: no criteria", r.Name, ErrInvalidRule)
+	}
+	switch r.Action {
+	case LifecycleDelete:
+	case LifecycleSetPolicy:
+		if r.Policy == nil {
+			return fmt.Errorf("rule %q: %w: missing policy", r.Name, ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("rule %q: %w: unknown action %q", r.Name, ErrInvalidRule, r.Action)
+	}
+	return nil
+}
+
+// Matches reports whether the blob meets the criteria of the rule at time now
+func (r LifecycleRule) Matches(blob Blob, now time.Time) bool {
+	if r.DataType != "" && blob.DataType != r.DataType {
+		return false
+	}
+	for key, value := range r.Tags {
+		if !hasTag(blob, key, value) {
+			return false
+		}
+	}
+	if r.OlderThan > 0 {
+		created, ok := blobTime(blob)
+		if !ok || now.Sub(created) < r.OlderThan {
+			return false
+		}
+	}
+	return r.Match == nil || r.Match(blob)
+}
+
+// RunLifecycle applies the rules in order. The blobs of a rule are all selected before
+// its actions are applied, as deleting while paging would shift the pages. A failing
+// action is recorded in the report and does not stop the run; an invalid rule, a failed
+// search or ctx ending does, returning the report so far
+func (b *BlobsService) RunLifecycle(ctx context.Context, rules []LifecycleRule, opts *LifecycleOptions) (*LifecycleReport, error) {
+	var o LifecycleOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("RunLifecycle: %w", err)
+		}
+	}
+	var interval time.Duration
+	if o.RatePerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / o.RatePerSecond)
+	}
+	var last time.Time
+
+	report := &LifecycleReport{}
+	now := o.Now()
+	for _, rule := range rules {
+		opt := &GetBlobOptions{}
+		if rule.DataType != "" {
+			opt.DataType = &rule.DataType
+		}
+		if o.PageSize > 0 {
+			opt.Count = &o.PageSize
+		}
+		var matched []Blob
+		it := b.Iterate(opt, WithContext(ctx))
+		for it.Next() {
+			if rule.Matches(it.Blob(), now) {
+				matched = append(matched, it.Blob())
+			}
+		}
+		if err := it.Err(); err != nil {
+			return report, fmt.Errorf("RunLifecycle: rule %q: %w", rule.Name, err)
+		}
+
+		for _, blob := range matched {
+			if o.MaxActions > 0 && len(report.Results) >= o.MaxActions {
+				return report, nil
+			}
+			result := LifecycleResult{Rule: rule.Name, Action: rule.Action, Blob: blob, DryRun: o.DryRun}
+			if !o.DryRun {
+				if wait := interval - time.Since(last); interval > 0 && wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						return report, ctx.Err()
+					}
+				}
+				last = time.Now()
+				result.Err = b.applyLifecycle(ctx, rule, blob)
+			}
+			report.Results = append(report.Results, result)
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (b *BlobsService) applyLifecycle(ctx context.Context, rule LifecycleRule, blob Blob) error {
+	var ok bool
+	var err error
+	switch rule.Action {
+	case LifecycleDelete:
+		ok, _, err = b.Delete(blob, WithContext(ctx))
+	case LifecycleSetPolicy:
+		ok, _, err = b.SetPolicy(blob, *rule.Policy, WithContext(ctx))
+	}
+	if err == nil && !ok {
+		err = ErrOperationFailed
+	}
+	return err
+}
+
+func hasTag(blob Blob, key, value string) bool {
+	if blob.Tags == nil {
+		return false
+	}
+	for _, tag := range *blob.Tags {
+		if tag.Key == key && tag.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// blobTime returns the creation time of the blob, or the last update when unknown
+func blobTime(blob Blob) (time.Time, bool) {
+	if blob.Creation != nil {
+		if created, err := time.Parse(time.RFC3339, *blob.Creation); err == nil {
+			return created, true
+		}
+	}
+	if blob.Meta != nil && !blob.Meta.LastUpdated.IsZero() {
+		return blob.Meta.LastUpdated, true
+	}
+	return time.Time{}, false
+}
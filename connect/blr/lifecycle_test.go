@@ -0,0 +1,144 @@
+package blr_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/connect/blr"
+	"github.com/philips-software/go-hsdp-api/internal/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+const blobBase = "/connect/blobrepository/Blob"
+
+// newBlobStore serves blobs two per page and records the policies set on them
+func newBlobStore(blobs ...blr.Blob) (*storetest.Store, map[string]blr.BlobPolicy) {
+	store := storetest.New("/connect/blobrepository")
+	store.PageSize = 2
+	policies := map[string]blr.BlobPolicy{}
+	store.Actions = map[string]storetest.Action{
+		"$setPolicy": func(w http.ResponseWriter, r *http.Request, id string) {
+			var policy blr.BlobPolicy
+			if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			policies[id] = policy
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+	for _, blob := range blobs {
+		store.Add("Blob", blob)
+	}
+	return store, policies
+}
+
+func lifecycleBlob(id, dataType string, age time.Duration, tags ...blr.Tag) blr.Blob {
+	created := time.Now().Add(-age).UTC().Format(time.RFC3339)
+	blob := blr.Blob{ResourceType: "Blob", ID: id, DataType: dataType, Creation: &created}
+	if len(tags) > 0 {
+		blob.Tags = &tags
+	}
+	return blob
+}
+
+func TestIterate(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store, _ := newBlobStore(
+		lifecycleBlob("a", "logs", time.Hour),
+		lifecycleBlob("b", "logs", time.Hour),
+		lifecycleBlob("c", "logs", time.Hour),
+		lifecycleBlob("d", "images", time.Hour))
+	var queries []string
+	muxBLR.HandleFunc(blobBase, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		store.ServeHTTP(w, r)
+	})
+
+	dataType := "logs"
+	var ids []string
+	it := blrClient.Blobs.Iterate(&blr.GetBlobOptions{DataType: &dataType})
+	for it.Next() {
+		ids = append(ids, it.Blob().ID)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+	assert.Equal(t, []string{"dataType=logs", "dataType=logs&page=1"}, queries)
+}
+
+func TestRunLifecycle(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store, policies := newBlobStore(
+		lifecycleBlob("old-log", "logs", 40*24*time.Hour),
+		lifecycleBlob("new-log", "logs", time.Hour),
+		lifecycleBlob("older-log", "logs", 90*24*time.Hour),
+		lifecycleBlob("prod-image", "images", time.Hour, blr.Tag{Key: "env", Value: "prod"}),
+		lifecycleBlob("test-image", "images", time.Hour, blr.Tag{Key: "env", Value: "test"}))
+	failing := map[string]bool{}
+	muxBLR.Handle(blobBase, store)
+	muxBLR.HandleFunc(blobBase+"/", func(w http.ResponseWriter, r *http.Request) {
+		if failing[strings.Split(strings.TrimPrefix(r.URL.Path, blobBase+"/"), "/")[0]] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		store.ServeHTTP(w, r)
+	})
+	policy := blr.BlobPolicy{ResourceType: "BlobPolicy", Statement: []blr.PolicyStatement{{
+		ResourceType: "BlobPolicyStatement",
+		Principal:    blr.PrincipalResourceList{HSDP: []string{"prn:hsdp:iam:org:group/auditors"}},
+		Effect:       "allow",
+		Action:       []string{"GET"},
+	}}}
+	rules := []blr.LifecycleRule{
+		{Name: "log retention", DataType: "logs", OlderThan: 30 * 24 * time.Hour, Action: blr.LifecycleDelete},
+		{Name: "prod access", Tags: map[string]string{"env": "prod"}, Action: blr.LifecycleSetPolicy, Policy: &policy},
+	}
+	outcome := func(report *blr.LifecycleReport) []string {
+		var result []string
+		for _, r := range report.Results {
+			result = append(result, string(r.Action)+" "+r.Blob.ID)
+		}
+		return result
+	}
+
+	report, err := blrClient.Blobs.RunLifecycle(context.Background(), rules, &blr.LifecycleOptions{DryRun: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"delete old-log", "delete older-log", "setPolicy prod-image"}, outcome(report))
+	assert.Equal(t, 5, store.Count("Blob"))
+	assert.Len(t, policies, 0)
+
+	failing["older-log"] = true
+	start := time.Now()
+	report, err = blrClient.Blobs.RunLifecycle(context.Background(), rules, &blr.LifecycleOptions{RatePerSecond: 50})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Len(t, report.Results, 3)
+	if failed := report.Failed(); assert.Len(t, failed, 1) {
+		assert.Equal(t, "older-log", failed[0].Blob.ID)
+	}
+	assert.Equal(t, []string{"new-log", "older-log", "prod-image", "test-image"}, store.IDs("Blob"))
+	assert.Equal(t, policy, policies["prod-image"])
+
+	report, err = blrClient.Blobs.RunLifecycle(context.Background(), rules, &blr.LifecycleOptions{MaxActions: 1})
+	if assert.Nil(t, err) {
+		assert.Len(t, report.Results, 1)
+	}
+
+	_, err = blrClient.Blobs.RunLifecycle(context.Background(), []blr.LifecycleRule{
+		{Name: "everything", Action: blr.LifecycleDelete},
+	}, nil)
+	assert.True(t, errors.Is(err, blr.ErrInvalidRule))
+}
@@ -0,0 +1,78 @@
+package blr
+
+import (
+	"sort"
+	"strings"
+)
+
+// Equals reports whether both policies grant the same access. Statements are compared
+// as a set, ignoring their order, SID and duplicates. Within a statement the principals
+// and actions are compared as sets, actions and effect case-insensitively
+func (p *BlobPolicy) Equals(other *BlobPolicy) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	keys := func(statements []PolicyStatement) []string {
+		result := make([]string, 0, len(statements))
+		for _, s := range statements {
+			result = append(result, statementKey(s.Effect, s.Action, s.Principal.HSDP, nil))
+		}
+		return canonicalSet(result, nil)
+	}
+	return equalStrings(keys(p.Statement), keys(other.Statement))
+}
+
+// Equals reports whether both policies grant the same access, comparing the statements
+// like BlobPolicy.Equals does. The IDs are not compared
+func (p *BlobStorePolicy) Equals(other *BlobStorePolicy) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	keys := func(statements []BlobStorePolicyStatement) []string {
+		result := make([]string, 0, len(statements))
+		for _, s := range statements {
+			result = append(result, statementKey(s.Effect, s.Action, s.Principal, s.Resource))
+		}
+		return canonicalSet(result, nil)
+	}
+	return equalStrings(keys(p.Statement), keys(other.Statement))
+}
+
+func statementKey(effect string, actions, principals, resources []string) string {
+	return strings.Join([]string{
+		strings.ToLower(strings.TrimSpace(effect)),
+		strings.Join(canonicalSet(actions, strings.ToUpper), ","),
+		strings.Join(canonicalSet(principals, nil), ","),
+		strings.Join(canonicalSet(resources, nil), ","),
+	}, "|")
+}
+
+// canonicalSet returns the sorted distinct values, trimmed and mapped by normalize
+func canonicalSet(values []string, normalize func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if normalize != nil {
+			v = normalize(v)
+		}
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,383 @@
+package blr
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/philips-software/go-hsdp-api/internal"
+)
+
+// DesiredConfiguration declares the buckets and policies Reconcile converges to
+type DesiredConfiguration struct {
+	// BlobStorePolicies without an ID are matched to an equal existing policy. With an
+	// ID the policy is replaced when it differs, as policies cannot be updated
+	BlobStorePolicies []BlobStorePolicy
+	// Buckets are identified by their Name
+	Buckets []Bucket
+	// BlobPolicies maps blob IDs to the policy they should have
+	BlobPolicies map[string]BlobPolicy
+}
+
+// ReconcileOptions controls how Reconcile converges the configuration
+type ReconcileOptions struct {
+	// DryRun only reports the changes Reconcile would make
+	DryRun bool
+	// Prune deletes blob store policies that are not declared and buckets that are not
+	// declared in the propositions of the declared buckets
+	Prune bool
+	// ReplaceBuckets deletes and recreates buckets whose immutable fields differ. As this
+	// deletes the bucket contents the difference is only reported as drift otherwise
+	ReplaceBuckets bool
+}
+
+// ChangeAction is the kind of change made by Reconcile
+type ChangeAction string
+
+const (
+	ChangeCreate  ChangeAction = "create"
+	ChangeUpdate  ChangeAction = "update"
+	ChangeDelete  ChangeAction = "delete"
+	ChangeReplace ChangeAction = "replace"
+	// ChangeDrift reports a difference which was not reconciled
+	ChangeDrift ChangeAction = "drift"
+)
+
+// Change is a single change made, or in a dry run planned, by Reconcile
+type Change struct {
+	Resource string
+	Key      string
+	Action   ChangeAction
+	// Fields lists the JSON fields which differ for bucket updates, replacements and drift
+	Fields []string
+}
+
+// ReconcileReport lists the changes of a Reconcile
+type ReconcileReport struct {
+	Changes []Change
+}
+
+// Drift returns the differences which were not reconciled
+func (r *ReconcileReport) Drift() []Change {
+	var drift []Change
+	for _, change := range r.Changes {
+		if change.Action == ChangeDrift {
+			drift = append(drift, change)
+		}
+	}
+	return drift
+}
+
+// immutableBucketFields cannot be changed by UpdateBucket
+var immutableBucketFields = map[string]bool{
+	"propositionId":    true,
+	"enableHSDPDomain": true,
+}
+
+// Reconcile converges blob store policies, buckets and blob policies to the desired
+// configuration, in that order. It stops at the first failing request and returns the
+// changes made until then
+func (c *ConfigurationsService) Reconcile(desired DesiredConfiguration, opts ReconcileOptions, options ...OptionFunc) (*ReconcileReport, error) {
+	r := &reconciler{c: c, opts: opts, options: options, report: &ReconcileReport{}}
+	if err := r.blobStorePolicies(desired.BlobStorePolicies); err != nil {
+		return r.report, fmt.Errorf("Reconcile: %w", err)
+	}
+	if err := r.buckets(desired.Buckets); err != nil {
+		return r.report, fmt.Errorf("Reconcile: %w", err)
+	}
+	if err := r.blobPolicies(desired.BlobPolicies); err != nil {
+		return r.report, fmt.Errorf("Reconcile: %w", err)
+	}
+	return r.report, nil
+}
+
+type reconciler struct {
+	c       *ConfigurationsService
+	opts    ReconcileOptions
+	options []OptionFunc
+	report  *ReconcileReport
+}
+
+func (r *reconciler) record(resource, key string, action ChangeAction, fields ...string) {
+	r.report.Changes = append(r.report.Changes, Change{Resource: resource, Key: key, Action: action, Fields: fields})
+}
+
+func (r *reconciler) blobStorePolicies(desired []BlobStorePolicy) error {
+	if len(desired) == 0 && !r.opts.Prune {
+		return nil
+	}
+	existing, err := findAll(func(options ...OptionFunc) ([]BlobStorePolicy, *internal.Bundle, error) {
+		page, bundle, _, err := r.c.findBlobStorePolicy(nil, options...)
+		return page, bundle, err
+	}, r.options)
+	if err != nil {
+		return fmt.Errorf("find blob store policies: %w", err)
+	}
+	kept := make(map[string]bool)
+	for _, policy := range desired {
+		var match, replaced *BlobStorePolicy
+		for i := range existing {
+			current := &existing[i]
+			if policy.ID != "" && current.ID == policy.ID {
+				if current.Equals(&policy) {
+					match = current
+				} else {
+					replaced = current
+				}
+				break
+			}
+			if policy.ID == "" && !kept[current.ID] && current.Equals(&policy) {
+				match = current
+				break
+			}
+		}
+		if match != nil {
+			kept[match.ID] = true
+			continue
+		}
+		action := ChangeCreate
+		if replaced != nil {
+			action = ChangeReplace
+		}
+		r.record("blobStorePolicy", policy.ID, action)
+		if r.opts.DryRun {
+			continue
+		}
+		policy.ID = ""
+		created, _, err := r.c.CreateBlobStorePolicy(policy, r.options...)
+		if err != nil {
+			return fmt.Errorf("create blob store policy: %w", err)
+		}
+		kept[created.ID] = true
+		if replaced != nil {
+			if err := r.deleteBlobStorePolicy(*replaced); err != nil {
+				return err
+			}
+		}
+	}
+	if !r.opts.Prune {
+		return nil
+	}
+	for _, current := range existing {
+		if kept[current.ID] || r.replaced(current.ID) {
+			continue
+		}
+		r.record("blobStorePolicy", current.ID, ChangeDelete)
+		if r.opts.DryRun {
+			continue
+		}
+		if err := r.deleteBlobStorePolicy(current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) replaced(id string) bool {
+	for _, change := range r.report.Changes {
+		if change.Resource == "blobStorePolicy" && change.Key == id && change.Action == ChangeReplace {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *reconciler) deleteBlobStorePolicy(policy BlobStorePolicy) error {
+	ok, _, err := r.c.DeleteBlobStorePolicy(policy, r.options...)
+	if err == nil && !ok {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return fmt.Errorf("delete blob store policy %s: %w", policy.ID, err)
+	}
+	return nil
+}
+
+func (r *reconciler) buckets(desired []Bucket) error {
+	declared := make(map[string]bool)
+	propositions := make(map[string]bool)
+	for _, bucket := range desired {
+		declared[bucket.Name] = true
+		propositions[bucket.PropositionID.Reference] = true
+		name := bucket.Name
+		found, err := r.findBuckets(&GetBucketOptions{Name: &name})
+		if err != nil {
+			return fmt.Errorf("find bucket %s: %w", name, err)
+		}
+		var current *Bucket
+		for i := range found {
+			if found[i].Name == name {
+				current = &found[i]
+			}
+		}
+		if current == nil {
+			r.record("bucket", name, ChangeCreate)
+			if !r.opts.DryRun {
+				if _, _, err := r.c.CreateBucket(bucket, r.options...); err != nil {
+					return fmt.Errorf("create bucket %s: %w", name, err)
+				}
+			}
+			continue
+		}
+		fields, immutable := bucketDiff(bucket, *current)
+		switch {
+		case len(fields) == 0:
+		case immutable && !r.opts.ReplaceBuckets:
+			r.record("bucket", name, ChangeDrift, fields...)
+		case immutable:
+			r.record("bucket", name, ChangeReplace, fields...)
+			if r.opts.DryRun {
+				continue
+			}
+			if err := r.deleteBucket(*current); err != nil {
+				return err
+			}
+			bucket.ID = ""
+			if _, _, err := r.c.CreateBucket(bucket, r.options...); err != nil {
+				return fmt.Errorf("create bucket %s: %w", name, err)
+			}
+		default:
+			r.record("bucket", name, ChangeUpdate, fields...)
+			if r.opts.DryRun {
+				continue
+			}
+			bucket.ID = current.ID
+			if _, _, err := r.c.UpdateBucket(bucket, r.options...); err != nil {
+				return fmt.Errorf("update bucket %s: %w", name, err)
+			}
+		}
+	}
+	if !r.opts.Prune {
+		return nil
+	}
+	references := make([]string, 0, len(propositions))
+	for reference := range propositions {
+		references = append(references, reference)
+	}
+	sort.Strings(references)
+	for _, reference := range references {
+		reference := reference
+		found, err := r.findBuckets(&GetBucketOptions{PropositionID: &reference})
+		if err != nil {
+			return fmt.Errorf("find buckets of proposition %s: %w", reference, err)
+		}
+		for _, current := range found {
+			if declared[current.Name] {
+				continue
+			}
+			r.record("bucket", current.Name, ChangeDelete)
+			if r.opts.DryRun {
+				continue
+			}
+			if err := r.deleteBucket(current); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) findBuckets(opt *GetBucketOptions) ([]Bucket, error) {
+	return findAll(func(options ...OptionFunc) ([]Bucket, *internal.Bundle, error) {
+		page, bundle, _, err := r.c.findBucket(opt, options...)
+		return page, bundle, err
+	}, r.options)
+}
+
+func (r *reconciler) deleteBucket(bucket Bucket) error {
+	ok, _, err := r.c.DeleteBucket(bucket, r.options...)
+	if err == nil && !ok {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return fmt.Errorf("delete bucket %s: %w", bucket.Name, err)
+	}
+	return nil
+}
+
+func (r *reconciler) blobPolicies(desired map[string]BlobPolicy) error {
+	ids := make([]string, 0, len(desired))
+	for id := range desired {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		policy := desired[id]
+		blob := Blob{ID: id}
+		current, resp, err := r.c.Blobs.GetPolicy(blob, r.options...)
+		action := ChangeUpdate
+		switch {
+		case err != nil && resp != nil && resp.StatusCode() == http.StatusNotFound:
+			action = ChangeCreate
+		case err != nil:
+			return fmt.Errorf("get policy of blob %s: %w", id, err)
+		case current.Equals(&policy):
+			continue
+		}
+		r.record("blobPolicy", id, action)
+		if r.opts.DryRun {
+			continue
+		}
+		policy.ResourceType = "BlobPolicy"
+		ok, _, err := r.c.Blobs.SetPolicy(blob, policy, r.options...)
+		if err == nil && !ok {
+			err = ErrOperationFailed
+		}
+		if err != nil {
+			return fmt.Errorf("set policy of blob %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// findAll collects the results of every page of a search
+func findAll[T any](find func(options ...OptionFunc) ([]T, *internal.Bundle, error), options []OptionFunc) ([]T, error) {
+	pager := internal.NewPager(find, options...)
+	var results []T
+	for pager.Next() {
+		results = append(results, pager.Current())
+	}
+	return results, pager.Err()
+}
+
+// bucketDiff returns the JSON fields of the bucket which differ and whether any of
+// them is immutable
+func bucketDiff(desired, actual Bucket) ([]string, bool) {
+	var fields []string
+	differs := func(field string, equal bool) {
+		if !equal {
+			fields = append(fields, field)
+		}
+	}
+	differs("enableHSDPDomain", desired.EnableHSDPDomain == actual.EnableHSDPDomain)
+	differs("enableCDN", desired.EnableCDN == actual.EnableCDN)
+	// An empty price class or cache control age leaves the one chosen by BLR
+	differs("priceClass", desired.PriceClass == "" || desired.PriceClass == actual.PriceClass)
+	differs("cacheControlAge", desired.CacheControlAge == 0 || desired.CacheControlAge == actual.CacheControlAge)
+	differs("propositionId", desired.PropositionID.Reference == actual.PropositionID.Reference)
+	differs("corsConfiguration", equalCors(desired.CorsConfiguration, actual.CorsConfiguration))
+	differs("enableCreateOrDeleteBlobMeta", desired.EnableCreateOrDeleteBlobMeta == actual.EnableCreateOrDeleteBlobMeta)
+	immutable := false
+	for _, field := range fields {
+		immutable = immutable || immutableBucketFields[field]
+	}
+	return fields, immutable
+}
+
+func equalCors(a, b CorsConfiguration) bool {
+	sets := func(c CorsConfiguration) [][]string {
+		return [][]string{
+			canonicalSet(c.AllowedOrigins, nil),
+			canonicalSet(c.AllowedMethods, nil),
+			canonicalSet(c.AllowedHeaders, nil),
+			canonicalSet(c.ExposeHeaders, nil),
+		}
+	}
+	setsA, setsB := sets(a), sets(b)
+	for i := range setsA {
+		if !equalStrings(setsA[i], setsB[i]) {
+			return false
+		}
+	}
+	return a.MaxAgeSeconds == b.MaxAgeSeconds
+}
@@ -0,0 +1,296 @@
+package blr_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/connect/blr"
+	"github.com/philips-software/go-hsdp-api/internal/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyEquals(t *testing.T) {
+	sid := "first"
+	a := blr.BlobPolicy{ResourceType: "BlobPolicy", Statement: []blr.PolicyStatement{
+		{SID: &sid, Effect: "Allow", Action: []string{"get", "PUT"}, Principal: blr.PrincipalResourceList{HSDP: []string{"p1", "p2"}}},
+		{Effect: "deny", Action: []string{"DELETE"}, Principal: blr.PrincipalResourceList{HSDP: []string{"p3"}}},
+	}}
+	b := blr.BlobPolicy{ResourceType: "BlobPolicy", Statement: []blr.PolicyStatement{
+		{Effect: "deny", Action: []string{"DELETE"}, Principal: blr.PrincipalResourceList{HSDP: []string{"p3"}}},
+		{Effect: "allow", Action: []string{"PUT", "GET", "GET"}, Principal: blr.PrincipalResourceList{HSDP: []string{"p2", "p1"}}},
+	}}
+	assert.True(t, a.Equals(&b))
+	b.Statement[1].Principal.HSDP = []string{"p1"}
+	assert.False(t, a.Equals(&b))
+	assert.False(t, a.Equals(nil))
+
+	s1 := blr.BlobStorePolicy{ID: "1", Statement: []blr.BlobStorePolicyStatement{
+		{Effect: "allow", Action: []string{"GET"}, Principal: []string{"p1"}, Resource: []string{"r1", "r2"}},
+	}}
+	s2 := blr.BlobStorePolicy{ID: "2", Statement: []blr.BlobStorePolicyStatement{
+		{Effect: "allow", Action: []string{"GET"}, Principal: []string{"p1"}, Resource: []string{"r2", "r1"}},
+	}}
+	assert.True(t, s1.Equals(&s2))
+	s2.Statement[0].Effect = "deny"
+	assert.False(t, s1.Equals(&s2))
+}
+
+func TestReconcile(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := storetest.New("/connect/blobrepository/configuration")
+	muxBLR.Handle("/connect/blobrepository/configuration/", store)
+	blobPolicies := map[string]blr.BlobPolicy{}
+	muxBLR.HandleFunc("/connect/blobrepository/Blob/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/connect/blobrepository/Blob/"), "/")
+		switch parts[1] {
+		case "$getPolicy":
+			policy, ok := blobPolicies[parts[0]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(policy)
+		case "$setPolicy":
+			var policy blr.BlobPolicy
+			_ = json.NewDecoder(r.Body).Decode(&policy)
+			blobPolicies[parts[0]] = policy
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	readPolicy := blr.BlobStorePolicy{Statement: []blr.BlobStorePolicyStatement{
+		{Effect: "allow", Action: []string{"GET"}, Principal: []string{"prn:hsdp:iam:org:group/readers"}, Resource: []string{"bucket-a/*"}},
+	}}
+	store.Add("BlobStorePolicy", blr.BlobStorePolicy{ID: "existing-read", ResourceType: "BlobStorePolicy",
+		Statement: []blr.BlobStorePolicyStatement{{Effect: "Allow", Action: []string{"get"},
+			Principal: []string{"prn:hsdp:iam:org:group/readers"}, Resource: []string{"bucket-a/*"}}}})
+	store.Add("BlobStorePolicy", blr.BlobStorePolicy{ID: "stale", ResourceType: "BlobStorePolicy"})
+	store.Add("Bucket", blr.Bucket{ID: "bucket-a", Name: "bucket-a", PropositionID: blr.Reference{Reference: "prop"}, EnableCDN: true, CacheControlAge: 3600})
+	store.Add("Bucket", blr.Bucket{ID: "bucket-b", Name: "bucket-b", PropositionID: blr.Reference{Reference: "old-prop"}})
+	store.Add("Bucket", blr.Bucket{ID: "bucket-old", Name: "bucket-old", PropositionID: blr.Reference{Reference: "prop"}})
+	blobPolicy := blr.BlobPolicy{Statement: []blr.PolicyStatement{{ResourceType: "BlobPolicyStatement",
+		Effect: "allow", Action: []string{"GET"}, Principal: blr.PrincipalResourceList{HSDP: []string{"p1"}}}}}
+
+	desired := blr.DesiredConfiguration{
+		BlobStorePolicies: []blr.BlobStorePolicy{readPolicy, {Statement: []blr.BlobStorePolicyStatement{
+			{Effect: "allow", Action: []string{"PUT"}, Principal: []string{"prn:hsdp:iam:org:group/writers"}, Resource: []string{"bucket-a/*"}},
+		}}},
+		Buckets: []blr.Bucket{
+			{Name: "bucket-a", PropositionID: blr.Reference{Reference: "prop"}, EnableCDN: false},
+			{Name: "bucket-b", PropositionID: blr.Reference{Reference: "prop"}},
+			{Name: "bucket-c", PropositionID: blr.Reference{Reference: "prop"}},
+		},
+		BlobPolicies: map[string]blr.BlobPolicy{"blob-1": blobPolicy},
+	}
+	changes := func(report *blr.ReconcileReport) []string {
+		var result []string
+		for _, change := range report.Changes {
+			result = append(result, string(change.Action)+" "+change.Resource+" "+change.Key+" "+strings.Join(change.Fields, ","))
+		}
+		return result
+	}
+
+	report, err := blrClient.Configurations.Reconcile(desired, blr.ReconcileOptions{DryRun: true, Prune: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	expected := []string{
+		"create blobStorePolicy  ",
+		"delete blobStorePolicy stale ",
+		"update bucket bucket-a enableCDN",
+		"drift bucket bucket-b propositionId",
+		"create bucket bucket-c ",
+		"delete bucket bucket-old ",
+		"create blobPolicy blob-1 ",
+	}
+	assert.Equal(t, expected, changes(report))
+	assert.Equal(t, 2, store.Count("BlobStorePolicy"))
+	assert.Equal(t, 3, store.Count("Bucket"))
+
+	report, err = blrClient.Configurations.Reconcile(desired, blr.ReconcileOptions{Prune: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, expected, changes(report))
+	assert.Equal(t, 2, store.Count("BlobStorePolicy"))
+	assert.Contains(t, store.IDs("BlobStorePolicy"), "existing-read")
+	var bucketA, bucketB blr.Bucket
+	if assert.True(t, store.Get("Bucket", "bucket-a", &bucketA)) {
+		assert.False(t, bucketA.EnableCDN)
+	}
+	if assert.True(t, store.Get("Bucket", "bucket-b", &bucketB)) {
+		assert.Equal(t, "old-prop", bucketB.PropositionID.Reference)
+	}
+	assert.NotContains(t, store.IDs("Bucket"), "bucket-old")
+	assert.Equal(t, blobPolicy.Statement, blobPolicies["blob-1"].Statement)
+	assert.Len(t, report.Drift(), 1)
+
+	report, err = blrClient.Configurations.Reconcile(desired, blr.ReconcileOptions{Prune: true, ReplaceBuckets: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"replace bucket bucket-b propositionId"}, changes(report))
+	assert.NotContains(t, store.IDs("Bucket"), "bucket-b")
+
+	// Changing a policy with an ID replaces it
+	desired.BlobStorePolicies[0].ID = "existing-read"
+	desired.BlobStorePolicies[0].Statement[0].Action = []string{"GET", "PUT"}
+	report, err = blrClient.Configurations.Reconcile(desired, blr.ReconcileOptions{Prune: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"replace blobStorePolicy existing-read "}, changes(report))
+	assert.NotContains(t, store.IDs("BlobStorePolicy"), "existing-read")
+	assert.Equal(t, 2, store.Count("BlobStorePolicy"))
+
+	desired.BlobStorePolicies[0].ID = ""
+	report, err = blrClient.Configurations.Reconcile(desired, blr.ReconcileOptions{Prune: true})
+	if assert.Nil(t, err) {
+		assert.Len(t, report.Changes, 0)
+	}
+}
+
+func TestReconcilePaging(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxBLR.HandleFunc("/connect/blobrepository/configuration/BlobStorePolicy", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, http.MethodGet, r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = io.WriteString(w, `{
+  "resourceType": "Bundle",
+  "type": "searchset",
+  "entry": [
+    {
+      "resource": {
+        "resourceType": "BlobStorePolicy",
+        "id": "policy-2",
+        "statement": [
+          {
+            "effect": "allow",
+            "action": ["GET"],
+            "principal": ["prn:hsdp:iam:org:group/readers"],
+            "resource": ["bucket-a/*"]
+          }
+        ]
+      }
+    }
+  ]
+}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+  "resourceType": "Bundle",
+  "type": "searchset",
+  "link": [
+    {
+      "relation": "next",
+      "url": "`+serverBLR.URL+`/connect/blobrepository/configuration/BlobStorePolicy?page=2"
+    }
+  ],
+  "entry": [
+    {
+      "resource": {
+        "resourceType": "BlobStorePolicy",
+        "id": "policy-1",
+        "statement": []
+      }
+    }
+  ]
+}`)
+	})
+	muxBLR.HandleFunc("/connect/blobrepository/configuration/Bucket", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, http.MethodGet, r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		switch {
+		case r.URL.Query().Get("page") == "2":
+			_, _ = io.WriteString(w, `{
+  "resourceType": "Bundle",
+  "type": "searchset",
+  "entry": [
+    {
+      "resource": {
+        "resourceType": "Bucket",
+        "id": "bucket-a",
+        "name": "bucket-a",
+        "propositionId": {"reference": "prop"}
+      }
+    }
+  ]
+}`)
+		case r.URL.Query().Get("propositionId") == "prop":
+			_, _ = io.WriteString(w, `{
+  "resourceType": "Bundle",
+  "type": "searchset",
+  "link": [
+    {
+      "relation": "next",
+      "url": "`+serverBLR.URL+`/connect/blobrepository/configuration/Bucket?name=bucket-a&page=2"
+    }
+  ],
+  "entry": [
+    {
+      "resource": {
+        "resourceType": "Bucket",
+        "id": "bucket-old",
+        "name": "bucket-old",
+        "propositionId": {"reference": "prop"}
+      }
+    }
+  ]
+}`)
+		default:
+			_, _ = io.WriteString(w, `{
+  "resourceType": "Bundle",
+  "type": "searchset",
+  "link": [
+    {
+      "relation": "next",
+      "url": "`+serverBLR.URL+`/connect/blobrepository/configuration/Bucket?name=bucket-a&page=2"
+    }
+  ],
+  "entry": [
+    {
+      "resource": {
+        "resourceType": "Bucket",
+        "id": "bucket-a-archive",
+        "name": "bucket-a-archive",
+        "propositionId": {"reference": "other-prop"}
+      }
+    }
+  ]
+}`)
+		}
+	})
+
+	desired := blr.DesiredConfiguration{
+		BlobStorePolicies: []blr.BlobStorePolicy{{Statement: []blr.BlobStorePolicyStatement{
+			{Effect: "allow", Action: []string{"GET"}, Principal: []string{"prn:hsdp:iam:org:group/readers"}, Resource: []string{"bucket-a/*"}},
+		}}},
+		Buckets: []blr.Bucket{{Name: "bucket-a", PropositionID: blr.Reference{Reference: "prop"}}},
+	}
+	report, err := blrClient.Configurations.Reconcile(desired, blr.ReconcileOptions{DryRun: true, Prune: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	// The policy and the bucket on the second pages exist, the first page items are pruned
+	assert.Equal(t, []blr.Change{
+		{Resource: "blobStorePolicy", Key: "policy-1", Action: blr.ChangeDelete},
+		{Resource: "bucket", Key: "bucket-old", Action: blr.ChangeDelete},
+	}, report.Changes)
+}
@@ -0,0 +1,592 @@
+package blr
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPartSize is the part size used when TransferOptions does not set one
+	DefaultPartSize int64 = 16 << 20
+	// MinPartSize is the smallest part size the object store accepts, except for the last part
+	MinPartSize int64 = 5 << 20
+	// MaxParts is the maximum number of parts of a multipart upload
+	MaxParts = 10000
+	// DefaultConcurrency is the number of parts transferred in parallel
+	DefaultConcurrency = 4
+	// DefaultMaxRetries is the number of times a failed part is retried
+	DefaultMaxRetries = 3
+	// DefaultRetryWait is the wait before the first retry of a part
+	DefaultRetryWait = 500 * time.Millisecond
+)
+
+// Progress reports the state of a transfer after a part completes
+type Progress struct {
+	PartNumber  int
+	Transferred int64
+	Total       int64
+}
+
+// TransferOptions configures Upload and Download. The zero value uses the defaults
+type TransferOptions struct {
+	// PartSize is the size of the parts. Upload raises it when the blob would
+	// otherwise need more than MaxParts parts. Sizes below MinPartSize are only
+	// accepted by the object store for single part blobs
+	PartSize int64
+	// Concurrency is the number of parts transferred in parallel
+	Concurrency int
+	// MaxRetries is the number of retries of a part failing with a network error,
+	// a 5xx or 429 status or a checksum mismatch
+	MaxRetries int
+	// RetryWait is the wait before the first retry. It doubles on every retry
+	RetryWait time.Duration
+	// Progress is called after each part. Calls are never concurrent
+	Progress func(Progress)
+	// KeepOnFailure skips the AbortUpload of a failed multipart upload, so it can be
+	// resumed by passing the blob to Upload again
+	KeepOnFailure bool
+	// IgnoreETag skips comparing ETags with the MD5 of the data, for object stores
+	// whose ETags are not MD5 digests, e.g. when using KMS encryption
+	IgnoreETag bool
+}
+
+// PartCount returns the number of parts Upload uses for a new blob of size bytes, e.g. to
+// create the blob before uploading to it
+func (o *TransferOptions) PartCount(size int64) int {
+	var partSize int64
+	if o != nil {
+		partSize = o.PartSize
+	}
+	return partCount(size, choosePartSize(size, partSize))
+}
+
+type transfer struct {
+	TransferOptions
+	client *http.Client
+
+	mu          sync.Mutex
+	transferred int64
+	total       int64
+}
+
+func newTransfer(b *BlobsService, opts *TransferOptions, total int64) *transfer {
+	t := &transfer{client: b.HttpClient(), total: total}
+	if opts != nil {
+		t.TransferOptions = *opts
+	}
+	if t.PartSize <= 0 {
+		t.PartSize = DefaultPartSize
+	}
+	if t.Concurrency <= 0 {
+		t.Concurrency = DefaultConcurrency
+	}
+	if t.MaxRetries < 0 {
+		t.MaxRetries = 0
+	} else if t.MaxRetries == 0 {
+		t.MaxRetries = DefaultMaxRetries
+	}
+	if t.RetryWait <= 0 {
+		t.RetryWait = DefaultRetryWait
+	}
+	return t
+}
+
+// Upload uploads size bytes from r to the blob and returns the blob. A blob without
+// an ID is created first, as a multipart blob when it needs more than one part.
+// Uploading to an existing multipart blob resumes it: parts reported by ListParts whose
+// size and ETag match the data are not uploaded again. Unless KeepOnFailure is set a
+// multipart upload that fails is aborted, except when ctx is cancelled
+func (b *BlobsService) Upload(ctx context.Context, blob Blob, r io.ReaderAt, size int64, opts *TransferOptions) (*Blob, error) {
+	t := newTransfer(b, opts, size)
+	partSize := choosePartSize(size, t.PartSize)
+	count := partCount(size, partSize)
+	multipart := count > 1
+	var uploaded []PartUpload
+
+	if blob.ID == "" {
+		blob.MultipartEnabled = multipart
+		blob.NoOfParts = nil
+		if multipart {
+			blob.NoOfParts = &count
+		}
+		created, _, err := b.Create(blob, WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("Upload: create blob: %w", err)
+		}
+		blob = *created
+	} else {
+		multipart = blob.MultipartEnabled
+		if multipart {
+			if blob.NoOfParts == nil {
+				return nil, fmt.Errorf("Upload: %w", ErrPartCountMismatch)
+			}
+			listed, _, err := b.ListParts(blob, WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("Upload: list parts: %w", err)
+			}
+			uploaded = listed.BlobParts
+			count = *blob.NoOfParts
+			// The first part has the part size the upload was started with
+			for _, part := range uploaded {
+				if part.PartNumber == 1 && count > 1 {
+					partSize = int64(part.Size)
+				}
+			}
+			if partCount(size, partSize) != count {
+				return nil, fmt.Errorf("Upload: %w: %d parts of %d bytes cannot hold %d bytes",
+					ErrPartCountMismatch, count, partSize, size)
+			}
+		}
+	}
+
+	access, _, err := b.GetAccessURL(blob, WithContext(ctx))
+	if err != nil {
+		return nil, b.abort(ctx, t, blob, multipart, fmt.Errorf("Upload: get access URL: %w", err))
+	}
+	if !multipart {
+		data, err := readSection(r, 0, size)
+		if err != nil {
+			return nil, fmt.Errorf("Upload: %w", err)
+		}
+		if _, err := t.put(ctx, access.URL, data); err != nil {
+			return nil, fmt.Errorf("Upload: %w", err)
+		}
+		t.progress(1, size)
+		return &blob, nil
+	}
+
+	urls := make(map[int]string, len(access.BlobPartURLs))
+	for _, part := range access.BlobPartURLs {
+		urls[part.PartNumber] = part.DataAccessURL
+	}
+	existing := make(map[int]PartUpload, len(uploaded))
+	for _, part := range uploaded {
+		existing[part.PartNumber] = part
+	}
+	section := func(partNumber int) (int64, int64) {
+		offset := int64(partNumber-1) * partSize
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		return offset, length
+	}
+
+	var (
+		mu        sync.Mutex
+		completed []PartUpload
+		pending   []int
+	)
+	for partNumber := 1; partNumber <= count; partNumber++ {
+		offset, length := section(partNumber)
+		if part, ok := existing[partNumber]; ok && int64(part.Size) == length {
+			data, err := readSection(r, offset, length)
+			if err != nil {
+				return nil, b.abort(ctx, t, blob, multipart, fmt.Errorf("Upload: %w", err))
+			}
+			if t.etagMatches(part.ETag, data) {
+				completed = append(completed, part)
+				t.transferred += length
+				continue
+			}
+		}
+		if urls[partNumber] == "" {
+			return nil, b.abort(ctx, t, blob, multipart, fmt.Errorf("Upload: part %d: %w", partNumber, ErrMissingPartURL))
+		}
+		pending = append(pending, partNumber)
+	}
+
+	err = t.run(ctx, pending, func(ctx context.Context, partNumber int) (int64, error) {
+		offset, length := section(partNumber)
+		data, err := readSection(r, offset, length)
+		if err != nil {
+			return 0, err
+		}
+		etag, err := t.put(ctx, urls[partNumber], data)
+		if err != nil {
+			return 0, err
+		}
+		mu.Lock()
+		completed = append(completed, PartUpload{PartNumber: partNumber, Size: int(length), ETag: etag})
+		mu.Unlock()
+		return length, nil
+	})
+	if err != nil {
+		return nil, b.abort(ctx, t, blob, multipart, fmt.Errorf("Upload: %w", err))
+	}
+
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].PartNumber < completed[j].PartNumber
+	})
+	ok, _, err := b.CompleteUpload(blob, BlobPartUpload{
+		ResourceType: "BlobPartUpload",
+		BlobParts:    completed,
+	}, WithContext(ctx))
+	if err == nil && !ok {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return nil, b.abort(ctx, t, blob, multipart, fmt.Errorf("Upload: complete upload: %w", err))
+	}
+	return &blob, nil
+}
+
+// abort aborts a failed multipart upload unless it should be kept for resuming
+func (b *BlobsService) abort(ctx context.Context, t *transfer, blob Blob, multipart bool, err error) error {
+	if !multipart || t.KeepOnFailure || ctx.Err() != nil {
+		return err
+	}
+	if _, _, abortErr := b.AbortUpload(blob, WithContext(ctx)); abortErr != nil {
+		return fmt.Errorf("%w (abort upload: %v)", err, abortErr)
+	}
+	return err
+}
+
+// Download writes the blob data to w and returns the number of bytes written. The
+// first part is fetched alone to learn the size, the remaining parts are fetched in
+// parallel as byte ranges of the same version. When w is also an io.ReaderAt the
+// data is verified against the ETag, if the ETag is an MD5 digest
+func (b *BlobsService) Download(ctx context.Context, blob Blob, w io.WriterAt, opts *TransferOptions) (int64, error) {
+	if blob.ID == "" {
+		return 0, fmt.Errorf("Download: %w", ErrMissingBlobID)
+	}
+	t := newTransfer(b, opts, -1)
+	access, _, err := b.GetAccessURL(blob, WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("Download: get access URL: %w", err)
+	}
+	partSize := t.PartSize
+
+	var etag string
+	var ranged bool
+	err = t.retry(ctx, func() error {
+		resp, err := t.get(ctx, access.URL, 0, partSize, "")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		etag = resp.Header.Get("ETag")
+		switch resp.StatusCode {
+		case http.StatusOK:
+			// Ranges are not supported, the body is the whole blob
+			n, err := io.Copy(io.NewOffsetWriter(w, 0), resp.Body)
+			if err != nil {
+				return err
+			}
+			if resp.ContentLength >= 0 && n != resp.ContentLength {
+				return io.ErrUnexpectedEOF
+			}
+			t.total = n
+			return nil
+		case http.StatusPartialContent:
+			total, err := parseContentRange(resp.Header.Get("Content-Range"))
+			if err != nil {
+				return err
+			}
+			t.total = total
+			ranged = true
+			length := partSize
+			if length > total {
+				length = total
+			}
+			return copyRange(w, resp.Body, 0, length)
+		case http.StatusRequestedRangeNotSatisfiable:
+			t.total = 0
+			return nil
+		}
+		return &statusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Download: part 1: %w", err)
+	}
+	first := t.total
+	if ranged && first > partSize {
+		first = partSize
+	}
+	t.progress(1, first)
+
+	if ranged {
+		var pending []int
+		for partNumber := 2; int64(partNumber-1)*partSize < t.total; partNumber++ {
+			pending = append(pending, partNumber)
+		}
+		err = t.run(ctx, pending, func(ctx context.Context, partNumber int) (int64, error) {
+			offset := int64(partNumber-1) * partSize
+			length := partSize
+			if offset+length > t.total {
+				length = t.total - offset
+			}
+			err := t.retry(ctx, func() error {
+				resp, err := t.get(ctx, access.URL, offset, length, etag)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = resp.Body.Close()
+				}()
+				switch resp.StatusCode {
+				case http.StatusPartialContent:
+					return copyRange(w, resp.Body, offset, length)
+				case http.StatusPreconditionFailed:
+					return ErrBlobModified
+				}
+				return &statusError{StatusCode: resp.StatusCode, Status: resp.Status}
+			})
+			return length, err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("Download: %w", err)
+		}
+	}
+
+	if ra, ok := w.(io.ReaderAt); ok && !t.IgnoreETag && isMD5ETag(etag) {
+		hash := md5.New()
+		if _, err := io.Copy(hash, io.NewSectionReader(ra, 0, t.total)); err != nil {
+			return t.total, fmt.Errorf("Download: verify: %w", err)
+		}
+		if !t.etagMatchesSum(etag, hash.Sum(nil)) {
+			return t.total, fmt.Errorf("Download: %w: ETag %s", ErrChecksumMismatch, etag)
+		}
+	}
+	return t.total, nil
+}
+
+// run calls fn for the parts using Concurrency workers and stops at the first error
+func (t *transfer) run(ctx context.Context, parts []int, fn func(ctx context.Context, partNumber int) (int64, error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i := 0; i < t.Concurrency && i < len(parts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range jobs {
+				n, err := fn(ctx, partNumber)
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("part %d: %w", partNumber, err)
+						cancel()
+					})
+					continue
+				}
+				t.progress(partNumber, n)
+			}
+		}()
+	}
+	queued := 0
+queue:
+	for _, partNumber := range parts {
+		select {
+		case jobs <- partNumber:
+			queued++
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if queued < len(parts) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (t *transfer) progress(partNumber int, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.transferred += n
+	if t.Progress != nil {
+		t.Progress(Progress{PartNumber: partNumber, Transferred: t.transferred, Total: t.total})
+	}
+}
+
+// retry calls fn until it succeeds, fails permanently or MaxRetries is exhausted
+func (t *transfer) retry(ctx context.Context, fn func() error) error {
+	wait := t.RetryWait
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= t.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// put uploads data to a pre-signed URL and returns the ETag. The object store
+// verifies the Content-MD5 header, the ETag is verified here
+func (t *transfer) put(ctx context.Context, url string, data []byte) (string, error) {
+	sum := md5.Sum(data)
+	var etag string
+	err := t.retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		resp, err := t.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return &statusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		}
+		etag = resp.Header.Get("ETag")
+		if etag == "" || !t.etagMatchesSum(etag, sum[:]) {
+			return fmt.Errorf("%w: ETag %q", ErrChecksumMismatch, etag)
+		}
+		return nil
+	})
+	return etag, err
+}
+
+// get requests length bytes at offset from a pre-signed URL
+func (t *transfer) get(ctx context.Context, url string, offset, length int64, etag string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	return t.client.Do(req)
+}
+
+func (t *transfer) etagMatches(etag string, data []byte) bool {
+	sum := md5.Sum(data)
+	return t.etagMatchesSum(etag, sum[:])
+}
+
+func (t *transfer) etagMatchesSum(etag string, sum []byte) bool {
+	if t.IgnoreETag {
+		return true
+	}
+	return strings.EqualFold(strings.Trim(etag, `"`), hex.EncodeToString(sum))
+}
+
+// isMD5ETag reports whether the ETag is the MD5 digest of the data, which is not
+// the case for blobs uploaded in multiple parts
+func isMD5ETag(etag string) bool {
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(etag)
+	return err == nil
+}
+
+// statusError is an unexpected status from the object store
+type statusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *statusError) Error() string {
+	return ErrTransferFailed.Error() + ": " + e.Status
+}
+
+func (e *statusError) Unwrap() error {
+	return ErrTransferFailed
+}
+
+func retryable(err error) bool {
+	var se *statusError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, ErrBlobModified), errors.Is(err, ErrMalformedInputValue):
+		return false
+	case errors.As(err, &se):
+		return se.StatusCode >= 500 || se.StatusCode == http.StatusTooManyRequests ||
+			se.StatusCode == http.StatusRequestTimeout
+	}
+	// Network errors, short reads and checksum mismatches
+	return true
+}
+
+func choosePartSize(size, partSize int64) int64 {
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	if minimum := (size + MaxParts - 1) / MaxParts; partSize < minimum {
+		// Round up to whole MiB
+		partSize = (minimum + 1<<20 - 1) &^ (1<<20 - 1)
+	}
+	return partSize
+}
+
+func partCount(size, partSize int64) int {
+	if size <= 0 {
+		return 1
+	}
+	return int((size + partSize - 1) / partSize)
+}
+
+func readSection(r io.ReaderAt, offset, length int64) ([]byte, error) {
+	data := make([]byte, length)
+	n, err := r.ReadAt(data, offset)
+	if int64(n) == length {
+		return data, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, fmt.Errorf("read %d bytes at %d: %w", length, offset, err)
+}
+
+func copyRange(w io.WriterAt, body io.Reader, offset, length int64) error {
+	n, err := io.Copy(io.NewOffsetWriter(w, offset), io.LimitReader(body, length))
+	if err != nil {
+		return err
+	}
+	if n != length {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// parseContentRange returns the total size from a "bytes 0-99/1000" header
+func parseContentRange(value string) (int64, error) {
+	i := strings.LastIndex(value, "/")
+	if !strings.HasPrefix(value, "bytes ") || i < 0 {
+		return 0, fmt.Errorf("%w: Content-Range %q", ErrMalformedInputValue, value)
+	}
+	total, err := strconv.ParseInt(value[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: Content-Range %q", ErrMalformedInputValue, value)
+	}
+	return total, nil
+}
@@ -0,0 +1,277 @@
+package blr_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/connect/blr"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeObjectStore serves the BLR multipart endpoints of one blob and the
+// pre-signed object store URLs they hand out
+type fakeObjectStore struct {
+	sync.Mutex
+	t         *testing.T
+	blob      blr.Blob
+	parts     map[int][]byte
+	listed    []blr.PartUpload
+	object    []byte
+	puts      []int
+	failures  map[int]int
+	forbidden map[int]bool
+	aborted   bool
+}
+
+func newFakeObjectStore(t *testing.T, blobID string) *fakeObjectStore {
+	return &fakeObjectStore{
+		t:         t,
+		blob:      blr.Blob{ResourceType: "Blob", ID: blobID, DataType: "firmware"},
+		parts:     map[int][]byte{},
+		failures:  map[int]int{},
+		forbidden: map[int]bool{},
+	}
+}
+
+func (f *fakeObjectStore) create(w http.ResponseWriter, r *http.Request) {
+	id := f.blob.ID
+	if !assert.Nil(f.t, json.NewDecoder(r.Body).Decode(&f.blob)) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.blob.ID = id
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(f.blob)
+}
+
+func (f *fakeObjectStore) accessURL(w http.ResponseWriter, r *http.Request) {
+	access := blr.AccessURL{ResourceType: "BlobAccessUrl", URL: serverBLR.URL + "/s3/object"}
+	if f.blob.NoOfParts != nil {
+		for i := 1; i <= *f.blob.NoOfParts; i++ {
+			access.BlobPartURLs = append(access.BlobPartURLs, blr.BlobPart{
+				PartNumber:    i,
+				DataAccessURL: fmt.Sprintf("%s/s3/part/%d", serverBLR.URL, i),
+			})
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(access)
+}
+
+func (f *fakeObjectStore) listParts(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(blr.BlobPartUpload{ResourceType: "BlobPartUpload", BlobParts: f.listed})
+}
+
+func (f *fakeObjectStore) putPart(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	partNumber, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/s3/part/"))
+	data, _ := io.ReadAll(r.Body)
+	f.puts = append(f.puts, partNumber)
+	if f.forbidden[partNumber] {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if f.failures[partNumber] > 0 {
+		f.failures[partNumber]--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	sum := md5.Sum(data)
+	if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.parts[partNumber] = data
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *fakeObjectStore) complete(w http.ResponseWriter, r *http.Request) {
+	var upload blr.BlobPartUpload
+	_ = json.NewDecoder(r.Body).Decode(&upload)
+	var object []byte
+	for i, part := range upload.BlobParts {
+		data := f.parts[part.PartNumber]
+		sum := md5.Sum(data)
+		if part.PartNumber != i+1 || part.ETag != `"`+hex.EncodeToString(sum[:])+`"` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		object = append(object, data...)
+	}
+	f.object = object
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeObjectStore) abort(w http.ResponseWriter, r *http.Request) {
+	f.aborted = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeObjectStore) getObject(w http.ResponseWriter, r *http.Request) {
+	sum := md5.Sum(f.object)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(f.object))
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestUploadDownload(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeObjectStore(t, "f1e2d3c4-0000-4000-8000-000000000001")
+	blobPath := blobBase + "/" + store.blob.ID
+	muxBLR.HandleFunc(blobBase, store.create)
+	muxBLR.HandleFunc(blobPath+"/$getAccessUrl", store.accessURL)
+	muxBLR.HandleFunc(blobPath+"/$listPart", store.listParts)
+	muxBLR.HandleFunc(blobPath+"/$completeUpload", store.complete)
+	muxBLR.HandleFunc(blobPath+"/$abortUpload", store.abort)
+	muxBLR.HandleFunc("/s3/part/", store.putPart)
+	muxBLR.HandleFunc("/s3/object", store.getObject)
+	store.failures[2] = 2
+	data := testData(2500)
+
+	var progress []blr.Progress
+	blob, err := blrClient.Blobs.Upload(context.Background(), blr.Blob{DataType: "firmware"},
+		bytes.NewReader(data), int64(len(data)), &blr.TransferOptions{
+			PartSize:  1024,
+			RetryWait: time.Millisecond,
+			Progress: func(p blr.Progress) {
+				progress = append(progress, p)
+			},
+		})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, store.blob.ID, blob.ID)
+	assert.True(t, store.blob.MultipartEnabled)
+	if assert.NotNil(t, store.blob.NoOfParts) {
+		assert.Equal(t, 3, *store.blob.NoOfParts)
+	}
+	assert.Equal(t, data, store.object)
+	assert.Len(t, store.puts, 5)
+	if assert.Len(t, progress, 3) {
+		assert.Equal(t, blr.Progress{PartNumber: progress[2].PartNumber, Transferred: 2500, Total: 2500}, progress[2])
+	}
+
+	name := filepath.Join(t.TempDir(), "blob.bin")
+	file, err := os.Create(name)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	progress = nil
+	n, err := blrClient.Blobs.Download(context.Background(), *blob, file, &blr.TransferOptions{
+		PartSize: 1000,
+		Progress: func(p blr.Progress) {
+			progress = append(progress, p)
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2500), n)
+	assert.Len(t, progress, 3)
+	downloaded, _ := os.ReadFile(name)
+	assert.Equal(t, data, downloaded)
+
+	// The downloaded data is verified against the ETag
+	store.object = append([]byte{}, data...)
+	store.object[0]++
+	mismatch := &tamperedWriter{File: file}
+	_, err = blrClient.Blobs.Download(context.Background(), *blob, mismatch, &blr.TransferOptions{PartSize: 1000})
+	assert.True(t, errors.Is(err, blr.ErrChecksumMismatch))
+}
+
+// tamperedWriter corrupts the first byte it writes
+type tamperedWriter struct {
+	*os.File
+}
+
+func (w *tamperedWriter) WriteAt(p []byte, off int64) (int, error) {
+	if off == 0 && len(p) > 0 {
+		p = append([]byte{p[0] + 1}, p[1:]...)
+	}
+	return w.File.WriteAt(p, off)
+}
+
+func TestUploadResumeAndAbort(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeObjectStore(t, "f1e2d3c4-0000-4000-8000-000000000002")
+	blobPath := blobBase + "/" + store.blob.ID
+	muxBLR.HandleFunc(blobBase, store.create)
+	muxBLR.HandleFunc(blobPath+"/$getAccessUrl", store.accessURL)
+	muxBLR.HandleFunc(blobPath+"/$listPart", store.listParts)
+	muxBLR.HandleFunc(blobPath+"/$completeUpload", store.complete)
+	muxBLR.HandleFunc(blobPath+"/$abortUpload", store.abort)
+	muxBLR.HandleFunc("/s3/part/", store.putPart)
+	muxBLR.HandleFunc("/s3/object", store.getObject)
+	data := testData(2500)
+	parts := 3
+	store.blob.MultipartEnabled = true
+	store.blob.NoOfParts = &parts
+	first := md5.Sum(data[:1024])
+	store.parts[1] = data[:1024]
+	store.listed = []blr.PartUpload{
+		{PartNumber: 1, Size: 1024, ETag: `"` + hex.EncodeToString(first[:]) + `"`},
+		{PartNumber: 2, Size: 1024, ETag: `"00000000000000000000000000000000"`},
+	}
+
+	// A fatal failure aborts the upload
+	store.forbidden[3] = true
+	_, err := blrClient.Blobs.Upload(context.Background(), store.blob, bytes.NewReader(data), int64(len(data)),
+		&blr.TransferOptions{Concurrency: 1})
+	assert.True(t, errors.Is(err, blr.ErrTransferFailed))
+	assert.True(t, store.aborted)
+
+	// Unless it should be kept for resuming
+	store.aborted = false
+	store.puts = nil
+	_, err = blrClient.Blobs.Upload(context.Background(), store.blob, bytes.NewReader(data), int64(len(data)),
+		&blr.TransferOptions{Concurrency: 1, KeepOnFailure: true})
+	assert.NotNil(t, err)
+	assert.False(t, store.aborted)
+
+	// Resuming uploads the parts that are missing or do not match
+	store.forbidden[3] = false
+	store.puts = nil
+	blob, err := blrClient.Blobs.Upload(context.Background(), store.blob, bytes.NewReader(data), int64(len(data)), nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, store.blob.ID, blob.ID)
+	assert.ElementsMatch(t, []int{2, 3}, store.puts)
+	assert.Equal(t, data, store.object)
+
+	// The part size of the upload must fit the blob
+	_, err = blrClient.Blobs.Upload(context.Background(), store.blob, bytes.NewReader(data), 5000, nil)
+	assert.True(t, errors.Is(err, blr.ErrPartCountMismatch))
+}
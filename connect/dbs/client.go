@@ -3,6 +3,7 @@ package dbs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,6 +27,14 @@ const (
 // OptionFunc is the function signature function for options
 type OptionFunc func(*http.Request) error
 
+// WithContext runs the request with the provided context
+func WithContext(ctx context.Context) OptionFunc {
+	return func(req *http.Request) error {
+		*req = *req.WithContext(ctx)
+		return nil
+	}
+}
+
 // Config contains the configuration of a Client
 type Config struct {
 	Region      string
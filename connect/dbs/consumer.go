@@ -0,0 +1,329 @@
+package dbs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConsumerWorkers   = 4
+	defaultWaitTime          = 20 * time.Second
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultMaxExtension      = 12 * time.Hour
+	defaultReceiveBackoff    = time.Second
+	maxReceiveBackoff        = time.Minute
+	maxMessagesPerReceive    = 10
+	queueTypeFIFO            = "FIFO"
+	maxSQSWaitTimeSeconds    = 20
+)
+
+// Envelope is a data broker message as delivered to subscriptions that do not
+// deliver data only
+type Envelope struct {
+	DataType      string          `json:"dataType"`
+	Timestamp     string          `json:"timestamp,omitempty"`
+	DeviceID      string          `json:"deviceId,omitempty"`
+	PropositionID string          `json:"propositionId,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Message is a received message together with its data broker envelope
+type Message struct {
+	QueueMessage
+	Envelope Envelope
+}
+
+// Decode unmarshals the data of the message into v
+func (m *Message) Decode(v interface{}) error {
+	if err := json.Unmarshal(m.Envelope.Data, v); err != nil {
+		return fmt.Errorf("message %s: %v: %w", m.ID, err, ErrInvalidEnvelope)
+	}
+	return nil
+}
+
+// Handler handles messages of a Consumer. Returning nil acknowledges the message,
+// which deletes it from the queue. Returning an error makes it visible again for redelivery
+type Handler interface {
+	HandleMessage(ctx context.Context, msg *Message) error
+}
+
+// HandlerFunc adapts a function to a Handler
+type HandlerFunc func(ctx context.Context, msg *Message) error
+
+func (f HandlerFunc) HandleMessage(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// Typed returns a Handler that decodes the data of messages into T
+func Typed[T any](fn func(ctx context.Context, msg *Message, data T) error) HandlerFunc {
+	return func(ctx context.Context, msg *Message) error {
+		var data T
+		if err := msg.Decode(&data); err != nil {
+			return err
+		}
+		return fn(ctx, msg, data)
+	}
+}
+
+// Router dispatches messages to the Handler of their data type
+type Router map[string]Handler
+
+func (r Router) HandleMessage(ctx context.Context, msg *Message) error {
+	handler, ok := r[msg.Envelope.DataType]
+	if !ok {
+		return fmt.Errorf("no handler for data type %q: %w", msg.Envelope.DataType, ErrInvalidEnvelope)
+	}
+	return handler.HandleMessage(ctx, msg)
+}
+
+// ConsumerOptions configures a Consumer. Zero values select the defaults
+type ConsumerOptions struct {
+	// Workers is the maximum number of messages handled concurrently
+	Workers int
+	// MaxMessages is the maximum number of messages per receive, at most 10
+	MaxMessages int
+	// WaitTime is the long poll duration of a receive
+	WaitTime time.Duration
+	// VisibilityTimeout keeps received messages from other consumers. It is extended
+	// every half period while a handler is still running
+	VisibilityTimeout time.Duration
+	// MaxExtension limits how long visibility is extended for a single message
+	MaxExtension time.Duration
+	// NackDelay is the visibility timeout set on a failed message before it is redelivered
+	NackDelay time.Duration
+	// FIFO handles the messages of a message group one after the other. When a
+	// message fails, the remaining messages of its group are released as well
+	FIFO bool
+	// DataOnly treats message bodies as data for topic subscriptions that deliver data only
+	DataOnly bool
+	// DataType is set on the envelopes of data only messages
+	DataType string
+	// OnError receives queue and handler errors
+	OnError func(err error)
+}
+
+// Consumer long polls a Queue and handles its messages with a bounded pool of workers
+type Consumer struct {
+	queue   Queue
+	handler Handler
+	options ConsumerOptions
+}
+
+// NewConsumer returns a Consumer of the queue
+func NewConsumer(queue Queue, handler Handler, options ConsumerOptions) *Consumer {
+	if options.Workers <= 0 {
+		options.Workers = defaultConsumerWorkers
+	}
+	if options.MaxMessages <= 0 || options.MaxMessages > maxMessagesPerReceive {
+		options.MaxMessages = maxMessagesPerReceive
+	}
+	if options.WaitTime <= 0 {
+		options.WaitTime = defaultWaitTime
+	}
+	if options.VisibilityTimeout <= 0 {
+		options.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if options.MaxExtension <= 0 {
+		options.MaxExtension = defaultMaxExtension
+	}
+	if options.NackDelay < 0 {
+		options.NackDelay = 0
+	}
+	return &Consumer{queue: queue, handler: handler, options: options}
+}
+
+// Consumer returns a Consumer of the SQS queue of the subscriber. FIFO and the
+// wait time are taken from the subscriber unless set in options
+func (b *SubscribersService) Consumer(subscriber SQSSubscriber, handler Handler, options ConsumerOptions, opts ...OptionFunc) (*Consumer, error) {
+	queue, err := b.Queue(subscriber, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if subscriber.QueueType == queueTypeFIFO {
+		options.FIFO = true
+	}
+	if options.WaitTime == 0 && subscriber.ReceiveMessageWaitTimeSeconds > 0 &&
+		subscriber.ReceiveMessageWaitTimeSeconds <= maxSQSWaitTimeSeconds {
+		options.WaitTime = time.Duration(subscriber.ReceiveMessageWaitTimeSeconds) * time.Second
+	}
+	return NewConsumer(queue, handler, options), nil
+}
+
+// Run consumes messages until ctx is done, then waits for the handlers in flight.
+// Handlers get a context that is cancelled together with ctx
+func (c *Consumer) Run(ctx context.Context) error {
+	slots := make(chan struct{}, c.options.Workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	backoff := defaultReceiveBackoff
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		free := 1
+		for free < c.options.MaxMessages && c.acquire(slots) {
+			free++
+		}
+		messages, err := c.queue.Receive(ctx, free, c.options.WaitTime, c.options.VisibilityTimeout)
+		if len(messages) > free {
+			messages = messages[:free]
+		}
+		for i := len(messages); i < free; i++ {
+			<-slots
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			c.report(fmt.Errorf("receive: %w", err))
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil
+			}
+			if backoff *= 2; backoff > maxReceiveBackoff {
+				backoff = maxReceiveBackoff
+			}
+			continue
+		}
+		backoff = defaultReceiveBackoff
+		received := time.Now()
+		for _, batch := range c.batches(messages) {
+			wg.Add(1)
+			go func(batch []QueueMessage) {
+				defer wg.Done()
+				c.process(ctx, batch, received, slots)
+			}(batch)
+		}
+	}
+}
+
+func (c *Consumer) acquire(slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// batches returns the messages that must be handled one after the other. Without FIFO
+// each message is handled on its own
+func (c *Consumer) batches(messages []QueueMessage) [][]QueueMessage {
+	var batches [][]QueueMessage
+	groups := map[string]int{}
+	for _, msg := range messages {
+		if c.options.FIFO {
+			if i, ok := groups[msg.GroupID()]; ok {
+				batches[i] = append(batches[i], msg)
+				continue
+			}
+			groups[msg.GroupID()] = len(batches)
+		}
+		batches = append(batches, []QueueMessage{msg})
+	}
+	return batches
+}
+
+// process handles a batch in order while extending the visibility of the messages
+// that are not done yet. Each message releases its worker slot when done
+func (c *Consumer) process(ctx context.Context, batch []QueueMessage, received time.Time, slots chan struct{}) {
+	var mu sync.Mutex
+	pending := batch
+	done := make(chan struct{})
+	defer close(done)
+	go c.extend(received, done, func() []QueueMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return pending
+	})
+
+	for i, msg := range batch {
+		err := c.handle(ctx, msg)
+		mu.Lock()
+		pending = batch[i+1:]
+		if err != nil && c.options.FIFO {
+			pending = nil
+		}
+		mu.Unlock()
+		if err == nil {
+			c.ack(msg)
+			<-slots
+			continue
+		}
+		c.report(fmt.Errorf("message %s: %w", msg.ID, err))
+		if !c.options.FIFO {
+			c.nack(msg)
+			<-slots
+			continue
+		}
+		// Release the rest of the group first so it cannot be received ahead of msg
+		for j := len(batch) - 1; j >= i; j-- {
+			c.nack(batch[j])
+			<-slots
+		}
+		return
+	}
+}
+
+// extend keeps the pending messages invisible while they wait or are being handled
+func (c *Consumer) extend(received time.Time, done chan struct{}, pending func() []QueueMessage) {
+	ticker := time.NewTicker(c.options.VisibilityTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if time.Since(received) >= c.options.MaxExtension {
+			return
+		}
+		for _, msg := range pending() {
+			if err := c.queue.ChangeVisibility(context.Background(), msg.ReceiptHandle, c.options.VisibilityTimeout); err != nil {
+				c.report(fmt.Errorf("extend visibility of message %s: %w", msg.ID, err))
+			}
+		}
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, queueMessage QueueMessage) (err error) {
+	msg := &Message{QueueMessage: queueMessage}
+	if c.options.DataOnly {
+		msg.Envelope = Envelope{DataType: c.options.DataType, Data: json.RawMessage(queueMessage.Body)}
+	} else if err := json.Unmarshal([]byte(queueMessage.Body), &msg.Envelope); err != nil {
+		return fmt.Errorf("%v: %w", err, ErrInvalidEnvelope)
+	} else if msg.Envelope.Data == nil {
+		return fmt.Errorf("missing data: %w", ErrInvalidEnvelope)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return c.handler.HandleMessage(ctx, msg)
+}
+
+func (c *Consumer) ack(msg QueueMessage) {
+	if err := c.queue.Delete(context.Background(), msg.ReceiptHandle); err != nil {
+		c.report(fmt.Errorf("ack message %s: %w", msg.ID, err))
+	}
+}
+
+func (c *Consumer) nack(msg QueueMessage) {
+	if err := c.queue.ChangeVisibility(context.Background(), msg.ReceiptHandle, c.options.NackDelay); err != nil {
+		c.report(fmt.Errorf("nack message %s: %w", msg.ID, err))
+	}
+}
+
+func (c *Consumer) report(err error) {
+	if c.options.OnError != nil {
+		c.options.OnError(err)
+	}
+}
@@ -0,0 +1,138 @@
+package dbs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/connect/dbs"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingQueue struct {
+	*dbs.MemoryQueue
+	mu       sync.Mutex
+	extended int
+}
+
+func (q *recordingQueue) ChangeVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error {
+	if timeout > 0 {
+		q.mu.Lock()
+		q.extended++
+		q.mu.Unlock()
+	}
+	return q.MemoryQueue.ChangeVisibility(ctx, receiptHandle, timeout)
+}
+
+func runUntil(t *testing.T, consumer *dbs.Consumer, done func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- consumer.Run(ctx) }()
+	deadline := time.Now().Add(10 * time.Second)
+	for !done() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	assert.Nil(t, <-stopped)
+}
+
+type reading struct {
+	Value int `json:"value"`
+}
+
+func TestConsumerAckNack(t *testing.T) {
+	queue := dbs.NewMemoryQueue(false)
+	queue.Send(`{"dataType":"Reading","deviceId":"device-1","data":{"value":1}}`, "")
+	queue.Send(`{"dataType":"Reading","deviceId":"device-1","data":{"value":2}}`, "")
+	queue.Send(`not an envelope`, "")
+
+	var mu sync.Mutex
+	var values []int
+	attempts := map[int]int{}
+	var errs []error
+	consumer := dbs.NewConsumer(queue, dbs.Router{
+		"Reading": dbs.Typed(func(ctx context.Context, msg *dbs.Message, data reading) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[data.Value]++
+			assert.Equal(t, "device-1", msg.Envelope.DeviceID)
+			if data.Value == 2 && attempts[data.Value] == 1 {
+				return errors.New("try again")
+			}
+			values = append(values, data.Value)
+			return nil
+		}),
+	}, dbs.ConsumerOptions{
+		Workers:  2,
+		WaitTime: 50 * time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	runUntil(t, consumer, func() bool { return queue.Len() == 1 })
+
+	assert.Equal(t, 1, queue.Len())
+	assert.ElementsMatch(t, []int{1, 2}, values)
+	assert.Equal(t, 2, attempts[2])
+	var invalid bool
+	for _, err := range errs {
+		invalid = invalid || errors.Is(err, dbs.ErrInvalidEnvelope)
+	}
+	assert.True(t, invalid)
+}
+
+func TestConsumerFIFO(t *testing.T) {
+	queue := dbs.NewMemoryQueue(true)
+	for i := 0; i < 5; i++ {
+		queue.Send(`{"dataType":"Reading","data":{"value":`+string(rune('0'+i))+`}}`, "a")
+		queue.Send(`{"dataType":"Reading","data":{"value":`+string(rune('5'+i))+`}}`, "b")
+	}
+
+	var mu sync.Mutex
+	groups := map[string][]int{}
+	failed := false
+	consumer := dbs.NewConsumer(queue, dbs.Typed(func(ctx context.Context, msg *dbs.Message, data reading) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if data.Value == 2 && !failed {
+			failed = true
+			return errors.New("try again")
+		}
+		groups[msg.GroupID()] = append(groups[msg.GroupID()], data.Value)
+		return nil
+	}), dbs.ConsumerOptions{Workers: 4, MaxMessages: 3, WaitTime: 50 * time.Millisecond, FIFO: true})
+	runUntil(t, consumer, func() bool { return queue.Len() == 0 })
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, groups["a"])
+	assert.Equal(t, []int{5, 6, 7, 8, 9}, groups["b"])
+}
+
+func TestConsumerVisibilityExtension(t *testing.T) {
+	queue := &recordingQueue{MemoryQueue: dbs.NewMemoryQueue(false)}
+	queue.Send(`{"value":1}`, "")
+
+	var mu sync.Mutex
+	handled := 0
+	consumer := dbs.NewConsumer(queue, dbs.Typed(func(ctx context.Context, msg *dbs.Message, data reading) error {
+		assert.Equal(t, "Reading", msg.Envelope.DataType)
+		assert.Equal(t, 1, data.Value)
+		time.Sleep(1500 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		handled++
+		return nil
+	}), dbs.ConsumerOptions{
+		WaitTime:          50 * time.Millisecond,
+		VisibilityTimeout: time.Second,
+		DataOnly:          true,
+		DataType:          "Reading",
+	})
+	runUntil(t, consumer, func() bool { return queue.Len() == 0 })
+
+	assert.Equal(t, 1, handled)
+	assert.GreaterOrEqual(t, queue.extended, 1)
+}
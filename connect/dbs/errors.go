@@ -19,4 +19,8 @@ var (
 	ErrEmptyResults                   = errors.New("empty results")
 	ErrOperationFailed                = errors.New("operation failed")
 	ErrCouldNoReadResourceAfterCreate = errors.New("could not read resource after create")
+	ErrMissingSubscriberID            = errors.New("missing subscriber ID")
+	ErrInvalidQueueURL                = errors.New("invalid queue URL")
+	ErrQueueRequestFailed             = errors.New("queue request failed")
+	ErrInvalidEnvelope                = errors.New("invalid data broker envelope")
 )
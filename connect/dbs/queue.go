@@ -0,0 +1,193 @@
+package dbs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Queue is a message queue a Consumer polls. SQSQueue implements it for Amazon SQS
+// and MemoryQueue for local testing
+type Queue interface {
+	// Receive waits up to wait for at most max messages, which stay invisible to other
+	// receivers for the visibility timeout
+	Receive(ctx context.Context, max int, wait, visibility time.Duration) ([]QueueMessage, error)
+	// Delete removes a received message from the queue
+	Delete(ctx context.Context, receiptHandle string) error
+	// ChangeVisibility makes a received message visible again after timeout
+	ChangeVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error
+}
+
+// Attributes of received messages
+const (
+	AttributeMessageGroupID          = "MessageGroupId"
+	AttributeApproximateReceiveCount = "ApproximateReceiveCount"
+)
+
+// QueueMessage is a message received from a Queue
+type QueueMessage struct {
+	ID            string
+	ReceiptHandle string
+	Body          string
+	Attributes    map[string]string
+}
+
+// GroupID returns the message group of a message of a FIFO queue
+func (m QueueMessage) GroupID() string {
+	return m.Attributes[AttributeMessageGroupID]
+}
+
+// ReceiveCount returns the number of times the message was received
+func (m QueueMessage) ReceiveCount() int {
+	count, _ := strconv.Atoi(m.Attributes[AttributeApproximateReceiveCount])
+	return count
+}
+
+// MemoryQueue is an in-memory Queue with the delivery semantics of SQS, for testing
+// consumers locally. A FIFO MemoryQueue delivers the messages of a group in order and
+// holds back a group while one of its messages is in flight
+type MemoryQueue struct {
+	mu       sync.Mutex
+	fifo     bool
+	messages []*memoryMessage
+	nextID   int
+	changed  chan struct{}
+	now      func() time.Time
+}
+
+type memoryMessage struct {
+	message   QueueMessage
+	visibleAt time.Time
+	receives  int
+}
+
+// NewMemoryQueue returns an empty MemoryQueue
+func NewMemoryQueue(fifo bool) *MemoryQueue {
+	return &MemoryQueue{fifo: fifo, changed: make(chan struct{}), now: time.Now}
+}
+
+// Send adds a message to the queue and returns its ID. The group is only used by FIFO queues
+func (q *MemoryQueue) Send(body, groupID string) string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	id := "message-" + strconv.Itoa(q.nextID)
+	attributes := map[string]string{}
+	if q.fifo {
+		attributes[AttributeMessageGroupID] = groupID
+	}
+	q.messages = append(q.messages, &memoryMessage{
+		message: QueueMessage{ID: id, Body: body, Attributes: attributes},
+	})
+	q.notify()
+	return id
+}
+
+// Len returns the number of messages that were not deleted
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+// notify wakes up waiting receivers. It must be called with the lock held
+func (q *MemoryQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (q *MemoryQueue) Receive(ctx context.Context, max int, wait, visibility time.Duration) ([]QueueMessage, error) {
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	for {
+		q.mu.Lock()
+		received, next := q.receive(max, visibility)
+		changed := q.changed
+		q.mu.Unlock()
+		if len(received) > 0 {
+			return received, nil
+		}
+		var retry <-chan time.Time
+		if !next.IsZero() {
+			timer := time.NewTimer(next.Sub(q.now()))
+			defer timer.Stop()
+			retry = timer.C
+		}
+		select {
+		case <-changed:
+		case <-retry:
+		case <-deadline.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// receive takes the visible messages and returns them, or the time the first invisible
+// message becomes visible again. It must be called with the lock held
+func (q *MemoryQueue) receive(max int, visibility time.Duration) ([]QueueMessage, time.Time) {
+	now := q.now()
+	var received []QueueMessage
+	var next time.Time
+	blocked := map[string]bool{}
+	for _, m := range q.messages {
+		if m.visibleAt.After(now) {
+			if next.IsZero() || m.visibleAt.Before(next) {
+				next = m.visibleAt
+			}
+			blocked[m.message.GroupID()] = q.fifo
+		}
+	}
+	for _, m := range q.messages {
+		if m.visibleAt.After(now) || blocked[m.message.GroupID()] || len(received) >= max {
+			continue
+		}
+		m.receives++
+		m.visibleAt = now.Add(visibility)
+		m.message.ReceiptHandle = fmt.Sprintf("%s/%d", m.message.ID, m.receives)
+		m.message.Attributes[AttributeApproximateReceiveCount] = strconv.Itoa(m.receives)
+		message := m.message
+		message.Attributes = make(map[string]string, len(m.message.Attributes))
+		for k, v := range m.message.Attributes {
+			message.Attributes[k] = v
+		}
+		received = append(received, message)
+	}
+	return received, next
+}
+
+func (q *MemoryQueue) find(receiptHandle string) (int, error) {
+	for i, m := range q.messages {
+		if m.message.ReceiptHandle == receiptHandle {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("receipt handle %q: %w", receiptHandle, ErrQueueRequestFailed)
+}
+
+func (q *MemoryQueue) Delete(_ context.Context, receiptHandle string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i, err := q.find(receiptHandle)
+	if err != nil {
+		return err
+	}
+	q.messages = append(q.messages[:i], q.messages[i+1:]...)
+	q.notify()
+	return nil
+}
+
+func (q *MemoryQueue) ChangeVisibility(_ context.Context, receiptHandle string, timeout time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i, err := q.find(receiptHandle)
+	if err != nil {
+		return err
+	}
+	q.messages[i].visibleAt = q.now().Add(timeout)
+	q.notify()
+	return nil
+}
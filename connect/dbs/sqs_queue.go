@@ -0,0 +1,203 @@
+package dbs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	sqsService      = "sqs"
+	sqsContentType  = "application/x-amz-json-1.0"
+	sqsTargetPrefix = "AmazonSQS."
+)
+
+// SQSQueue is a Queue backed by Amazon SQS. It speaks the SQS JSON protocol and signs
+// its requests with the credentials of the provider
+type SQSQueue struct {
+	URL         string
+	Region      string
+	Credentials aws.CredentialsProvider
+	HTTPClient  *http.Client
+
+	endpoint string
+	signer   *v4.Signer
+}
+
+// NewSQSQueue returns a Queue for the SQS queue URL. The region is derived from the
+// queue URL when empty
+func NewSQSQueue(queueURL, region string, credentials aws.CredentialsProvider) (*SQSQueue, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("%q: %w", queueURL, ErrInvalidQueueURL)
+	}
+	if region == "" {
+		// sqs.<region>.amazonaws.com
+		parts := strings.Split(u.Hostname(), ".")
+		if len(parts) < 4 || parts[0] != sqsService {
+			return nil, fmt.Errorf("%q has no region: %w", queueURL, ErrInvalidQueueURL)
+		}
+		region = parts[1]
+	}
+	return &SQSQueue{
+		URL:         queueURL,
+		Region:      region,
+		Credentials: credentials,
+		HTTPClient:  &http.Client{Timeout: 60 * time.Second},
+		endpoint:    u.Scheme + "://" + u.Host + "/",
+		signer:      v4.NewSigner(),
+	}, nil
+}
+
+type sqsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+type sqsReceiveMessageResult struct {
+	Messages []struct {
+		MessageID     string            `json:"MessageId"`
+		ReceiptHandle string            `json:"ReceiptHandle"`
+		Body          string            `json:"Body"`
+		Attributes    map[string]string `json:"Attributes"`
+	} `json:"Messages"`
+}
+
+func (q *SQSQueue) call(ctx context.Context, action string, input interface{}, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", sqsContentType)
+	req.Header.Set("X-Amz-Target", sqsTargetPrefix+action)
+	credentials, err := q.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	hash := sha256.Sum256(body)
+	if err := q.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), sqsService, q.Region, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	resp, err := q.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	if resp.StatusCode >= 300 {
+		var sqsErr sqsError
+		_ = json.Unmarshal(data, &sqsErr)
+		return fmt.Errorf("%s: %d %s %s: %w", action, resp.StatusCode, sqsErr.Type, sqsErr.Message, ErrQueueRequestFailed)
+	}
+	if output == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, output)
+}
+
+func (q *SQSQueue) Receive(ctx context.Context, max int, wait, visibility time.Duration) ([]QueueMessage, error) {
+	var result sqsReceiveMessageResult
+	err := q.call(ctx, "ReceiveMessage", map[string]interface{}{
+		"QueueUrl":              q.URL,
+		"MaxNumberOfMessages":   max,
+		"WaitTimeSeconds":       int(wait / time.Second),
+		"VisibilityTimeout":     int(visibility / time.Second),
+		"AttributeNames":        []string{"All"},
+		"MessageAttributeNames": []string{"All"},
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]QueueMessage, 0, len(result.Messages))
+	for _, m := range result.Messages {
+		messages = append(messages, QueueMessage{
+			ID:            m.MessageID,
+			ReceiptHandle: m.ReceiptHandle,
+			Body:          m.Body,
+			Attributes:    m.Attributes,
+		})
+	}
+	return messages, nil
+}
+
+func (q *SQSQueue) Delete(ctx context.Context, receiptHandle string) error {
+	return q.call(ctx, "DeleteMessage", map[string]interface{}{
+		"QueueUrl":      q.URL,
+		"ReceiptHandle": receiptHandle,
+	}, nil)
+}
+
+func (q *SQSQueue) ChangeVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error {
+	return q.call(ctx, "ChangeMessageVisibility", map[string]interface{}{
+		"QueueUrl":          q.URL,
+		"ReceiptHandle":     receiptHandle,
+		"VisibilityTimeout": int(timeout / time.Second),
+	}, nil)
+}
+
+// sqsCredentialsProvider hands out the temporary credentials of an SQS subscriber,
+// starting with the ones fetched together with the queue URL
+type sqsCredentialsProvider struct {
+	mu         sync.Mutex
+	service    *SubscribersService
+	subscriber SQSSubscriber
+	details    *SQSAccessDetails
+}
+
+func (p *sqsCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	details := p.details
+	p.details = nil
+	if details == nil {
+		var err error
+		details, _, err = p.service.GetSQSAccessDetails(p.subscriber, WithContext(ctx))
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+	}
+	return aws.Credentials{
+		AccessKeyID:     details.Credentials.AccessKey,
+		SecretAccessKey: details.Credentials.SecretKey,
+		SessionToken:    details.Credentials.SessionToken,
+		Source:          "DataBrokerSQSSubscriber",
+		CanExpire:       !details.Credentials.Expiration.IsZero(),
+		Expires:         details.Credentials.Expiration,
+	}, nil
+}
+
+// Queue returns the SQS queue of the subscriber. Its temporary credentials are
+// refreshed through the access details of the subscriber before they expire
+func (b *SubscribersService) Queue(subscriber SQSSubscriber, options ...OptionFunc) (*SQSQueue, error) {
+	details, _, err := b.GetSQSAccessDetails(subscriber, options...)
+	if err != nil {
+		return nil, err
+	}
+	provider := aws.NewCredentialsCache(&sqsCredentialsProvider{
+		service:    b,
+		subscriber: subscriber,
+		details:    details,
+	}, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = time.Minute
+	})
+	return NewSQSQueue(details.QueueURL, details.Region, provider)
+}
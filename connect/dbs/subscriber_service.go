@@ -56,6 +56,22 @@ type Meta struct {
 	VersionID   string    `json:"versionId,omitempty"`
 }
 
+// SQSAccessDetails holds the queue URL and temporary credentials to consume an SQS subscriber
+type SQSAccessDetails struct {
+	ResourceType string            `json:"resourceType"`
+	QueueURL     string            `json:"queueUrl"`
+	Region       string            `json:"region"`
+	Credentials  AccessCredentials `json:"credentials"`
+}
+
+// AccessCredentials are temporary AWS credentials
+type AccessCredentials struct {
+	AccessKey    string    `json:"accessKey"`
+	SecretKey    string    `json:"secretKey"`
+	SessionToken string    `json:"sessionToken"`
+	Expiration   time.Time `json:"expiration"`
+}
+
 type SQSBundle struct {
 	Type  string          `json:"type,omitempty"`
 	Entry []SQSSubscriber `json:"entry,omitempty"`
@@ -140,3 +156,27 @@ func (b *SubscribersService) DeleteSQS(subscriber SQSSubscriber) (bool, *Respons
 	}
 	return true, resp, nil
 }
+
+// GetSQSAccessDetails returns the queue URL and temporary credentials of the subscriber
+func (b *SubscribersService) GetSQSAccessDetails(subscriber SQSSubscriber, options ...OptionFunc) (*SQSAccessDetails, *Response, error) {
+	if subscriber.ID == "" {
+		return nil, nil, ErrMissingSubscriberID
+	}
+	req, err := b.NewRequest(http.MethodGet, "/Subscriber/SQS/"+subscriber.ID+"/$accessDetails", nil, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("api-version", subscriberAPIVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	var details SQSAccessDetails
+
+	resp, err := b.Do(req, &details)
+	if err != nil {
+		return nil, resp, err
+	}
+	if details.QueueURL == "" || details.Credentials.AccessKey == "" {
+		return nil, resp, fmt.Errorf("GetSQSAccessDetails: %w", ErrEmptyResult)
+	}
+	return &details, resp, nil
+}
@@ -1,11 +1,14 @@
 package dbs_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/philips-software/go-hsdp-api/connect/dbs"
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -89,3 +92,66 @@ func TestSQSCRUD(t *testing.T) {
 	assert.True(t, res)
 	assert.Equal(t, http.StatusNoContent, resp.StatusCode())
 }
+
+func TestSQSQueue(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	sqsID := "9f80f9e0-5cb2-4ebd-8980-03f550cb453f"
+	muxDBS.HandleFunc("/client-test/connect/databroker/Subscriber/SQS/"+sqsID+"/$accessDetails", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+  "resourceType": "SQSAccessDetails",
+  "queueUrl": "`+serverDBS.URL+`/123456789012/dbs-my_infix-`+sqsID+`.fifo",
+  "region": "eu-west-1",
+  "credentials": {
+    "accessKey": "AKIAEXAMPLE",
+    "secretKey": "secret",
+    "sessionToken": "token",
+    "expiration": "2099-01-01T00:00:00Z"
+  }
+}`)
+	})
+	var targets []string
+	muxDBS.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/x-amz-json-1.0", r.Header.Get("Content-Type"))
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKIAEXAMPLE/")
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/sqs/aws4_request")
+		assert.Equal(t, "token", r.Header.Get("X-Amz-Security-Token"))
+		targets = append(targets, r.Header.Get("X-Amz-Target"))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSQS.ReceiveMessage":
+			_, _ = io.WriteString(w, `{"Messages":[{"MessageId":"m-1","ReceiptHandle":"r-1",
+"Body":"{\"dataType\":\"Reading\",\"data\":{}}","Attributes":{"MessageGroupId":"g-1","ApproximateReceiveCount":"2"}}]}`)
+		case "AmazonSQS.DeleteMessage":
+			_, _ = io.WriteString(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"__type":"com.amazonaws.sqs#ReceiptHandleIsInvalid","message":"invalid"}`)
+		}
+	})
+
+	_, err := dbsClient.Subscribers.Queue(dbs.SQSSubscriber{})
+	assert.True(t, errors.Is(err, dbs.ErrMissingSubscriberID))
+
+	queue, err := dbsClient.Subscribers.Queue(dbs.SQSSubscriber{ID: sqsID})
+	if !assert.Nil(t, err) {
+		return
+	}
+	messages, err := queue.Receive(context.Background(), 10, time.Second, 30*time.Second)
+	if !assert.Nil(t, err) || !assert.Len(t, messages, 1) {
+		return
+	}
+	assert.Equal(t, "g-1", messages[0].GroupID())
+	assert.Equal(t, 2, messages[0].ReceiveCount())
+	assert.Nil(t, queue.Delete(context.Background(), messages[0].ReceiptHandle))
+	err = queue.ChangeVisibility(context.Background(), "stale", 0)
+	assert.True(t, errors.Is(err, dbs.ErrQueueRequestFailed))
+	assert.Equal(t, []string{"AmazonSQS.ReceiveMessage", "AmazonSQS.DeleteMessage", "AmazonSQS.ChangeMessageVisibility"}, targets)
+
+	_, err = dbs.NewSQSQueue("https://example.com/123/queue", "", nil)
+	assert.True(t, errors.Is(err, dbs.ErrInvalidQueueURL))
+}
@@ -0,0 +1,16 @@
+package device
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidRegistration    = errors.New("invalid registration")
+	ErrInconsistentDeviceType = errors.New("device type does not line up with its device group and application")
+	ErrOAuthClientNotFound    = errors.New("OAuth client not found")
+	ErrDataContractNotFound   = errors.New("blob data contract not found")
+	ErrMissingCredentials     = errors.New("missing device credentials")
+	ErrOperationFailed        = errors.New("operation failed")
+	ErrMissingBlobRepository  = errors.New("a BLR client is needed to grant data contracts")
+	ErrDeviceNotRegistered    = errors.New("device is not registered")
+)
@@ -0,0 +1,559 @@
+// Package device coordinates IAM, MDM and BLR along the lifecycle of a connected device
+package device
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/philips-software/go-hsdp-api/connect/blr"
+	"github.com/philips-software/go-hsdp-api/connect/mdm"
+	"github.com/philips-software/go-hsdp-api/iam"
+)
+
+// DefaultActions are the BLR actions granted on data contracts when a Registration
+// does not list any
+var DefaultActions = []string{"GET", "PUT"}
+
+// Registration describes a device to register
+type Registration struct {
+	// DeviceTypeID is the ID of the MDM device type of the device. Its device group,
+	// application and proposition determine the IAM application, organization and
+	// groups of the device
+	DeviceTypeID      string
+	LoginID           string
+	DeviceExtID       iam.DeviceIdentifier
+	GlobalReferenceID string
+	Text              string
+	ForTest           bool
+	// OAuthClient is the name of the MDM OAuth client of the application the device
+	// authenticates with. It can be omitted when the application has a single client
+	OAuthClient string
+	// OAuthClientSecret is the secret of the OAuth client. MDM only returns it when the
+	// client is created, so the caller supplies it for the credentials of the device
+	OAuthClientSecret string
+	// DataContracts lists the names of the MDM blob data contracts of the proposition
+	// the device is granted access to
+	DataContracts []string
+	// Actions are the BLR actions granted on the data contracts, DefaultActions if empty
+	Actions []string
+}
+
+// Credentials are the secrets a device authenticates with
+type Credentials struct {
+	LoginID      string
+	Password     string
+	ClientID     string
+	ClientSecret string
+}
+
+// Grant is the BLR blob store policy granting a device access to a data contract
+type Grant struct {
+	DataContract string
+	PolicyID     string
+}
+
+// Record is the state of a registered device. The other lifecycle operations take
+// it and keep it up to date. Callers should store it safely, as it holds the device
+// credentials
+type Record struct {
+	Device      iam.Device
+	DeviceType  mdm.DeviceType
+	DeviceGroup mdm.DeviceGroup
+	// Groups are the IDs of the IAM groups the device is a member of
+	Groups      []string
+	Grants      []Grant
+	Credentials Credentials
+}
+
+// Step is a change made by a lifecycle operation
+type Step struct {
+	Action   string
+	Resource string
+	// Err is the error of the failed step, or of undoing a completed step
+	Err error
+}
+
+func (s Step) String() string {
+	if s.Err != nil {
+		return s.Action + " " + s.Resource + ": " + s.Err.Error()
+	}
+	return s.Action + " " + s.Resource
+}
+
+// PartialStateError is returned when a lifecycle operation failed after changing some
+// of the services. Completed lists the changes that are still in effect, RolledBack
+// the changes that were undone. A failed Register is rolled back, so Completed only
+// lists changes whose undo failed. Decommission does not roll back: it can be retried
+// with the same Record, which no longer holds the resources already removed
+type PartialStateError struct {
+	Operation  string
+	Failed     Step
+	Completed  []Step
+	RolledBack []Step
+}
+
+func (e *PartialStateError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.Operation, e.Failed)
+	if len(e.RolledBack) > 0 {
+		fmt.Fprintf(&b, " (rolled back %d changes)", len(e.RolledBack))
+	}
+	if len(e.Completed) > 0 {
+		var steps []string
+		for _, s := range e.Completed {
+			steps = append(steps, s.String())
+		}
+		fmt.Fprintf(&b, " (still in effect: %s)", strings.Join(steps, "; "))
+	}
+	return b.String()
+}
+
+func (e *PartialStateError) Unwrap() error {
+	return e.Failed.Err
+}
+
+// Lifecycle registers, activates, suspends and decommissions devices and rotates
+// their credentials
+type Lifecycle struct {
+	IAM *iam.Client
+	MDM *mdm.Client
+	// BLR is only needed to grant data contracts
+	BLR *blr.Client
+	// Password generates device passwords and defaults to random passwords of 24 characters
+	Password func() (string, error)
+}
+
+// NewLifecycle returns a Lifecycle using the given clients. blrClient may be nil when
+// no data contracts are granted
+func NewLifecycle(iamClient *iam.Client, mdmClient *mdm.Client, blrClient *blr.Client) *Lifecycle {
+	return &Lifecycle{IAM: iamClient, MDM: mdmClient, BLR: blrClient, Password: generatePassword}
+}
+
+// contractGrant is a data contract resolved before registering
+type contractGrant struct {
+	contract mdm.BlobDataContract
+	bucket   mdm.Bucket
+}
+
+// Register creates the IAM device of a registration, adds it to the IAM groups of its
+// MDM device group and device type, and grants it access to the data contracts. The
+// device is registered inactive. When a step fails the completed steps are undone and
+// a *PartialStateError is returned
+func (l *Lifecycle) Register(ctx context.Context, r Registration) (*Record, error) {
+	if r.DeviceTypeID == "" || r.LoginID == "" || r.GlobalReferenceID == "" {
+		return nil, fmt.Errorf("register: device type, login ID and global reference ID are required: %w", ErrInvalidRegistration)
+	}
+	if len(r.DataContracts) > 0 && l.BLR == nil {
+		return nil, fmt.Errorf("register: %w", ErrMissingBlobRepository)
+	}
+	record, application, proposition, err := l.resolve(ctx, r.DeviceTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("register: %w", err)
+	}
+	client, err := l.oauthClient(application, r.OAuthClient)
+	if err != nil {
+		return nil, fmt.Errorf("register: %w", err)
+	}
+	grants, err := l.resolveContracts(ctx, proposition, r.DataContracts)
+	if err != nil {
+		return nil, fmt.Errorf("register: %w", err)
+	}
+	password, err := l.password()
+	if err != nil {
+		return nil, fmt.Errorf("register: generate password: %w", err)
+	}
+	record.Credentials = Credentials{
+		LoginID:      r.LoginID,
+		Password:     password,
+		ClientID:     client.ClientID,
+		ClientSecret: r.OAuthClientSecret,
+	}
+
+	tx := &transaction{operation: "register"}
+	step := Step{Action: "create IAM device", Resource: r.LoginID}
+	device, _, err := l.IAM.Devices.CreateDevice(iam.Device{
+		LoginID:           r.LoginID,
+		DeviceExtID:       r.DeviceExtID,
+		Password:          password,
+		Type:              record.DeviceType.CTN,
+		ForTest:           r.ForTest,
+		OrganizationID:    proposition.OrganizationGuid.Value,
+		GlobalReferenceID: r.GlobalReferenceID,
+		Text:              r.Text,
+		ApplicationID:     application.ApplicationGuid.Value,
+	})
+	if err == nil && device == nil {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return nil, tx.fail(step, err)
+	}
+	record.Device = *device
+	step.Resource = "Device/" + device.ID
+	tx.done(step, func(ctx context.Context) error {
+		return l.deleteDevice(device.ID)
+	})
+
+	for _, groupID := range deviceGroups(record) {
+		groupID := groupID
+		step := Step{Action: "add device to IAM group", Resource: "Group/" + groupID}
+		if _, _, err := l.IAM.Groups.AddDevices(ctx, iam.Group{ID: groupID}, device.ID); err != nil {
+			return nil, tx.fail(step, err)
+		}
+		record.Groups = append(record.Groups, groupID)
+		tx.done(step, func(ctx context.Context) error {
+			_, _, err := l.IAM.Groups.RemoveDevices(ctx, iam.Group{ID: groupID}, device.ID)
+			return err
+		})
+	}
+
+	actions := r.Actions
+	if len(actions) == 0 {
+		actions = DefaultActions
+	}
+	for _, g := range grants {
+		step := Step{Action: "grant data contract", Resource: "BlobDataContract/" + g.contract.ID}
+		policy, _, err := l.BLR.Configurations.CreateBlobStorePolicy(blr.BlobStorePolicy{
+			Statement: []blr.BlobStorePolicyStatement{{
+				Effect:    "allow",
+				Action:    actions,
+				Principal: []string{Principal(record.Device)},
+				Resource:  []string{ContractResource(g.bucket, g.contract)},
+			}},
+		}, blr.WithContext(ctx))
+		if err != nil {
+			return nil, tx.fail(step, err)
+		}
+		record.Grants = append(record.Grants, Grant{DataContract: g.contract.Name, PolicyID: policy.ID})
+		tx.done(step, func(ctx context.Context) error {
+			return l.deletePolicy(ctx, policy.ID)
+		})
+	}
+	return record, nil
+}
+
+// Activate activates the IAM device
+func (l *Lifecycle) Activate(ctx context.Context, record *Record) error {
+	return l.setActive(record, true)
+}
+
+// Suspend deactivates the IAM device, which can then no longer log in. Its group
+// memberships and grants are kept so it can be activated again
+func (l *Lifecycle) Suspend(ctx context.Context, record *Record) error {
+	return l.setActive(record, false)
+}
+
+func (l *Lifecycle) setActive(record *Record, active bool) error {
+	operation := "suspend"
+	if active {
+		operation = "activate"
+	}
+	if record == nil || record.Device.ID == "" {
+		return fmt.Errorf("%s: %w", operation, ErrDeviceNotRegistered)
+	}
+	device := record.Device
+	device.IsActive = active
+	device.Password = ""
+	updated, _, err := l.IAM.Devices.UpdateDevice(device)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+	record.Device = *updated
+	return nil
+}
+
+// RotateCredentials replaces the password of the device. The OAuth client credentials
+// are shared by the devices of an application and are not changed
+func (l *Lifecycle) RotateCredentials(ctx context.Context, record *Record) (*Credentials, error) {
+	if record == nil || record.Device.ID == "" {
+		return nil, fmt.Errorf("rotate credentials: %w", ErrDeviceNotRegistered)
+	}
+	if record.Credentials.Password == "" {
+		return nil, fmt.Errorf("rotate credentials: %w", ErrMissingCredentials)
+	}
+	password, err := l.password()
+	if err != nil {
+		return nil, fmt.Errorf("rotate credentials: generate password: %w", err)
+	}
+	ok, _, err := l.IAM.Devices.ChangePassword(record.Device.ID, record.Credentials.Password, password)
+	if err == nil && !ok {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rotate credentials: %w", err)
+	}
+	record.Credentials.Password = password
+	credentials := record.Credentials
+	return &credentials, nil
+}
+
+// Decommission revokes the grants of the device, removes it from its IAM groups and
+// deletes the IAM device. Removed resources are dropped from the record, so a failed
+// decommission, reported as a *PartialStateError, can be retried with the same record
+func (l *Lifecycle) Decommission(ctx context.Context, record *Record) error {
+	if record == nil || record.Device.ID == "" {
+		return fmt.Errorf("decommission: %w", ErrDeviceNotRegistered)
+	}
+	e := &PartialStateError{Operation: "decommission"}
+	fail := func(step Step, err error) error {
+		step.Err = err
+		e.Failed = step
+		if len(e.Completed) == 0 {
+			return fmt.Errorf("decommission: %s: %w", step, err)
+		}
+		return e
+	}
+	for len(record.Grants) > 0 {
+		grant := record.Grants[0]
+		step := Step{Action: "revoke data contract", Resource: "BlobStorePolicy/" + grant.PolicyID}
+		if l.BLR == nil {
+			return fail(step, ErrMissingBlobRepository)
+		}
+		if err := l.deletePolicy(ctx, grant.PolicyID); err != nil {
+			return fail(step, err)
+		}
+		record.Grants = record.Grants[1:]
+		e.Completed = append(e.Completed, step)
+	}
+	for len(record.Groups) > 0 {
+		groupID := record.Groups[0]
+		step := Step{Action: "remove device from IAM group", Resource: "Group/" + groupID}
+		if _, _, err := l.IAM.Groups.RemoveDevices(ctx, iam.Group{ID: groupID}, record.Device.ID); err != nil {
+			return fail(step, err)
+		}
+		record.Groups = record.Groups[1:]
+		e.Completed = append(e.Completed, step)
+	}
+	step := Step{Action: "delete IAM device", Resource: "Device/" + record.Device.ID}
+	if err := l.deleteDevice(record.Device.ID); err != nil {
+		return fail(step, err)
+	}
+	record.Device.ID = ""
+	record.Device.IsActive = false
+	record.Credentials.Password = ""
+	return nil
+}
+
+// resolve looks up the device type with its device group, application and proposition
+// and checks they line up
+func (l *Lifecycle) resolve(ctx context.Context, deviceTypeID string) (*Record, *mdm.Application, *mdm.Proposition, error) {
+	deviceType, _, err := l.MDM.DeviceTypes.GetByID(deviceTypeID, mdm.WithContext(ctx))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("device type %s: %w", deviceTypeID, err)
+	}
+	if deviceType.CTN == "" || deviceType.DeviceGroupId.Reference == "" {
+		return nil, nil, nil, fmt.Errorf("device type %s has no CTN or device group: %w", deviceTypeID, ErrInconsistentDeviceType)
+	}
+	group, _, err := l.MDM.DeviceGroups.GetByID(refID(deviceType.DeviceGroupId), mdm.WithContext(ctx))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("device group of device type %s: %w", deviceTypeID, err)
+	}
+	application, _, err := l.MDM.Applications.GetApplicationByID(refID(group.ApplicationId))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("application of device group %s: %w", group.ID, err)
+	}
+	if application.ApplicationGuid == nil || application.ApplicationGuid.Value == "" {
+		return nil, nil, nil, fmt.Errorf("application %s has no IAM application: %w", application.ID, ErrInconsistentDeviceType)
+	}
+	proposition, _, err := l.MDM.Propositions.GetPropositionByID(refID(application.PropositionID))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("proposition of application %s: %w", application.ID, err)
+	}
+	if proposition.OrganizationGuid.Value == "" {
+		return nil, nil, nil, fmt.Errorf("proposition %s has no organization: %w", proposition.ID, ErrInconsistentDeviceType)
+	}
+	return &Record{DeviceType: *deviceType, DeviceGroup: *group}, application, proposition, nil
+}
+
+// oauthClient returns the OAuth client of the application with the given name, or its
+// only client when name is empty
+func (l *Lifecycle) oauthClient(application *mdm.Application, name string) (*mdm.OAuthClient, error) {
+	opt := &mdm.GetOAuthClientsOptions{ApplicationID: &application.ID}
+	if name != "" {
+		opt.Name = &name
+	}
+	clients, _, err := l.MDM.OAuthClients.GetOAuthClients(opt)
+	if err != nil {
+		return nil, fmt.Errorf("OAuth clients of application %s: %w", application.ID, err)
+	}
+	var found []mdm.OAuthClient
+	for _, c := range *clients {
+		if (name == "" || c.Name == name) && !c.ClientRevoked {
+			found = append(found, c)
+		}
+	}
+	if len(found) != 1 {
+		return nil, fmt.Errorf("application %s has %d matching OAuth clients: %w", application.ID, len(found), ErrOAuthClientNotFound)
+	}
+	return &found[0], nil
+}
+
+// resolveContracts looks up the data contracts of the proposition and their buckets
+func (l *Lifecycle) resolveContracts(ctx context.Context, proposition *mdm.Proposition, names []string) ([]contractGrant, error) {
+	var grants []contractGrant
+	for _, name := range names {
+		name := name
+		contracts, _, err := l.MDM.BlobDataContracts.Find(&mdm.GetBlobDataContractOptions{Name: &name}, mdm.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("data contract %s: %w", name, err)
+		}
+		var grant *contractGrant
+		for _, contract := range *contracts {
+			if contract.Name != name {
+				continue
+			}
+			dataType, _, err := l.MDM.DataTypes.GetByID(refID(contract.DataTypeID), mdm.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("data type of data contract %s: %w", name, err)
+			}
+			if refID(dataType.PropositionId) != proposition.ID {
+				continue
+			}
+			bucket, _, err := l.MDM.Buckets.GetByID(refID(contract.BucketID), mdm.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("bucket of data contract %s: %w", name, err)
+			}
+			grant = &contractGrant{contract: contract, bucket: *bucket}
+			break
+		}
+		if grant == nil {
+			return nil, fmt.Errorf("%s in proposition %s: %w", name, proposition.ID, ErrDataContractNotFound)
+		}
+		grants = append(grants, *grant)
+	}
+	return grants, nil
+}
+
+// Principal returns the BLR policy principal of an IAM device
+func Principal(device iam.Device) string {
+	return "prn:hsdp:iam:" + device.OrganizationID + ":device/" + device.ID
+}
+
+// ContractResource returns the BLR policy resource covering the blobs of a data contract
+func ContractResource(bucket mdm.Bucket, contract mdm.BlobDataContract) string {
+	return path.Join(bucket.Name, contract.RootPathInBucket) + "/*"
+}
+
+func (l *Lifecycle) deleteDevice(id string) error {
+	ok, resp, err := l.IAM.Devices.DeleteDevice(iam.Device{ID: id})
+	if err != nil {
+		return err
+	}
+	if !ok && (resp == nil || resp.StatusCode() != http.StatusNotFound) {
+		return ErrOperationFailed
+	}
+	return nil
+}
+
+func (l *Lifecycle) deletePolicy(ctx context.Context, id string) error {
+	ok, resp, err := l.BLR.Configurations.DeleteBlobStorePolicy(blr.BlobStorePolicy{ID: id}, blr.WithContext(ctx))
+	if resp != nil && resp.StatusCode() == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOperationFailed
+	}
+	return nil
+}
+
+func (l *Lifecycle) password() (string, error) {
+	if l.Password != nil {
+		return l.Password()
+	}
+	return generatePassword()
+}
+
+// deviceGroups returns the IAM groups of the device group and device type
+func deviceGroups(record *Record) []string {
+	var groups []string
+	for _, guid := range []*mdm.Identifier{record.DeviceGroup.DefaultGroupGuid, record.DeviceType.DefaultGroupGuid} {
+		if guid == nil || guid.Value == "" {
+			continue
+		}
+		if len(groups) == 0 || groups[0] != guid.Value {
+			groups = append(groups, guid.Value)
+		}
+	}
+	return groups
+}
+
+func refID(reference mdm.Reference) string {
+	return path.Base(reference.Reference)
+}
+
+// transaction undoes the completed steps of a failed operation
+type transaction struct {
+	operation string
+	steps     []Step
+	undo      []func(ctx context.Context) error
+}
+
+func (t *transaction) done(step Step, undo func(ctx context.Context) error) {
+	t.steps = append(t.steps, step)
+	t.undo = append(t.undo, undo)
+}
+
+// fail undoes the completed steps in reverse order. The undo runs without the context
+// of the operation, which may have been cancelled
+func (t *transaction) fail(step Step, err error) error {
+	step.Err = err
+	if len(t.steps) == 0 {
+		return fmt.Errorf("%s: %s: %w", t.operation, step.Action, err)
+	}
+	e := &PartialStateError{Operation: t.operation, Failed: step}
+	for i := len(t.steps) - 1; i >= 0; i-- {
+		done := t.steps[i]
+		if undoErr := t.undo[i](context.Background()); undoErr != nil {
+			done.Err = undoErr
+			e.Completed = append(e.Completed, done)
+			continue
+		}
+		e.RolledBack = append(e.RolledBack, done)
+	}
+	return e
+}
+
+const (
+	passwordLength   = 24
+	passwordLower    = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits   = "0123456789"
+	passwordSpecials = "!#$%&*+-.=?@_"
+)
+
+// generatePassword returns a random password with lower and upper case letters,
+// digits and special characters
+func generatePassword() (string, error) {
+	classes := []string{passwordLower, passwordUpper, passwordDigits, passwordSpecials}
+	all := strings.Join(classes, "")
+	password := make([]byte, passwordLength)
+	for i := range password {
+		chars := all
+		if i < len(classes) {
+			chars = classes[i]
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = chars[n.Int64()]
+	}
+	// Move the characters of each class to random positions
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
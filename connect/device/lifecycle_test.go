@@ -0,0 +1,302 @@
+package device_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/connect/blr"
+	"github.com/philips-software/go-hsdp-api/connect/device"
+	"github.com/philips-software/go-hsdp-api/connect/mdm"
+	"github.com/philips-software/go-hsdp-api/iam"
+	"github.com/philips-software/go-hsdp-api/internal/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	muxIAM     *http.ServeMux
+	serverIAM  *httptest.Server
+	muxHSDP    *http.ServeMux
+	serverHSDP *httptest.Server
+
+	iamClient *iam.Client
+	mdmClient *mdm.Client
+	blrClient *blr.Client
+)
+
+func setup(t *testing.T) func() {
+	muxIAM = http.NewServeMux()
+	serverIAM = httptest.NewServer(muxIAM)
+	muxHSDP = http.NewServeMux()
+	serverHSDP = httptest.NewServer(muxHSDP)
+
+	var err error
+
+	iamClient, err = iam.NewClient(nil, &iam.Config{
+		OAuth2ClientID: "TestClient",
+		OAuth2Secret:   "Secret",
+		SharedKey:      "SharedKey",
+		SecretKey:      "SecretKey",
+		IAMURL:         serverIAM.URL,
+		IDMURL:         serverHSDP.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create iamClient: %v", err)
+	}
+	mdmClient, err = mdm.NewClient(iamClient, &mdm.Config{
+		BaseURL: serverHSDP.URL + "/connect/mdm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mdmClient: %v", err)
+	}
+	blrClient, err = blr.NewClient(iamClient, &blr.Config{
+		BaseURL: serverHSDP.URL + "/connect/blobrepository",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create blrClient: %v", err)
+	}
+
+	muxIAM.HandleFunc("/authorize/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+    "scope": "mail",
+    "access_token": "44d20214-7879-4e35-923d-f9d4e01c9746",
+    "refresh_token": "31f1a449-ef8e-4bfc-a227-4f2353fde547",
+    "expires_in": 1799,
+    "token_type": "Bearer"
+}`)
+	})
+	err = iamClient.Login("username", "password")
+	assert.Nil(t, err)
+
+	return func() {
+		serverIAM.Close()
+		serverHSDP.Close()
+	}
+}
+
+// mdmHandler serves the MDM resources of one proposition
+func mdmHandler(t *testing.T) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body interface{}
+		switch strings.TrimPrefix(r.URL.Path, "/connect/mdm/") {
+		case "DeviceType/type-1":
+			body = mdm.DeviceType{ID: "type-1", Name: "monitor", CTN: "HX6000",
+				DeviceGroupId:    mdm.Reference{Reference: "DeviceGroup/group-1"},
+				DefaultGroupGuid: &mdm.Identifier{Value: "iam-type-group"}}
+		case "DeviceGroup/group-1":
+			body = mdm.DeviceGroup{ID: "group-1", Name: "monitors",
+				ApplicationId:    mdm.Reference{Reference: "Application/app-1"},
+				DefaultGroupGuid: &mdm.Identifier{Value: "iam-device-group"}}
+		case "Application":
+			body = bundle(mdm.Application{ID: "app-1", Name: "app",
+				PropositionID:   mdm.Reference{Reference: "Proposition/prop-1"},
+				ApplicationGuid: &mdm.Identifier{Value: "iam-app-1"}})
+		case "Proposition":
+			body = bundle(mdm.Proposition{ID: "prop-1", Name: "prop", OrganizationGuid: mdm.Identifier{Value: "org-1"}})
+		case "OAuthClient":
+			assert.Equal(t, "app-1", r.URL.Query().Get("applicationId"))
+			body = bundle(mdm.OAuthClient{ID: "client-1", Name: "devices", ClientID: "device-client"})
+		case "BlobDataContract":
+			body = bundle(mdm.BlobDataContract{ID: "contract-1", Name: r.URL.Query().Get("name"),
+				DataTypeID:       mdm.Reference{Reference: "DataType/data-1"},
+				BucketID:         mdm.Reference{Reference: "Bucket/bucket-1"},
+				RootPathInBucket: "telemetry"})
+		case "DataType/data-1":
+			body = mdm.DataType{ID: "data-1", Name: "telemetry", PropositionId: mdm.Reference{Reference: "Proposition/prop-1"}}
+		case "Bucket/bucket-1":
+			body = mdm.Bucket{ID: "bucket-1", Name: "device-data"}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+func bundle(resources ...interface{}) map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, r := range resources {
+		entries = append(entries, map[string]interface{}{"resource": r})
+	}
+	return map[string]interface{}{"resourceType": "Bundle", "entry": entries}
+}
+
+// serveConnect serves the IAM devices and groups and the BLR blob store policies
+// from memory. It returns the device passwords and group members
+func serveConnect(t *testing.T, failPolicies bool) (devices, policies *storetest.Store, passwords map[string]string, members map[string][]string) {
+	passwords, members = map[string]string{}, map[string][]string{}
+	devices = storetest.New("/authorize/identity")
+	devices.Results = func(resources []storetest.Resource, _ string) interface{} {
+		return map[string]interface{}{"total": len(resources), "entry": resources}
+	}
+	devices.Created = func(_ string, device storetest.Resource) {
+		passwords[device["id"].(string)], _ = device["password"].(string)
+		delete(device, "password")
+	}
+	devices.Actions = map[string]storetest.Action{
+		"$change-password": func(w http.ResponseWriter, r *http.Request, id string) {
+			var password struct {
+				OldPassword string `json:"oldPassword"`
+				NewPassword string `json:"newPassword"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&password)
+			if password.OldPassword != passwords[id] {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			passwords[id] = password.NewPassword
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+	muxHSDP.Handle("/authorize/identity/", devices)
+	muxHSDP.HandleFunc("/authorize/identity/Group/", func(w http.ResponseWriter, r *http.Request) {
+		group := strings.Split(strings.TrimPrefix(r.URL.Path, "/authorize/identity/Group/"), "/")
+		w.Header().Set("Content-Type", "application/json")
+		if len(group) == 1 {
+			w.Header().Set("ETag", "v1")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(iam.Group{ID: group[0], Name: group[0]})
+			return
+		}
+		var body struct {
+			MemberType string   `json:"memberType"`
+			Value      []string `json:"value"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, "DEVICE", body.MemberType)
+		switch group[1] {
+		case "$assign":
+			members[group[0]] = append(members[group[0]], body.Value...)
+		case "$remove":
+			var kept []string
+			for _, m := range members[group[0]] {
+				if m != body.Value[0] {
+					kept = append(kept, m)
+				}
+			}
+			members[group[0]] = kept
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{}`)
+	})
+
+	policies = storetest.New("/connect/blobrepository/configuration")
+	muxHSDP.Handle("/connect/blobrepository/configuration/", policies)
+	muxHSDP.HandleFunc("/connect/blobrepository/configuration/BlobStorePolicy", func(w http.ResponseWriter, r *http.Request) {
+		if failPolicies {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		policies.ServeHTTP(w, r)
+	})
+	return devices, policies, passwords, members
+}
+
+func registration() device.Registration {
+	return device.Registration{
+		DeviceTypeID:      "type-1",
+		LoginID:           "monitor-001",
+		DeviceExtID:       iam.DeviceIdentifier{System: "serial", Value: "001", Type: iam.CodeableConcept{Code: "SN", Text: "serial"}},
+		GlobalReferenceID: "monitor-001",
+		OAuthClientSecret: "secret",
+		DataContracts:     []string{"telemetry"},
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	devices, policies, passwords, members := serveConnect(t, false)
+	muxHSDP.HandleFunc("/connect/mdm/", mdmHandler(t))
+	lifecycle := device.NewLifecycle(iamClient, mdmClient, blrClient)
+	ctx := context.Background()
+
+	record, err := lifecycle.Register(ctx, registration())
+	if !assert.Nil(t, err) {
+		return
+	}
+	d := record.Device
+	assert.Equal(t, "HX6000", d.Type)
+	assert.Equal(t, "org-1", d.OrganizationID)
+	assert.Equal(t, "iam-app-1", d.ApplicationID)
+	assert.False(t, d.IsActive)
+	assert.Equal(t, []string{"iam-device-group", "iam-type-group"}, record.Groups)
+	assert.Equal(t, []string{d.ID}, members["iam-device-group"])
+	assert.Equal(t, "device-client", record.Credentials.ClientID)
+	assert.Equal(t, "secret", record.Credentials.ClientSecret)
+	assert.Equal(t, passwords[d.ID], record.Credentials.Password)
+	assert.Len(t, record.Credentials.Password, 24)
+	var policy blr.BlobStorePolicy
+	if assert.Len(t, record.Grants, 1) && assert.True(t, policies.Get("BlobStorePolicy", record.Grants[0].PolicyID, &policy)) {
+		statement := policy.Statement[0]
+		assert.Equal(t, []string{"prn:hsdp:iam:org-1:device/" + d.ID}, statement.Principal)
+		assert.Equal(t, []string{"device-data/telemetry/*"}, statement.Resource)
+		assert.Equal(t, device.DefaultActions, statement.Action)
+	}
+
+	assert.Nil(t, lifecycle.Activate(ctx, record))
+	var stored iam.Device
+	if assert.True(t, devices.Get("Device", d.ID, &stored)) {
+		assert.True(t, stored.IsActive)
+	}
+	assert.True(t, record.Device.IsActive)
+
+	old := record.Credentials.Password
+	credentials, err := lifecycle.RotateCredentials(ctx, record)
+	if assert.Nil(t, err) {
+		assert.NotEqual(t, old, credentials.Password)
+		assert.Equal(t, passwords[d.ID], credentials.Password)
+	}
+
+	assert.Nil(t, lifecycle.Suspend(ctx, record))
+	if assert.True(t, devices.Get("Device", d.ID, &stored)) {
+		assert.False(t, stored.IsActive)
+	}
+
+	assert.Nil(t, lifecycle.Decommission(ctx, record))
+	assert.Equal(t, 0, devices.Count("Device"))
+	assert.Equal(t, 0, policies.Count("BlobStorePolicy"))
+	assert.Len(t, members["iam-device-group"], 0)
+	assert.Len(t, members["iam-type-group"], 0)
+	assert.True(t, errors.Is(lifecycle.Decommission(ctx, record), device.ErrDeviceNotRegistered))
+}
+
+func TestLifecycleRegisterRollback(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	devices, _, _, members := serveConnect(t, true)
+	muxHSDP.HandleFunc("/connect/mdm/", mdmHandler(t))
+	lifecycle := device.NewLifecycle(iamClient, mdmClient, blrClient)
+
+	_, err := lifecycle.Register(context.Background(), registration())
+	var partial *device.PartialStateError
+	if !assert.True(t, errors.As(err, &partial)) {
+		return
+	}
+	assert.Equal(t, "grant data contract", partial.Failed.Action)
+	assert.Len(t, partial.RolledBack, 3)
+	assert.Len(t, partial.Completed, 0)
+	assert.Equal(t, 0, devices.Count("Device"))
+	assert.Len(t, members["iam-device-group"], 0)
+
+	r := registration()
+	r.DataContracts = []string{"telemetry"}
+	_, err = device.NewLifecycle(iamClient, mdmClient, nil).Register(context.Background(), r)
+	assert.True(t, errors.Is(err, device.ErrMissingBlobRepository))
+	r.OAuthClient = "other"
+	r.DataContracts = nil
+	_, err = lifecycle.Register(context.Background(), r)
+	assert.True(t, errors.Is(err, device.ErrOAuthClientNotFound))
+	assert.Equal(t, 0, devices.Count("Device"))
+}
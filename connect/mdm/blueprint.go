@@ -0,0 +1,861 @@
+package mdm
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Blueprint describes the MDM resources of a connected device proposition. Resources
+// refer to each other by name: a reference "Application/@name", or "@name" for the
+// serviceAgentId of a DataBrokerSubscription, refers to the resource of that type and
+// name, either in the blueprint or already present in MDM. Other references are used
+// as is. Names must be unique per resource type. SubscriberTypes, DataAdapters,
+// ServiceAgents, ServiceActions, StorageClasses and Regions can only be referred to
+type Blueprint struct {
+	AuthenticationMethods   []AuthenticationMethod   `json:"authenticationMethods,omitempty"`
+	Proposition             Proposition              `json:"proposition"`
+	Applications            []Application            `json:"applications,omitempty"`
+	OAuthClients            []BlueprintOAuthClient   `json:"oauthClients,omitempty"`
+	StandardServices        []StandardService        `json:"standardServices,omitempty"`
+	ServiceReferences       []ServiceReference       `json:"serviceReferences,omitempty"`
+	DeviceGroups            []DeviceGroup            `json:"deviceGroups,omitempty"`
+	DeviceTypes             []DeviceType             `json:"deviceTypes,omitempty"`
+	DataTypes               []DataType               `json:"dataTypes,omitempty"`
+	Buckets                 []Bucket                 `json:"buckets,omitempty"`
+	BlobDataContracts       []BlobDataContract       `json:"blobDataContracts,omitempty"`
+	DataSubscribers         []DataSubscriber         `json:"dataSubscribers,omitempty"`
+	DataBrokerSubscriptions []DataBrokerSubscription `json:"dataBrokerSubscriptions,omitempty"`
+	BlobSubscriptions       []BlobSubscription       `json:"blobSubscriptions,omitempty"`
+}
+
+// BlueprintOAuthClient is an OAuthClient with its scopes
+type BlueprintOAuthClient struct {
+	OAuthClient
+	Scopes        []string `json:"scopes,omitempty"`
+	DefaultScopes []string `json:"defaultScopes,omitempty"`
+}
+
+// BlueprintAction is the action taken on a blueprint resource
+type BlueprintAction string
+
+const (
+	BlueprintCreate BlueprintAction = "create"
+	BlueprintUpdate BlueprintAction = "update"
+)
+
+// BlueprintChange is a change made, or in a dry run to be made, by Provision
+type BlueprintChange struct {
+	Resource string
+	Name     string
+	ID       string
+	Action   BlueprintAction
+	// Fields lists the JSON fields that differ for an update
+	Fields []string
+}
+
+// BlueprintReport is the outcome of Provision
+type BlueprintReport struct {
+	Changes []BlueprintChange
+	// IDs holds the IDs of the blueprint resources by resource type and name. In a dry
+	// run resources still to be created have the ID "@name"
+	IDs map[string]map[string]string
+}
+
+// ProvisionOptions configures Provision
+type ProvisionOptions struct {
+	// DryRun reports the changes without making them
+	DryRun bool
+}
+
+// blueprintRef is a reference to a resource of a kind. A bare reference holds only the ID
+type blueprintRef struct {
+	kind  string
+	value *string
+	bare  bool
+}
+
+func ref(kind string, reference *Reference) blueprintRef {
+	return blueprintRef{kind: kind, value: &reference.Reference}
+}
+
+// symbolicName returns the name of a reference by name
+func (r blueprintRef) symbolicName() (string, bool, error) {
+	value := *r.value
+	if r.bare {
+		return strings.TrimPrefix(value, "@"), strings.HasPrefix(value, "@"), nil
+	}
+	if !strings.Contains(value, "/@") {
+		return "", false, nil
+	}
+	if !strings.HasPrefix(value, r.kind+"/@") {
+		return "", false, fmt.Errorf("%w: %q is not a %s", ErrUnresolvedReference, value, r.kind)
+	}
+	return strings.TrimPrefix(value, r.kind+"/@"), true, nil
+}
+
+func (r blueprintRef) set(id string) {
+	if r.bare {
+		*r.value = id
+		return
+	}
+	*r.value = r.kind + "/" + id
+}
+
+// id returns the ID the reference refers to
+func (r blueprintRef) id() string {
+	return path.Base(*r.value)
+}
+
+// pending reports whether the reference refers to a resource a dry run did not create
+func (r blueprintRef) pending() bool {
+	return strings.HasPrefix(r.id(), "@")
+}
+
+func refID(reference Reference) string {
+	return path.Base(reference.Reference)
+}
+
+func propositionRefs(p *Proposition) []blueprintRef {
+	if p.AuthenticationMethodID == nil {
+		return nil
+	}
+	return []blueprintRef{ref("AuthenticationMethod", p.AuthenticationMethodID)}
+}
+
+func applicationRefs(a *Application) []blueprintRef {
+	return []blueprintRef{ref("Proposition", &a.PropositionID)}
+}
+
+func oauthClientRefs(c *BlueprintOAuthClient) []blueprintRef {
+	return []blueprintRef{ref("Application", &c.ApplicationId)}
+}
+
+func standardServiceRefs(s *StandardService) []blueprintRef {
+	var refs []blueprintRef
+	for i := range s.ServiceUrls {
+		if s.ServiceUrls[i].AuthenticationMethodID != nil {
+			refs = append(refs, ref("AuthenticationMethod", s.ServiceUrls[i].AuthenticationMethodID))
+		}
+	}
+	return refs
+}
+
+func serviceReferenceRefs(s *ServiceReference) []blueprintRef {
+	refs := []blueprintRef{ref("Application", &s.ApplicationID), ref("StandardService", &s.StandardServiceID)}
+	for i := range s.ServiceActionIDs {
+		refs = append(refs, ref("ServiceAction", &s.ServiceActionIDs[i]))
+	}
+	return refs
+}
+
+func deviceGroupRefs(g *DeviceGroup) []blueprintRef {
+	return []blueprintRef{ref("Application", &g.ApplicationId)}
+}
+
+func deviceTypeRefs(t *DeviceType) []blueprintRef {
+	return []blueprintRef{ref("DeviceGroup", &t.DeviceGroupId)}
+}
+
+func dataTypeRefs(t *DataType) []blueprintRef {
+	return []blueprintRef{ref("Proposition", &t.PropositionId)}
+}
+
+func bucketRefs(b *Bucket) []blueprintRef {
+	refs := []blueprintRef{ref("Proposition", &b.PropositionID), ref("Region", &b.DefaultRegionID)}
+	if b.ReplicationRegionID != nil {
+		refs = append(refs, ref("Region", b.ReplicationRegionID))
+	}
+	return refs
+}
+
+func blobDataContractRefs(c *BlobDataContract) []blueprintRef {
+	return []blueprintRef{ref("DataType", &c.DataTypeID), ref("Bucket", &c.BucketID), ref("StorageClass", &c.StorageClassID)}
+}
+
+func dataSubscriberRefs(s *DataSubscriber) []blueprintRef {
+	return []blueprintRef{ref("SubscriberType", &s.SubscriberTypeId)}
+}
+
+func dataBrokerSubscriptionRefs(s *DataBrokerSubscription) []blueprintRef {
+	return []blueprintRef{
+		{kind: "ServiceAgent", value: &s.ServiceAgentId, bare: true},
+		ref("DataSubscriber", &s.DataSubscriberId),
+		ref("DataAdapter", &s.DataAdapterId),
+		ref("AuthenticationMethod", &s.AuthenticationMethodId),
+		ref("DataType", &s.DataTypeID),
+	}
+}
+
+func blobSubscriptionRefs(s *BlobSubscription) []blueprintRef {
+	return []blueprintRef{ref("DataType", &s.DataTypeId)}
+}
+
+// lookup finds resources of a kind by name or ID
+type lookup struct {
+	byName func(name string) ([]string, error)
+	byID   func(id string) (string, error)
+}
+
+func newLookup[T any](find func(name string) ([]T, error), get func(id string) (*T, *Response, error), name, id func(*T) string) lookup {
+	return lookup{
+		byName: func(want string) ([]string, error) {
+			found, err := find(want)
+			if err != nil {
+				return nil, err
+			}
+			var ids []string
+			for i := range found {
+				if name(&found[i]) == want {
+					ids = append(ids, id(&found[i]))
+				}
+			}
+			return ids, nil
+		},
+		byID: func(id string) (string, error) {
+			resource, _, err := get(id)
+			if err != nil {
+				return "", err
+			}
+			if resource == nil {
+				return "", ErrNotFound
+			}
+			return name(resource), nil
+		},
+	}
+}
+
+func (c *Client) lookups() map[string]lookup {
+	return map[string]lookup{
+		"AuthenticationMethod": newLookup(func(name string) ([]AuthenticationMethod, error) {
+			return c.AuthenticationMethods.Iterate(&GetAuthenticationMethodOptions{Name: &name}).All()
+		}, func(id string) (*AuthenticationMethod, *Response, error) { return c.AuthenticationMethods.GetByID(id) },
+			func(r *AuthenticationMethod) string { return r.Name }, func(r *AuthenticationMethod) string { return r.ID }),
+		"Proposition": newLookup(func(name string) ([]Proposition, error) {
+			return c.Propositions.Iterate(&GetPropositionsOptions{Name: &name}).All()
+		}, c.Propositions.GetPropositionByID,
+			func(r *Proposition) string { return r.Name }, func(r *Proposition) string { return r.ID }),
+		"Application": newLookup(func(name string) ([]Application, error) {
+			return c.Applications.Iterate(&GetApplicationsOptions{Name: &name}).All()
+		}, c.Applications.GetApplicationByID,
+			func(r *Application) string { return r.Name }, func(r *Application) string { return r.ID }),
+		"StandardService": newLookup(func(name string) ([]StandardService, error) {
+			return c.StandardServices.Iterate(&GetStandardServiceOptions{Name: &name}).All()
+		}, c.StandardServices.GetStandardServiceByID,
+			func(r *StandardService) string { return r.Name }, func(r *StandardService) string { return r.ID }),
+		"ServiceAction": newLookup(func(name string) ([]ServiceAction, error) {
+			return c.ServiceActions.Iterate(&GetServiceActionOptions{Name: &name}).All()
+		}, func(id string) (*ServiceAction, *Response, error) { return c.ServiceActions.GetByID(id) },
+			func(r *ServiceAction) string { return r.Name }, func(r *ServiceAction) string { return r.ID }),
+		"DeviceGroup": newLookup(func(name string) ([]DeviceGroup, error) {
+			return c.DeviceGroups.Iterate(&GetDeviceGroupOptions{Name: &name}).All()
+		}, func(id string) (*DeviceGroup, *Response, error) { return c.DeviceGroups.GetByID(id) },
+			func(r *DeviceGroup) string { return r.Name }, func(r *DeviceGroup) string { return r.ID }),
+		"DataType": newLookup(func(name string) ([]DataType, error) {
+			return c.DataTypes.Iterate(&GetDataTypeOptions{Name: &name}).All()
+		}, func(id string) (*DataType, *Response, error) { return c.DataTypes.GetByID(id) },
+			func(r *DataType) string { return r.Name }, func(r *DataType) string { return r.ID }),
+		"Bucket": newLookup(func(name string) ([]Bucket, error) {
+			return c.Buckets.Iterate(&GetBucketOptions{Name: &name}).All()
+		}, func(id string) (*Bucket, *Response, error) { return c.Buckets.GetByID(id) },
+			func(r *Bucket) string { return r.Name }, func(r *Bucket) string { return r.ID }),
+		"DataSubscriber": newLookup(func(name string) ([]DataSubscriber, error) {
+			return c.DataSubscribers.Iterate(&GetDataSubscriberOptions{Name: &name}).All()
+		}, c.DataSubscribers.GetByID,
+			func(r *DataSubscriber) string { return r.Name }, func(r *DataSubscriber) string { return r.ID }),
+		"SubscriberType": newLookup(func(name string) ([]SubscriberType, error) {
+			return c.SubscriberTypes.Iterate(&GetSubscriberTypeOptions{Name: &name}).All()
+		}, c.SubscriberTypes.GetByID,
+			func(r *SubscriberType) string { return r.Name }, func(r *SubscriberType) string { return r.ID }),
+		"DataAdapter": newLookup(func(name string) ([]DataAdapter, error) {
+			return c.DataAdapters.Iterate(&GetDataAdapterOptions{Name: &name}).All()
+		}, c.DataAdapters.GetByID,
+			func(r *DataAdapter) string { return r.Name }, func(r *DataAdapter) string { return r.ID }),
+		"ServiceAgent": newLookup(func(name string) ([]ServiceAgent, error) {
+			return c.ServiceAgents.Iterate(&GetServiceAgentOptions{Name: &name}).All()
+		}, c.ServiceAgents.GetByID,
+			func(r *ServiceAgent) string { return r.Name }, func(r *ServiceAgent) string { return r.ID }),
+		"StorageClass": newLookup(func(name string) ([]StorageClass, error) {
+			return c.StorageClasses.Iterate(&GetStorageClassOptions{Name: &name}).All()
+		}, c.StorageClasses.GetStorageClassByID,
+			func(r *StorageClass) string { return r.Name }, func(r *StorageClass) string { return r.ID }),
+		"Region": newLookup(func(name string) ([]Region, error) {
+			return c.Regions.Iterate(&GetRegionOptions{Name: &name}).All()
+		}, c.Regions.GetRegionByID,
+			func(r *Region) string { return r.Name }, func(r *Region) string { return r.ID }),
+	}
+}
+
+// blueprintKind describes how a resource type is provisioned
+type blueprintKind[T any] struct {
+	resource string
+	name     func(*T) string
+	refs     func(*T) []blueprintRef
+	// find returns the candidates for the existing resource, which are matched on
+	// name and on the reference returned by scope
+	find   func(*T) ([]T, error)
+	scope  func(*T) Reference
+	create func(T) (*T, error)
+	update func(T) (*T, error)
+	// after runs after the resource has been created or found
+	after func(desired, actual *T) error
+	// ignore lists JSON fields that are not compared, e.g. secrets that are not returned
+	ignore []string
+}
+
+type provisioner struct {
+	client  *Client
+	opts    ProvisionOptions
+	lookups map[string]lookup
+	report  *BlueprintReport
+}
+
+// Provision creates the blueprint resources in dependency order. Resources that
+// exist, matched by name within their parent, are updated when they differ from the
+// blueprint, so provisioning a blueprint again changes nothing. Resources that are no
+// longer in the blueprint are left alone. The scopes of OAuth clients are set on
+// every run, as they cannot be read back
+func (c *Client) Provision(blueprint Blueprint, opts ProvisionOptions) (*BlueprintReport, error) {
+	if err := blueprint.validate(); err != nil {
+		return nil, err
+	}
+	p := &provisioner{
+		client:  c,
+		opts:    opts,
+		lookups: c.lookups(),
+		report:  &BlueprintReport{IDs: make(map[string]map[string]string)},
+	}
+	steps := []func() error{
+		func() error { return provisionKind(p, p.authenticationMethods(), blueprint.AuthenticationMethods) },
+		func() error { return provisionKind(p, p.propositions(), []Proposition{blueprint.Proposition}) },
+		func() error { return provisionKind(p, p.applications(), blueprint.Applications) },
+		func() error { return provisionKind(p, p.oauthClients(), blueprint.OAuthClients) },
+		func() error { return provisionKind(p, p.standardServices(), blueprint.StandardServices) },
+		func() error { return provisionKind(p, p.serviceReferences(), blueprint.ServiceReferences) },
+		func() error { return provisionKind(p, p.deviceGroups(), blueprint.DeviceGroups) },
+		func() error { return provisionKind(p, p.deviceTypes(), blueprint.DeviceTypes) },
+		func() error { return provisionKind(p, p.dataTypes(), blueprint.DataTypes) },
+		func() error { return provisionKind(p, p.buckets(), blueprint.Buckets) },
+		func() error { return provisionKind(p, p.blobDataContracts(), blueprint.BlobDataContracts) },
+		func() error { return provisionKind(p, p.dataSubscribers(), blueprint.DataSubscribers) },
+		func() error { return provisionKind(p, p.dataBrokerSubscriptions(), blueprint.DataBrokerSubscriptions) },
+		func() error { return provisionKind(p, p.blobSubscriptions(), blueprint.BlobSubscriptions) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return p.report, err
+		}
+	}
+	return p.report, nil
+}
+
+func (b *Blueprint) validate() error {
+	if b.Proposition.Name == "" {
+		return fmt.Errorf("%w: proposition has no name", ErrInvalidBlueprint)
+	}
+	names := make(map[string]bool)
+	check := func(resource, name string) error {
+		key := resource + "/" + name
+		if name == "" {
+			return fmt.Errorf("%w: %s without name", ErrInvalidBlueprint, resource)
+		}
+		if names[key] {
+			return fmt.Errorf("%w: duplicate %s %q", ErrInvalidBlueprint, resource, name)
+		}
+		names[key] = true
+		return nil
+	}
+	var err error
+	add := func(resource, name string) {
+		if err == nil {
+			err = check(resource, name)
+		}
+	}
+	for _, r := range b.AuthenticationMethods {
+		add("AuthenticationMethod", r.Name)
+	}
+	for _, r := range b.Applications {
+		add("Application", r.Name)
+	}
+	for _, r := range b.OAuthClients {
+		add("OAuthClient", r.Name)
+	}
+	for _, r := range b.StandardServices {
+		add("StandardService", r.Name)
+	}
+	for _, r := range b.ServiceReferences {
+		add("ServiceReference", r.Name)
+	}
+	for _, r := range b.DeviceGroups {
+		add("DeviceGroup", r.Name)
+	}
+	for _, r := range b.DeviceTypes {
+		add("DeviceType", r.Name)
+	}
+	for _, r := range b.DataTypes {
+		add("DataType", r.Name)
+	}
+	for _, r := range b.Buckets {
+		add("Bucket", r.Name)
+	}
+	for _, r := range b.BlobDataContracts {
+		add("BlobDataContract", r.Name)
+	}
+	for _, r := range b.DataSubscribers {
+		add("DataSubscriber", r.Name)
+	}
+	for _, r := range b.DataBrokerSubscriptions {
+		add("DataBrokerSubscription", r.Name)
+	}
+	for _, r := range b.BlobSubscriptions {
+		add("BlobSubscription", r.Name)
+	}
+	return err
+}
+
+// resolve replaces references by name with references by ID
+func (p *provisioner) resolve(refs []blueprintRef) error {
+	for _, r := range refs {
+		name, ok, err := r.symbolicName()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		id, err := p.lookup(r.kind, name)
+		if err != nil {
+			return err
+		}
+		r.set(id)
+	}
+	return nil
+}
+
+func (p *provisioner) lookup(kind, name string) (string, error) {
+	if id, ok := p.report.IDs[kind][name]; ok {
+		return id, nil
+	}
+	l, ok := p.lookups[kind]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown resource type %s", ErrUnresolvedReference, kind)
+	}
+	ids, err := l.byName(name)
+	if err != nil {
+		return "", fmt.Errorf("lookup %s %q: %w", kind, name, err)
+	}
+	if len(ids) != 1 {
+		return "", fmt.Errorf("%w: %d %s resources named %q", ErrUnresolvedReference, len(ids), kind, name)
+	}
+	p.setID(kind, name, ids[0])
+	return ids[0], nil
+}
+
+func (p *provisioner) setID(kind, name, id string) {
+	if p.report.IDs[kind] == nil {
+		p.report.IDs[kind] = make(map[string]string)
+	}
+	p.report.IDs[kind][name] = id
+}
+
+func provisionKind[T any](p *provisioner, kind blueprintKind[T], desired []T) error {
+	for i := range desired {
+		resource := desired[i]
+		name := kind.name(&resource)
+		fail := func(err error) error {
+			return fmt.Errorf("%s %q: %w", kind.resource, name, err)
+		}
+		refs := kind.refs(&resource)
+		if err := p.resolve(refs); err != nil {
+			return fail(err)
+		}
+		existing, err := findExisting(kind, &resource, refs)
+		if err != nil {
+			return fail(err)
+		}
+		if existing == nil {
+			change := BlueprintChange{Resource: kind.resource, Name: name, Action: BlueprintCreate, ID: "@" + name}
+			if !p.opts.DryRun {
+				created, err := kind.create(resource)
+				if err == nil && created == nil {
+					err = ErrOperationFailed
+				}
+				if err != nil {
+					return fail(err)
+				}
+				change.ID = resourceField(created, "ID")
+				if kind.after != nil {
+					if err := kind.after(&resource, created); err != nil {
+						return fail(err)
+					}
+				}
+			}
+			p.setID(kind.resource, name, change.ID)
+			p.report.Changes = append(p.report.Changes, change)
+			continue
+		}
+		id := resourceField(existing, "ID")
+		p.setID(kind.resource, name, id)
+		fields, merged, err := compareResource(&resource, existing, kind.ignore)
+		if err != nil {
+			return fail(err)
+		}
+		actual := existing
+		if len(fields) > 0 {
+			p.report.Changes = append(p.report.Changes, BlueprintChange{
+				Resource: kind.resource,
+				Name:     name,
+				ID:       id,
+				Action:   BlueprintUpdate,
+				Fields:   fields,
+			})
+			if !p.opts.DryRun {
+				if actual, err = kind.update(*merged); err != nil {
+					return fail(err)
+				}
+			}
+		}
+		if kind.after != nil && !p.opts.DryRun {
+			if err := kind.after(&resource, actual); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	return nil
+}
+
+// findExisting returns the existing resource with the name of the desired resource
+// and the same scope. Resources referring to a resource that is yet to be created
+// cannot exist
+func findExisting[T any](kind blueprintKind[T], desired *T, refs []blueprintRef) (*T, error) {
+	for _, r := range refs {
+		if r.pending() {
+			return nil, nil
+		}
+	}
+	found, err := kind.find(desired)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		candidate := &found[i]
+		if kind.name(candidate) != kind.name(desired) {
+			continue
+		}
+		if kind.scope != nil && refID(kind.scope(candidate)) != refID(kind.scope(desired)) {
+			continue
+		}
+		return candidate, nil
+	}
+	return nil, nil
+}
+
+// compareResource returns the JSON fields set in desired that differ from existing,
+// and existing with those fields replaced
+func compareResource[T any](desired, existing *T, ignore []string) ([]string, *T, error) {
+	desiredFields, err := jsonFields(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	existingFields, err := jsonFields(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+	skip := map[string]bool{"id": true, "meta": true, "resourceType": true}
+	for _, field := range ignore {
+		skip[field] = true
+	}
+	var fields []string
+	for field, value := range desiredFields {
+		if skip[field] || reflect.DeepEqual(value, existingFields[field]) {
+			continue
+		}
+		fields = append(fields, field)
+		existingFields[field] = value
+	}
+	if len(fields) == 0 {
+		return nil, existing, nil
+	}
+	sort.Strings(fields)
+	data, err := json.Marshal(existingFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	var merged T
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, nil, err
+	}
+	return fields, &merged, nil
+}
+
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// apiResult drops the response of a service call
+func apiResult[T any](resource *T, _ *Response, err error) (*T, error) {
+	return resource, err
+}
+
+func (p *provisioner) authenticationMethods() blueprintKind[AuthenticationMethod] {
+	s := p.client.AuthenticationMethods
+	return blueprintKind[AuthenticationMethod]{
+		resource: "AuthenticationMethod",
+		name:     func(r *AuthenticationMethod) string { return r.Name },
+		refs:     func(r *AuthenticationMethod) []blueprintRef { return nil },
+		find: func(r *AuthenticationMethod) ([]AuthenticationMethod, error) {
+			return s.Iterate(&GetAuthenticationMethodOptions{Name: &r.Name}).All()
+		},
+		create: func(r AuthenticationMethod) (*AuthenticationMethod, error) { return apiResult(s.Create(r)) },
+		update: func(r AuthenticationMethod) (*AuthenticationMethod, error) { return apiResult(s.Update(r)) },
+		ignore: []string{"password", "clientSecret"},
+	}
+}
+
+func (p *provisioner) propositions() blueprintKind[Proposition] {
+	s := p.client.Propositions
+	return blueprintKind[Proposition]{
+		resource: "Proposition",
+		name:     func(r *Proposition) string { return r.Name },
+		refs:     propositionRefs,
+		find: func(r *Proposition) ([]Proposition, error) {
+			opt := &GetPropositionsOptions{Name: &r.Name}
+			if r.OrganizationGuid.Value != "" {
+				opt.OrganizationID = &r.OrganizationGuid.Value
+			}
+			return s.Iterate(opt).All()
+		},
+		create: func(r Proposition) (*Proposition, error) { return apiResult(s.CreateProposition(r)) },
+		update: func(r Proposition) (*Proposition, error) { return apiResult(s.UpdateProposition(r)) },
+	}
+}
+
+func (p *provisioner) applications() blueprintKind[Application] {
+	s := p.client.Applications
+	return blueprintKind[Application]{
+		resource: "Application",
+		name:     func(r *Application) string { return r.Name },
+		refs:     applicationRefs,
+		find: func(r *Application) ([]Application, error) {
+			id := refID(r.PropositionID)
+			return s.Iterate(&GetApplicationsOptions{Name: &r.Name, PropositionID: &id}).All()
+		},
+		scope:  func(r *Application) Reference { return r.PropositionID },
+		create: func(r Application) (*Application, error) { return apiResult(s.CreateApplication(r)) },
+		update: func(r Application) (*Application, error) { return apiResult(s.UpdateApplication(r)) },
+	}
+}
+
+func (p *provisioner) oauthClients() blueprintKind[BlueprintOAuthClient] {
+	s := p.client.OAuthClients
+	wrap := func(client *OAuthClient, desired BlueprintOAuthClient, err error) (*BlueprintOAuthClient, error) {
+		if client == nil {
+			return nil, err
+		}
+		return &BlueprintOAuthClient{OAuthClient: *client, Scopes: desired.Scopes, DefaultScopes: desired.DefaultScopes}, err
+	}
+	return blueprintKind[BlueprintOAuthClient]{
+		resource: "OAuthClient",
+		name:     func(r *BlueprintOAuthClient) string { return r.Name },
+		refs:     oauthClientRefs,
+		find: func(r *BlueprintOAuthClient) ([]BlueprintOAuthClient, error) {
+			id := refID(r.ApplicationId)
+			clients, err := s.Iterate(&GetOAuthClientsOptions{Name: &r.Name, ApplicationID: &id}).All()
+			if err != nil {
+				return nil, err
+			}
+			var found []BlueprintOAuthClient
+			for _, client := range clients {
+				found = append(found, BlueprintOAuthClient{OAuthClient: client})
+			}
+			return found, nil
+		},
+		scope: func(r *BlueprintOAuthClient) Reference { return r.ApplicationId },
+		create: func(r BlueprintOAuthClient) (*BlueprintOAuthClient, error) {
+			client, _, err := s.CreateOAuthClient(r.OAuthClient)
+			return wrap(client, r, err)
+		},
+		update: func(r BlueprintOAuthClient) (*BlueprintOAuthClient, error) {
+			client, _, err := s.Update(r.OAuthClient)
+			return wrap(client, r, err)
+		},
+		after: func(desired, actual *BlueprintOAuthClient) error {
+			if len(desired.Scopes) == 0 && len(desired.DefaultScopes) == 0 {
+				return nil
+			}
+			ok, _, err := s.UpdateScopes(actual.OAuthClient, desired.Scopes, desired.DefaultScopes)
+			if err == nil && !ok {
+				err = ErrOperationFailed
+			}
+			if err != nil {
+				return fmt.Errorf("update scopes: %w", err)
+			}
+			return nil
+		},
+		ignore: []string{"scopes", "defaultScopes", "clientSecret", "bootstrapClientSecret"},
+	}
+}
+
+func (p *provisioner) standardServices() blueprintKind[StandardService] {
+	s := p.client.StandardServices
+	return blueprintKind[StandardService]{
+		resource: "StandardService",
+		name:     func(r *StandardService) string { return r.Name },
+		refs:     standardServiceRefs,
+		find: func(r *StandardService) ([]StandardService, error) {
+			return s.Iterate(&GetStandardServiceOptions{Name: &r.Name}).All()
+		},
+		create: func(r StandardService) (*StandardService, error) { return apiResult(s.CreateStandardService(r)) },
+		update: func(r StandardService) (*StandardService, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) serviceReferences() blueprintKind[ServiceReference] {
+	s := p.client.ServiceReferences
+	return blueprintKind[ServiceReference]{
+		resource: "ServiceReference",
+		name:     func(r *ServiceReference) string { return r.Name },
+		refs:     serviceReferenceRefs,
+		find: func(r *ServiceReference) ([]ServiceReference, error) {
+			id := refID(r.ApplicationID)
+			return s.Iterate(&GetServiceReferenceOptions{Name: &r.Name, ApplicationID: &id}).All()
+		},
+		scope:  func(r *ServiceReference) Reference { return r.ApplicationID },
+		create: func(r ServiceReference) (*ServiceReference, error) { return apiResult(s.Create(r)) },
+		update: func(r ServiceReference) (*ServiceReference, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) deviceGroups() blueprintKind[DeviceGroup] {
+	s := p.client.DeviceGroups
+	return blueprintKind[DeviceGroup]{
+		resource: "DeviceGroup",
+		name:     func(r *DeviceGroup) string { return r.Name },
+		refs:     deviceGroupRefs,
+		find: func(r *DeviceGroup) ([]DeviceGroup, error) {
+			id := refID(r.ApplicationId)
+			return s.Iterate(&GetDeviceGroupOptions{Name: &r.Name, ApplicationID: &id}).All()
+		},
+		scope:  func(r *DeviceGroup) Reference { return r.ApplicationId },
+		create: func(r DeviceGroup) (*DeviceGroup, error) { return apiResult(s.Create(r)) },
+		update: func(r DeviceGroup) (*DeviceGroup, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) deviceTypes() blueprintKind[DeviceType] {
+	s := p.client.DeviceTypes
+	return blueprintKind[DeviceType]{
+		resource: "DeviceType",
+		name:     func(r *DeviceType) string { return r.Name },
+		refs:     deviceTypeRefs,
+		find: func(r *DeviceType) ([]DeviceType, error) {
+			id := refID(r.DeviceGroupId)
+			return s.Iterate(&GetDeviceTypeOptions{Name: &r.Name, DeviceGroupID: &id}).All()
+		},
+		scope:  func(r *DeviceType) Reference { return r.DeviceGroupId },
+		create: func(r DeviceType) (*DeviceType, error) { return apiResult(s.Create(r)) },
+		update: func(r DeviceType) (*DeviceType, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) dataTypes() blueprintKind[DataType] {
+	s := p.client.DataTypes
+	return blueprintKind[DataType]{
+		resource: "DataType",
+		name:     func(r *DataType) string { return r.Name },
+		refs:     dataTypeRefs,
+		find: func(r *DataType) ([]DataType, error) {
+			id := refID(r.PropositionId)
+			return s.Iterate(&GetDataTypeOptions{Name: &r.Name, PropositionID: &id}).All()
+		},
+		scope:  func(r *DataType) Reference { return r.PropositionId },
+		create: func(r DataType) (*DataType, error) { return apiResult(s.Create(r)) },
+		update: func(r DataType) (*DataType, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) buckets() blueprintKind[Bucket] {
+	s := p.client.Buckets
+	return blueprintKind[Bucket]{
+		resource: "Bucket",
+		name:     func(r *Bucket) string { return r.Name },
+		refs:     bucketRefs,
+		find: func(r *Bucket) ([]Bucket, error) {
+			id := refID(r.PropositionID)
+			return s.Iterate(&GetBucketOptions{Name: &r.Name, PropositionID: &id}).All()
+		},
+		scope:  func(r *Bucket) Reference { return r.PropositionID },
+		create: func(r Bucket) (*Bucket, error) { return apiResult(s.Create(r)) },
+		update: func(r Bucket) (*Bucket, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) blobDataContracts() blueprintKind[BlobDataContract] {
+	s := p.client.BlobDataContracts
+	return blueprintKind[BlobDataContract]{
+		resource: "BlobDataContract",
+		name:     func(r *BlobDataContract) string { return r.Name },
+		refs:     blobDataContractRefs,
+		find: func(r *BlobDataContract) ([]BlobDataContract, error) {
+			id := refID(r.DataTypeID)
+			return s.Iterate(&GetBlobDataContractOptions{Name: &r.Name, DataTypeID: &id}).All()
+		},
+		scope:  func(r *BlobDataContract) Reference { return r.DataTypeID },
+		create: func(r BlobDataContract) (*BlobDataContract, error) { return apiResult(s.Create(r)) },
+		update: func(r BlobDataContract) (*BlobDataContract, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) dataSubscribers() blueprintKind[DataSubscriber] {
+	s := p.client.DataSubscribers
+	return blueprintKind[DataSubscriber]{
+		resource: "DataSubscriber",
+		name:     func(r *DataSubscriber) string { return r.Name },
+		refs:     dataSubscriberRefs,
+		find: func(r *DataSubscriber) ([]DataSubscriber, error) {
+			return s.Iterate(&GetDataSubscriberOptions{Name: &r.Name}).All()
+		},
+		create: func(r DataSubscriber) (*DataSubscriber, error) { return apiResult(s.Create(r)) },
+		update: func(r DataSubscriber) (*DataSubscriber, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) dataBrokerSubscriptions() blueprintKind[DataBrokerSubscription] {
+	s := p.client.DataBrokerSubscriptions
+	return blueprintKind[DataBrokerSubscription]{
+		resource: "DataBrokerSubscription",
+		name:     func(r *DataBrokerSubscription) string { return r.Name },
+		refs:     dataBrokerSubscriptionRefs,
+		find: func(r *DataBrokerSubscription) ([]DataBrokerSubscription, error) {
+			return s.Iterate(&GetDataBrokerSubscriptionOptions{Name: &r.Name}).All()
+		},
+		scope:  func(r *DataBrokerSubscription) Reference { return r.DataTypeID },
+		create: func(r DataBrokerSubscription) (*DataBrokerSubscription, error) { return apiResult(s.Create(r)) },
+		update: func(r DataBrokerSubscription) (*DataBrokerSubscription, error) { return apiResult(s.Update(r)) },
+	}
+}
+
+func (p *provisioner) blobSubscriptions() blueprintKind[BlobSubscription] {
+	s := p.client.BlobSubscriptions
+	return blueprintKind[BlobSubscription]{
+		resource: "BlobSubscription",
+		name:     func(r *BlobSubscription) string { return r.Name },
+		refs:     blobSubscriptionRefs,
+		find: func(r *BlobSubscription) ([]BlobSubscription, error) {
+			id := refID(r.DataTypeId)
+			return s.Iterate(&GetBlobSubscriptionOptions{Name: &r.Name, DataTypeID: &id}).All()
+		},
+		scope:  func(r *BlobSubscription) Reference { return r.DataTypeId },
+		create: func(r BlobSubscription) (*BlobSubscription, error) { return apiResult(s.Create(r)) },
+		update: func(r BlobSubscription) (*BlobSubscription, error) { return apiResult(s.Update(r)) },
+	}
+}
@@ -0,0 +1,311 @@
+package mdm
+
+import (
+	"fmt"
+)
+
+// ExportBlueprint returns the blueprint of an existing proposition: its applications
+// with their OAuth clients, service references, device groups and device types, the
+// standard services these refer to, its data types with their blob data contracts and
+// subscriptions, its buckets, its data broker subscriptions with the data subscribers
+// they refer to, and the authentication methods referred to. References are replaced
+// by references by name, IDs, metadata and identifiers generated by MDM are removed.
+// Passwords and client secrets are not exported and have to be filled in before
+// provisioning the blueprint
+func (c *Client) ExportBlueprint(propositionID string) (*Blueprint, error) {
+	proposition, _, err := c.Propositions.GetPropositionByID(propositionID)
+	if err != nil {
+		return nil, fmt.Errorf("export proposition: %w", err)
+	}
+	e := &exporter{client: c, names: make(map[string]map[string]string)}
+	bp := &Blueprint{Proposition: *proposition}
+	e.add("Proposition", proposition.ID, proposition.Name)
+	if proposition.AuthenticationMethodID != nil {
+		e.authenticationMethod(*proposition.AuthenticationMethodID)
+	}
+
+	applications, err := c.Applications.Iterate(&GetApplicationsOptions{PropositionID: &propositionID}).All()
+	if err != nil {
+		return nil, fmt.Errorf("export applications: %w", err)
+	}
+	for _, application := range applications {
+		applicationID := application.ID
+		bp.Applications = append(bp.Applications, application)
+		e.add("Application", application.ID, application.Name)
+
+		clients, err := c.OAuthClients.Iterate(&GetOAuthClientsOptions{ApplicationID: &applicationID}).All()
+		if err != nil {
+			return nil, fmt.Errorf("export OAuth clients: %w", err)
+		}
+		for _, client := range clients {
+			bp.OAuthClients = append(bp.OAuthClients, BlueprintOAuthClient{OAuthClient: client})
+		}
+		references, err := c.ServiceReferences.Iterate(&GetServiceReferenceOptions{ApplicationID: &applicationID}).All()
+		if err != nil {
+			return nil, fmt.Errorf("export service references: %w", err)
+		}
+		for _, reference := range references {
+			bp.ServiceReferences = append(bp.ServiceReferences, reference)
+			if err := e.standardService(bp, reference.StandardServiceID); err != nil {
+				return nil, err
+			}
+		}
+		groups, err := c.DeviceGroups.Iterate(&GetDeviceGroupOptions{ApplicationID: &applicationID}).All()
+		if err != nil {
+			return nil, fmt.Errorf("export device groups: %w", err)
+		}
+		for _, group := range groups {
+			groupID := group.ID
+			bp.DeviceGroups = append(bp.DeviceGroups, group)
+			e.add("DeviceGroup", group.ID, group.Name)
+			types, err := c.DeviceTypes.Iterate(&GetDeviceTypeOptions{DeviceGroupID: &groupID}).All()
+			if err != nil {
+				return nil, fmt.Errorf("export device types: %w", err)
+			}
+			bp.DeviceTypes = append(bp.DeviceTypes, types...)
+		}
+	}
+
+	dataTypes, err := c.DataTypes.Iterate(&GetDataTypeOptions{PropositionID: &propositionID}).All()
+	if err != nil {
+		return nil, fmt.Errorf("export data types: %w", err)
+	}
+	for _, dataType := range dataTypes {
+		dataTypeID := dataType.ID
+		bp.DataTypes = append(bp.DataTypes, dataType)
+		e.add("DataType", dataType.ID, dataType.Name)
+		contracts, err := c.BlobDataContracts.Iterate(&GetBlobDataContractOptions{DataTypeID: &dataTypeID}).All()
+		if err != nil {
+			return nil, fmt.Errorf("export blob data contracts: %w", err)
+		}
+		bp.BlobDataContracts = append(bp.BlobDataContracts, contracts...)
+		subscriptions, err := c.BlobSubscriptions.Iterate(&GetBlobSubscriptionOptions{DataTypeID: &dataTypeID}).All()
+		if err != nil {
+			return nil, fmt.Errorf("export blob subscriptions: %w", err)
+		}
+		bp.BlobSubscriptions = append(bp.BlobSubscriptions, subscriptions...)
+	}
+	buckets, err := c.Buckets.Iterate(&GetBucketOptions{PropositionID: &propositionID}).All()
+	if err != nil {
+		return nil, fmt.Errorf("export buckets: %w", err)
+	}
+	for _, bucket := range buckets {
+		bp.Buckets = append(bp.Buckets, bucket)
+		e.add("Bucket", bucket.ID, bucket.Name)
+	}
+	subscriptions, err := c.DataBrokerSubscriptions.Iterate(&GetDataBrokerSubscriptionOptions{PropositionID: &propositionID}).All()
+	if err != nil {
+		return nil, fmt.Errorf("export data broker subscriptions: %w", err)
+	}
+	for _, subscription := range subscriptions {
+		bp.DataBrokerSubscriptions = append(bp.DataBrokerSubscriptions, subscription)
+		e.authenticationMethod(subscription.AuthenticationMethodId)
+		if err := e.dataSubscriber(bp, subscription.DataSubscriberId); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range e.authenticationMethods {
+		method, _, err := c.AuthenticationMethods.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("export authentication method %s: %w", id, err)
+		}
+		method.Password, method.ClientSecret = "", ""
+		bp.AuthenticationMethods = append(bp.AuthenticationMethods, *method)
+		e.add("AuthenticationMethod", method.ID, method.Name)
+	}
+	if err := e.symbolize(bp); err != nil {
+		return nil, err
+	}
+	bp.strip()
+	return bp, nil
+}
+
+type exporter struct {
+	client *Client
+	// names holds the names of resources by kind and ID
+	names                 map[string]map[string]string
+	authenticationMethods []string
+	lookups               map[string]lookup
+}
+
+func (e *exporter) add(kind, id, name string) {
+	if e.names[kind] == nil {
+		e.names[kind] = make(map[string]string)
+	}
+	e.names[kind][id] = name
+}
+
+func (e *exporter) authenticationMethod(reference Reference) {
+	id := refID(reference)
+	for _, known := range e.authenticationMethods {
+		if known == id {
+			return
+		}
+	}
+	e.authenticationMethods = append(e.authenticationMethods, id)
+}
+
+func (e *exporter) standardService(bp *Blueprint, reference Reference) error {
+	id := refID(reference)
+	if _, ok := e.names["StandardService"][id]; ok {
+		return nil
+	}
+	service, _, err := e.client.StandardServices.GetStandardServiceByID(id)
+	if err != nil {
+		return fmt.Errorf("export standard service %s: %w", id, err)
+	}
+	bp.StandardServices = append(bp.StandardServices, *service)
+	e.add("StandardService", service.ID, service.Name)
+	for _, u := range service.ServiceUrls {
+		if u.AuthenticationMethodID != nil {
+			e.authenticationMethod(*u.AuthenticationMethodID)
+		}
+	}
+	return nil
+}
+
+func (e *exporter) dataSubscriber(bp *Blueprint, reference Reference) error {
+	id := refID(reference)
+	if _, ok := e.names["DataSubscriber"][id]; ok {
+		return nil
+	}
+	subscriber, _, err := e.client.DataSubscribers.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("export data subscriber %s: %w", id, err)
+	}
+	bp.DataSubscribers = append(bp.DataSubscribers, *subscriber)
+	e.add("DataSubscriber", subscriber.ID, subscriber.Name)
+	return nil
+}
+
+// symbolize replaces references by ID with references by name
+func (e *exporter) symbolize(bp *Blueprint) error {
+	var refs []blueprintRef
+	refs = append(refs, propositionRefs(&bp.Proposition)...)
+	for i := range bp.Applications {
+		refs = append(refs, applicationRefs(&bp.Applications[i])...)
+	}
+	for i := range bp.OAuthClients {
+		refs = append(refs, oauthClientRefs(&bp.OAuthClients[i])...)
+	}
+	for i := range bp.StandardServices {
+		refs = append(refs, standardServiceRefs(&bp.StandardServices[i])...)
+	}
+	for i := range bp.ServiceReferences {
+		refs = append(refs, serviceReferenceRefs(&bp.ServiceReferences[i])...)
+	}
+	for i := range bp.DeviceGroups {
+		refs = append(refs, deviceGroupRefs(&bp.DeviceGroups[i])...)
+	}
+	for i := range bp.DeviceTypes {
+		refs = append(refs, deviceTypeRefs(&bp.DeviceTypes[i])...)
+	}
+	for i := range bp.DataTypes {
+		refs = append(refs, dataTypeRefs(&bp.DataTypes[i])...)
+	}
+	for i := range bp.Buckets {
+		refs = append(refs, bucketRefs(&bp.Buckets[i])...)
+	}
+	for i := range bp.BlobDataContracts {
+		refs = append(refs, blobDataContractRefs(&bp.BlobDataContracts[i])...)
+	}
+	for i := range bp.DataSubscribers {
+		refs = append(refs, dataSubscriberRefs(&bp.DataSubscribers[i])...)
+	}
+	for i := range bp.DataBrokerSubscriptions {
+		refs = append(refs, dataBrokerSubscriptionRefs(&bp.DataBrokerSubscriptions[i])...)
+	}
+	for i := range bp.BlobSubscriptions {
+		refs = append(refs, blobSubscriptionRefs(&bp.BlobSubscriptions[i])...)
+	}
+	for _, r := range refs {
+		if *r.value == "" {
+			continue
+		}
+		name, err := e.name(r.kind, r.id())
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		if r.bare {
+			*r.value = "@" + name
+		} else {
+			*r.value = r.kind + "/@" + name
+		}
+	}
+	return nil
+}
+
+// name returns the name of a resource, or an empty string when the reference to it is
+// kept by ID because it is neither in the blueprint nor of a type that is only referred to
+func (e *exporter) name(kind, id string) (string, error) {
+	if name, ok := e.names[kind][id]; ok {
+		return name, nil
+	}
+	switch kind {
+	case "ServiceAction", "SubscriberType", "DataAdapter", "ServiceAgent", "StorageClass", "Region":
+	default:
+		return "", nil
+	}
+	if e.lookups == nil {
+		e.lookups = e.client.lookups()
+	}
+	name, err := e.lookups[kind].byID(id)
+	if err != nil {
+		return "", fmt.Errorf("export %s %s: %w", kind, id, err)
+	}
+	e.add(kind, id, name)
+	return name, nil
+}
+
+// strip removes IDs, metadata and identifiers generated by MDM
+func (b *Blueprint) strip() {
+	b.Proposition.ID, b.Proposition.Meta, b.Proposition.PropositionGuid = "", nil, nil
+	for i := range b.AuthenticationMethods {
+		b.AuthenticationMethods[i].ID, b.AuthenticationMethods[i].Meta = "", nil
+	}
+	for i := range b.Applications {
+		r := &b.Applications[i]
+		r.ID, r.Meta, r.ApplicationGuid, r.DefaultGroupGuid = "", nil, nil, nil
+	}
+	for i := range b.OAuthClients {
+		r := &b.OAuthClients[i]
+		r.ID, r.Meta = "", nil
+		r.ClientGuid, r.ClientID, r.ClientSecret, r.ClientRevoked = nil, "", "", false
+		r.BootstrapClientGuid, r.BootstrapClientID, r.BootstrapClientSecret, r.BootstrapClientRevoked = nil, "", "", false
+	}
+	for i := range b.StandardServices {
+		b.StandardServices[i].ID, b.StandardServices[i].Meta = "", nil
+	}
+	for i := range b.ServiceReferences {
+		b.ServiceReferences[i].ID, b.ServiceReferences[i].Meta = "", nil
+	}
+	for i := range b.DeviceGroups {
+		b.DeviceGroups[i].ID, b.DeviceGroups[i].DefaultGroupGuid = "", nil
+	}
+	for i := range b.DeviceTypes {
+		r := &b.DeviceTypes[i]
+		r.ID, r.Meta, r.DefaultGroupGuid = "", nil, nil
+	}
+	for i := range b.DataTypes {
+		b.DataTypes[i].ID, b.DataTypes[i].Meta = "", nil
+	}
+	for i := range b.Buckets {
+		b.Buckets[i].ID, b.Buckets[i].Meta = "", nil
+	}
+	for i := range b.BlobDataContracts {
+		b.BlobDataContracts[i].ID, b.BlobDataContracts[i].Meta = "", nil
+	}
+	for i := range b.DataSubscribers {
+		b.DataSubscribers[i].ID, b.DataSubscribers[i].Meta = "", nil
+	}
+	for i := range b.DataBrokerSubscriptions {
+		b.DataBrokerSubscriptions[i].ID, b.DataBrokerSubscriptions[i].Meta = "", nil
+	}
+	for i := range b.BlobSubscriptions {
+		b.BlobSubscriptions[i].ID, b.BlobSubscriptions[i].Meta = "", nil
+	}
+}
@@ -0,0 +1,202 @@
+package mdm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/connect/mdm"
+	"github.com/philips-software/go-hsdp-api/internal/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBlueprint() mdm.Blueprint {
+	replicationRegion := mdm.Reference{Reference: "Region/@eu-west"}
+	return mdm.Blueprint{
+		AuthenticationMethods: []mdm.AuthenticationMethod{{
+			Name: "broker-auth", LoginName: "login", Password: "password", ClientID: "client", ClientSecret: "secret",
+		}},
+		Proposition: mdm.Proposition{
+			Name:                   "fleet",
+			OrganizationGuid:       mdm.Identifier{Value: "org-1"},
+			GlobalReferenceID:      "fleet-ref",
+			AuthenticationMethodID: &mdm.Reference{Reference: "AuthenticationMethod/@broker-auth"},
+		},
+		Applications: []mdm.Application{{
+			Name: "fleet-app", PropositionID: mdm.Reference{Reference: "Proposition/@fleet"}, GlobalReferenceID: "app-ref",
+		}},
+		OAuthClients: []mdm.BlueprintOAuthClient{{
+			OAuthClient: mdm.OAuthClient{Name: "device-client", ApplicationId: mdm.Reference{Reference: "Application/@fleet-app"},
+				GlobalReferenceID: "client-ref"},
+			Scopes: []string{"mail"},
+		}},
+		StandardServices: []mdm.StandardService{{
+			Name: "telemetry", Tags: []string{"telemetry"},
+			ServiceUrls: []mdm.ServiceURL{{URL: "https://telemetry.example.com", SortOrder: 1,
+				AuthenticationMethodID: &mdm.Reference{Reference: "AuthenticationMethod/@broker-auth"}}},
+		}},
+		ServiceReferences: []mdm.ServiceReference{{
+			Name: "telemetry-ref", ApplicationID: mdm.Reference{Reference: "Application/@fleet-app"},
+			StandardServiceID: mdm.Reference{Reference: "StandardService/@telemetry"},
+			ServiceActionIDs:  []mdm.Reference{{Reference: "ServiceAction/@read"}},
+		}},
+		DeviceGroups: []mdm.DeviceGroup{{
+			Name: "sensors", ApplicationId: mdm.Reference{Reference: "Application/@fleet-app"},
+		}},
+		DeviceTypes: []mdm.DeviceType{{
+			Name: "thermometer", CTN: "TH-1", DeviceGroupId: mdm.Reference{Reference: "DeviceGroup/@sensors"},
+		}},
+		DataTypes: []mdm.DataType{{
+			Name: "readings", PropositionId: mdm.Reference{Reference: "Proposition/@fleet"},
+		}},
+		Buckets: []mdm.Bucket{{
+			Name: "readings-bucket", PropositionID: mdm.Reference{Reference: "Proposition/@fleet"},
+			DefaultRegionID: mdm.Reference{Reference: "Region/@us-east"}, ReplicationRegionID: &replicationRegion,
+		}},
+		BlobDataContracts: []mdm.BlobDataContract{{
+			Name: "readings-contract", DataTypeID: mdm.Reference{Reference: "DataType/@readings"},
+			BucketID:         mdm.Reference{Reference: "Bucket/@readings-bucket"},
+			StorageClassID:   mdm.Reference{Reference: "StorageClass/@standard"},
+			RootPathInBucket: "/readings",
+		}},
+		DataSubscribers: []mdm.DataSubscriber{{
+			Name: "subscriber", SubscriberGuid: mdm.Identifier{Value: "subscriber-guid"},
+			SubscriberTypeId: mdm.Reference{Reference: "SubscriberType/@webhook"},
+		}},
+		DataBrokerSubscriptions: []mdm.DataBrokerSubscription{{
+			Name: "readings-sub", ServiceAgentId: "@agent",
+			DataSubscriberId:       mdm.Reference{Reference: "DataSubscriber/@subscriber"},
+			DataAdapterId:          mdm.Reference{Reference: "DataAdapter/@adapter"},
+			AuthenticationMethodId: mdm.Reference{Reference: "AuthenticationMethod/@broker-auth"},
+			DataTypeID:             mdm.Reference{Reference: "DataType/@readings"},
+		}},
+		BlobSubscriptions: []mdm.BlobSubscription{{
+			Name: "readings-blobs", DataTypeId: mdm.Reference{Reference: "DataType/@readings"},
+			NotificationTopicGuid: mdm.Identifier{Value: "topic-1"},
+		}},
+	}
+}
+
+func TestBlueprintProvision(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	// Searches return one resource per page, so finding all of them takes paging
+	store := storetest.New("/connect/mdm")
+	store.PageSize = 1
+	store.Created = func(resourceType string, resource storetest.Resource) {
+		resource["meta"] = map[string]interface{}{"versionId": "1"}
+		if resourceType == "OAuthClient" {
+			resource["clientGuid"] = map[string]interface{}{"value": "guid-" + resource["id"].(string)}
+		}
+	}
+	scopes := map[string][]string{}
+	store.Actions = map[string]storetest.Action{
+		"$scopes": func(w http.ResponseWriter, r *http.Request, id string) {
+			var body struct {
+				Scopes []string `json:"scopes"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			scopes[id] = body.Scopes
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+	muxMDM.Handle("/connect/mdm/", store)
+	named := func(name string) map[string]string { return map[string]string{"name": name} }
+	store.Add("ServiceAction", named("read"))
+	store.Add("Region", named("us-east"))
+	store.Add("Region", named("eu-west"))
+	store.Add("StorageClass", named("standard"))
+	webhookID := store.Add("SubscriberType", named("webhook"))
+	store.Add("DataAdapter", named("adapter"))
+	agentID := store.Add("ServiceAgent", named("agent"))
+	blueprint := testBlueprint()
+
+	report, err := mdmClient.Provision(blueprint, mdm.ProvisionOptions{DryRun: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, report.Changes, 14)
+	assert.Equal(t, "@sensors", report.IDs["DeviceGroup"]["sensors"])
+	assert.Equal(t, 0, store.Count("Proposition"))
+
+	report, err = mdmClient.Provision(blueprint, mdm.ProvisionOptions{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	var resources []string
+	for _, change := range report.Changes {
+		assert.Equal(t, mdm.BlueprintCreate, change.Action)
+		resources = append(resources, change.Resource)
+	}
+	assert.Equal(t, []string{"AuthenticationMethod", "Proposition", "Application", "OAuthClient", "StandardService",
+		"ServiceReference", "DeviceGroup", "DeviceType", "DataType", "Bucket", "BlobDataContract",
+		"DataSubscriber", "DataBrokerSubscription", "BlobSubscription"}, resources)
+	deviceType, _, err := mdmClient.DeviceTypes.GetByID(report.IDs["DeviceType"]["thermometer"])
+	if assert.Nil(t, err) {
+		assert.Equal(t, "DeviceGroup/"+report.IDs["DeviceGroup"]["sensors"], deviceType.DeviceGroupId.Reference)
+	}
+	subscription, _, err := mdmClient.DataBrokerSubscriptions.GetByID(report.IDs["DataBrokerSubscription"]["readings-sub"])
+	if assert.Nil(t, err) {
+		assert.Equal(t, agentID, subscription.ServiceAgentId)
+		assert.Equal(t, "DataSubscriber/"+report.IDs["DataSubscriber"]["subscriber"], subscription.DataSubscriberId.Reference)
+	}
+	subscriber, _, err := mdmClient.DataSubscribers.GetByID(report.IDs["DataSubscriber"]["subscriber"])
+	if assert.Nil(t, err) {
+		assert.Equal(t, "SubscriberType/"+webhookID, subscriber.SubscriberTypeId.Reference)
+	}
+	assert.Equal(t, []string{"mail"}, scopes[report.IDs["OAuthClient"]["device-client"]])
+
+	// Provisioning again changes nothing
+	report, err = mdmClient.Provision(blueprint, mdm.ProvisionOptions{})
+	if assert.Nil(t, err) {
+		assert.Len(t, report.Changes, 0)
+	}
+	blueprint.Applications[0].Description = "Fleet application"
+	report, err = mdmClient.Provision(blueprint, mdm.ProvisionOptions{})
+	if assert.Nil(t, err) && assert.Len(t, report.Changes, 1) {
+		assert.Equal(t, mdm.BlueprintUpdate, report.Changes[0].Action)
+		assert.Equal(t, []string{"description"}, report.Changes[0].Fields)
+	}
+	assert.Equal(t, 1, store.Count("Application"))
+	// Both data types are only found by paging
+	blueprint.DataTypes = append(blueprint.DataTypes, mdm.DataType{
+		Name: "events", PropositionId: mdm.Reference{Reference: "Proposition/@fleet"},
+	})
+	report, err = mdmClient.Provision(blueprint, mdm.ProvisionOptions{})
+	if assert.Nil(t, err) && assert.Len(t, report.Changes, 1) {
+		assert.Equal(t, mdm.BlueprintCreate, report.Changes[0].Action)
+	}
+
+	// The exported blueprint refers by name and provisions to the same resources
+	exported, err := mdmClient.ExportBlueprint(report.IDs["Proposition"]["fleet"])
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "", exported.Proposition.ID)
+	assert.Equal(t, "AuthenticationMethod/@broker-auth", exported.Proposition.AuthenticationMethodID.Reference)
+	assert.Equal(t, "DeviceGroup/@sensors", exported.DeviceTypes[0].DeviceGroupId.Reference)
+	assert.Equal(t, "@agent", exported.DataBrokerSubscriptions[0].ServiceAgentId)
+	assert.Equal(t, "Region/@eu-west", exported.Buckets[0].ReplicationRegionID.Reference)
+	assert.Equal(t, "", exported.AuthenticationMethods[0].Password)
+	assert.Nil(t, exported.OAuthClients[0].ClientGuid)
+	assert.Len(t, exported.StandardServices, 1)
+	assert.Len(t, exported.DataTypes, 2)
+	assert.Len(t, exported.BlobSubscriptions, 1)
+	if assert.Len(t, exported.DataSubscribers, 1) {
+		assert.Equal(t, "", exported.DataSubscribers[0].ID)
+		assert.Equal(t, "SubscriberType/@webhook", exported.DataSubscribers[0].SubscriberTypeId.Reference)
+	}
+	assert.Equal(t, "DataSubscriber/@subscriber", exported.DataBrokerSubscriptions[0].DataSubscriberId.Reference)
+	report, err = mdmClient.Provision(*exported, mdm.ProvisionOptions{})
+	if assert.Nil(t, err) {
+		assert.Len(t, report.Changes, 0)
+	}
+
+	blueprint.DeviceTypes[0].DeviceGroupId.Reference = "DeviceGroup/@unknown"
+	_, err = mdmClient.Provision(blueprint, mdm.ProvisionOptions{})
+	assert.ErrorIs(t, err, mdm.ErrUnresolvedReference)
+	blueprint.DeviceTypes = append(blueprint.DeviceTypes, blueprint.DeviceTypes[0])
+	_, err = mdmClient.Provision(blueprint, mdm.ProvisionOptions{})
+	assert.ErrorIs(t, err, mdm.ErrInvalidBlueprint)
+}
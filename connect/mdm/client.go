@@ -3,6 +3,7 @@ package mdm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -25,6 +26,14 @@ const (
 // OptionFunc is the function signature function for options
 type OptionFunc func(*http.Request) error
 
+// WithContext runs the request with the provided context
+func WithContext(ctx context.Context) OptionFunc {
+	return func(req *http.Request) error {
+		*req = *req.WithContext(ctx)
+		return nil
+	}
+}
+
 // Config contains the configuration of a Client
 type Config struct {
 	Region      string
@@ -95,31 +104,31 @@ func NewClient(iamClient *iam.Client, config *Config) (*Client, error) {
 	if baseIAM := c.BaseIAMURL(); baseIAM != nil {
 		c.systemIAM = baseIAM.String()
 	}
-	c.Propositions = &PropositionsService{Client: c, validate: validator.New()}
-	c.Applications = &ApplicationsService{Client: c, validate: validator.New()}
-	c.Regions = &RegionsService{Client: c}
-	c.StorageClasses = &StorageClassService{Client: c}
-	c.OAuthClientScopes = &OAuthClientScopesService{Client: c}
-	c.OAuthClients = &OAuthClientsService{Client: c, validate: validator.New()}
-	c.StandardServices = &StandardServicesService{Client: c, validate: validator.New()}
-	c.ServiceActions = &ServiceActionsService{Client: c, validate: validator.New()}
-	c.DeviceGroups = &DeviceGroupsService{Client: c, validate: validator.New()}
-	c.DeviceTypes = &DeviceTypesService{Client: c, validate: validator.New()}
-	c.AuthenticationMethods = &AuthenticationMethodsService{Client: c, validate: validator.New()}
-	c.ServiceReferences = &ServiceReferencesService{Client: c, validate: validator.New()}
-	c.Buckets = &BucketsService{Client: c, validate: validator.New()}
-	c.DataTypes = &DataTypesService{Client: c, validate: validator.New()}
-	c.BlobDataContracts = &BlobDataContractsService{Client: c, validate: validator.New()}
-	c.DataBrokerSubscriptions = &DataBrokerSubscriptionsService{Client: c, validate: validator.New()}
-	c.BlobSubscriptions = &BlobSubscriptionsService{Client: c, validate: validator.New()}
-	c.FirmwareComponents = &FirmwareComponentsService{Client: c, validate: validator.New()}
-	c.FirmwareComponentVersions = &FirmwareComponentVersionsService{Client: c, validate: validator.New()}
+	c.Propositions = &PropositionsService{Client: c, resourceService: newResourceService[Proposition, GetPropositionsOptions](c, "Proposition", propositionAPIVersion)}
+	c.Applications = &ApplicationsService{Client: c, resourceService: newResourceService[Application, GetApplicationsOptions](c, "Application", applicationAPIVersion)}
+	c.Regions = &RegionsService{Client: c, readOnlyService: newReadOnlyService[Region, GetRegionOptions](c, "Region", regionAPIVersion)}
+	c.StorageClasses = &StorageClassService{Client: c, readOnlyService: newReadOnlyService[StorageClass, GetStorageClassOptions](c, "StorageClass", storageClassAPIVersion)}
+	c.OAuthClientScopes = &OAuthClientScopesService{Client: c, readOnlyService: newReadOnlyService[OAuthClientScope, GetOAuthClientScopeOptions](c, "OAuthClientScope", oauthClientScopeAPIVersion)}
+	c.OAuthClients = &OAuthClientsService{Client: c, resourceService: newResourceService[OAuthClient, GetOAuthClientsOptions](c, "OAuthClient", clientAPIVersion)}
+	c.StandardServices = &StandardServicesService{Client: c, resourceService: newResourceService[StandardService, GetStandardServiceOptions](c, "StandardService", standardServiceAPIVersion)}
+	c.ServiceActions = &ServiceActionsService{Client: c, resourceService: newResourceService[ServiceAction, GetServiceActionOptions](c, "ServiceAction", serviceActionAPIVersion).readAfterCreate()}
+	c.DeviceGroups = &DeviceGroupsService{Client: c, resourceService: newResourceService[DeviceGroup, GetDeviceGroupOptions](c, "DeviceGroup", deviceGroupAPIVersion)}
+	c.DeviceTypes = &DeviceTypesService{Client: c, resourceService: newResourceService[DeviceType, GetDeviceTypeOptions](c, "DeviceType", deviceTypeAPIVersion)}
+	c.AuthenticationMethods = &AuthenticationMethodsService{Client: c, resourceService: newResourceService[AuthenticationMethod, GetAuthenticationMethodOptions](c, "AuthenticationMethod", authenticationMethodAPIVersion)}
+	c.ServiceReferences = &ServiceReferencesService{Client: c, resourceService: newResourceService[ServiceReference, GetServiceReferenceOptions](c, "ServiceReference", serviceReferenceAPIVersion).readAfterCreate()}
+	c.Buckets = &BucketsService{Client: c, resourceService: newResourceService[Bucket, GetBucketOptions](c, "Bucket", bucketAPIVersion)}
+	c.DataTypes = &DataTypesService{Client: c, resourceService: newResourceService[DataType, GetDataTypeOptions](c, "DataType", dataTypesAPIVersion)}
+	c.BlobDataContracts = &BlobDataContractsService{Client: c, resourceService: newResourceService[BlobDataContract, GetBlobDataContractOptions](c, "BlobDataContract", blobDataContractPIVersion)}
+	c.DataBrokerSubscriptions = &DataBrokerSubscriptionsService{Client: c, resourceService: newResourceService[DataBrokerSubscription, GetDataBrokerSubscriptionOptions](c, "DataBrokerSubscription", dataBrokerSubscriptionAPIVersion).readAfterCreate()}
+	c.BlobSubscriptions = &BlobSubscriptionsService{Client: c, resourceService: newResourceService[BlobSubscription, GetBlobSubscriptionOptions](c, "BlobSubscription", blobSubscriptionPIVersion)}
+	c.FirmwareComponents = &FirmwareComponentsService{Client: c, resourceService: newResourceService[FirmwareComponent, GetFirmwareComponentOptions](c, "FirmwareComponent", firmwareComponentAPIVersion).readAfterCreate()}
+	c.FirmwareComponentVersions = &FirmwareComponentVersionsService{Client: c, resourceService: newResourceService[FirmwareComponentVersion, GetFirmwareComponentVersionOptions](c, "FirmwareComponentVersion", firmwareComponentVersionAPIVersion).readAfterCreate()}
 	c.ResourcesLimits = &ResourceLimitsService{Client: c}
-	c.SubscriberTypes = &SubscriberTypesService{Client: c}
-	c.DataAdapters = &DataAdaptersService{Client: c}
-	c.DataSubscribers = &DataSubscribersService{Client: c}
-	c.FirmwareDistributionRequests = &FirmwareDistributionRequestsService{Client: c, validate: validator.New()}
-	c.ServiceAgents = &ServiceAgentsService{Client: c}
+	c.SubscriberTypes = &SubscriberTypesService{Client: c, readOnlyService: newReadOnlyService[SubscriberType, GetSubscriberTypeOptions](c, "SubscriberType", subscriberTypeAPIVersion)}
+	c.DataAdapters = &DataAdaptersService{Client: c, readOnlyService: newReadOnlyService[DataAdapter, GetDataAdapterOptions](c, "DataAdapter", dataAdapterAPIVersion)}
+	c.DataSubscribers = &DataSubscribersService{Client: c, resourceService: newResourceService[DataSubscriber, GetDataSubscriberOptions](c, "DataSubscriber", dataSubscriberAPIVersion)}
+	c.FirmwareDistributionRequests = &FirmwareDistributionRequestsService{Client: c, resourceService: newResourceService[FirmwareDistributionRequest, GetFirmwareDistributionRequestOptions](c, "FirmwareDistributionRequest", firmwareDistributionRequestAPIVersion).readAfterCreate()}
+	c.ServiceAgents = &ServiceAgentsService{Client: c, readOnlyService: newReadOnlyService[ServiceAgent, GetServiceAgentOptions](c, "ServiceAgent", serviceAgentAPIVersion)}
 
 	return c, nil
 }
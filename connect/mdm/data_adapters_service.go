@@ -1,15 +1,15 @@
 package mdm
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
+import "fmt"
 
-	"github.com/philips-software/go-hsdp-api/internal"
+var (
+	dataAdapterAPIVersion = "1"
 )
 
+// DataAdaptersService provides read access to the DataAdapter resources managed by HSDP
 type DataAdaptersService struct {
 	*Client
+	readOnlyService[DataAdapter, GetDataAdapterOptions]
 }
 
 type DataAdapter struct {
@@ -31,33 +31,15 @@ type GetDataAdapterOptions struct {
 	SubscriberTypeFGUID *string `url:"subscriberGuid,omitempty"`
 }
 
+// Get returns the first page of DataAdapter resources matching opt
 func (r *DataAdaptersService) Get(opt *GetDataAdapterOptions) (*[]DataAdapter, *Response, error) {
-	req, err := r.NewRequest(http.MethodGet, "/DataAdapter", opt)
-	if err != nil {
-		return nil, nil, err
-	}
-	var bundleResponse internal.Bundle
-
-	resp, err := r.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	if err := internal.CheckResponse(resp.Response); err != nil {
-		return nil, resp, err
-	}
-	var resources []DataAdapter
-	for _, s := range bundleResponse.Entry {
-		var resource DataAdapter
-		if err := json.Unmarshal(s.Resource, &resource); err == nil {
-			resources = append(resources, resource)
-		}
-	}
-	return &resources, resp, nil
+	return r.Find(opt)
 }
 
+// GetByID searches for the DataAdapter with the ID
 func (r *DataAdaptersService) GetByID(id string) (*DataAdapter, *Response, error) {
 	if len(id) == 0 {
-		return nil, nil, fmt.Errorf("GetByID: missing id")
+		return nil, nil, fmt.Errorf("GetByID: %w", ErrMissingID)
 	}
 	resources, resp, err := r.Get(&GetDataAdapterOptions{
 		ID: &id,
@@ -3,23 +3,26 @@ package mdm
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
+)
 
-	"github.com/philips-software/go-hsdp-api/internal"
+var (
+	dataSubscriberAPIVersion = "1"
 )
 
 type DataSubscribersService struct {
 	*Client
+	*resourceService[DataSubscriber, GetDataSubscriberOptions]
 }
 
 type DataSubscriber struct {
 	ResourceType     string          `json:"resourceType"`
-	ID               string          `json:"id"`
-	Name             string          `json:"name"`
+	ID               string          `json:"id,omitempty"`
+	Name             string          `json:"name" validate:"required"`
+	Description      string          `json:"description,omitempty"`
 	SubscriberGuid   Identifier      `json:"subscriberGuid"`
 	SubscriberTypeId Reference       `json:"subscriberTypeId"`
-	Configuration    json.RawMessage `json:"configuration"`
-	Meta             *Meta           `json:"meta"`
+	Configuration    json.RawMessage `json:"configuration,omitempty"`
+	Meta             *Meta           `json:"meta,omitempty"`
 }
 
 type GetDataSubscriberOptions struct {
@@ -32,33 +35,15 @@ type GetDataSubscriberOptions struct {
 	SubscriberTypeFGUID *string `url:"subscriberGuid,omitempty"`
 }
 
+// Get returns the first page of data subscribers matching opt
 func (r *DataSubscribersService) Get(opt *GetDataSubscriberOptions) (*[]DataSubscriber, *Response, error) {
-	req, err := r.NewRequest(http.MethodGet, "/DataSubscriber", opt)
-	if err != nil {
-		return nil, nil, err
-	}
-	var bundleResponse internal.Bundle
-
-	resp, err := r.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	if err := internal.CheckResponse(resp.Response); err != nil {
-		return nil, resp, err
-	}
-	var resources []DataSubscriber
-	for _, s := range bundleResponse.Entry {
-		var resource DataSubscriber
-		if err := json.Unmarshal(s.Resource, &resource); err == nil {
-			resources = append(resources, resource)
-		}
-	}
-	return &resources, resp, nil
+	return r.Find(opt)
 }
 
+// GetByID searches for the data subscriber with the ID
 func (r *DataSubscribersService) GetByID(id string) (*DataSubscriber, *Response, error) {
 	if len(id) == 0 {
-		return nil, nil, fmt.Errorf("GetByID: missing id")
+		return nil, nil, fmt.Errorf("GetByID: %w", ErrMissingID)
 	}
 	resources, resp, err := r.Get(&GetDataSubscriberOptions{
 		ID: &id,
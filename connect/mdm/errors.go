@@ -10,4 +10,12 @@ var (
 	ErrEmptyResult                    = errors.New("empty result")
 	ErrOperationFailed                = errors.New("operation failed")
 	ErrCouldNoReadResourceAfterCreate = errors.New("could not read resource after create")
+	ErrFirmwareVersionExists          = errors.New("firmware component version already exists")
+	ErrInvalidVersionChain            = errors.New("invalid firmware component version chain")
+	ErrInvalidEncryptionKey           = errors.New("invalid encryption key")
+	ErrInvalidBlueprint               = errors.New("invalid blueprint")
+	ErrUnresolvedReference            = errors.New("unresolved reference")
+	ErrConflict                       = errors.New("resource conflict")
+	ErrPreconditionFailed             = errors.New("precondition failed")
+	ErrMissingID                      = errors.New("missing resource ID")
 )
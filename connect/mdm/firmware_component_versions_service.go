@@ -1,17 +1,10 @@
 package mdm
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-
-	"github.com/go-playground/validator/v10"
-	"github.com/philips-software/go-hsdp-api/internal"
-)
+import "encoding/json"
 
 type FirmwareComponentVersionsService struct {
 	*Client
-	validate *validator.Validate
+	*resourceService[FirmwareComponentVersion, GetFirmwareComponentVersionOptions]
 }
 
 var (
@@ -49,120 +42,9 @@ type FirmwareComponentVersion struct {
 
 // GetFirmwareComponentVersionOptions struct describes search criteria for looking up FirmwareComponentVersion
 type GetFirmwareComponentVersionOptions struct {
-	ID            *string `url:"_id,omitempty"`
-	Name          *string `url:"name,omitempty"`
-	ApplicationID *string `url:"applicationId,omitempty"`
-}
-
-// Create creates a FirmwareComponentVersion
-func (c *FirmwareComponentVersionsService) Create(ac FirmwareComponentVersion) (*FirmwareComponentVersion, *Response, error) {
-	ac.ResourceType = "FirmwareComponentVersion"
-	if err := c.validate.Struct(ac); err != nil {
-		return nil, nil, err
-	}
-
-	req, _ := c.NewRequest(http.MethodPost, "/FirmwareComponentVersion", ac, nil)
-	req.Header.Set("api-version", firmwareComponentVersionAPIVersion)
-
-	var created FirmwareComponentVersion
-
-	resp, err := c.Do(req, &created)
-
-	ok := resp != nil && (resp.StatusCode() == http.StatusOK || resp.StatusCode() == http.StatusCreated)
-	if !ok {
-		return nil, resp, err
-	}
-	if resp == nil {
-		return nil, resp, fmt.Errorf("create (resp=nil): %w", ErrCouldNoReadResourceAfterCreate)
-	}
-
-	return c.GetByID(created.ID)
-}
-
-// Delete deletes the given FirmwareComponentVersion
-func (c *FirmwareComponentVersionsService) Delete(ac FirmwareComponentVersion) (bool, *Response, error) {
-	req, err := c.NewRequest(http.MethodDelete, "/FirmwareComponentVersion/"+ac.ID, nil, nil)
-	if err != nil {
-		return false, nil, err
-	}
-	req.Header.Set("api-version", firmwareComponentVersionAPIVersion)
-
-	var deleteResponse interface{}
-
-	resp, err := c.Do(req, &deleteResponse)
-	if resp == nil || resp.StatusCode() != http.StatusNoContent {
-		return false, resp, err
-	}
-	return true, resp, nil
-}
-
-// GetByID finds a client by its ID
-func (c *FirmwareComponentVersionsService) GetByID(id string) (*FirmwareComponentVersion, *Response, error) {
-	if len(id) == 0 {
-		return nil, nil, fmt.Errorf("GetById: missing id")
-	}
-	req, err := c.NewRequest(http.MethodGet, "/FirmwareComponentVersion/"+id, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", firmwareComponentVersionAPIVersion)
-	req.Header.Set("Content-Type", "application/json")
-
-	var resource FirmwareComponentVersion
-
-	resp, err := c.Do(req, &resource)
-	if err != nil {
-		return nil, resp, err
-	}
-	err = internal.CheckResponse(resp.Response)
-	if err != nil {
-		return nil, resp, fmt.Errorf("GetByID: %w", err)
-	}
-	return &resource, resp, nil
-}
-
-// Find looks up services based on GetFirmwareComponentVersionOptions
-func (c *FirmwareComponentVersionsService) Find(opt *GetFirmwareComponentVersionOptions, options ...OptionFunc) (*[]FirmwareComponentVersion, *Response, error) {
-	req, err := c.NewRequest(http.MethodGet, "/FirmwareComponentVersion", opt, options...)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", firmwareComponentVersionAPIVersion)
-	req.Header.Set("Content-Type", "application/json")
-
-	var bundleResponse internal.Bundle
-
-	resp, err := c.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	var resources []FirmwareComponentVersion
-	for _, c := range bundleResponse.Entry {
-		var resource FirmwareComponentVersion
-		if err := json.Unmarshal(c.Resource, &resource); err == nil {
-			resources = append(resources, resource)
-		}
-	}
-	return &resources, resp, err
-}
-
-// Update updates a standard service
-func (c *FirmwareComponentVersionsService) Update(ac FirmwareComponentVersion) (*FirmwareComponentVersion, *Response, error) {
-	ac.ResourceType = "FirmwareComponentVersion"
-	if err := c.validate.Struct(ac); err != nil {
-		return nil, nil, err
-	}
-	req, err := c.NewRequest(http.MethodPut, "/FirmwareComponentVersion/"+ac.ID, ac, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", firmwareComponentVersionAPIVersion)
-
-	var updated FirmwareComponentVersion
-
-	resp, err := c.Do(req, &updated)
-	if err != nil {
-		return nil, resp, err
-	}
-	return &updated, resp, nil
+	ID                  *string `url:"_id,omitempty"`
+	Name                *string `url:"name,omitempty"`
+	ApplicationID       *string `url:"applicationId,omitempty"`
+	FirmwareComponentID *string `url:"firmwareComponentId,omitempty"`
+	Version             *string `url:"version,omitempty"`
 }
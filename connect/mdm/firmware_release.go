@@ -0,0 +1,377 @@
+package mdm
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/philips-software/go-hsdp-api/connect/blr"
+)
+
+const (
+	// FingerprintAlgorithm is the algorithm of the fingerprints computed by FirmwareRelease
+	FingerprintAlgorithm = "SHA-256"
+	// EncryptionAlgorithm is the algorithm FirmwareRelease encrypts firmware with. The
+	// encrypted data is the 12 byte nonce followed by the sealed firmware
+	EncryptionAlgorithm = "AES-256-GCM"
+
+	// maxDistributionTargets is the maximum number of targets of a FirmwareDistributionRequest
+	maxDistributionTargets = 10
+)
+
+// FirmwareManifest describes a firmware release
+type FirmwareManifest struct {
+	// FirmwareComponentID is the ID of the FirmwareComponent the firmware is a version of
+	FirmwareComponentID string `json:"firmwareComponentId" validate:"required"`
+	Version             string `json:"version" validate:"required,min=1,max=20"`
+	// PreviousVersion is the version the release follows. When empty the release follows
+	// the latest version of the component, if any
+	PreviousVersion   string `json:"previousVersion,omitempty"`
+	Description       string `json:"description,omitempty" validate:"omitempty,max=250"`
+	ComponentRequired bool   `json:"componentRequired"`
+	EffectiveDate     string `json:"effectiveDate" validate:"required"`
+	DeprecatedDate    string `json:"deprecatedDate,omitempty"`
+
+	Blob         FirmwareBlob          `json:"blob"`
+	Encryption   *FirmwareEncryption   `json:"encryption,omitempty"`
+	Distribution *FirmwareDistribution `json:"distribution,omitempty"`
+}
+
+// FirmwareBlob describes the BLR blob the firmware is stored in
+type FirmwareBlob struct {
+	DataType             string    `json:"dataType" validate:"required"`
+	BlobPath             string    `json:"blobPath,omitempty"`
+	BlobName             string    `json:"blobName,omitempty"`
+	ManagingOrganization string    `json:"managingOrganization,omitempty"`
+	PropositionGuid      string    `json:"propositionGuid,omitempty"`
+	Tags                 []blr.Tag `json:"tags,omitempty" validate:"omitempty,max=10"`
+}
+
+// FirmwareEncryption enables encryption of the firmware before it is uploaded
+type FirmwareEncryption struct {
+	// Key is the base64 encoded 32 byte key. A random key is used when empty
+	Key string `json:"key,omitempty"`
+}
+
+// FirmwareDistribution describes the distribution of a release to device groups
+type FirmwareDistribution struct {
+	// DeviceGroups are the IDs of the device groups to distribute to. A request is
+	// created for every 10 device groups
+	DeviceGroups        []string `json:"deviceGroups" validate:"required,min=1"`
+	Status              string   `json:"status" validate:"required"`
+	OrchestrationMode   string   `json:"orchestrationMode" validate:"omitempty,oneof=none continuous snapshot"`
+	UserConsentRequired bool     `json:"userConsentRequired"`
+	Description         string   `json:"description,omitempty" validate:"omitempty,max=250"`
+}
+
+// FirmwareReleaseResult holds the resources created by a release
+type FirmwareReleaseResult struct {
+	Blob                 *blr.Blob
+	ComponentVersion     *FirmwareComponentVersion
+	DistributionRequests []FirmwareDistributionRequest
+}
+
+// FirmwareRelease uploads firmware to BLR and registers it in MDM: it creates the
+// blob, uploads the optionally encrypted firmware, creates the FirmwareComponentVersion
+// with its fingerprint, blob URL and encryption info, chained to the previous version,
+// and creates FirmwareDistributionRequests for the device groups. When a step fails
+// the resources created by the earlier steps are deleted again
+type FirmwareRelease struct {
+	MDM *Client
+	BLR *blr.Client
+	// Transfer configures the upload of the firmware
+	Transfer *blr.TransferOptions
+
+	validate *validator.Validate
+}
+
+// NewFirmwareRelease returns a FirmwareRelease using the MDM and BLR clients
+func NewFirmwareRelease(mdmClient *Client, blrClient *blr.Client) *FirmwareRelease {
+	return &FirmwareRelease{
+		MDM:      mdmClient,
+		BLR:      blrClient,
+		validate: validator.New(),
+	}
+}
+
+// Run releases size bytes of firmware read from r as described by the manifest. The
+// manifest and the version chain are validated before any resource is created. The
+// fingerprint and size of the version are those of the uploaded data, so devices can
+// verify a download before decrypting it. Encryption reads the firmware into memory
+func (f *FirmwareRelease) Run(ctx context.Context, manifest FirmwareManifest, r io.ReaderAt, size int64) (*FirmwareReleaseResult, error) {
+	if f.validate == nil {
+		f.validate = validator.New()
+	}
+	if err := f.validate.Struct(manifest); err != nil {
+		return nil, err
+	}
+	if _, _, err := f.MDM.FirmwareComponents.GetByID(manifest.FirmwareComponentID, WithContext(ctx)); err != nil {
+		return nil, fmt.Errorf("release: firmware component: %w", err)
+	}
+	previous, err := f.previousVersion(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var encryptionInfo *EncryptionInfo
+	if manifest.Encryption != nil {
+		var key []byte
+		key, encryptionInfo, err = encryptionKey(manifest.Encryption)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := encrypt(key, r, size)
+		if err != nil {
+			return nil, err
+		}
+		r, size = bytes.NewReader(encrypted), int64(len(encrypted))
+	}
+	fingerprint, err := fingerprint(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FirmwareReleaseResult{}
+	var rollback []func(ctx context.Context) error
+	fail := func(err error) (*FirmwareReleaseResult, error) {
+		// Rollback also has to complete when ctx is cancelled
+		ctx := context.Background()
+		var errs []error
+		for i := len(rollback) - 1; i >= 0; i-- {
+			if rollbackErr := rollback[i](ctx); rollbackErr != nil {
+				errs = append(errs, rollbackErr)
+			}
+		}
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("%w (rollback: %v)", err, errors.Join(errs...))
+		}
+		return nil, err
+	}
+
+	parts := f.Transfer.PartCount(size)
+	blob := blr.Blob{
+		ResourceType:         "Blob",
+		DataType:             manifest.Blob.DataType,
+		BlobPath:             manifest.Blob.BlobPath,
+		BlobName:             manifest.Blob.BlobName,
+		ManagingOrganization: manifest.Blob.ManagingOrganization,
+		PropositionGuid:      manifest.Blob.PropositionGuid,
+		MultipartEnabled:     parts > 1,
+	}
+	if len(manifest.Blob.Tags) > 0 {
+		tags := manifest.Blob.Tags
+		blob.Tags = &tags
+	}
+	if parts > 1 {
+		blob.NoOfParts = &parts
+	}
+	created, _, err := f.BLR.Blobs.Create(blob, blr.WithContext(ctx))
+	if err == nil && created == nil {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("release: create blob: %w", err)
+	}
+	rollback = append(rollback, func(ctx context.Context) error {
+		return deleted("blob "+created.ID, func() (bool, error) {
+			ok, _, err := f.BLR.Blobs.Delete(*created, blr.WithContext(ctx))
+			return ok, err
+		})
+	})
+	if result.Blob, err = f.BLR.Blobs.Upload(ctx, *created, r, size, f.Transfer); err != nil {
+		return fail(fmt.Errorf("release: %w", err))
+	}
+
+	version := FirmwareComponentVersion{
+		Version:             manifest.Version,
+		Description:         manifest.Description,
+		Size:                int(size),
+		BlobURL:             f.BLR.GetBaseURL() + "Blob/" + result.Blob.ID,
+		ComponentRequired:   manifest.ComponentRequired,
+		FingerPrint:         fingerprint,
+		EncryptionInfo:      encryptionInfo,
+		FirmwareComponentId: Reference{Reference: "FirmwareComponent/" + manifest.FirmwareComponentID},
+		EffectiveDate:       manifest.EffectiveDate,
+		DeprecatedDate:      manifest.DeprecatedDate,
+	}
+	if encryptionInfo == nil {
+		version.EncryptionInfo = &EncryptionInfo{Encrypted: false}
+	}
+	if previous != nil {
+		version.PreviousComponentVersionId = &Reference{Reference: "FirmwareComponentVersion/" + previous.ID}
+	}
+	if result.ComponentVersion, _, err = f.MDM.FirmwareComponentVersions.Create(version, WithContext(ctx)); err == nil && result.ComponentVersion == nil {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return fail(fmt.Errorf("release: create firmware component version: %w", err))
+	}
+	componentVersion := *result.ComponentVersion
+	rollback = append(rollback, func(ctx context.Context) error {
+		return deleted("firmware component version "+componentVersion.ID, func() (bool, error) {
+			ok, _, err := f.MDM.FirmwareComponentVersions.Delete(componentVersion, WithContext(ctx))
+			return ok, err
+		})
+	})
+
+	if manifest.Distribution == nil {
+		return result, nil
+	}
+	distribution := manifest.Distribution
+	mode := distribution.OrchestrationMode
+	if mode == "" {
+		mode = "none"
+	}
+	groups := distribution.DeviceGroups
+	for len(groups) > 0 {
+		n := len(groups)
+		if n > maxDistributionTargets {
+			n = maxDistributionTargets
+		}
+		request := FirmwareDistributionRequest{
+			Status:                    distribution.Status,
+			UserConsentRequired:       distribution.UserConsentRequired,
+			FirmwareVersion:           manifest.Version,
+			OrchestrationMode:         mode,
+			FirmwareComponentVersions: []Reference{{Reference: "FirmwareComponentVersion/" + componentVersion.ID}},
+			Description:               distribution.Description,
+		}
+		for _, group := range groups[:n] {
+			request.DistributionTargets = append(request.DistributionTargets, Reference{Reference: "DeviceGroup/" + group})
+		}
+		groups = groups[n:]
+		created, _, err := f.MDM.FirmwareDistributionRequests.Create(request, WithContext(ctx))
+		if err == nil && created == nil {
+			err = ErrOperationFailed
+		}
+		if err != nil {
+			return fail(fmt.Errorf("release: create firmware distribution request: %w", err))
+		}
+		result.DistributionRequests = append(result.DistributionRequests, *created)
+		rollback = append(rollback, func(ctx context.Context) error {
+			return deleted("firmware distribution request "+created.ID, func() (bool, error) {
+				ok, _, err := f.MDM.FirmwareDistributionRequests.Delete(*created, WithContext(ctx))
+				return ok, err
+			})
+		})
+	}
+	return result, nil
+}
+
+// previousVersion validates the version chain of the component and returns the
+// version the release follows, or nil for the first version. Only the latest
+// version, which no other version follows, can be followed
+func (f *FirmwareRelease) previousVersion(ctx context.Context, manifest FirmwareManifest) (*FirmwareComponentVersion, error) {
+	componentID := manifest.FirmwareComponentID
+	versions, err := f.MDM.FirmwareComponentVersions.Iterate(&GetFirmwareComponentVersionOptions{
+		FirmwareComponentID: &componentID,
+	}, WithContext(ctx)).All()
+	if err != nil {
+		return nil, fmt.Errorf("release: find firmware component versions: %w", err)
+	}
+	followed := make(map[string]string)
+	for _, v := range versions {
+		if v.Version == manifest.Version {
+			return nil, fmt.Errorf("release: version %q: %w", v.Version, ErrFirmwareVersionExists)
+		}
+		if v.PreviousComponentVersionId != nil {
+			followed[path.Base(v.PreviousComponentVersionId.Reference)] = v.Version
+		}
+	}
+	var latest []*FirmwareComponentVersion
+	for i := range versions {
+		v := &versions[i]
+		if manifest.PreviousVersion != "" && v.Version == manifest.PreviousVersion {
+			if next, ok := followed[v.ID]; ok {
+				return nil, fmt.Errorf("release: version %q is already followed by %q: %w", v.Version, next, ErrInvalidVersionChain)
+			}
+			return v, nil
+		}
+		if _, ok := followed[v.ID]; !ok {
+			latest = append(latest, v)
+		}
+	}
+	switch {
+	case manifest.PreviousVersion != "":
+		return nil, fmt.Errorf("release: previous version %q not found: %w", manifest.PreviousVersion, ErrInvalidVersionChain)
+	case len(versions) == 0:
+		return nil, nil
+	case len(latest) == 1:
+		return latest[0], nil
+	}
+	return nil, fmt.Errorf("release: %d versions without a successor: %w", len(latest), ErrInvalidVersionChain)
+}
+
+// encryptionKey returns the key to encrypt with and the matching EncryptionInfo
+func encryptionKey(encryption *FirmwareEncryption) ([]byte, *EncryptionInfo, error) {
+	key := make([]byte, 32)
+	if encryption.Key == "" {
+		if _, err := rand.Read(key); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var err error
+		if key, err = base64.StdEncoding.DecodeString(encryption.Key); err != nil || len(key) != 32 {
+			return nil, nil, fmt.Errorf("release: %w: expected 32 base64 encoded bytes", ErrInvalidEncryptionKey)
+		}
+	}
+	return key, &EncryptionInfo{
+		Encrypted:     true,
+		Algorithm:     EncryptionAlgorithm,
+		DecryptionKey: base64.StdEncoding.EncodeToString(key),
+	}, nil
+}
+
+func encrypt(key []byte, r io.ReaderAt, size int64) ([]byte, error) {
+	plain := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(r, 0, size), plain); err != nil {
+		return nil, fmt.Errorf("release: read firmware: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func fingerprint(r io.ReaderAt, size int64) (*Fingerprint, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, io.NewSectionReader(r, 0, size))
+	if err == nil && n != size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("release: read firmware: %w", err)
+	}
+	return &Fingerprint{Algorithm: FingerprintAlgorithm, Hash: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// deleted runs a delete and reports a failure to delete as an error
+func deleted(what string, fn func() (bool, error)) error {
+	ok, err := fn()
+	if err == nil && !ok {
+		err = ErrOperationFailed
+	}
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", what, err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package mdm_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/connect/blr"
+	"github.com/philips-software/go-hsdp-api/connect/mdm"
+	"github.com/philips-software/go-hsdp-api/internal/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveFirmware serves the MDM firmware resources and the BLR blobs from memory,
+// the version searches one version per page. Creating distribution requests fails
+// once failRequests of them exist. The returned function returns the object uploaded
+// to the pre-signed URL
+func serveFirmware(failRequests int) (*storetest.Store, *storetest.Store, func() []byte) {
+	mdmStore := storetest.New("/connect/mdm")
+	mdmStore.PageSize = 1
+	mdmStore.Add("FirmwareComponent", mdm.FirmwareComponent{ResourceType: "FirmwareComponent", ID: "component-1", Name: "bootloader"})
+	muxMDM.Handle("/connect/mdm/", mdmStore)
+	muxMDM.HandleFunc("/connect/mdm/FirmwareDistributionRequest", func(w http.ResponseWriter, r *http.Request) {
+		if failRequests > 0 && mdmStore.Count("FirmwareDistributionRequest") >= failRequests {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		mdmStore.ServeHTTP(w, r)
+	})
+
+	blobStore := storetest.New("/connect/blobrepository")
+	blobStore.Actions = map[string]storetest.Action{
+		"$getAccessUrl": func(w http.ResponseWriter, r *http.Request, id string) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(blr.AccessURL{ResourceType: "BlobAccessUrl", URL: serverMDM.URL + "/s3/object"})
+		},
+	}
+	muxMDM.Handle("/connect/blobrepository/", blobStore)
+
+	var object []byte
+	muxMDM.HandleFunc("/s3/object", func(w http.ResponseWriter, r *http.Request) {
+		object, _ = io.ReadAll(r.Body)
+		sum := md5.Sum(object)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		w.WriteHeader(http.StatusOK)
+	})
+	return mdmStore, blobStore, func() []byte { return object }
+}
+
+func newFirmwareRelease(t *testing.T) *mdm.FirmwareRelease {
+	blrClient, err := blr.NewClient(iamClient, &blr.Config{
+		BaseURL: serverMDM.URL + "/connect/blobrepository",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create blrClient: %v", err)
+	}
+	return mdm.NewFirmwareRelease(mdmClient, blrClient)
+}
+
+func firmwareManifest(version string) mdm.FirmwareManifest {
+	var groups []string
+	for i := 0; i < 12; i++ {
+		groups = append(groups, "group-"+strconv.Itoa(i))
+	}
+	return mdm.FirmwareManifest{
+		FirmwareComponentID: "component-1",
+		Version:             version,
+		EffectiveDate:       "2024-06-01",
+		Blob:                mdm.FirmwareBlob{DataType: "firmware"},
+		Encryption:          &mdm.FirmwareEncryption{},
+		Distribution: &mdm.FirmwareDistribution{
+			DeviceGroups: groups,
+			Status:       "active",
+		},
+	}
+}
+
+func TestFirmwareRelease(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	// The version chain is only complete after following the next links
+	mdmStore, blobStore, object := serveFirmware(0)
+	component := mdm.Reference{Reference: "FirmwareComponent/component-1"}
+	mdmStore.Add("FirmwareComponentVersion", mdm.FirmwareComponentVersion{ID: "v1", Version: "1.0.0", FirmwareComponentId: component})
+	mdmStore.Add("FirmwareComponentVersion", mdm.FirmwareComponentVersion{ID: "v2", Version: "1.1.0", FirmwareComponentId: component,
+		PreviousComponentVersionId: &mdm.Reference{Reference: "FirmwareComponentVersion/v1"}})
+	release := newFirmwareRelease(t)
+	firmware := []byte(strings.Repeat("firmware image ", 100))
+
+	result, err := release.Run(context.Background(), firmwareManifest("1.2.0"), bytes.NewReader(firmware), int64(len(firmware)))
+	if !assert.Nil(t, err) {
+		return
+	}
+	version := result.ComponentVersion
+	if assert.NotNil(t, version.PreviousComponentVersionId) {
+		assert.Equal(t, "FirmwareComponentVersion/v2", version.PreviousComponentVersionId.Reference)
+	}
+	assert.Equal(t, "FirmwareComponent/component-1", version.FirmwareComponentId.Reference)
+	assert.Equal(t, serverMDM.URL+"/connect/blobrepository/Blob/"+result.Blob.ID, version.BlobURL)
+	assert.Equal(t, len(object()), version.Size)
+	sum := sha256.Sum256(object())
+	assert.Equal(t, &mdm.Fingerprint{Algorithm: "SHA-256", Hash: hex.EncodeToString(sum[:])}, version.FingerPrint)
+
+	// The uploaded firmware decrypts with the key in the encryption info
+	if assert.NotNil(t, version.EncryptionInfo) && assert.True(t, version.EncryptionInfo.Encrypted) {
+		key, _ := base64.StdEncoding.DecodeString(version.EncryptionInfo.DecryptionKey)
+		block, _ := aes.NewCipher(key)
+		gcm, _ := cipher.NewGCM(block)
+		plain, err := gcm.Open(nil, object()[:gcm.NonceSize()], object()[gcm.NonceSize():], nil)
+		assert.Nil(t, err)
+		assert.Equal(t, firmware, plain)
+	}
+
+	if assert.Len(t, result.DistributionRequests, 2) {
+		assert.Len(t, result.DistributionRequests[0].DistributionTargets, 10)
+		assert.Len(t, result.DistributionRequests[1].DistributionTargets, 2)
+		assert.Equal(t, "DeviceGroup/group-11", result.DistributionRequests[1].DistributionTargets[1].Reference)
+		assert.Equal(t, "none", result.DistributionRequests[0].OrchestrationMode)
+		assert.Equal(t, []mdm.Reference{{Reference: "FirmwareComponentVersion/" + version.ID}},
+			result.DistributionRequests[0].FirmwareComponentVersions)
+	}
+
+	// Versions must be unique and follow the latest version
+	_, err = release.Run(context.Background(), firmwareManifest("1.2.0"), bytes.NewReader(firmware), int64(len(firmware)))
+	assert.True(t, errors.Is(err, mdm.ErrFirmwareVersionExists))
+	manifest := firmwareManifest("1.3.0")
+	manifest.PreviousVersion = "1.1.0"
+	_, err = release.Run(context.Background(), manifest, bytes.NewReader(firmware), int64(len(firmware)))
+	assert.True(t, errors.Is(err, mdm.ErrInvalidVersionChain))
+	manifest.PreviousVersion = "0.9.0"
+	_, err = release.Run(context.Background(), manifest, bytes.NewReader(firmware), int64(len(firmware)))
+	assert.True(t, errors.Is(err, mdm.ErrInvalidVersionChain))
+	manifest.PreviousVersion = ""
+	manifest.Encryption.Key = "c2hvcnQ="
+	_, err = release.Run(context.Background(), manifest, bytes.NewReader(firmware), int64(len(firmware)))
+	assert.True(t, errors.Is(err, mdm.ErrInvalidEncryptionKey))
+	assert.Equal(t, 1, blobStore.Count("Blob"))
+	assert.Equal(t, 3, mdmStore.Count("FirmwareComponentVersion"))
+}
+
+func TestFirmwareReleaseRollback(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	mdmStore, blobStore, object := serveFirmware(1)
+	release := newFirmwareRelease(t)
+	firmware := []byte("firmware image")
+	manifest := firmwareManifest("1.0.0")
+	manifest.Encryption = nil
+
+	_, err := release.Run(context.Background(), manifest, bytes.NewReader(firmware), int64(len(firmware)))
+	assert.NotNil(t, err)
+	assert.Equal(t, firmware, object())
+	assert.Equal(t, 0, mdmStore.Count("FirmwareDistributionRequest"))
+	assert.Equal(t, 0, mdmStore.Count("FirmwareComponentVersion"))
+	assert.Equal(t, 0, blobStore.Count("Blob"))
+}
@@ -1,15 +1,15 @@
 package mdm
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
+import "fmt"
 
-	"github.com/philips-software/go-hsdp-api/internal"
+var (
+	oauthClientScopeAPIVersion = "1"
 )
 
+// OAuthClientScopesService provides read access to the OAuthClientScope resources managed by HSDP
 type OAuthClientScopesService struct {
 	*Client
+	readOnlyService[OAuthClientScope, GetOAuthClientScopeOptions]
 }
 
 type OAuthClientScope struct {
@@ -38,56 +38,29 @@ type GetOAuthClientScopeOptions struct {
 	Service      *string `url:"action,omitempty"`
 }
 
+// GetOAuthClientScopes returns the OAuthClientScope resources matching opt, following all result pages
 func (r *OAuthClientScopesService) GetOAuthClientScopes(opt *GetOAuthClientScopeOptions) (*[]OAuthClientScope, *Response, error) {
-	var scopes []OAuthClientScope
-	var resp *Response
-	var req *http.Request
-	var err error
-
-	requestPath := "OAuthClientScope"
-	for {
-		req, err = r.NewRequest(http.MethodGet, "/"+requestPath, opt)
-		if err != nil {
-			return nil, nil, err
-		}
-		var bundleResponse internal.Bundle
-
-		resp, err = r.Do(req, &bundleResponse)
-		if err != nil {
-			return nil, resp, err
-		}
-		if err := internal.CheckResponse(resp.Response); err != nil {
-			return nil, resp, err
-		}
-		for _, s := range bundleResponse.Entry {
-			var scope OAuthClientScope
-			err := json.Unmarshal(s.Resource, &scope)
-			if err == nil {
-				scopes = append(scopes, scope)
-			}
-		}
-		next := bundleResponse.Link.Next()
-		if next == nil { // No next page
-			break
-		}
-		requestPath = next.URL
+	it := r.Iterate(opt)
+	resources, err := it.All()
+	if err != nil {
+		return nil, it.Response(), err
 	}
-
-	return &scopes, resp, nil
+	return &resources, it.Response(), nil
 }
 
+// GetOAuthClientScopeByID searches for the OAuthClientScope with the ID
 func (r *OAuthClientScopesService) GetOAuthClientScopeByID(id string) (*OAuthClientScope, *Response, error) {
 	if len(id) == 0 {
-		return nil, nil, fmt.Errorf("GetOAuthClientScopeByID: missing id")
+		return nil, nil, fmt.Errorf("GetOAuthClientScopeByID: %w", ErrMissingID)
 	}
-	classes, resp, err := r.GetOAuthClientScopes(&GetOAuthClientScopeOptions{
+	resources, resp, err := r.GetOAuthClientScopes(&GetOAuthClientScopeOptions{
 		ID: &id,
 	})
 	if err != nil {
 		return nil, resp, err
 	}
-	if len(*classes) == 0 {
+	if len(*resources) == 0 {
 		return nil, resp, ErrEmptyResult
 	}
-	return &(*classes)[0], resp, nil
+	return &(*resources)[0], resp, nil
 }
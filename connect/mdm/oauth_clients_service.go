@@ -2,12 +2,8 @@ package mdm
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"net/http"
-
-	validator "github.com/go-playground/validator/v10"
-	"github.com/philips-software/go-hsdp-api/internal"
 )
 
 var (
@@ -42,7 +38,7 @@ type Reference struct {
 // OAuthClientsService provides operations on IAM roles resources
 type OAuthClientsService struct {
 	*Client
-	validate *validator.Validate
+	*resourceService[OAuthClient, GetOAuthClientsOptions]
 }
 
 // GetOAuthClientsOptions describes search criteria for looking up roles
@@ -55,91 +51,22 @@ type GetOAuthClientsOptions struct {
 
 // CreateOAuthClient creates a Client
 func (c *OAuthClientsService) CreateOAuthClient(ac OAuthClient) (*OAuthClient, *Response, error) {
-	ac.ResourceType = "OAuthClient"
-	if err := c.validate.Struct(ac); err != nil {
-		return nil, nil, err
-	}
-
-	req, _ := c.NewRequest(http.MethodPost, "/OAuthClient", ac, nil)
-	req.Header.Set("api-version", clientAPIVersion)
-
-	var createdClient OAuthClient
-
-	resp, err := c.Do(req, &createdClient)
-
-	ok := resp != nil && (resp.StatusCode() == http.StatusOK || resp.StatusCode() == http.StatusCreated)
-	if !ok {
-		return nil, resp, err
-	}
-	if resp == nil {
-		return nil, resp, fmt.Errorf("CreateOAuthClient (resp=nil): %w", ErrCouldNoReadResourceAfterCreate)
-	}
-
-	return &createdClient, resp, nil
+	return c.Create(ac)
 }
 
 // DeleteOAuthClient deletes the given Client
 func (c *OAuthClientsService) DeleteOAuthClient(ac OAuthClient) (bool, *Response, error) {
-	req, err := c.NewRequest(http.MethodDelete, "/OAuthClient/"+ac.ID, nil, nil)
-	if err != nil {
-		return false, nil, err
-	}
-	req.Header.Set("api-version", clientAPIVersion)
-
-	var deleteResponse interface{}
-
-	resp, err := c.Do(req, &deleteResponse)
-	if resp == nil || resp.StatusCode() != http.StatusNoContent {
-		return false, resp, err
-	}
-	return true, resp, nil
+	return c.Delete(ac)
 }
 
 // GetOAuthClientByID finds a client by its ID
 func (c *OAuthClientsService) GetOAuthClientByID(id string) (*OAuthClient, *Response, error) {
-	req, err := c.NewRequest(http.MethodGet, "/OAuthClient/"+id, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", clientAPIVersion)
-	req.Header.Set("Content-Type", "application/json")
-
-	var client OAuthClient
-
-	resp, err := c.Do(req, &client)
-	if err != nil {
-		return nil, resp, err
-	}
-	err = internal.CheckResponse(resp.Response)
-	if err != nil {
-		return nil, resp, fmt.Errorf("GetOAuthClientByID: %w", err)
-	}
-	return &client, resp, nil
+	return c.GetByID(id)
 }
 
 // GetOAuthClients looks up clients based on GetClientsOptions
 func (c *OAuthClientsService) GetOAuthClients(opt *GetOAuthClientsOptions, options ...OptionFunc) (*[]OAuthClient, *Response, error) {
-	req, err := c.NewRequest(http.MethodGet, "/OAuthClient", opt, options...)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", clientAPIVersion)
-	req.Header.Set("Content-Type", "application/json")
-
-	var bundleResponse internal.Bundle
-
-	resp, err := c.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	var clients []OAuthClient
-	for _, c := range bundleResponse.Entry {
-		var client OAuthClient
-		if err := json.Unmarshal(c.Resource, &client); err == nil {
-			clients = append(clients, client)
-		}
-	}
-	return &clients, resp, err
+	return c.Find(opt, options...)
 }
 
 // UpdateScopes updates a clients scope
@@ -189,24 +116,3 @@ func (c *OAuthClientsService) UpdateScopesByFlag(ac OAuthClient, scopes []string
 	}
 	return true, resp, nil
 }
-
-// Update updates a client
-func (c *OAuthClientsService) Update(ac OAuthClient) (*OAuthClient, *Response, error) {
-	ac.ResourceType = "OAuthClient"
-	if err := c.validate.Struct(ac); err != nil {
-		return nil, nil, err
-	}
-	req, err := c.NewRequest(http.MethodPut, "/OAuthClient/"+ac.ID, ac, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", clientAPIVersion)
-
-	var updatedClient OAuthClient
-
-	resp, err := c.Do(req, &updatedClient)
-	if err != nil {
-		return nil, resp, err
-	}
-	return &updatedClient, resp, nil
-}
@@ -1,14 +1,6 @@
 package mdm
 
-import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-
-	"github.com/go-playground/validator/v10"
-	"github.com/philips-software/go-hsdp-api/internal"
-)
+import "fmt"
 
 const (
 	propositionAPIVersion = "1"
@@ -37,8 +29,7 @@ type Proposition struct {
 // PropositionsService implements actions on IAM Proposition entities
 type PropositionsService struct {
 	*Client
-
-	validate *validator.Validate
+	*resourceService[Proposition, GetPropositionsOptions]
 }
 
 // GetPropositionsOptions specifies what search criteria
@@ -73,79 +64,15 @@ func (p *PropositionsService) GetProposition(opt *GetPropositionsOptions, option
 
 // GetPropositions search for a Proposition entity based on the GetPropositions values
 func (p *PropositionsService) GetPropositions(opt *GetPropositionsOptions, options ...OptionFunc) (*[]Proposition, *Response, error) {
-	req, err := p.NewRequest(http.MethodGet, "/Proposition", opt, options...)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", propositionAPIVersion)
-	req.Header.Set("Content-Type", "application/json")
-
-	var bundleResponse internal.Bundle
-
-	resp, err := p.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	var props []Proposition
-	for _, p := range bundleResponse.Entry {
-		var prop Proposition
-		if err := json.Unmarshal(p.Resource, &prop); err == nil {
-			props = append(props, prop)
-		}
-	}
-	return &props, resp, err
+	return p.Find(opt, options...)
 }
 
 // CreateProposition creates a Proposition
 func (p *PropositionsService) CreateProposition(prop Proposition) (*Proposition, *Response, error) {
-	prop.ResourceType = "Proposition"
-	if err := p.validate.Struct(prop); err != nil {
-		return nil, nil, err
-	}
-	req, err := p.NewRequest(http.MethodPost, "/Proposition", &prop, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", "1")
-	req.Header.Set("Content-Type", "application/json")
-
-	var created Proposition
-
-	resp, err := p.Do(req, &created)
-	if err == io.EOF {
-		err = nil
-	}
-	if err != nil {
-		return nil, resp, err
-	}
-	ok := resp != nil && resp.StatusCode() == http.StatusCreated
-	if !ok {
-		return nil, resp, fmt.Errorf("CreateProposition failed: resp=%v", resp)
-	}
-	return &created, resp, nil
+	return p.Create(prop)
 }
 
-// UpdateProposition creates a Proposition
+// UpdateProposition updates a Proposition
 func (p *PropositionsService) UpdateProposition(prop Proposition) (*Proposition, *Response, error) {
-	prop.ResourceType = "Proposition"
-	if err := p.validate.Struct(prop); err != nil {
-		return nil, nil, err
-	}
-	req, err := p.NewRequest(http.MethodPut, "/Proposition/"+prop.ID, prop, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("api-version", "1")
-	req.Header.Set("Content-Type", "application/json")
-
-	var updated Proposition
-
-	resp, err := p.Do(req, &updated)
-	if err != nil {
-		return nil, resp, err
-	}
-	if err := internal.CheckResponse(resp.Response); err != nil {
-		return nil, resp, err
-	}
-	return &updated, resp, nil
+	return p.Update(prop)
 }
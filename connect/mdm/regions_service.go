@@ -1,14 +1,15 @@
 package mdm
 
-import (
-	"encoding/json"
-	"net/http"
+import "fmt"
 
-	"github.com/philips-software/go-hsdp-api/internal"
+var (
+	regionAPIVersion = "1"
 )
 
+// RegionsService provides read access to the Region resources managed by HSDP
 type RegionsService struct {
 	*Client
+	readOnlyService[Region, GetRegionOptions]
 }
 
 type Region struct {
@@ -31,39 +32,24 @@ type GetRegionOptions struct {
 	HSDPEnabled    *bool   `url:"hsdpEnabled,omitempty"`
 }
 
+// GetRegions returns the first page of Region resources matching opt
 func (r *RegionsService) GetRegions(opt *GetRegionOptions) (*[]Region, *Response, error) {
-	req, err := r.NewRequest(http.MethodGet, "/Region", opt)
-	if err != nil {
-		return nil, nil, err
-	}
-	var bundleResponse internal.Bundle
-
-	resp, err := r.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	if err := internal.CheckResponse(resp.Response); err != nil {
-		return nil, resp, err
-	}
-	var regions []Region
-	for _, s := range bundleResponse.Entry {
-		var region Region
-		if err := json.Unmarshal(s.Resource, &region); err == nil {
-			regions = append(regions, region)
-		}
-	}
-	return &regions, resp, nil
+	return r.Find(opt)
 }
 
+// GetRegionByID searches for the Region with the ID
 func (r *RegionsService) GetRegionByID(id string) (*Region, *Response, error) {
-	regions, resp, err := r.GetRegions(&GetRegionOptions{
+	if len(id) == 0 {
+		return nil, nil, fmt.Errorf("GetRegionByID: %w", ErrMissingID)
+	}
+	resources, resp, err := r.GetRegions(&GetRegionOptions{
 		ID: &id,
 	})
 	if err != nil {
 		return nil, resp, err
 	}
-	if len(*regions) == 0 {
+	if len(*resources) == 0 {
 		return nil, resp, ErrEmptyResult
 	}
-	return &(*regions)[0], resp, nil
+	return &(*resources)[0], resp, nil
 }
@@ -0,0 +1,336 @@
+package mdm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/philips-software/go-hsdp-api/internal"
+)
+
+// resourceService implements the operations shared by the MDM resource services.
+// T is the resource and O its search options. A service embeds a resourceService
+// to get Create, GetByID, Find, Iterate, Update and Delete for its resource type
+type resourceService[T any, O any] struct {
+	client       *Client
+	validate     *validator.Validate
+	resourceType string
+	apiVersion   string
+	// readBack makes Create return the resource as read after creating it
+	readBack bool
+}
+
+func newResourceService[T any, O any](c *Client, resourceType, apiVersion string) *resourceService[T, O] {
+	return &resourceService[T, O]{
+		client:       c,
+		validate:     validator.New(),
+		resourceType: resourceType,
+		apiVersion:   apiVersion,
+	}
+}
+
+// readAfterCreate makes Create read the resource back, for resources of which MDM
+// completes the representation after the create response
+func (s *resourceService[T, O]) readAfterCreate() *resourceService[T, O] {
+	s.readBack = true
+	return s
+}
+
+// ResourceError is returned when MDM rejects an operation on a resource. It matches
+// ErrNotFound, ErrConflict, ErrPreconditionFailed or ErrOperationFailed using
+// errors.Is, depending on the status code of the response
+type ResourceError struct {
+	ResourceType string
+	Operation    string
+	StatusCode   int
+	Err          error
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Operation, e.ResourceType, e.Err)
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the status code of the response corresponds with target
+func (e *ResourceError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusPreconditionFailed:
+		return target == ErrPreconditionFailed
+	}
+	return target == ErrOperationFailed
+}
+
+func (s *resourceService[T, O]) newRequest(method, path string, opt interface{}, options []OptionFunc) (*http.Request, error) {
+	req, err := s.client.NewRequest(method, path, opt, options...)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("api-version", s.apiVersion)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (s *resourceService[T, O]) do(operation string, req *http.Request, v interface{}) (*Response, error) {
+	resp, err := s.client.Do(req, v)
+	if v == nil && resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil && resp != nil && resp.StatusCode() >= http.StatusBadRequest {
+		err = &ResourceError{
+			ResourceType: s.resourceType,
+			Operation:    operation,
+			StatusCode:   resp.StatusCode(),
+			Err:          err,
+		}
+	}
+	return resp, err
+}
+
+// Create creates the resource and returns it as stored by MDM. When the response
+// has no body the resource is read from its Location. A failing read back returns
+// the resource as created
+func (s *resourceService[T, O]) Create(resource T, options ...OptionFunc) (*T, *Response, error) {
+	setResourceField(&resource, "ResourceType", s.resourceType)
+	if err := s.validate.Struct(resource); err != nil {
+		return nil, nil, err
+	}
+	req, err := s.newRequest(http.MethodPost, "/"+s.resourceType, resource, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	var created T
+	resp, err := s.do("create", req, &created)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+	id := resourceField(&created, "ID")
+	if id == "" {
+		if location := resp.Header.Get("Location"); location != "" {
+			return s.GetByID(path.Base(strings.TrimSuffix(location, "/")), options...)
+		}
+		return nil, resp, fmt.Errorf("create %s: %w", s.resourceType, ErrCouldNoReadResourceAfterCreate)
+	}
+	if !s.readBack {
+		return &created, resp, nil
+	}
+	read, readResp, err := s.GetByID(id, options...)
+	if err != nil {
+		return &created, resp, nil
+	}
+	return read, readResp, nil
+}
+
+// GetByID retrieves the resource by its ID
+func (s *resourceService[T, O]) GetByID(id string, options ...OptionFunc) (*T, *Response, error) {
+	if id == "" {
+		return nil, nil, fmt.Errorf("get %s: %w", s.resourceType, ErrMissingID)
+	}
+	req, err := s.newRequest(http.MethodGet, "/"+s.resourceType+"/"+id, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resource T
+	resp, err := s.do("get", req, &resource)
+	if err != nil {
+		return nil, resp, err
+	}
+	if resourceField(&resource, "ID") != id {
+		return nil, resp, fmt.Errorf("get %s: returned resource does not match", s.resourceType)
+	}
+	return &resource, resp, nil
+}
+
+// Find returns the first page of resources matching opt. Use Iterate to visit all of them
+func (s *resourceService[T, O]) Find(opt *O, options ...OptionFunc) (*[]T, *Response, error) {
+	resources, _, resp, err := s.find(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &resources, resp, nil
+}
+
+func (s *resourceService[T, O]) find(opt *O, options ...OptionFunc) ([]T, *internal.Bundle, *Response, error) {
+	req, err := s.newRequest(http.MethodGet, "/"+s.resourceType, opt, options)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var bundle internal.Bundle
+	resp, err := s.do("find", req, &bundle)
+	if err != nil {
+		return nil, nil, resp, err
+	}
+	resources := make([]T, 0, len(bundle.Entry))
+	for _, entry := range bundle.Entry {
+		var resource T
+		if err := json.Unmarshal(entry.Resource, &resource); err == nil {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, &bundle, resp, nil
+}
+
+// Update updates the resource. When the resource carries a version in its metadata
+// the update is conditional on it, and fails with ErrPreconditionFailed when the
+// resource was changed in the meantime
+func (s *resourceService[T, O]) Update(resource T, options ...OptionFunc) (*T, *Response, error) {
+	setResourceField(&resource, "ResourceType", s.resourceType)
+	if err := s.validate.Struct(resource); err != nil {
+		return nil, nil, err
+	}
+	id := resourceField(&resource, "ID")
+	if id == "" {
+		return nil, nil, fmt.Errorf("update %s: %w", s.resourceType, ErrMissingID)
+	}
+	req, err := s.newRequest(http.MethodPut, "/"+s.resourceType+"/"+id, resource, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version := resourceVersion(&resource); version != "" {
+		req.Header.Set("If-Match", `W/"`+version+`"`)
+	}
+	var updated T
+	resp, err := s.do("update", req, &updated)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &updated, resp, nil
+}
+
+// Delete deletes the resource. It returns true when MDM confirms the deletion
+func (s *resourceService[T, O]) Delete(resource T, options ...OptionFunc) (bool, *Response, error) {
+	id := resourceField(&resource, "ID")
+	if id == "" {
+		return false, nil, fmt.Errorf("delete %s: %w", s.resourceType, ErrMissingID)
+	}
+	req, err := s.newRequest(http.MethodDelete, "/"+s.resourceType+"/"+id, nil, options)
+	if err != nil {
+		return false, nil, err
+	}
+	resp, err := s.do("delete", req, nil)
+	if resp == nil || resp.StatusCode() != http.StatusNoContent {
+		return false, resp, err
+	}
+	return true, resp, nil
+}
+
+// Iterate returns an iterator over all resources matching opt, following the
+// next links of the result bundles
+func (s *resourceService[T, O]) Iterate(opt *O, options ...OptionFunc) *ResourceIterator[T] {
+	it := &ResourceIterator[T]{}
+	it.pager = internal.NewPager(func(options ...OptionFunc) ([]T, *internal.Bundle, error) {
+		page, bundle, resp, err := s.find(opt, options...)
+		it.resp = resp
+		return page, bundle, err
+	}, options...)
+	return it
+}
+
+// readOnlyService exposes the read operations of a resourceService, for resources
+// which are managed by HSDP and cannot be changed through the API
+type readOnlyService[T any, O any] struct {
+	resources *resourceService[T, O]
+}
+
+func newReadOnlyService[T any, O any](c *Client, resourceType, apiVersion string) readOnlyService[T, O] {
+	return readOnlyService[T, O]{resources: newResourceService[T, O](c, resourceType, apiVersion)}
+}
+
+// Find returns the first page of resources matching opt. Use Iterate to visit all of them
+func (s readOnlyService[T, O]) Find(opt *O, options ...OptionFunc) (*[]T, *Response, error) {
+	return s.resources.Find(opt, options...)
+}
+
+// Iterate returns an iterator over all resources matching opt
+func (s readOnlyService[T, O]) Iterate(opt *O, options ...OptionFunc) *ResourceIterator[T] {
+	return s.resources.Iterate(opt, options...)
+}
+
+// ResourceIterator iterates over the pages of a search
+type ResourceIterator[T any] struct {
+	pager *internal.Pager[T, OptionFunc]
+	resp  *Response
+}
+
+// Next advances to the next resource, fetching the next page when needed.
+// It returns false when the results are exhausted or an error occurred
+func (it *ResourceIterator[T]) Next() bool {
+	return it.pager.Next()
+}
+
+// Resource returns the current resource
+func (it *ResourceIterator[T]) Resource() T {
+	return it.pager.Current()
+}
+
+// Response returns the response of the last page fetched
+func (it *ResourceIterator[T]) Response() *Response {
+	return it.resp
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *ResourceIterator[T]) Err() error {
+	return it.pager.Err()
+}
+
+// All collects the remaining resources
+func (it *ResourceIterator[T]) All() ([]T, error) {
+	var resources []T
+	for it.Next() {
+		resources = append(resources, it.Resource())
+	}
+	return resources, it.Err()
+}
+
+// resourceField returns the string field of the resource with the given name
+func resourceField(resource interface{}, name string) string {
+	v := reflect.Indirect(reflect.ValueOf(resource))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
+	}
+	return ""
+}
+
+func setResourceField(resource interface{}, name, value string) {
+	v := reflect.Indirect(reflect.ValueOf(resource))
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String && f.CanSet() {
+		f.SetString(value)
+	}
+}
+
+// resourceVersion returns the version ID in the metadata of the resource
+func resourceVersion(resource interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(resource))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.FieldByName("Meta")
+	if !f.IsValid() {
+		return ""
+	}
+	if meta, ok := f.Interface().(*Meta); ok && meta != nil {
+		return meta.VersionID
+	}
+	return ""
+}
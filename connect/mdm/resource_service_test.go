@@ -0,0 +1,180 @@
+package mdm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/connect/mdm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceServiceIterate(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxMDM.HandleFunc("/connect/mdm/DataType", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "proposition-1", r.URL.Query().Get("propositionId"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("_page"))
+		var entries []map[string]interface{}
+		for i := 0; i < 2; i++ {
+			entries = append(entries, map[string]interface{}{
+				"resource": mdm.DataType{ResourceType: "DataType", ID: strconv.Itoa(page*2 + i)},
+			})
+		}
+		// Entries which cannot be decoded are skipped
+		entries = append(entries, map[string]interface{}{"resource": map[string]interface{}{"id": page}})
+		bundle := map[string]interface{}{"resourceType": "Bundle", "entry": entries}
+		if page < 2 {
+			bundle["link"] = []map[string]string{{
+				"relation": "next",
+				"url":      serverMDM.URL + "/connect/mdm/DataType?propositionId=proposition-1&_page=" + strconv.Itoa(page+1),
+			}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(bundle)
+	})
+
+	propositionID := "proposition-1"
+	all, err := mdmClient.DataTypes.Iterate(&mdm.GetDataTypeOptions{PropositionID: &propositionID}).All()
+	if !assert.Nil(t, err) {
+		return
+	}
+	var ids []string
+	for _, dataType := range all {
+		ids = append(ids, dataType.ID)
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4", "5"}, ids)
+
+	page, _, err := mdmClient.DataTypes.Find(&mdm.GetDataTypeOptions{PropositionID: &propositionID})
+	if assert.Nil(t, err) {
+		assert.Len(t, *page, 2)
+	}
+}
+
+func TestResourceServiceErrors(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxMDM.HandleFunc("/connect/mdm/Bucket/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/connect/mdm/Bucket/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/connect/mdm/Bucket/bucket-1":
+			assert.Equal(t, http.MethodPut, r.Method)
+			if r.Header.Get("If-Match") != `W/"2"` {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"resourceType":"Bucket","id":"bucket-1","meta":{"versionId":"3"}}`))
+		}
+	})
+
+	_, resp, err := mdmClient.Buckets.GetByID("missing")
+	assert.True(t, errors.Is(err, mdm.ErrNotFound))
+	var resourceErr *mdm.ResourceError
+	if assert.True(t, errors.As(err, &resourceErr)) {
+		assert.Equal(t, "Bucket", resourceErr.ResourceType)
+		assert.Equal(t, http.StatusNotFound, resourceErr.StatusCode)
+	}
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+	}
+
+	bucket := mdm.Bucket{
+		ID:              "bucket-1",
+		Name:            "bucket",
+		PropositionID:   mdm.Reference{Reference: "Proposition/proposition-1"},
+		DefaultRegionID: mdm.Reference{Reference: "Region/region-1"},
+		Meta:            &mdm.Meta{VersionID: "1"},
+	}
+	_, _, err = mdmClient.Buckets.Update(bucket)
+	assert.True(t, errors.Is(err, mdm.ErrPreconditionFailed))
+	assert.False(t, errors.Is(err, mdm.ErrNotFound))
+
+	bucket.Meta.VersionID = "2"
+	updated, _, err := mdmClient.Buckets.Update(bucket)
+	if assert.Nil(t, err) && assert.NotNil(t, updated) {
+		assert.Equal(t, "3", updated.Meta.VersionID)
+	}
+
+	_, _, err = mdmClient.Buckets.Update(mdm.Bucket{Name: "bucket"})
+	assert.NotNil(t, err)
+	ok, _, err := mdmClient.Buckets.Delete(mdm.Bucket{})
+	assert.False(t, ok)
+	assert.True(t, errors.Is(err, mdm.ErrMissingID))
+}
+
+func TestResourceServiceCreate(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxMDM.HandleFunc("/connect/mdm/ServiceAction", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"resourceType":"ServiceAction","id":"action-1","name":"reboot"}`))
+	})
+	muxMDM.HandleFunc("/connect/mdm/ServiceAction/action-1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	muxMDM.HandleFunc("/connect/mdm/DataType", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", serverMDM.URL+"/connect/mdm/DataType/type-1")
+		w.WriteHeader(http.StatusCreated)
+	})
+	muxMDM.HandleFunc("/connect/mdm/DataType/type-1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"resourceType":"DataType","id":"type-1","name":"vitals"}`))
+	})
+
+	// The read back fails, but the resource was created
+	action, resp, err := mdmClient.ServiceActions.Create(mdm.ServiceAction{Name: "reboot"})
+	if assert.Nil(t, err) && assert.NotNil(t, action) {
+		assert.Equal(t, "action-1", action.ID)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	}
+
+	dataType, _, err := mdmClient.DataTypes.Create(mdm.DataType{
+		Name:          "vitals",
+		PropositionId: mdm.Reference{Reference: "Proposition/proposition-1"},
+	})
+	if assert.Nil(t, err) && assert.NotNil(t, dataType) {
+		assert.Equal(t, "type-1", dataType.ID)
+	}
+}
+
+func TestReadOnlyServices(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxMDM.HandleFunc("/connect/mdm/OAuthClientScope", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.Header.Get("api-version"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("_page"))
+		bundle := map[string]interface{}{"resourceType": "Bundle", "entry": []map[string]interface{}{{
+			"resource": mdm.OAuthClientScope{ResourceType: "OAuthClientScope", ID: strconv.Itoa(page), Action: "read"},
+		}}}
+		if page == 0 {
+			bundle["link"] = []map[string]string{{"relation": "next", "url": serverMDM.URL + "/connect/mdm/OAuthClientScope?_page=1"}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(bundle)
+	})
+	muxMDM.HandleFunc("/connect/mdm/Region", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "region-1", r.URL.Query().Get("_id"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"resourceType":"Bundle","entry":[{"resource":{"resourceType":"Region","id":"region-1","name":"us-east"}}]}`))
+	})
+
+	scopes, resp, err := mdmClient.OAuthClientScopes.GetOAuthClientScopes(&mdm.GetOAuthClientScopeOptions{})
+	if assert.Nil(t, err) && assert.NotNil(t, resp) {
+		assert.Len(t, *scopes, 2)
+	}
+	region, _, err := mdmClient.Regions.GetRegionByID("region-1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "us-east", region.Name)
+	}
+	_, _, err = mdmClient.Regions.GetRegionByID("")
+	assert.True(t, errors.Is(err, mdm.ErrMissingID))
+}
@@ -1,15 +1,15 @@
 package mdm
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
+import "fmt"
 
-	"github.com/philips-software/go-hsdp-api/internal"
+var (
+	serviceAgentAPIVersion = "1"
 )
 
+// ServiceAgentsService provides read access to the ServiceAgent resources managed by HSDP
 type ServiceAgentsService struct {
 	*Client
+	readOnlyService[ServiceAgent, GetServiceAgentOptions]
 }
 
 type ServiceAgent struct {
@@ -33,33 +33,15 @@ type GetServiceAgentOptions struct {
 	DataSubscriberID *string `url:"dataSubscriberId,omitempty"`
 }
 
+// Get returns the first page of ServiceAgent resources matching opt
 func (r *ServiceAgentsService) Get(opt *GetServiceAgentOptions) (*[]ServiceAgent, *Response, error) {
-	req, err := r.NewRequest(http.MethodGet, "/ServiceAgent", opt)
-	if err != nil {
-		return nil, nil, err
-	}
-	var bundleResponse internal.Bundle
-
-	resp, err := r.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	if err := internal.CheckResponse(resp.Response); err != nil {
-		return nil, resp, err
-	}
-	var resources []ServiceAgent
-	for _, s := range bundleResponse.Entry {
-		var resource ServiceAgent
-		if err := json.Unmarshal(s.Resource, &resource); err == nil {
-			resources = append(resources, resource)
-		}
-	}
-	return &resources, resp, nil
+	return r.Find(opt)
 }
 
+// GetByID searches for the ServiceAgent with the ID
 func (r *ServiceAgentsService) GetByID(id string) (*ServiceAgent, *Response, error) {
 	if len(id) == 0 {
-		return nil, nil, fmt.Errorf("GetByID: missing id")
+		return nil, nil, fmt.Errorf("GetByID: %w", ErrMissingID)
 	}
 	resources, resp, err := r.Get(&GetServiceAgentOptions{
 		ID: &id,
@@ -1,14 +1,15 @@
 package mdm
 
-import (
-	"encoding/json"
-	"net/http"
+import "fmt"
 
-	"github.com/philips-software/go-hsdp-api/internal"
+var (
+	storageClassAPIVersion = "1"
 )
 
+// StorageClassService provides read access to the StorageClass resources managed by HSDP
 type StorageClassService struct {
 	*Client
+	readOnlyService[StorageClass, GetStorageClassOptions]
 }
 
 type StorageClass struct {
@@ -27,39 +28,24 @@ type GetStorageClassOptions struct {
 	Name           *string `url:"name,omitempty"`
 }
 
+// GetStorageClasses returns the first page of StorageClass resources matching opt
 func (r *StorageClassService) GetStorageClasses(opt *GetStorageClassOptions) (*[]StorageClass, *Response, error) {
-	req, err := r.NewRequest(http.MethodGet, "/StorageClass", opt)
-	if err != nil {
-		return nil, nil, err
-	}
-	var bundleResponse internal.Bundle
-
-	resp, err := r.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	if err := internal.CheckResponse(resp.Response); err != nil {
-		return nil, resp, err
-	}
-	var classes []StorageClass
-	for _, s := range bundleResponse.Entry {
-		var class StorageClass
-		if err := json.Unmarshal(s.Resource, &class); err == nil {
-			classes = append(classes, class)
-		}
-	}
-	return &classes, resp, nil
+	return r.Find(opt)
 }
 
+// GetStorageClassByID searches for the StorageClass with the ID
 func (r *StorageClassService) GetStorageClassByID(id string) (*StorageClass, *Response, error) {
-	classes, resp, err := r.GetStorageClasses(&GetStorageClassOptions{
+	if len(id) == 0 {
+		return nil, nil, fmt.Errorf("GetStorageClassByID: %w", ErrMissingID)
+	}
+	resources, resp, err := r.GetStorageClasses(&GetStorageClassOptions{
 		ID: &id,
 	})
 	if err != nil {
 		return nil, resp, err
 	}
-	if len(*classes) == 0 {
+	if len(*resources) == 0 {
 		return nil, resp, ErrEmptyResult
 	}
-	return &(*classes)[0], resp, nil
+	return &(*resources)[0], resp, nil
 }
@@ -1,15 +1,15 @@
 package mdm
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
+import "fmt"
 
-	"github.com/philips-software/go-hsdp-api/internal"
+var (
+	subscriberTypeAPIVersion = "1"
 )
 
+// SubscriberTypesService provides read access to the SubscriberType resources managed by HSDP
 type SubscriberTypesService struct {
 	*Client
+	readOnlyService[SubscriberType, GetSubscriberTypeOptions]
 }
 
 type SubscriberType struct {
@@ -30,33 +30,15 @@ type GetSubscriberTypeOptions struct {
 	Name           *string `url:"name,omitempty"`
 }
 
+// Get returns the first page of SubscriberType resources matching opt
 func (r *SubscriberTypesService) Get(opt *GetSubscriberTypeOptions) (*[]SubscriberType, *Response, error) {
-	req, err := r.NewRequest(http.MethodGet, "/SubscriberType", opt)
-	if err != nil {
-		return nil, nil, err
-	}
-	var bundleResponse internal.Bundle
-
-	resp, err := r.Do(req, &bundleResponse)
-	if err != nil {
-		return nil, resp, err
-	}
-	if err := internal.CheckResponse(resp.Response); err != nil {
-		return nil, resp, err
-	}
-	var resources []SubscriberType
-	for _, s := range bundleResponse.Entry {
-		var resource SubscriberType
-		if err := json.Unmarshal(s.Resource, &resource); err == nil {
-			resources = append(resources, resource)
-		}
-	}
-	return &resources, resp, nil
+	return r.Find(opt)
 }
 
+// GetByID searches for the SubscriberType with the ID
 func (r *SubscriberTypesService) GetByID(id string) (*SubscriberType, *Response, error) {
 	if len(id) == 0 {
-		return nil, nil, fmt.Errorf("GetByID: missing id")
+		return nil, nil, fmt.Errorf("GetByID: %w", ErrMissingID)
 	}
 	resources, resp, err := r.Get(&GetSubscriberTypeOptions{
 		ID: &id,
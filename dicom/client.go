@@ -51,6 +51,9 @@ type Client struct {
 	UserAgent string
 
 	Config *ConfigService
+	QIDO   *QIDOService
+	WADO   *WADOService
+	STOW   *STOWService
 }
 
 // NewClient returns a new HSDP DICOM API client. Configured console and IAM clients
@@ -76,6 +79,9 @@ func newClient(iamClient *iam.Client, config *Config) (*Client, error) {
 	}
 
 	c.Config = &ConfigService{client: c, ma: ma, um: um, profile: "production"}
+	c.QIDO = &QIDOService{client: c}
+	c.WADO = &WADOService{client: c}
+	c.STOW = &STOWService{client: c}
 
 	return c, nil
 }
@@ -0,0 +1,503 @@
+package dicom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// DesiredState describes the DICOM store configuration of an organization. Nil singletons
+// and empty lists are left alone, unless ApplyOptions.Prune is set for the lists
+type DesiredState struct {
+	CDRServiceAccount    *CDRServiceAccount
+	FHIRStore            *FHIRStore
+	Notification         *Notification
+	StoreService         *BrokenSCPConfig
+	QueryRetrieveService *BrokenSCPConfig
+	ImportService        *ImportService
+	// ObjectStores are identified by their Description, which must be unique
+	ObjectStores []ObjectStore
+	// Repositories are identified by their OrganizationID
+	Repositories []DesiredRepository
+	// RemoteNodes are identified by their AETitle
+	RemoteNodes []RemoteNode
+}
+
+// DesiredRepository is a repository whose active object store can refer to an object
+// store of the desired state that does not exist yet
+type DesiredRepository struct {
+	Repository
+	// ObjectStoreDescription resolves ActiveObjectStoreID to the object store with this description
+	ObjectStoreDescription string
+}
+
+// ApplyOptions controls how Apply reconciles the desired state
+type ApplyOptions struct {
+	// DryRun only reports the changes Apply would make
+	DryRun bool
+	// Prune deletes object stores, repositories and remote nodes missing from the desired state
+	Prune bool
+	// ReplaceDrifted deletes and recreates object stores, repositories and remote nodes which
+	// differ from the desired state. They cannot be updated in place so drift is only reported otherwise
+	ReplaceDrifted bool
+	QueryOptions   *QueryOptions
+}
+
+// ChangeAction is the kind of change made by Apply
+type ChangeAction string
+
+const (
+	ChangeCreate  ChangeAction = "create"
+	ChangeUpdate  ChangeAction = "update"
+	ChangeDelete  ChangeAction = "delete"
+	ChangeReplace ChangeAction = "replace"
+	// ChangeDrift reports a difference which was not reconciled
+	ChangeDrift ChangeAction = "drift"
+)
+
+// Change is a single change made, or in a dry run planned, by Apply
+type Change struct {
+	Resource string
+	Key      string
+	Action   ChangeAction
+	// Fields lists the JSON fields which differ for updates, replacements and drift
+	Fields []string
+}
+
+// ApplyReport lists the changes of an Apply
+type ApplyReport struct {
+	Changes []Change
+}
+
+// Drift returns the differences which were not reconciled
+func (r *ApplyReport) Drift() []Change {
+	var drift []Change
+	for _, change := range r.Changes {
+		if change.Action == ChangeDrift {
+			drift = append(drift, change)
+		}
+	}
+	return drift
+}
+
+func (r *ApplyReport) add(resource, key string, action ChangeAction, fields ...string) {
+	r.Changes = append(r.Changes, Change{Resource: resource, Key: key, Action: action, Fields: fields})
+}
+
+// Apply reconciles the configuration with the desired state. Singletons are set when they are
+// missing or differ, object stores, repositories and remote nodes are created and, depending
+// on opts, deleted or replaced. Resources are applied before the services referring to them.
+// The report holds the changes made up to an error
+func (c *ConfigService) Apply(desired DesiredState, opts ApplyOptions, options ...OptionFunc) (*ApplyReport, error) {
+	a := &applier{c: c, desired: desired, opts: opts, options: options, report: &ApplyReport{}}
+	for _, step := range []func() error{
+		a.cdrServiceAccount,
+		a.fhirStore,
+		a.objectStores,
+		a.repositories,
+		a.remoteNodes,
+		a.storeService,
+		a.queryRetrieveService,
+		a.importService,
+		a.notification,
+	} {
+		if err := step(); err != nil {
+			return a.report, err
+		}
+	}
+	return a.report, nil
+}
+
+type applier struct {
+	c       *ConfigService
+	desired DesiredState
+	opts    ApplyOptions
+	options []OptionFunc
+	report  *ApplyReport
+	// objectStoreIDs maps descriptions to the IDs of existing or created object stores
+	objectStoreIDs map[string]string
+}
+
+// absent reports whether a Get call failed because the resource is not configured
+func absent(resp *Response, err error) (bool, error) {
+	if err == nil {
+		return false, nil
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrEmptyResult) {
+		return true, nil
+	}
+	if resp != nil && resp.StatusCode() == http.StatusNotFound {
+		return true, nil
+	}
+	return false, err
+}
+
+// diff returns the JSON fields set in desired which differ from current, ignoring IDs.
+// Fields only present in current are populated by the server and not compared
+func diff(current, desired interface{}, ignore ...string) []string {
+	toMap := func(v interface{}) map[string]interface{} {
+		m := map[string]interface{}{}
+		data, _ := json.Marshal(v)
+		_ = json.Unmarshal(data, &m)
+		for _, field := range append(ignore, "id") {
+			delete(m, field)
+		}
+		return m
+	}
+	a, b := toMap(current), toMap(desired)
+	var fields []string
+	for key, value := range b {
+		if !reflect.DeepEqual(a[key], value) {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// singleton sets a configuration singleton when it is missing or differs
+func (a *applier) singleton(resource string, desired interface{}, get func() (interface{}, *Response, error), set func() error, ignore ...string) error {
+	current, resp, err := get()
+	missing, err := absent(resp, err)
+	if err != nil {
+		return fmt.Errorf("Apply %s: %w", resource, err)
+	}
+	action, fields := ChangeUpdate, []string(nil)
+	if missing || current == nil {
+		action = ChangeCreate
+	} else if fields = diff(current, desired, ignore...); len(fields) == 0 {
+		return nil
+	}
+	a.report.add(resource, "", action, fields...)
+	if a.opts.DryRun {
+		return nil
+	}
+	if err := set(); err != nil {
+		return fmt.Errorf("Apply %s: %w", resource, err)
+	}
+	return nil
+}
+
+func (a *applier) cdrServiceAccount() error {
+	desired := a.desired.CDRServiceAccount
+	if desired == nil {
+		return nil
+	}
+	return a.singleton("cdrServiceAccount", desired, func() (interface{}, *Response, error) {
+		current, resp, err := a.c.GetCDRServiceAccount(a.opts.QueryOptions, a.options...)
+		if current == nil || current.ServiceID == "" {
+			return nil, resp, err
+		}
+		return current, resp, err
+	}, func() error {
+		_, _, err := a.c.SetCDRServiceAccount(*desired, a.opts.QueryOptions, a.options...)
+		return err
+	}, "privateKey")
+}
+
+func (a *applier) fhirStore() error {
+	desired := a.desired.FHIRStore
+	if desired == nil {
+		return nil
+	}
+	return a.singleton("fhirStore", desired, func() (interface{}, *Response, error) {
+		current, resp, err := a.c.GetFHIRStore(a.opts.QueryOptions, a.options...)
+		if current == nil || !current.Valid() {
+			return nil, resp, err
+		}
+		return current, resp, err
+	}, func() error {
+		_, _, err := a.c.SetFHIRStore(*desired, a.opts.QueryOptions, a.options...)
+		return err
+	})
+}
+
+func (a *applier) notification() error {
+	desired := a.desired.Notification
+	if desired == nil {
+		return nil
+	}
+	return a.singleton("notification", desired, func() (interface{}, *Response, error) {
+		current, resp, err := a.c.GetNotification(a.opts.QueryOptions, a.options...)
+		if current == nil {
+			return nil, resp, err
+		}
+		return current, resp, err
+	}, func() error {
+		_, _, err := a.c.CreateNotification(*desired, a.opts.QueryOptions, a.options...)
+		return err
+	})
+}
+
+func (a *applier) storeService() error {
+	desired := a.desired.StoreService
+	if desired == nil {
+		return nil
+	}
+	return a.singleton("storeService", desired, func() (interface{}, *Response, error) {
+		current, resp, err := a.c.GetStoreService(a.opts.QueryOptions, a.options...)
+		if current == nil {
+			return nil, resp, err
+		}
+		return current, resp, err
+	}, func() error {
+		_, _, err := a.c.SetStoreService(*desired, a.opts.QueryOptions, a.options...)
+		return err
+	})
+}
+
+func (a *applier) queryRetrieveService() error {
+	desired := a.desired.QueryRetrieveService
+	if desired == nil {
+		return nil
+	}
+	return a.singleton("queryRetrieveService", desired, func() (interface{}, *Response, error) {
+		current, resp, err := a.c.GetQueryRetrieveService(a.opts.QueryOptions, a.options...)
+		if current == nil {
+			return nil, resp, err
+		}
+		return current, resp, err
+	}, func() error {
+		_, _, err := a.c.SetQueryRetrieveService(*desired, a.opts.QueryOptions, a.options...)
+		return err
+	})
+}
+
+func (a *applier) importService() error {
+	desired := a.desired.ImportService
+	if desired == nil {
+		return nil
+	}
+	return a.singleton("importService", desired, func() (interface{}, *Response, error) {
+		current, resp, err := a.c.GetImportService(a.opts.QueryOptions, a.options...)
+		if current == nil || current.AETitle == "" {
+			return nil, resp, err
+		}
+		return current, resp, err
+	}, func() error {
+		_, _, err := a.c.SetImportService(*desired, a.opts.QueryOptions, a.options...)
+		return err
+	})
+}
+
+// comparableObjectStore clears the credentials, which are not returned by the API
+func comparableObjectStore(store ObjectStore) ObjectStore {
+	store.ErrorResponse = ErrorResponse{}
+	if store.StaticAccess != nil {
+		static := *store.StaticAccess
+		static.AccessKey, static.SecretKey = "", ""
+		store.StaticAccess = &static
+	}
+	if store.CredServiceAccess != nil {
+		creds := *store.CredServiceAccess
+		creds.ServiceAccount.PrivateKey = ""
+		store.CredServiceAccess = &creds
+	}
+	return store
+}
+
+func (a *applier) objectStores() error {
+	a.objectStoreIDs = map[string]string{}
+	if len(a.desired.ObjectStores) == 0 && !a.opts.Prune {
+		return nil
+	}
+	current, _, err := a.c.GetObjectStores(a.opts.QueryOptions, a.options...)
+	if err != nil {
+		return fmt.Errorf("Apply objectStores: %w", err)
+	}
+	existing := map[string]ObjectStore{}
+	for _, store := range *current {
+		existing[store.Description] = store
+		a.objectStoreIDs[store.Description] = store.ID
+	}
+	for _, store := range a.desired.ObjectStores {
+		found, ok := existing[store.Description]
+		action := ChangeCreate
+		var fields []string
+		if ok {
+			if fields = diff(comparableObjectStore(found), comparableObjectStore(store)); len(fields) == 0 {
+				continue
+			}
+			action = ChangeDrift
+			if a.opts.ReplaceDrifted {
+				action = ChangeReplace
+			}
+		}
+		a.report.add("objectStore", store.Description, action, fields...)
+		if a.opts.DryRun || action == ChangeDrift {
+			continue
+		}
+		if action == ChangeReplace {
+			if _, _, err := a.c.DeleteObjectStore(found, a.opts.QueryOptions, a.options...); err != nil {
+				return fmt.Errorf("Apply objectStore %s: %w", store.Description, err)
+			}
+		}
+		created, _, err := a.c.CreateObjectStore(store, a.opts.QueryOptions, a.options...)
+		if err != nil {
+			return fmt.Errorf("Apply objectStore %s: %w", store.Description, err)
+		}
+		a.objectStoreIDs[store.Description] = created.ID
+	}
+	return nil
+}
+
+func (a *applier) pruneObjectStores() error {
+	current, _, err := a.c.GetObjectStores(a.opts.QueryOptions, a.options...)
+	if err != nil {
+		return fmt.Errorf("Apply objectStores: %w", err)
+	}
+	desired := map[string]bool{}
+	for _, store := range a.desired.ObjectStores {
+		desired[store.Description] = true
+	}
+	for _, store := range *current {
+		if desired[store.Description] {
+			continue
+		}
+		a.report.add("objectStore", store.Description, ChangeDelete)
+		if a.opts.DryRun {
+			continue
+		}
+		if _, _, err := a.c.DeleteObjectStore(store, a.opts.QueryOptions, a.options...); err != nil {
+			return fmt.Errorf("Apply objectStore %s: %w", store.Description, err)
+		}
+	}
+	return nil
+}
+
+// repositories reconciles the repositories and then prunes object stores, as repositories refer to them
+func (a *applier) repositories() error {
+	if len(a.desired.Repositories) > 0 || a.opts.Prune {
+		if err := a.reconcileRepositories(); err != nil {
+			return err
+		}
+	}
+	if a.opts.Prune {
+		return a.pruneObjectStores()
+	}
+	return nil
+}
+
+func (a *applier) reconcileRepositories() error {
+	current, _, err := a.c.GetRepositories(a.opts.QueryOptions, a.options...)
+	if err != nil {
+		return fmt.Errorf("Apply repositories: %w", err)
+	}
+	existing := map[string]Repository{}
+	for _, repo := range *current {
+		existing[repo.OrganizationID] = repo
+	}
+	desired := map[string]bool{}
+	for _, d := range a.desired.Repositories {
+		repo := d.Repository
+		desired[repo.OrganizationID] = true
+		if d.ObjectStoreDescription != "" {
+			id, ok := a.objectStoreIDs[d.ObjectStoreDescription]
+			if !ok && !a.opts.DryRun {
+				return fmt.Errorf("Apply repository %s: %w: object store %q", repo.OrganizationID, ErrNotFound, d.ObjectStoreDescription)
+			}
+			repo.ActiveObjectStoreID = id
+		}
+		found, ok := existing[repo.OrganizationID]
+		action := ChangeCreate
+		var fields []string
+		if ok {
+			if fields = diff(found, repo); len(fields) == 0 {
+				continue
+			}
+			action = ChangeDrift
+			if a.opts.ReplaceDrifted {
+				action = ChangeReplace
+			}
+		}
+		a.report.add("repository", repo.OrganizationID, action, fields...)
+		if a.opts.DryRun || action == ChangeDrift {
+			continue
+		}
+		if action == ChangeReplace {
+			if _, _, err := a.c.DeleteRepository(found, a.opts.QueryOptions, a.options...); err != nil {
+				return fmt.Errorf("Apply repository %s: %w", repo.OrganizationID, err)
+			}
+		}
+		if _, _, err := a.c.CreateRepository(repo, a.opts.QueryOptions, a.options...); err != nil {
+			return fmt.Errorf("Apply repository %s: %w", repo.OrganizationID, err)
+		}
+	}
+	if !a.opts.Prune {
+		return nil
+	}
+	for _, repo := range *current {
+		if desired[repo.OrganizationID] {
+			continue
+		}
+		a.report.add("repository", repo.OrganizationID, ChangeDelete)
+		if a.opts.DryRun {
+			continue
+		}
+		if _, _, err := a.c.DeleteRepository(repo, a.opts.QueryOptions, a.options...); err != nil {
+			return fmt.Errorf("Apply repository %s: %w", repo.OrganizationID, err)
+		}
+	}
+	return nil
+}
+
+func (a *applier) remoteNodes() error {
+	if len(a.desired.RemoteNodes) == 0 && !a.opts.Prune {
+		return nil
+	}
+	current, _, err := a.c.GetRemoteNodes(a.opts.QueryOptions, a.options...)
+	if err != nil {
+		return fmt.Errorf("Apply remoteNodes: %w", err)
+	}
+	existing := map[string]RemoteNode{}
+	for _, node := range *current {
+		existing[node.AETitle] = node
+	}
+	desired := map[string]bool{}
+	for _, node := range a.desired.RemoteNodes {
+		desired[node.AETitle] = true
+		found, ok := existing[node.AETitle]
+		action := ChangeCreate
+		var fields []string
+		if ok {
+			if fields = diff(found, node); len(fields) == 0 {
+				continue
+			}
+			action = ChangeDrift
+			if a.opts.ReplaceDrifted {
+				action = ChangeReplace
+			}
+		}
+		a.report.add("remoteNode", node.AETitle, action, fields...)
+		if a.opts.DryRun || action == ChangeDrift {
+			continue
+		}
+		if action == ChangeReplace {
+			if _, _, err := a.c.DeleteRemoteNode(found, a.opts.QueryOptions, a.options...); err != nil {
+				return fmt.Errorf("Apply remoteNode %s: %w", node.AETitle, err)
+			}
+		}
+		if _, _, err := a.c.CreateRemoteNode(node, a.opts.QueryOptions, a.options...); err != nil {
+			return fmt.Errorf("Apply remoteNode %s: %w", node.AETitle, err)
+		}
+	}
+	if !a.opts.Prune {
+		return nil
+	}
+	for _, node := range *current {
+		if desired[node.AETitle] {
+			continue
+		}
+		a.report.add("remoteNode", node.AETitle, ChangeDelete)
+		if a.opts.DryRun {
+			continue
+		}
+		if _, _, err := a.c.DeleteRemoteNode(node, a.opts.QueryOptions, a.options...); err != nil {
+			return fmt.Errorf("Apply remoteNode %s: %w", node.AETitle, err)
+		}
+	}
+	return nil
+}
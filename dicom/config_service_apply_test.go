@@ -0,0 +1,161 @@
+package dicom_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/dicom"
+	"github.com/philips-software/go-hsdp-api/internal/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	base := "/store/dicom/config/dicom/production/"
+	// The object stores, repositories and remote nodes are listed in full
+	store := storetest.New(base)
+	store.Results = func(items []storetest.Resource, _ string) interface{} {
+		return items
+	}
+	store.Created = func(collection string, item storetest.Resource) {
+		if _, ok := item["storeAsComposite"]; !ok && collection == "dicomRepositories" {
+			item["storeAsComposite"] = false
+		}
+	}
+	muxDICOM.Handle(base, store)
+
+	var fhirStore *dicom.FHIRStore
+	var fhirStoreSets int
+	muxDICOM.HandleFunc(base+"fhirStore", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			if fhirStore == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(fhirStore)
+		case "POST":
+			fhirStore = &dicom.FHIRStore{}
+			_ = json.NewDecoder(r.Body).Decode(fhirStore)
+			fhirStore.ID = "fhir"
+			fhirStoreSets++
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(fhirStore)
+		}
+	})
+	notifications := []dicom.Notification{}
+	muxDICOM.HandleFunc(base+"notification", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			var n dicom.Notification
+			_ = json.NewDecoder(r.Body).Decode(&n)
+			n.DefaultOrganizationID = cdrOrgID
+			notifications = []dicom.Notification{n}
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(n)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(notifications)
+	})
+
+	store.Add("objectStores", dicom.ObjectStore{ID: "stale", Description: "stale", AccessType: "direct"})
+	store.Add("remoteNodes", dicom.RemoteNode{ID: "pacs", Title: "PACS", AETitle: "PACS",
+		NetworkConnection: dicom.NetworkConnection{HostName: "pacs.example.com", Port: 104}})
+	store.Add("remoteNodes", dicom.RemoteNode{ID: "old", Title: "Old", AETitle: "OLD"})
+
+	desired := dicom.DesiredState{
+		FHIRStore:    &dicom.FHIRStore{MPIEndpoint: "https://cdr.example.com/store/fhir/org"},
+		Notification: &dicom.Notification{Enabled: true, Endpoint: "https://hook.example.com"},
+		ObjectStores: []dicom.ObjectStore{{
+			Description: "primary",
+			AccessType:  "direct",
+			StaticAccess: &dicom.StaticAccess{
+				Endpoint:   "https://s3.example.com",
+				BucketName: "bucket",
+				AccessKey:  "key",
+				SecretKey:  "secret",
+			},
+		}},
+		Repositories: []dicom.DesiredRepository{{
+			Repository:             dicom.Repository{OrganizationID: cdrOrgID},
+			ObjectStoreDescription: "primary",
+		}},
+		RemoteNodes: []dicom.RemoteNode{
+			{Title: "PACS", AETitle: "PACS", NetworkConnection: dicom.NetworkConnection{HostName: "pacs.example.com", Port: 11112}},
+			{Title: "Viewer", AETitle: "VIEWER", NetworkConnection: dicom.NetworkConnection{HostName: "viewer.example.com", Port: 104}},
+		},
+	}
+	actions := func(report *dicom.ApplyReport) []string {
+		var result []string
+		for _, change := range report.Changes {
+			result = append(result, string(change.Action)+" "+change.Resource+" "+change.Key)
+		}
+		return result
+	}
+
+	report, err := dicomClient.Config.Apply(desired, dicom.ApplyOptions{DryRun: true, Prune: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"create fhirStore ",
+		"create objectStore primary",
+		"create repository " + cdrOrgID,
+		"delete objectStore stale",
+		"drift remoteNode PACS",
+		"create remoteNode VIEWER",
+		"delete remoteNode OLD",
+		"create notification ",
+	}, actions(report))
+	assert.Equal(t, []string{"networkConnection"}, report.Drift()[0].Fields)
+	assert.Equal(t, 1, store.Count("objectStores"))
+	assert.Nil(t, fhirStore)
+
+	report, err = dicomClient.Config.Apply(desired, dicom.ApplyOptions{Prune: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, report.Changes, 8)
+	if assert.Equal(t, 1, store.Count("objectStores")) && assert.Equal(t, 1, store.Count("dicomRepositories")) {
+		var repo dicom.Repository
+		var objectStore dicom.ObjectStore
+		store.Get("dicomRepositories", store.IDs("dicomRepositories")[0], &repo)
+		store.Get("objectStores", store.IDs("objectStores")[0], &objectStore)
+		assert.Equal(t, "primary", objectStore.Description)
+		assert.Equal(t, objectStore.ID, repo.ActiveObjectStoreID)
+	}
+	assert.Equal(t, 2, store.Count("remoteNodes"))
+	assert.Equal(t, 1, fhirStoreSets)
+
+	report, err = dicomClient.Config.Apply(desired, dicom.ApplyOptions{Prune: true, ReplaceDrifted: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"replace remoteNode PACS"}, actions(report))
+	assert.Len(t, report.Drift(), 0)
+
+	report, err = dicomClient.Config.Apply(desired, dicom.ApplyOptions{Prune: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, report.Changes, 0)
+	assert.Equal(t, 1, fhirStoreSets)
+
+	// A singleton which cannot be read must not be overwritten
+	storeServiceSets := 0
+	muxDICOM.HandleFunc(base+"storeService", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			storeServiceSets++
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{"))
+	})
+	desired.StoreService = &dicom.BrokenSCPConfig{Title: "Store"}
+	_, err = dicomClient.Config.Apply(desired, dicom.ApplyOptions{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, storeServiceSets)
+}
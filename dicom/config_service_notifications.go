@@ -54,7 +54,7 @@ func (c *ConfigService) GetNotification(opt *QueryOptions, options ...OptionFunc
 		return nil, resp, err
 	}
 	if len(resources) == 0 {
-		return nil, resp, fmt.Errorf("notification not found")
+		return nil, resp, fmt.Errorf("notification %w", ErrNotFound)
 	}
 	return &resources[0], resp, nil
 }
@@ -0,0 +1,313 @@
+package dicom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag is a DICOM attribute tag as group << 16 | element
+type Tag uint32
+
+// Common attribute tags
+const (
+	TagSpecificCharacterSet           Tag = 0x00080005
+	TagSOPClassUID                    Tag = 0x00080016
+	TagSOPInstanceUID                 Tag = 0x00080018
+	TagStudyDate                      Tag = 0x00080020
+	TagStudyTime                      Tag = 0x00080030
+	TagAccessionNumber                Tag = 0x00080050
+	TagModality                       Tag = 0x00080060
+	TagModalitiesInStudy              Tag = 0x00080061
+	TagReferringPhysicianName         Tag = 0x00080090
+	TagStudyDescription               Tag = 0x00081030
+	TagSeriesDescription              Tag = 0x0008103E
+	TagFailedSOPSequence              Tag = 0x00081198
+	TagReferencedSOPSequence          Tag = 0x00081199
+	TagReferencedSOPClassUID          Tag = 0x00081150
+	TagReferencedSOPInstanceUID       Tag = 0x00081155
+	TagRetrieveURL                    Tag = 0x00081190
+	TagWarningReason                  Tag = 0x00081196
+	TagFailureReason                  Tag = 0x00081197
+	TagPatientName                    Tag = 0x00100010
+	TagPatientID                      Tag = 0x00100020
+	TagPatientBirthDate               Tag = 0x00100030
+	TagPatientSex                     Tag = 0x00100040
+	TagStudyInstanceUID               Tag = 0x0020000D
+	TagSeriesInstanceUID              Tag = 0x0020000E
+	TagStudyID                        Tag = 0x00200010
+	TagSeriesNumber                   Tag = 0x00200011
+	TagInstanceNumber                 Tag = 0x00200013
+	TagNumberOfStudyRelatedSeries     Tag = 0x00201206
+	TagNumberOfStudyRelatedInstances  Tag = 0x00201208
+	TagNumberOfSeriesRelatedInstances Tag = 0x00201209
+	TagNumberOfFrames                 Tag = 0x00280008
+	TagRows                           Tag = 0x00280010
+	TagColumns                        Tag = 0x00280011
+	TagPixelData                      Tag = 0x7FE00010
+)
+
+// ParseTag parses a tag in the DICOM JSON notation, e.g. 0020000D
+func ParseTag(s string) (Tag, error) {
+	s = strings.NewReplacer("(", "", ")", "", ",", "").Replace(s)
+	if len(s) != 8 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidTag, s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidTag, s)
+	}
+	return Tag(v), nil
+}
+
+// Group returns the group number of the tag
+func (t Tag) Group() uint16 {
+	return uint16(t >> 16)
+}
+
+// Element returns the element number of the tag
+func (t Tag) Element() uint16 {
+	return uint16(t)
+}
+
+// String returns the tag in the DICOM JSON notation
+func (t Tag) String() string {
+	return fmt.Sprintf("%08X", uint32(t))
+}
+
+// PersonName is a PN value in the DICOM JSON model
+type PersonName struct {
+	Alphabetic  string `json:"Alphabetic,omitempty"`
+	Ideographic string `json:"Ideographic,omitempty"`
+	Phonetic    string `json:"Phonetic,omitempty"`
+}
+
+// Attribute is a single attribute of a DICOM JSON dataset. Values are typed by VR:
+// SQ values are Dataset, PN values are PersonName, numeric VRs are float64 and
+// all others are string
+type Attribute struct {
+	VR           string        `json:"vr"`
+	Value        []interface{} `json:"Value,omitempty"`
+	BulkDataURI  string        `json:"BulkDataURI,omitempty"`
+	InlineBinary string        `json:"InlineBinary,omitempty"`
+}
+
+func isNumericVR(vr string) bool {
+	switch vr {
+	case "DS", "IS", "FL", "FD", "SL", "SS", "UL", "US", "SV", "UV":
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes an attribute and types its values according to the VR
+func (a *Attribute) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		VR           string            `json:"vr"`
+		Value        []json.RawMessage `json:"Value"`
+		BulkDataURI  string            `json:"BulkDataURI"`
+		InlineBinary string            `json:"InlineBinary"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*a = Attribute{VR: raw.VR, BulkDataURI: raw.BulkDataURI, InlineBinary: raw.InlineBinary}
+	for _, v := range raw.Value {
+		value, err := decodeValue(raw.VR, v)
+		if err != nil {
+			return err
+		}
+		a.Value = append(a.Value, value)
+	}
+	return nil
+}
+
+func decodeValue(vr string, data json.RawMessage) (interface{}, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+	switch {
+	case vr == "SQ":
+		var ds Dataset
+		err := json.Unmarshal(data, &ds)
+		return ds, err
+	case vr == "PN":
+		var pn PersonName
+		err := json.Unmarshal(data, &pn)
+		return pn, err
+	case isNumericVR(vr):
+		var f float64
+		if err := json.Unmarshal(data, &f); err == nil {
+			return f, nil
+		}
+		// DS and IS are sometimes encoded as strings
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	var s string
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
+
+// Dataset is a set of DICOM attributes as returned by QIDO-RS and WADO-RS metadata
+type Dataset map[Tag]Attribute
+
+// UnmarshalJSON decodes a dataset in the DICOM JSON model
+func (d *Dataset) UnmarshalJSON(data []byte) error {
+	var raw map[string]Attribute
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	ds := make(Dataset, len(raw))
+	for key, attr := range raw {
+		tag, err := ParseTag(key)
+		if err != nil {
+			return err
+		}
+		ds[tag] = attr
+	}
+	*d = ds
+	return nil
+}
+
+// MarshalJSON encodes the dataset in the DICOM JSON model
+func (d Dataset) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]Attribute, len(d))
+	for tag, attr := range d {
+		raw[tag.String()] = attr
+	}
+	return json.Marshal(raw)
+}
+
+// Set stores values for the tag with the given VR
+func (d Dataset) Set(tag Tag, vr string, values ...interface{}) {
+	d[tag] = Attribute{VR: vr, Value: values}
+}
+
+// Strings returns all values of the tag formatted as strings
+func (d Dataset) Strings(tag Tag) []string {
+	attr, ok := d[tag]
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(attr.Value))
+	for _, v := range attr.Value {
+		switch value := v.(type) {
+		case string:
+			values = append(values, value)
+		case PersonName:
+			values = append(values, value.Alphabetic)
+		case float64:
+			values = append(values, strconv.FormatFloat(value, 'f', -1, 64))
+		case nil:
+			values = append(values, "")
+		default:
+			values = append(values, fmt.Sprint(value))
+		}
+	}
+	return values
+}
+
+// String returns the first value of the tag formatted as string
+func (d Dataset) String(tag Tag) string {
+	if values := d.Strings(tag); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Float returns the first numeric value of the tag
+func (d Dataset) Float(tag Tag) (float64, bool) {
+	attr, ok := d[tag]
+	if !ok || len(attr.Value) == 0 {
+		return 0, false
+	}
+	f, ok := attr.Value[0].(float64)
+	return f, ok
+}
+
+// Int returns the first numeric value of the tag as an integer
+func (d Dataset) Int(tag Tag) (int, bool) {
+	f, ok := d.Float(tag)
+	return int(f), ok
+}
+
+// PersonName returns the first PN value of the tag
+func (d Dataset) PersonName(tag Tag) PersonName {
+	attr, ok := d[tag]
+	if !ok || len(attr.Value) == 0 {
+		return PersonName{}
+	}
+	pn, _ := attr.Value[0].(PersonName)
+	return pn
+}
+
+// Sequence returns the items of a SQ attribute
+func (d Dataset) Sequence(tag Tag) []Dataset {
+	attr, ok := d[tag]
+	if !ok {
+		return nil
+	}
+	items := make([]Dataset, 0, len(attr.Value))
+	for _, v := range attr.Value {
+		if item, ok := v.(Dataset); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Date parses the first DA value of the tag
+func (d Dataset) Date(tag Tag) (time.Time, error) {
+	return time.Parse(dateLayout, d.String(tag))
+}
+
+// StudyInstanceUID returns the Study Instance UID (0020,000D)
+func (d Dataset) StudyInstanceUID() string {
+	return d.String(TagStudyInstanceUID)
+}
+
+// SeriesInstanceUID returns the Series Instance UID (0020,000E)
+func (d Dataset) SeriesInstanceUID() string {
+	return d.String(TagSeriesInstanceUID)
+}
+
+// SOPInstanceUID returns the SOP Instance UID (0008,0018)
+func (d Dataset) SOPInstanceUID() string {
+	return d.String(TagSOPInstanceUID)
+}
+
+// SOPClassUID returns the SOP Class UID (0008,0016)
+func (d Dataset) SOPClassUID() string {
+	return d.String(TagSOPClassUID)
+}
+
+// PatientID returns the Patient ID (0010,0020)
+func (d Dataset) PatientID() string {
+	return d.String(TagPatientID)
+}
+
+// PatientName returns the alphabetic Patient's Name (0010,0010)
+func (d Dataset) PatientName() string {
+	return d.PersonName(TagPatientName).Alphabetic
+}
+
+// Modality returns the Modality (0008,0060)
+func (d Dataset) Modality() string {
+	return d.String(TagModality)
+}
+
+// StudyDate returns the Study Date (0008,0020)
+func (d Dataset) StudyDate() (time.Time, error) {
+	return d.Date(TagStudyDate)
+}
+
+// RetrieveURL returns the Retrieve URL (0008,1190)
+func (d Dataset) RetrieveURL() string {
+	return d.String(TagRetrieveURL)
+}
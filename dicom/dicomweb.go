@@ -0,0 +1,72 @@
+package dicom
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+)
+
+const (
+	// MediaTypeDICOMJSON is the media type of the DICOM JSON model
+	MediaTypeDICOMJSON = "application/dicom+json"
+	// MediaTypeDICOM is the media type of DICOM Part 10 files
+	MediaTypeDICOM = "application/dicom"
+)
+
+// WithContext runs the request with the provided context
+func WithContext(ctx context.Context) OptionFunc {
+	return func(req *http.Request) error {
+		*req = *req.WithContext(ctx)
+		return nil
+	}
+}
+
+// dicomWebPath returns the DICOMweb root of the organization on the given service URL
+func (c *Client) dicomWebPath(serviceURL string) (string, error) {
+	if c.config.OrganizationID == "" {
+		return "", ErrMissingOrganizationID
+	}
+	return strings.TrimSuffix(serviceURL, "/") + "/store/dicomweb/" + c.config.OrganizationID + "/dicomWeb/", nil
+}
+
+// newDICOMWebRequest creates a new QIDO-RS, WADO-RS or STOW-RS request. The path is relative
+// to the DICOMweb root of the service. Options are applied last so they can override the
+// default Accept header
+func (c *Client) newDICOMWebRequest(method, serviceURL, path string, body io.Reader, opt interface{}, options ...OptionFunc) (*http.Request, error) {
+	root, err := c.dicomWebPath(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, root+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if opt != nil {
+		q, err := query.Values(opt)
+		if err != nil {
+			return nil, err
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	token, err := c.iamClient.Token()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", MediaTypeDICOMJSON)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	for _, fn := range options {
+		if fn == nil {
+			continue
+		}
+		if err := fn(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
@@ -9,4 +9,15 @@ var (
 	ErrDICOMURLCannotBeEmpty = errors.New("base DICOM URL cannot be empty")
 	ErrEmptyResult           = errors.New("empty result")
 	ErrNotFound              = errors.New("not found")
+	ErrMissingOrganizationID = errors.New("missing organization ID")
+	ErrMissingStudyUID       = errors.New("missing study instance UID")
+	ErrInvalidTag            = errors.New("invalid tag")
+	ErrMissingSeriesUID      = errors.New("missing series instance UID")
+	ErrMissingInstanceUID    = errors.New("missing SOP instance UID")
+	ErrMissingFrames         = errors.New("missing frame numbers")
+	ErrInvalidMultipart      = errors.New("invalid multipart response")
+	ErrInvalidInstance       = errors.New("invalid DICOM Part 10 instance")
+	ErrInstanceRejected      = errors.New("instance rejected")
+	ErrMissingStoreResult    = errors.New("instance missing from store response")
+	ErrStoreFailed           = errors.New("instances failed to store")
 )
@@ -0,0 +1,253 @@
+package part10
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/philips-software/go-hsdp-api/dicom"
+)
+
+// Action is a PS3.15 Annex E de-identification action
+type Action int
+
+const (
+	// Keep retains the attribute (K)
+	Keep Action = iota
+	// Remove deletes the attribute (X)
+	Remove
+	// Empty replaces the value with a zero length value (Z)
+	Empty
+	// Dummy replaces the value with a dummy value consistent with the VR (D)
+	Dummy
+	// HashUID replaces the UID with one derived from it, consistently across files (U)
+	HashUID
+)
+
+const (
+	// DefaultUIDRoot is the UUID derived root of hashed UIDs
+	DefaultUIDRoot = "2.25"
+	// BasicProfileMethod is stored in Deidentification Method (0012,0063)
+	BasicProfileMethod = "PS3.15 Basic Application Level Confidentiality Profile"
+
+	tagPatientIdentityRemoved dicom.Tag = 0x00120062
+	tagDeidentificationMethod dicom.Tag = 0x00120063
+)
+
+// basicProfile is the subset of PS3.15 Table E.1-1 handled by BasicProfile. Attributes
+// with a choice of actions use the least destructive one that keeps the file valid
+var basicProfile = map[dicom.Tag]Action{
+	0x00020003: HashUID, // MediaStorageSOPInstanceUID
+	0x00080014: HashUID, // InstanceCreatorUID
+	0x00080018: HashUID, // SOPInstanceUID
+	0x00080020: Empty,   // StudyDate
+	0x00080021: Remove,  // SeriesDate
+	0x00080022: Remove,  // AcquisitionDate
+	0x00080023: Empty,   // ContentDate
+	0x0008002A: Remove,  // AcquisitionDateTime
+	0x00080030: Empty,   // StudyTime
+	0x00080031: Remove,  // SeriesTime
+	0x00080032: Remove,  // AcquisitionTime
+	0x00080033: Empty,   // ContentTime
+	0x00080050: Empty,   // AccessionNumber
+	0x00080080: Remove,  // InstitutionName
+	0x00080081: Remove,  // InstitutionAddress
+	0x00080090: Empty,   // ReferringPhysicianName
+	0x00080092: Remove,  // ReferringPhysicianAddress
+	0x00080094: Remove,  // ReferringPhysicianTelephoneNumbers
+	0x00080096: Remove,  // ReferringPhysicianIdentificationSequence
+	0x00081010: Remove,  // StationName
+	0x00081030: Remove,  // StudyDescription
+	0x0008103E: Remove,  // SeriesDescription
+	0x00081040: Remove,  // InstitutionalDepartmentName
+	0x00081048: Remove,  // PhysiciansOfRecord
+	0x00081050: Remove,  // PerformingPhysicianName
+	0x00081060: Remove,  // NameOfPhysiciansReadingStudy
+	0x00081070: Remove,  // OperatorsName
+	0x00081080: Remove,  // AdmittingDiagnosesDescription
+	0x00081110: Remove,  // ReferencedStudySequence
+	0x00081111: Remove,  // ReferencedPerformedProcedureStepSequence
+	0x00081120: Remove,  // ReferencedPatientSequence
+	0x00081155: HashUID, // ReferencedSOPInstanceUID
+	0x00081195: HashUID, // TransactionUID
+	0x00082111: Remove,  // DerivationDescription
+	0x00083010: HashUID, // IrradiationEventUID
+	0x00100010: Empty,   // PatientName
+	0x00100020: Empty,   // PatientID
+	0x00100021: Remove,  // IssuerOfPatientID
+	0x00100030: Empty,   // PatientBirthDate
+	0x00100032: Remove,  // PatientBirthTime
+	0x00100040: Empty,   // PatientSex
+	0x00101000: Remove,  // OtherPatientIDs
+	0x00101001: Remove,  // OtherPatientNames
+	0x00101002: Remove,  // OtherPatientIDsSequence
+	0x00101005: Remove,  // PatientBirthName
+	0x00101010: Remove,  // PatientAge
+	0x00101020: Remove,  // PatientSize
+	0x00101030: Remove,  // PatientWeight
+	0x00101040: Remove,  // PatientAddress
+	0x00101060: Remove,  // PatientMotherBirthName
+	0x00101080: Remove,  // MilitaryRank
+	0x00101090: Remove,  // MedicalRecordLocator
+	0x00102154: Remove,  // PatientTelephoneNumbers
+	0x00102160: Remove,  // EthnicGroup
+	0x00102180: Remove,  // Occupation
+	0x001021B0: Remove,  // AdditionalPatientHistory
+	0x001021F0: Remove,  // PatientReligiousPreference
+	0x00104000: Remove,  // PatientComments
+	0x00181000: Remove,  // DeviceSerialNumber
+	0x00181030: Remove,  // ProtocolName
+	0x0020000D: HashUID, // StudyInstanceUID
+	0x0020000E: HashUID, // SeriesInstanceUID
+	0x00200010: Empty,   // StudyID
+	0x00200052: HashUID, // FrameOfReferenceUID
+	0x00200200: HashUID, // SynchronizationFrameOfReferenceUID
+	0x00204000: Remove,  // ImageComments
+	0x00209161: HashUID, // ConcatenationUID
+	0x00209164: HashUID, // DimensionOrganizationUID
+	0x00321032: Remove,  // RequestingPhysician
+	0x00321060: Remove,  // RequestedProcedureDescription
+	0x00380010: Remove,  // AdmissionID
+	0x00400244: Remove,  // PerformedProcedureStepStartDate
+	0x00400245: Remove,  // PerformedProcedureStepStartTime
+	0x00400253: Remove,  // PerformedProcedureStepID
+	0x00400254: Remove,  // PerformedProcedureStepDescription
+	0x00400275: Remove,  // RequestAttributesSequence
+	0x0040A124: HashUID, // UID
+	0x00880140: HashUID, // StorageMediaFileSetUID
+	0x30060024: HashUID, // ReferencedFrameOfReferenceUID
+	0x300600C2: HashUID, // RelatedFrameOfReferenceUID
+}
+
+// Profile configures de-identification. Actions apply at every nesting level;
+// attributes without an action are kept
+type Profile struct {
+	Actions map[dicom.Tag]Action
+	// Dummies overrides the dummy value used for the Dummy action of a tag
+	Dummies map[dicom.Tag]string
+	// Secret keys the UID hash so UIDs map consistently within, but cannot be
+	// linked across, de-identification domains
+	Secret []byte
+	// UIDRoot prefixes hashed UIDs and defaults to DefaultUIDRoot
+	UIDRoot string
+	// RemovePrivateTags removes all attributes of odd groups
+	RemovePrivateTags bool
+	// RemoveCurvesAndOverlays removes curve data (50xx) and overlay data and comments (60xx)
+	RemoveCurvesAndOverlays bool
+}
+
+// BasicProfile returns a Profile implementing the PS3.15 Basic Application Level
+// Confidentiality Profile. The Actions map is a copy and can be adjusted
+func BasicProfile(secret []byte) *Profile {
+	actions := make(map[dicom.Tag]Action, len(basicProfile))
+	for tag, action := range basicProfile {
+		actions[tag] = action
+	}
+	return &Profile{
+		Actions:                 actions,
+		Dummies:                 map[dicom.Tag]string{},
+		Secret:                  secret,
+		RemovePrivateTags:       true,
+		RemoveCurvesAndOverlays: true,
+	}
+}
+
+// Apply de-identifies the file in place and records it in Patient Identity Removed
+// (0012,0062) and Deidentification Method (0012,0063). The preamble is zeroed, as it
+// can hold application data such as a TIFF header
+func (p *Profile) Apply(f *File) {
+	f.Preamble = [preambleLength]byte{}
+	p.apply(&f.Meta)
+	p.apply(&f.DataSet)
+	f.DataSet.Set(tagPatientIdentityRemoved, "CS", "YES")
+	f.DataSet.Set(tagDeidentificationMethod, "LO", BasicProfileMethod)
+}
+
+func (p *Profile) apply(ds *DataSet) {
+	elements := ds.Elements[:0]
+	for _, e := range ds.Elements {
+		if p.removed(e.Tag) {
+			continue
+		}
+		switch p.Actions[e.Tag] {
+		case Remove:
+			continue
+		case Empty:
+			e.SetString()
+		case Dummy:
+			e.SetString(p.dummy(e))
+		case HashUID:
+			values := e.Strings()
+			for i, uid := range values {
+				if uid != "" {
+					values[i] = p.HashUID(uid)
+				}
+			}
+			e.SetString(values...)
+		}
+		for _, item := range e.Items {
+			p.apply(item)
+		}
+		elements = append(elements, e)
+	}
+	ds.Elements = elements
+}
+
+func (p *Profile) removed(tag dicom.Tag) bool {
+	group := tag.Group()
+	if p.RemovePrivateTags && group%2 == 1 && group != 0xFFFF {
+		return true
+	}
+	if p.RemoveCurvesAndOverlays {
+		switch {
+		case group&0xFF00 == 0x5000:
+			return true
+		case group&0xFF00 == 0x6000 && (tag.Element() == 0x3000 || tag.Element() == 0x4000):
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Profile) dummy(e *Element) string {
+	if value, ok := p.Dummies[e.Tag]; ok {
+		return value
+	}
+	switch e.VR {
+	case "DA":
+		return "19000101"
+	case "TM":
+		return "000000"
+	case "DT":
+		return "19000101000000"
+	case "AS":
+		return "000Y"
+	case "IS", "DS":
+		return "0"
+	case "UI":
+		return p.HashUID(e.String())
+	case "PN", "LO", "SH", "LT", "ST", "UT", "UC", "CS", "AE":
+		return "ANONYMOUS"
+	}
+	return ""
+}
+
+// HashUID maps a UID to a new UID below UIDRoot. The mapping is stable for a Secret so
+// references between files remain intact
+func (p *Profile) HashUID(uid string) string {
+	mac := hmac.New(sha256.New, p.Secret)
+	_, _ = mac.Write([]byte(uid))
+	// 2.25 UIDs are formed from a 128 bit UUID
+	sum := mac.Sum(nil)[:16]
+	sum[6] = sum[6]&0x0F | 0x80
+	sum[8] = sum[8]&0x3F | 0x80
+	root := p.UIDRoot
+	if root == "" {
+		root = DefaultUIDRoot
+	}
+	hashed := root + "." + new(big.Int).SetBytes(sum).String()
+	if len(hashed) > 64 {
+		hashed = hashed[:64]
+	}
+	return hashed
+}
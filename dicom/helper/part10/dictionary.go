@@ -0,0 +1,153 @@
+package part10
+
+import (
+	"github.com/philips-software/go-hsdp-api/dicom"
+)
+
+// dictionary holds the VR of the attributes needed to parse implicit VR data sets
+// and to de-identify them. Attributes missing from it are treated as UN
+var dictionary = map[dicom.Tag]string{
+	0x00020001: "OB", // FileMetaInformationVersion
+	0x00020002: "UI", // MediaStorageSOPClassUID
+	0x00020003: "UI", // MediaStorageSOPInstanceUID
+	0x00020010: "UI", // TransferSyntaxUID
+	0x00020012: "UI", // ImplementationClassUID
+	0x00020013: "SH", // ImplementationVersionName
+	0x00020016: "AE", // SourceApplicationEntityTitle
+	0x00080005: "CS", // SpecificCharacterSet
+	0x00080008: "CS", // ImageType
+	0x00080012: "DA", // InstanceCreationDate
+	0x00080013: "TM", // InstanceCreationTime
+	0x00080014: "UI", // InstanceCreatorUID
+	0x00080016: "UI", // SOPClassUID
+	0x00080018: "UI", // SOPInstanceUID
+	0x00080020: "DA", // StudyDate
+	0x00080021: "DA", // SeriesDate
+	0x00080022: "DA", // AcquisitionDate
+	0x00080023: "DA", // ContentDate
+	0x0008002A: "DT", // AcquisitionDateTime
+	0x00080030: "TM", // StudyTime
+	0x00080031: "TM", // SeriesTime
+	0x00080032: "TM", // AcquisitionTime
+	0x00080033: "TM", // ContentTime
+	0x00080050: "SH", // AccessionNumber
+	0x00080060: "CS", // Modality
+	0x00080061: "CS", // ModalitiesInStudy
+	0x00080064: "CS", // ConversionType
+	0x00080070: "LO", // Manufacturer
+	0x00080080: "LO", // InstitutionName
+	0x00080081: "ST", // InstitutionAddress
+	0x00080090: "PN", // ReferringPhysicianName
+	0x00080092: "ST", // ReferringPhysicianAddress
+	0x00080094: "SH", // ReferringPhysicianTelephoneNumbers
+	0x00080096: "SQ", // ReferringPhysicianIdentificationSequence
+	0x00081010: "SH", // StationName
+	0x00081030: "LO", // StudyDescription
+	0x0008103E: "LO", // SeriesDescription
+	0x00081040: "LO", // InstitutionalDepartmentName
+	0x00081048: "PN", // PhysiciansOfRecord
+	0x00081050: "PN", // PerformingPhysicianName
+	0x00081060: "PN", // NameOfPhysiciansReadingStudy
+	0x00081070: "PN", // OperatorsName
+	0x00081080: "LO", // AdmittingDiagnosesDescription
+	0x00081090: "LO", // ManufacturerModelName
+	0x00081110: "SQ", // ReferencedStudySequence
+	0x00081111: "SQ", // ReferencedPerformedProcedureStepSequence
+	0x00081115: "SQ", // ReferencedSeriesSequence
+	0x00081120: "SQ", // ReferencedPatientSequence
+	0x00081140: "SQ", // ReferencedImageSequence
+	0x00081150: "UI", // ReferencedSOPClassUID
+	0x00081155: "UI", // ReferencedSOPInstanceUID
+	0x00081190: "UR", // RetrieveURL
+	0x00081195: "UI", // TransactionUID
+	0x00081198: "SQ", // FailedSOPSequence
+	0x00081199: "SQ", // ReferencedSOPSequence
+	0x00082111: "ST", // DerivationDescription
+	0x00082112: "SQ", // SourceImageSequence
+	0x00083010: "UI", // IrradiationEventUID
+	0x00100010: "PN", // PatientName
+	0x00100020: "LO", // PatientID
+	0x00100021: "LO", // IssuerOfPatientID
+	0x00100030: "DA", // PatientBirthDate
+	0x00100032: "TM", // PatientBirthTime
+	0x00100040: "CS", // PatientSex
+	0x00101000: "LO", // OtherPatientIDs
+	0x00101001: "PN", // OtherPatientNames
+	0x00101002: "SQ", // OtherPatientIDsSequence
+	0x00101005: "PN", // PatientBirthName
+	0x00101010: "AS", // PatientAge
+	0x00101020: "DS", // PatientSize
+	0x00101030: "DS", // PatientWeight
+	0x00101040: "LO", // PatientAddress
+	0x00101060: "PN", // PatientMotherBirthName
+	0x00101080: "LO", // MilitaryRank
+	0x00101090: "LO", // MedicalRecordLocator
+	0x00102154: "SH", // PatientTelephoneNumbers
+	0x00102160: "SH", // EthnicGroup
+	0x00102180: "SH", // Occupation
+	0x001021B0: "LT", // AdditionalPatientHistory
+	0x001021F0: "LO", // PatientReligiousPreference
+	0x00104000: "LT", // PatientComments
+	0x00120062: "CS", // PatientIdentityRemoved
+	0x00120063: "LO", // DeidentificationMethod
+	0x00180015: "CS", // BodyPartExamined
+	0x00180050: "DS", // SliceThickness
+	0x00181000: "LO", // DeviceSerialNumber
+	0x00181020: "LO", // SoftwareVersions
+	0x00181030: "LO", // ProtocolName
+	0x0020000D: "UI", // StudyInstanceUID
+	0x0020000E: "UI", // SeriesInstanceUID
+	0x00200010: "SH", // StudyID
+	0x00200011: "IS", // SeriesNumber
+	0x00200013: "IS", // InstanceNumber
+	0x00200032: "DS", // ImagePositionPatient
+	0x00200037: "DS", // ImageOrientationPatient
+	0x00200052: "UI", // FrameOfReferenceUID
+	0x00200200: "UI", // SynchronizationFrameOfReferenceUID
+	0x00204000: "LT", // ImageComments
+	0x00209161: "UI", // ConcatenationUID
+	0x00209164: "UI", // DimensionOrganizationUID
+	0x00280002: "US", // SamplesPerPixel
+	0x00280004: "CS", // PhotometricInterpretation
+	0x00280008: "IS", // NumberOfFrames
+	0x00280010: "US", // Rows
+	0x00280011: "US", // Columns
+	0x00280030: "DS", // PixelSpacing
+	0x00280100: "US", // BitsAllocated
+	0x00280101: "US", // BitsStored
+	0x00280102: "US", // HighBit
+	0x00280103: "US", // PixelRepresentation
+	0x00281050: "DS", // WindowCenter
+	0x00281051: "DS", // WindowWidth
+	0x00321032: "PN", // RequestingPhysician
+	0x00321060: "LO", // RequestedProcedureDescription
+	0x00380010: "LO", // AdmissionID
+	0x00400244: "DA", // PerformedProcedureStepStartDate
+	0x00400245: "TM", // PerformedProcedureStepStartTime
+	0x00400253: "SH", // PerformedProcedureStepID
+	0x00400254: "LO", // PerformedProcedureStepDescription
+	0x00400275: "SQ", // RequestAttributesSequence
+	0x0040A124: "UI", // UID
+	0x0040A730: "SQ", // ContentSequence
+	0x00880140: "UI", // StorageMediaFileSetUID
+	0x30060024: "UI", // ReferencedFrameOfReferenceUID
+	0x300600C2: "UI", // RelatedFrameOfReferenceUID
+	0x7FE00010: "OW", // PixelData
+}
+
+// VROf returns the VR of the tag from the built-in dictionary. Group length elements
+// are UL and unknown or private attributes are UN
+func VROf(tag dicom.Tag) string {
+	if vr, ok := dictionary[tag]; ok {
+		return vr
+	}
+	switch {
+	case tag.Element() == 0x0000:
+		return "UL"
+	case tag == TagItem || tag == TagItemDelimitationItem || tag == TagSequenceDelimitationItem:
+		return ""
+	case tag.Group()&0xFF00 == 0x6000 && tag.Element() == 0x3000:
+		return "OW" // OverlayData
+	}
+	return "UN"
+}
@@ -0,0 +1,614 @@
+// Package part10 reads and writes DICOM Part 10 files and de-identifies them
+// according to the PS3.15 Basic Application Level Confidentiality Profile
+package part10
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/philips-software/go-hsdp-api/dicom"
+)
+
+var (
+	ErrInvalidFile      = errors.New("invalid DICOM Part 10 file")
+	ErrUnexpectedEOF    = errors.New("unexpected end of data")
+	ErrValueTooLong     = errors.New("value too long for VR")
+	ErrMissingMetaValue = errors.New("missing File Meta Information value")
+)
+
+// Tags with special meaning in the encoding
+const (
+	TagFileMetaInformationGroupLength dicom.Tag = 0x00020000
+	TagMediaStorageSOPClassUID        dicom.Tag = 0x00020002
+	TagMediaStorageSOPInstanceUID     dicom.Tag = 0x00020003
+	TagTransferSyntaxUID              dicom.Tag = 0x00020010
+	TagItem                           dicom.Tag = 0xFFFEE000
+	TagItemDelimitationItem           dicom.Tag = 0xFFFEE00D
+	TagSequenceDelimitationItem       dicom.Tag = 0xFFFEE0DD
+)
+
+const (
+	preambleLength  = 128
+	undefinedLength = 0xFFFFFFFF
+	maxNesting      = 64
+)
+
+// Element is a single data element. Value holds the raw bytes in the byte order of the
+// transfer syntax. Sequences hold their Items and encapsulated pixel data holds its
+// Fragments, the first being the Basic Offset Table, which are kept as-is
+type Element struct {
+	Tag       dicom.Tag
+	VR        string
+	Value     []byte
+	Items     []*DataSet
+	Fragments [][]byte
+}
+
+// Encapsulated reports whether the element is encapsulated pixel data
+func (e *Element) Encapsulated() bool {
+	return e.Fragments != nil
+}
+
+// Strings returns the backslash separated values of a string element with padding removed
+func (e *Element) Strings() []string {
+	if len(e.Value) == 0 {
+		return nil
+	}
+	values := strings.Split(string(e.Value), `\`)
+	for i, v := range values {
+		values[i] = strings.TrimRight(v, "\x00 ")
+		if e.VR != "LT" && e.VR != "ST" && e.VR != "UT" {
+			values[i] = strings.TrimLeft(values[i], " ")
+		}
+	}
+	return values
+}
+
+// String returns the first value of a string element
+func (e *Element) String() string {
+	if values := e.Strings(); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// SetString replaces the value of the element with the backslash separated values,
+// padded to an even length
+func (e *Element) SetString(values ...string) {
+	value := []byte(strings.Join(values, `\`))
+	if len(value)%2 == 1 {
+		if e.VR == "UI" {
+			value = append(value, 0)
+		} else {
+			value = append(value, ' ')
+		}
+	}
+	e.Value = value
+	e.Items = nil
+	e.Fragments = nil
+}
+
+// DataSet is an ordered list of data elements
+type DataSet struct {
+	Elements []*Element
+}
+
+// Get returns the element with the tag or nil when it is not present
+func (d *DataSet) Get(tag dicom.Tag) *Element {
+	i := d.search(tag)
+	if i < len(d.Elements) && d.Elements[i].Tag == tag {
+		return d.Elements[i]
+	}
+	return nil
+}
+
+// String returns the first value of the string element with the tag
+func (d *DataSet) String(tag dicom.Tag) string {
+	if e := d.Get(tag); e != nil {
+		return e.String()
+	}
+	return ""
+}
+
+// Set stores string values in the element with the tag, inserting it in tag order
+// when it is not present
+func (d *DataSet) Set(tag dicom.Tag, vr string, values ...string) *Element {
+	e := d.Get(tag)
+	if e == nil {
+		e = &Element{Tag: tag, VR: vr}
+		i := d.search(tag)
+		d.Elements = append(d.Elements, nil)
+		copy(d.Elements[i+1:], d.Elements[i:])
+		d.Elements[i] = e
+	}
+	e.SetString(values...)
+	return e
+}
+
+// Remove deletes the element with the tag and reports whether it was present
+func (d *DataSet) Remove(tag dicom.Tag) bool {
+	i := d.search(tag)
+	if i < len(d.Elements) && d.Elements[i].Tag == tag {
+		d.Elements = append(d.Elements[:i], d.Elements[i+1:]...)
+		return true
+	}
+	return false
+}
+
+func (d *DataSet) search(tag dicom.Tag) int {
+	return sort.Search(len(d.Elements), func(i int) bool {
+		return d.Elements[i].Tag >= tag
+	})
+}
+
+// File is a parsed Part 10 file
+type File struct {
+	Preamble [preambleLength]byte
+	// Meta is the File Meta Information, group 0002
+	Meta DataSet
+	// DataSet holds all elements following the File Meta Information
+	DataSet DataSet
+}
+
+// TransferSyntax returns the Transfer Syntax UID of the data set
+func (f *File) TransferSyntax() string {
+	return f.Meta.String(TagTransferSyntaxUID)
+}
+
+type encoding struct {
+	order    binary.ByteOrder
+	explicit bool
+	deflated bool
+}
+
+func encodingOf(transferSyntax string) encoding {
+	switch transferSyntax {
+	case dicom.TransferSyntaxImplicitVRLittleEndian:
+		return encoding{order: binary.LittleEndian}
+	case dicom.TransferSyntaxExplicitVRBigEndian:
+		return encoding{order: binary.BigEndian, explicit: true}
+	case dicom.TransferSyntaxDeflatedExplicitVR:
+		return encoding{order: binary.LittleEndian, explicit: true, deflated: true}
+	}
+	// All encapsulated transfer syntaxes use explicit VR little endian
+	return encoding{order: binary.LittleEndian, explicit: true}
+}
+
+// ParseFile parses the Part 10 file at name
+func ParseFile(name string) (*File, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(data)
+}
+
+// Parse reads and parses a Part 10 stream
+func Parse(r io.Reader) (*File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(data)
+}
+
+// ParseBytes parses a Part 10 file held in memory
+func ParseBytes(data []byte) (*File, error) {
+	if len(data) < preambleLength+4 || string(data[preambleLength:preambleLength+4]) != "DICM" {
+		return nil, fmt.Errorf("%w: missing DICM prefix", ErrInvalidFile)
+	}
+	f := &File{}
+	copy(f.Preamble[:], data)
+
+	meta := &parser{data: data, pos: preambleLength + 4, encoding: encoding{order: binary.LittleEndian, explicit: true}}
+	for meta.pos+4 <= len(data) && binary.LittleEndian.Uint16(data[meta.pos:]) == 0x0002 {
+		e, err := meta.element(0)
+		if err != nil {
+			return nil, err
+		}
+		f.Meta.Elements = append(f.Meta.Elements, e)
+	}
+	transferSyntax := f.TransferSyntax()
+	if transferSyntax == "" {
+		return nil, fmt.Errorf("%w: Transfer Syntax UID", ErrMissingMetaValue)
+	}
+
+	body := &parser{data: data[meta.pos:], encoding: encodingOf(transferSyntax)}
+	if body.deflated {
+		inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(body.data)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		body.data = inflated
+	}
+	ds, err := body.dataSet(len(body.data), false, 0)
+	if err != nil {
+		return nil, err
+	}
+	f.DataSet = *ds
+	return f, nil
+}
+
+type parser struct {
+	encoding
+	data []byte
+	pos  int
+}
+
+func (p *parser) read(n int) ([]byte, error) {
+	if n < 0 || p.pos+n > len(p.data) {
+		return nil, fmt.Errorf("%w at offset %d", ErrUnexpectedEOF, p.pos)
+	}
+	b := p.data[p.pos : p.pos+n]
+	p.pos += n
+	return b, nil
+}
+
+func (p *parser) uint16() (uint16, error) {
+	b, err := p.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return p.order.Uint16(b), nil
+}
+
+func (p *parser) uint32() (uint32, error) {
+	b, err := p.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return p.order.Uint32(b), nil
+}
+
+func (p *parser) tag() (dicom.Tag, error) {
+	group, err := p.uint16()
+	if err != nil {
+		return 0, err
+	}
+	element, err := p.uint16()
+	if err != nil {
+		return 0, err
+	}
+	return dicom.Tag(uint32(group)<<16 | uint32(element)), nil
+}
+
+// dataSet parses elements up to end or, for items of undefined length, up to the
+// item delimitation
+func (p *parser) dataSet(end int, delimited bool, depth int) (*DataSet, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("%w: sequences nested too deep", ErrInvalidFile)
+	}
+	ds := &DataSet{}
+	for p.pos < end {
+		tag, err := p.tag()
+		if err != nil {
+			return nil, err
+		}
+		if tag == TagItemDelimitationItem {
+			if !delimited {
+				return nil, fmt.Errorf("%w: unexpected item delimitation at offset %d", ErrInvalidFile, p.pos)
+			}
+			_, err = p.uint32()
+			return ds, err
+		}
+		p.pos -= 4
+		e, err := p.element(depth)
+		if err != nil {
+			return nil, err
+		}
+		ds.Elements = append(ds.Elements, e)
+	}
+	if delimited {
+		return nil, fmt.Errorf("%w: missing item delimitation", ErrUnexpectedEOF)
+	}
+	return ds, nil
+}
+
+func (p *parser) element(depth int) (*Element, error) {
+	tag, err := p.tag()
+	if err != nil {
+		return nil, err
+	}
+	e := &Element{Tag: tag}
+	var length uint32
+	if p.explicit {
+		vr, err := p.read(2)
+		if err != nil {
+			return nil, err
+		}
+		e.VR = string(vr)
+		if longVR(e.VR) {
+			if _, err := p.read(2); err != nil {
+				return nil, err
+			}
+			length, err = p.uint32()
+		} else {
+			var short uint16
+			short, err = p.uint16()
+			length = uint32(short)
+		}
+	} else {
+		e.VR = VROf(tag)
+		length, err = p.uint32()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case length == undefinedLength && tag == dicom.TagPixelData:
+		return e, p.fragments(e)
+	case length == undefinedLength && (e.VR == "SQ" || e.VR == "UN"):
+		if e.VR == "UN" {
+			// UN of undefined length is a sequence in implicit VR little endian
+			saved := p.encoding
+			p.encoding = encoding{order: binary.LittleEndian}
+			defer func() { p.encoding = saved }()
+			e.VR = "SQ"
+		}
+		e.Items, err = p.items(-1, depth)
+		return e, err
+	case length == undefinedLength:
+		return nil, fmt.Errorf("%w: undefined length for %s %s", ErrInvalidFile, tag, e.VR)
+	case e.VR == "SQ":
+		if p.pos+int(length) > len(p.data) {
+			return nil, fmt.Errorf("%w at offset %d", ErrUnexpectedEOF, p.pos)
+		}
+		e.Items, err = p.items(p.pos+int(length), depth)
+		return e, err
+	}
+	value, err := p.read(int(length))
+	if err != nil {
+		return nil, err
+	}
+	e.Value = value
+	return e, nil
+}
+
+// items parses sequence items up to end or, when end is negative, up to the sequence delimitation
+func (p *parser) items(end int, depth int) ([]*DataSet, error) {
+	items := []*DataSet{}
+	for end < 0 || p.pos < end {
+		tag, err := p.tag()
+		if err != nil {
+			return nil, err
+		}
+		length, err := p.uint32()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case TagSequenceDelimitationItem:
+			if end < 0 {
+				return items, nil
+			}
+			continue
+		case TagItem:
+		default:
+			return nil, fmt.Errorf("%w: expected item, found %s at offset %d", ErrInvalidFile, tag, p.pos-8)
+		}
+		var item *DataSet
+		if length == undefinedLength {
+			item, err = p.dataSet(len(p.data), true, depth+1)
+		} else {
+			if p.pos+int(length) > len(p.data) {
+				return nil, fmt.Errorf("%w at offset %d", ErrUnexpectedEOF, p.pos)
+			}
+			item, err = p.dataSet(p.pos+int(length), false, depth+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// fragments reads the items of encapsulated pixel data without interpreting them
+func (p *parser) fragments(e *Element) error {
+	e.Fragments = [][]byte{}
+	for {
+		tag, err := p.tag()
+		if err != nil {
+			return err
+		}
+		length, err := p.uint32()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case TagSequenceDelimitationItem:
+			return nil
+		case TagItem:
+			fragment, err := p.read(int(length))
+			if err != nil {
+				return err
+			}
+			e.Fragments = append(e.Fragments, fragment)
+		default:
+			return fmt.Errorf("%w: expected fragment, found %s", ErrInvalidFile, tag)
+		}
+	}
+}
+
+func longVR(vr string) bool {
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UN", "UR", "UT", "UV":
+		return true
+	}
+	return false
+}
+
+// WriteFile writes the file to name
+func (f *File) WriteFile(name string) error {
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf.Bytes(), 0o600)
+}
+
+// Write encodes the file in its transfer syntax. The File Meta Information group length is
+// recalculated, sequences and items are written with undefined length
+func (f *File) Write(w io.Writer) error {
+	transferSyntax := f.TransferSyntax()
+	if transferSyntax == "" {
+		return fmt.Errorf("%w: Transfer Syntax UID", ErrMissingMetaValue)
+	}
+	meta := &writer{encoding: encoding{order: binary.LittleEndian, explicit: true}}
+	for _, e := range f.Meta.Elements {
+		if e.Tag == TagFileMetaInformationGroupLength {
+			continue
+		}
+		if err := meta.element(e); err != nil {
+			return err
+		}
+	}
+	groupLength := make([]byte, 4)
+	binary.LittleEndian.PutUint32(groupLength, uint32(meta.Len()))
+	header := &writer{encoding: meta.encoding}
+	header.Write(f.Preamble[:])
+	header.WriteString("DICM")
+	if err := header.element(&Element{Tag: TagFileMetaInformationGroupLength, VR: "UL", Value: groupLength}); err != nil {
+		return err
+	}
+
+	body := &writer{encoding: encodingOf(transferSyntax)}
+	if err := body.dataSet(&f.DataSet); err != nil {
+		return err
+	}
+	data := body.Bytes()
+	if body.deflated {
+		var deflated bytes.Buffer
+		fw, err := flate.NewWriter(&deflated, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+		if err := fw.Close(); err != nil {
+			return err
+		}
+		if deflated.Len()%2 == 1 {
+			deflated.WriteByte(0)
+		}
+		data = deflated.Bytes()
+	}
+	for _, b := range [][]byte{header.Bytes(), meta.Bytes(), data} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type writer struct {
+	bytes.Buffer
+	encoding
+}
+
+func (w *writer) uint16(v uint16) {
+	b := make([]byte, 2)
+	w.order.PutUint16(b, v)
+	w.Write(b)
+}
+
+func (w *writer) uint32(v uint32) {
+	b := make([]byte, 4)
+	w.order.PutUint32(b, v)
+	w.Write(b)
+}
+
+func (w *writer) tag(tag dicom.Tag) {
+	w.uint16(tag.Group())
+	w.uint16(tag.Element())
+}
+
+func (w *writer) header(tag dicom.Tag, vr string, length uint32) error {
+	w.tag(tag)
+	if !w.explicit {
+		w.uint32(length)
+		return nil
+	}
+	w.WriteString(vr)
+	if longVR(vr) {
+		w.uint16(0)
+		w.uint32(length)
+		return nil
+	}
+	if length > 0xFFFF {
+		return fmt.Errorf("%w: %s %s", ErrValueTooLong, tag, vr)
+	}
+	w.uint16(uint16(length))
+	return nil
+}
+
+func (w *writer) dataSet(ds *DataSet) error {
+	for _, e := range ds.Elements {
+		if err := w.element(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) element(e *Element) error {
+	vr := e.VR
+	if vr == "" {
+		vr = VROf(e.Tag)
+	}
+	switch {
+	case e.Fragments != nil:
+		if vr != "OW" {
+			vr = "OB"
+		}
+		if err := w.header(e.Tag, vr, undefinedLength); err != nil {
+			return err
+		}
+		for _, fragment := range e.Fragments {
+			w.tag(TagItem)
+			w.uint32(uint32(len(fragment)))
+			w.Write(fragment)
+		}
+		w.tag(TagSequenceDelimitationItem)
+		w.uint32(0)
+		return nil
+	case e.Items != nil || vr == "SQ":
+		if err := w.header(e.Tag, "SQ", undefinedLength); err != nil {
+			return err
+		}
+		for _, item := range e.Items {
+			w.tag(TagItem)
+			w.uint32(undefinedLength)
+			if err := w.dataSet(item); err != nil {
+				return err
+			}
+			w.tag(TagItemDelimitationItem)
+			w.uint32(0)
+		}
+		w.tag(TagSequenceDelimitationItem)
+		w.uint32(0)
+		return nil
+	}
+	value := e.Value
+	if len(value)%2 == 1 {
+		pad := byte(' ')
+		if vr == "UI" || vr == "OB" || vr == "UN" {
+			pad = 0
+		}
+		value = append(append([]byte{}, value...), pad)
+	}
+	if err := w.header(e.Tag, vr, uint32(len(value))); err != nil {
+		return err
+	}
+	w.Write(value)
+	return nil
+}
@@ -0,0 +1,147 @@
+package part10_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/dicom"
+	"github.com/philips-software/go-hsdp-api/dicom/helper/part10"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	referencedImageSequence dicom.Tag = 0x00081140
+	privateCreator          dicom.Tag = 0x00090010
+	institutionName         dicom.Tag = 0x00080080
+	overlayData             dicom.Tag = 0x60003000
+)
+
+func TestParseExplicit(t *testing.T) {
+	f, err := part10.ParseFile("testdata/ct_jpeg_explicit_le.dcm")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, dicom.TransferSyntaxJPEGBaseline, f.TransferSyntax())
+	assert.Equal(t, "1.2.826.0.1.3680043.2.1125.1.1", f.Meta.String(part10.TagMediaStorageSOPInstanceUID))
+	assert.Equal(t, "Swanson^Ron", f.DataSet.String(dicom.TagPatientName))
+	assert.Equal(t, "ISO_IR 100", f.DataSet.String(dicom.TagSpecificCharacterSet))
+	assert.Equal(t, "ACME", f.DataSet.String(privateCreator))
+
+	seq := f.DataSet.Get(referencedImageSequence)
+	if assert.NotNil(t, seq) && assert.Len(t, seq.Items, 1) {
+		assert.Equal(t, "1.2.826.0.1.3680043.2.1125.1.2", seq.Items[0].String(dicom.TagReferencedSOPInstanceUID))
+	}
+	pixels := f.DataSet.Get(dicom.TagPixelData)
+	if assert.NotNil(t, pixels) && assert.True(t, pixels.Encapsulated()) {
+		assert.Equal(t, [][]byte{{}, {0xFF, 0xD8, 0xFF, 0xE0}, {0xFF, 0xD9}}, pixels.Fragments)
+	}
+
+	// Writing back an unmodified file reproduces it, except for sequence lengths which
+	// are already undefined in this fixture
+	original, _ := os.ReadFile("testdata/ct_jpeg_explicit_le.dcm")
+	var buf bytes.Buffer
+	if assert.Nil(t, f.Write(&buf)) {
+		assert.Equal(t, original, buf.Bytes())
+	}
+}
+
+func TestParseImplicit(t *testing.T) {
+	f, err := part10.ParseFile("testdata/mr_implicit_le.dcm")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, dicom.TransferSyntaxImplicitVRLittleEndian, f.TransferSyntax())
+	assert.Equal(t, "Knope^Leslie", f.DataSet.String(dicom.TagPatientName))
+	assert.Equal(t, "PN", f.DataSet.Get(dicom.TagPatientName).VR)
+	seq := f.DataSet.Get(referencedImageSequence)
+	if assert.NotNil(t, seq) && assert.Len(t, seq.Items, 1) {
+		assert.Equal(t, "1.2.826.0.1.3680043.2.1125.2.2", seq.Items[0].String(dicom.TagReferencedSOPInstanceUID))
+	}
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7}, f.DataSet.Get(dicom.TagPixelData).Value)
+
+	name := filepath.Join(t.TempDir(), "out.dcm")
+	if !assert.Nil(t, f.WriteFile(name)) {
+		return
+	}
+	roundTrip, err := part10.ParseFile(name)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, f.DataSet, roundTrip.DataSet)
+
+	// Convert to explicit VR big endian and back
+	f.Meta.Set(part10.TagTransferSyntaxUID, "UI", dicom.TransferSyntaxExplicitVRBigEndian)
+	var buf bytes.Buffer
+	if !assert.Nil(t, f.Write(&buf)) {
+		return
+	}
+	bigEndian, err := part10.Parse(&buf)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Knope^Leslie", bigEndian.DataSet.String(dicom.TagPatientName))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := part10.Parse(strings.NewReader("not dicom"))
+	assert.True(t, errors.Is(err, part10.ErrInvalidFile))
+
+	data, _ := os.ReadFile("testdata/ct_jpeg_explicit_le.dcm")
+	_, err = part10.ParseBytes(data[:len(data)-10])
+	assert.True(t, errors.Is(err, part10.ErrUnexpectedEOF))
+}
+
+func TestDeidentify(t *testing.T) {
+	ct, err := part10.ParseFile("testdata/ct_jpeg_explicit_le.dcm")
+	if !assert.Nil(t, err) {
+		return
+	}
+	mr, err := part10.ParseFile("testdata/mr_implicit_le.dcm")
+	if !assert.Nil(t, err) {
+		return
+	}
+	profile := part10.BasicProfile([]byte("domain-secret"))
+	profile.Actions[dicom.TagAccessionNumber] = part10.Dummy
+	profile.Dummies[dicom.TagAccessionNumber] = "ANON-1"
+	// A TIFF header in the preamble naming the patient
+	copy(ct.Preamble[:], "II*\x00Swanson")
+	profile.Apply(ct)
+	profile.Apply(mr)
+
+	assert.Equal(t, make([]byte, len(ct.Preamble)), ct.Preamble[:])
+
+	assert.Nil(t, ct.DataSet.Get(institutionName))
+	assert.Nil(t, ct.DataSet.Get(privateCreator))
+	assert.Nil(t, ct.DataSet.Get(overlayData))
+	assert.NotNil(t, ct.DataSet.Get(dicom.TagPatientName))
+	assert.Equal(t, "", ct.DataSet.String(dicom.TagPatientName))
+	assert.Equal(t, "ANON-1", ct.DataSet.String(dicom.TagAccessionNumber))
+	assert.Equal(t, "CT", ct.DataSet.String(dicom.TagModality))
+	assert.Equal(t, "YES", ct.DataSet.String(0x00120062))
+
+	sopUID := ct.DataSet.String(dicom.TagSOPInstanceUID)
+	assert.True(t, strings.HasPrefix(sopUID, part10.DefaultUIDRoot+"."))
+	assert.LessOrEqual(t, len(sopUID), 64)
+	assert.Equal(t, profile.HashUID("1.2.826.0.1.3680043.2.1125.1.1"), sopUID)
+	assert.Equal(t, sopUID, ct.Meta.String(part10.TagMediaStorageSOPInstanceUID))
+	assert.Equal(t, profile.HashUID("1.2.826.0.1.3680043.2.1125.1.2"),
+		ct.DataSet.Get(referencedImageSequence).Items[0].String(dicom.TagReferencedSOPInstanceUID))
+	// Both files belong to the same study and still do
+	assert.Equal(t, ct.DataSet.String(dicom.TagStudyInstanceUID), mr.DataSet.String(dicom.TagStudyInstanceUID))
+	assert.NotEqual(t, part10.BasicProfile([]byte("other")).HashUID("1.2.3"), profile.HashUID("1.2.3"))
+
+	var buf bytes.Buffer
+	if !assert.Nil(t, ct.Write(&buf)) {
+		return
+	}
+	written, err := part10.Parse(&buf)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, sopUID, written.DataSet.String(dicom.TagSOPInstanceUID))
+	assert.Equal(t, ct.DataSet.Get(dicom.TagPixelData).Fragments, written.DataSet.Get(dicom.TagPixelData).Fragments)
+	assert.NotContains(t, buf.String(), "Swanson")
+}
@@ -0,0 +1,235 @@
+package dicom
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+const (
+	dateLayout = "20060102"
+
+	// DefaultPageSize is the limit used by iterators when SearchOptions.Limit is not set
+	DefaultPageSize = 100
+)
+
+// QIDOService implements the QIDO-RS search transaction on GetQIDOURL()
+type QIDOService struct {
+	client *Client
+}
+
+// DateRange is a DA range match. A zero From or To leaves that side of the range open
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// On returns a range matching a single date
+func On(date time.Time) *DateRange {
+	return &DateRange{From: date, To: date}
+}
+
+// Between returns a range matching dates from and to inclusive
+func Between(from, to time.Time) *DateRange {
+	return &DateRange{From: from, To: to}
+}
+
+// String returns the range in DICOM notation, e.g. 20210101-20211231
+func (r DateRange) String() string {
+	var from, to string
+	if !r.From.IsZero() {
+		from = r.From.Format(dateLayout)
+	}
+	if !r.To.IsZero() {
+		to = r.To.Format(dateLayout)
+	}
+	if from == to {
+		return from
+	}
+	return from + "-" + to
+}
+
+// EncodeValues implements query.Encoder
+func (r DateRange) EncodeValues(key string, v *url.Values) error {
+	if s := r.String(); s != "" && s != "-" {
+		v.Set(key, s)
+	}
+	return nil
+}
+
+// SearchOptions holds the match, include and paging parameters of a QIDO-RS search.
+// String matches support the * and ? wildcards
+type SearchOptions struct {
+	PatientID         string     `url:"PatientID,omitempty"`
+	PatientName       string     `url:"PatientName,omitempty"`
+	StudyInstanceUID  string     `url:"StudyInstanceUID,omitempty"`
+	SeriesInstanceUID string     `url:"SeriesInstanceUID,omitempty"`
+	SOPInstanceUID    string     `url:"SOPInstanceUID,omitempty"`
+	AccessionNumber   string     `url:"AccessionNumber,omitempty"`
+	StudyID           string     `url:"StudyID,omitempty"`
+	StudyDate         *DateRange `url:"StudyDate,omitempty"`
+	// Modality matches series and instances, ModalitiesInStudy matches studies
+	Modality          string `url:"Modality,omitempty"`
+	ModalitiesInStudy string `url:"ModalitiesInStudy,omitempty"`
+	// Match holds additional attribute matches keyed by keyword or tag, e.g. 00081030
+	Match map[string]string `url:"-"`
+	// FuzzyMatching requests fuzzy semantic matching of person names
+	FuzzyMatching bool `url:"fuzzymatching,omitempty"`
+	// IncludeField adds attributes to the results by keyword or tag. Use "all" for every attribute
+	IncludeField []string `url:"includefield,omitempty"`
+	Limit        int      `url:"limit,omitempty"`
+	Offset       int      `url:"offset,omitempty"`
+}
+
+// SearchStudies searches for studies
+func (q *QIDOService) SearchStudies(opt *SearchOptions, options ...OptionFunc) ([]Dataset, *Response, error) {
+	return q.search("SearchStudies", "studies", opt, options...)
+}
+
+// SearchSeries searches for series. When studyUID is set the search is limited to that study
+func (q *QIDOService) SearchSeries(studyUID string, opt *SearchOptions, options ...OptionFunc) ([]Dataset, *Response, error) {
+	path := "series"
+	if studyUID != "" {
+		path = "studies/" + url.PathEscape(studyUID) + "/series"
+	}
+	return q.search("SearchSeries", path, opt, options...)
+}
+
+// SearchInstances searches for instances. The search is limited to a study or series when the
+// respective UIDs are set. seriesUID requires studyUID
+func (q *QIDOService) SearchInstances(studyUID, seriesUID string, opt *SearchOptions, options ...OptionFunc) ([]Dataset, *Response, error) {
+	path := "instances"
+	switch {
+	case studyUID != "" && seriesUID != "":
+		path = "studies/" + url.PathEscape(studyUID) + "/series/" + url.PathEscape(seriesUID) + "/instances"
+	case studyUID != "":
+		path = "studies/" + url.PathEscape(studyUID) + "/instances"
+	case seriesUID != "":
+		return nil, nil, fmt.Errorf("SearchInstances: %w", ErrMissingStudyUID)
+	}
+	return q.search("SearchInstances", path, opt, options...)
+}
+
+func (q *QIDOService) search(name, path string, opt *SearchOptions, options ...OptionFunc) ([]Dataset, *Response, error) {
+	req, err := q.client.newDICOMWebRequest("GET", q.client.GetQIDOURL(), path, nil, nil, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opt != nil {
+		values, err := query.Values(opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range opt.Match {
+			values.Set(key, value)
+		}
+		req.URL.RawQuery = values.Encode()
+	}
+	var results []Dataset
+	resp, err := q.client.do(req, &results)
+	if err == io.EOF {
+		// An empty body is an empty result set
+		err = nil
+	}
+	if err != nil || resp == nil {
+		if resp == nil && err != nil {
+			err = fmt.Errorf("%s: %w", name, ErrEmptyResult)
+		}
+		return nil, resp, err
+	}
+	return results, resp, nil
+}
+
+// Iterator pages through QIDO-RS results using limit and offset
+type Iterator struct {
+	fetch   func(opt *SearchOptions) ([]Dataset, *Response, error)
+	opt     SearchOptions
+	page    []Dataset
+	index   int
+	current Dataset
+	done    bool
+	err     error
+}
+
+// IterateStudies returns an iterator over all studies matching opt
+func (q *QIDOService) IterateStudies(opt *SearchOptions, options ...OptionFunc) *Iterator {
+	return newIterator(opt, func(opt *SearchOptions) ([]Dataset, *Response, error) {
+		return q.SearchStudies(opt, options...)
+	})
+}
+
+// IterateSeries returns an iterator over all series matching opt
+func (q *QIDOService) IterateSeries(studyUID string, opt *SearchOptions, options ...OptionFunc) *Iterator {
+	return newIterator(opt, func(opt *SearchOptions) ([]Dataset, *Response, error) {
+		return q.SearchSeries(studyUID, opt, options...)
+	})
+}
+
+// IterateInstances returns an iterator over all instances matching opt
+func (q *QIDOService) IterateInstances(studyUID, seriesUID string, opt *SearchOptions, options ...OptionFunc) *Iterator {
+	return newIterator(opt, func(opt *SearchOptions) ([]Dataset, *Response, error) {
+		return q.SearchInstances(studyUID, seriesUID, opt, options...)
+	})
+}
+
+func newIterator(opt *SearchOptions, fetch func(opt *SearchOptions) ([]Dataset, *Response, error)) *Iterator {
+	it := &Iterator{fetch: fetch}
+	if opt != nil {
+		it.opt = *opt
+	}
+	if it.opt.Limit <= 0 {
+		it.opt.Limit = DefaultPageSize
+	}
+	return it
+}
+
+// Next advances to the next result, fetching the next page when needed.
+// It returns false when the results are exhausted or an error occurred
+func (it *Iterator) Next() bool {
+	for it.index >= len(it.page) {
+		if it.done || it.err != nil {
+			return false
+		}
+		page, resp, err := it.fetch(&it.opt)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.index = page, 0
+		it.opt.Offset += len(page)
+		if len(page) == 0 || (len(page) < it.opt.Limit && !moreResults(resp)) {
+			it.done = true
+		}
+	}
+	it.current = it.page[it.index]
+	it.index++
+	return true
+}
+
+// Dataset returns the current result
+func (it *Iterator) Dataset() Dataset {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// moreResults reports whether the server truncated the page below the requested limit,
+// which it signals with a 299 warning
+func moreResults(resp *Response) bool {
+	if resp == nil || resp.Response == nil {
+		return false
+	}
+	for _, warning := range resp.Header.Values("Warning") {
+		if strings.Contains(strings.ToLower(warning), "additional results") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package dicom_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/dicom"
+	"github.com/stretchr/testify/assert"
+)
+
+const dicomWebOrgID = "614b0053-7a57-44d8-ba8a-809b9362a9a6"
+
+// dicomWebClient returns a client with an organization so DICOMweb requests can be made
+func dicomWebClient(t *testing.T) *dicom.Client {
+	client, err := dicom.NewClient(iamClient, &dicom.Config{
+		DICOMConfigURL: serverDICOM.URL,
+		OrganizationID: dicomWebOrgID,
+	})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return client
+}
+
+const studies = `[
+  {
+    "00080020": {"vr": "DA", "Value": ["20210304"]},
+    "00080061": {"vr": "CS", "Value": ["CT", "SR"]},
+    "00081190": {"vr": "UR", "Value": ["https://wado.example.com/studies/1.2.3"]},
+    "00100010": {"vr": "PN", "Value": [{"Alphabetic": "Swanson^Ron"}]},
+    "00100020": {"vr": "LO", "Value": ["P-1"]},
+    "0020000D": {"vr": "UI", "Value": ["1.2.3"]},
+    "00201208": {"vr": "IS", "Value": [42]},
+    "00081199": {"vr": "SQ", "Value": [{"00081155": {"vr": "UI", "Value": ["1.2.3.4"]}}]}
+  },
+  {
+    "00100020": {"vr": "LO", "Value": ["P-2"]},
+    "0020000D": {"vr": "UI", "Value": ["1.2.4"]},
+    "00201208": {"vr": "IS", "Value": ["7"]}
+  }
+]`
+
+func TestSearchStudies(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	client := dicomWebClient(t)
+
+	muxDICOM.HandleFunc("/store/dicomweb/"+dicomWebOrgID+"/dicomWeb/studies", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "GET", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		assert.Equal(t, dicom.MediaTypeDICOMJSON, r.Header.Get("Accept"))
+		q := r.URL.Query()
+		assert.Equal(t, "P-*", q.Get("PatientID"))
+		assert.Equal(t, "20210101-20211231", q.Get("StudyDate"))
+		assert.Equal(t, "CT", q.Get("ModalitiesInStudy"))
+		assert.Equal(t, "true", q.Get("fuzzymatching"))
+		assert.Equal(t, []string{"00081030", "StudyID"}, q["includefield"])
+		assert.Equal(t, "Head*", q.Get("StudyDescription"))
+		assert.Equal(t, "10", q.Get("limit"))
+		w.Header().Set("Content-Type", dicom.MediaTypeDICOMJSON)
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, studies)
+	})
+
+	results, resp, err := client.QIDO.SearchStudies(&dicom.SearchOptions{
+		PatientID:         "P-*",
+		StudyDate:         dicom.Between(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)),
+		ModalitiesInStudy: "CT",
+		FuzzyMatching:     true,
+		IncludeField:      []string{dicom.TagStudyDescription.String(), "StudyID"},
+		Match:             map[string]string{"StudyDescription": "Head*"},
+		Limit:             10,
+	})
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) {
+		return
+	}
+	if !assert.Len(t, results, 2) {
+		return
+	}
+	study := results[0]
+	assert.Equal(t, "1.2.3", study.StudyInstanceUID())
+	assert.Equal(t, "P-1", study.PatientID())
+	assert.Equal(t, "Swanson^Ron", study.PatientName())
+	assert.Equal(t, []string{"CT", "SR"}, study.Strings(dicom.TagModalitiesInStudy))
+	assert.Equal(t, "https://wado.example.com/studies/1.2.3", study.RetrieveURL())
+	date, err := study.StudyDate()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), date)
+	count, ok := study.Int(dicom.TagNumberOfStudyRelatedInstances)
+	assert.True(t, ok)
+	assert.Equal(t, 42, count)
+	refs := study.Sequence(dicom.TagReferencedSOPSequence)
+	if assert.Len(t, refs, 1) {
+		assert.Equal(t, "1.2.3.4", refs[0].String(dicom.TagReferencedSOPInstanceUID))
+	}
+	count, _ = results[1].Int(dicom.TagNumberOfStudyRelatedInstances)
+	assert.Equal(t, 7, count)
+
+	data, err := json.Marshal(study)
+	if assert.Nil(t, err) {
+		var roundTrip dicom.Dataset
+		assert.Nil(t, json.Unmarshal(data, &roundTrip))
+		assert.Equal(t, study, roundTrip)
+	}
+}
+
+func TestSearchSeriesAndInstances(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	client := dicomWebClient(t)
+
+	root := "/store/dicomweb/" + dicomWebOrgID + "/dicomWeb/"
+	muxDICOM.HandleFunc(root+"studies/1.2.3/series", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "MR", r.URL.Query().Get("Modality"))
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `[{"0020000E": {"vr": "UI", "Value": ["1.2.3.1"]}, "00080060": {"vr": "CS", "Value": ["MR"]}}]`)
+	})
+	muxDICOM.HandleFunc(root+"studies/1.2.3/series/1.2.3.1/instances", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	series, _, err := client.QIDO.SearchSeries("1.2.3", &dicom.SearchOptions{Modality: "MR"})
+	if assert.Nil(t, err) && assert.Len(t, series, 1) {
+		assert.Equal(t, "1.2.3.1", series[0].SeriesInstanceUID())
+		assert.Equal(t, "MR", series[0].Modality())
+	}
+	instances, resp, err := client.QIDO.SearchInstances("1.2.3", "1.2.3.1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode())
+	assert.Len(t, instances, 0)
+
+	_, _, err = client.QIDO.SearchInstances("", "1.2.3.1", nil)
+	assert.True(t, errors.Is(err, dicom.ErrMissingStudyUID))
+
+	_, _, err = dicomClient.QIDO.SearchStudies(nil)
+	assert.True(t, errors.Is(err, dicom.ErrMissingOrganizationID))
+}
+
+func TestIterateInstances(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	client := dicomWebClient(t)
+
+	total := 7
+	muxDICOM.HandleFunc("/store/dicomweb/"+dicomWebOrgID+"/dicomWeb/instances", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		assert.Equal(t, 3, limit)
+		// The server caps pages at 2 results and signals the truncation
+		var page []dicom.Dataset
+		for i := offset; i < total && len(page) < 2; i++ {
+			ds := dicom.Dataset{}
+			ds.Set(dicom.TagSOPInstanceUID, "UI", "1.2.3."+strconv.Itoa(i))
+			page = append(page, ds)
+		}
+		if offset+len(page) < total {
+			w.Header().Set("Warning", `299 dicom "There are additional results that can be requested"`)
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(page)
+	})
+
+	it := client.QIDO.IterateInstances("", "", &dicom.SearchOptions{Limit: 3})
+	var uids []string
+	for it.Next() {
+		uids = append(uids, it.Dataset().SOPInstanceUID())
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"1.2.3.0", "1.2.3.1", "1.2.3.2", "1.2.3.3", "1.2.3.4", "1.2.3.5", "1.2.3.6"}, uids)
+
+	it = dicomClient.QIDO.IterateStudies(nil)
+	assert.False(t, it.Next())
+	assert.True(t, errors.Is(it.Err(), dicom.ErrMissingOrganizationID))
+}
@@ -0,0 +1,327 @@
+package dicom
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"github.com/philips-software/go-hsdp-api/internal"
+)
+
+const (
+	// DefaultMaxBatchSize is the default upper bound of the instance bytes sent in one STOW-RS request
+	DefaultMaxBatchSize = 64 * 1024 * 1024
+	// DefaultStoreWorkers is the default number of STOW-RS requests sent in parallel
+	DefaultStoreWorkers = 4
+)
+
+// STOWService implements the STOW-RS store transaction on GetSTOWURL()
+type STOWService struct {
+	client *Client
+
+	// MaxBatchSize bounds the instance bytes per request. An instance larger than
+	// MaxBatchSize is sent on its own
+	MaxBatchSize int64
+	// Workers is the number of requests sent in parallel
+	Workers int
+}
+
+// StoreResult is the outcome of storing a single instance
+type StoreResult struct {
+	// Index is the position of the instance in the arguments of Store
+	Index          int
+	SOPClassUID    string
+	SOPInstanceUID string
+	// RetrieveURL is set for stored instances
+	RetrieveURL string
+	// FailureReason is the DICOM failure reason code of rejected instances
+	FailureReason int
+	// WarningReason is the DICOM warning reason code of instances stored with coercion
+	WarningReason int
+	// Err is set when the instance was not stored
+	Err error
+}
+
+// Failed reports whether the instance was not stored and should be retried or inspected
+func (r StoreResult) Failed() bool {
+	return r.Err != nil
+}
+
+type storeInstance struct {
+	index  int
+	reader io.Reader
+	size   int64
+}
+
+// Store uploads DICOM Part 10 instances. Instances are grouped in batches of at most MaxBatchSize
+// bytes which are sent by Workers in parallel. Readers which do not report their size
+// through Len() or io.Seeker are buffered in memory. One result is returned per instance, in
+// argument order. The error wraps ErrStoreFailed when one or more instances were not stored
+func (s *STOWService) Store(ctx context.Context, instances ...io.Reader) ([]StoreResult, error) {
+	results := make([]StoreResult, len(instances))
+	var pending []storeInstance
+	for i, r := range instances {
+		results[i].Index = i
+		instance, classUID, instanceUID, err := prepareInstance(r)
+		results[i].SOPClassUID, results[i].SOPInstanceUID = classUID, instanceUID
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		instance.index = i
+		pending = append(pending, instance)
+	}
+
+	batches := make(chan []storeInstance)
+	workers := s.Workers
+	if workers <= 0 {
+		workers = DefaultStoreWorkers
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				s.storeBatch(ctx, batch, results)
+			}
+		}()
+	}
+	for _, batch := range s.batch(pending) {
+		batches <- batch
+	}
+	close(batches)
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("Store: %d of %d instances: %w", failed, len(instances), ErrStoreFailed)
+	}
+	return results, nil
+}
+
+// batch groups instances in order so that the size of each batch stays within MaxBatchSize
+func (s *STOWService) batch(instances []storeInstance) [][]storeInstance {
+	maxSize := s.MaxBatchSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBatchSize
+	}
+	var batches [][]storeInstance
+	var current []storeInstance
+	var size int64
+	for _, instance := range instances {
+		if len(current) > 0 && size+instance.size > maxSize {
+			batches = append(batches, current)
+			current, size = nil, 0
+		}
+		current = append(current, instance)
+		size += instance.size
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+func (s *STOWService) storeBatch(ctx context.Context, batch []storeInstance, results []StoreResult) {
+	response, err := s.send(ctx, batch)
+	if err != nil {
+		for _, instance := range batch {
+			results[instance.index].Err = err
+		}
+		return
+	}
+	failed := make(map[string]Dataset)
+	for _, item := range response.Sequence(TagFailedSOPSequence) {
+		failed[item.String(TagReferencedSOPInstanceUID)] = item
+	}
+	referenced := make(map[string]Dataset)
+	for _, item := range response.Sequence(TagReferencedSOPSequence) {
+		referenced[item.String(TagReferencedSOPInstanceUID)] = item
+	}
+	for _, instance := range batch {
+		result := &results[instance.index]
+		if item, ok := failed[result.SOPInstanceUID]; ok {
+			result.FailureReason, _ = item.Int(TagFailureReason)
+			result.Err = fmt.Errorf("%w: failure reason 0x%04X", ErrInstanceRejected, result.FailureReason)
+			continue
+		}
+		item, ok := referenced[result.SOPInstanceUID]
+		if !ok {
+			result.Err = ErrMissingStoreResult
+			continue
+		}
+		result.RetrieveURL = item.RetrieveURL()
+		result.WarningReason, _ = item.Int(TagWarningReason)
+	}
+}
+
+// send streams a batch as a multipart/related request and decodes the store response
+func (s *STOWService) send(ctx context.Context, batch []storeInstance) (Dataset, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		for _, instance := range batch {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Type", MediaTypeDICOM)
+			part, err := mw.CreatePart(header)
+			if err == nil {
+				_, err = io.Copy(part, instance.reader)
+			}
+			if err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+		_ = pw.CloseWithError(mw.Close())
+	}()
+	defer func() {
+		_ = pr.Close()
+	}()
+
+	req, err := s.client.newDICOMWebRequest("POST", s.client.GetSTOWURL(), "studies", pr, nil, WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `multipart/related; type="`+MediaTypeDICOM+`"; boundary=`+mw.Boundary())
+	resp, err := s.client.iamClient.HttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	// 409 Conflict reports that all instances failed, with the reasons in the body
+	if resp.StatusCode != http.StatusConflict {
+		if err := internal.CheckResponse(resp); err != nil {
+			return nil, err
+		}
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	response := Dataset{}
+	switch {
+	case len(data) == 0:
+		return response, nil
+	case data[0] == '[':
+		var datasets []Dataset
+		if err := json.Unmarshal(data, &datasets); err != nil {
+			return nil, err
+		}
+		for _, ds := range datasets {
+			for tag, attr := range ds {
+				response[tag] = attr
+			}
+		}
+		return response, nil
+	}
+	err = json.Unmarshal(data, &response)
+	return response, err
+}
+
+// prepareInstance determines the size of the instance and reads its SOP Class and
+// Instance UID from the File Meta Information without consuming the reader
+func prepareInstance(r io.Reader) (storeInstance, string, string, error) {
+	size, ok := readerSize(r)
+	if !ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return storeInstance{}, "", "", err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	header, classUID, instanceUID, err := readMetaUIDs(r)
+	if err != nil {
+		return storeInstance{}, "", "", err
+	}
+	return storeInstance{reader: io.MultiReader(bytes.NewReader(header), r), size: size}, classUID, instanceUID, nil
+}
+
+func readerSize(r io.Reader) (int64, bool) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len()), true
+	case io.Seeker:
+		current, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		if _, err := v.Seek(current, io.SeekStart); err != nil {
+			return 0, false
+		}
+		return end - current, true
+	}
+	return 0, false
+}
+
+// readMetaUIDs reads the preamble and File Meta Information group of a Part 10 stream.
+// It returns the bytes read so they can be replayed, and the media storage SOP UIDs
+func readMetaUIDs(r io.Reader) ([]byte, string, string, error) {
+	// preamble, DICM prefix and the (0002,0000) group length element
+	header := make([]byte, 132+12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, "", "", fmt.Errorf("%w: %v", ErrInvalidInstance, err)
+	}
+	if string(header[128:132]) != "DICM" || binary.LittleEndian.Uint16(header[132:]) != 0x0002 ||
+		binary.LittleEndian.Uint16(header[134:]) != 0x0000 {
+		return nil, "", "", fmt.Errorf("%w: missing File Meta Information", ErrInvalidInstance)
+	}
+	groupLength := binary.LittleEndian.Uint32(header[140:])
+	if groupLength > 64*1024 {
+		return nil, "", "", fmt.Errorf("%w: File Meta Information too large", ErrInvalidInstance)
+	}
+	meta := make([]byte, groupLength)
+	if _, err := io.ReadFull(r, meta); err != nil {
+		return nil, "", "", fmt.Errorf("%w: %v", ErrInvalidInstance, err)
+	}
+	var classUID, instanceUID string
+	for offset := 0; offset+8 <= len(meta); {
+		element := binary.LittleEndian.Uint16(meta[offset+2:])
+		vr := string(meta[offset+4 : offset+6])
+		var length, valueOffset int
+		switch vr {
+		case "OB", "OW", "OF", "SQ", "UT", "UN":
+			if offset+12 > len(meta) {
+				return nil, "", "", fmt.Errorf("%w: truncated File Meta Information", ErrInvalidInstance)
+			}
+			length, valueOffset = int(binary.LittleEndian.Uint32(meta[offset+8:])), offset+12
+		default:
+			length, valueOffset = int(binary.LittleEndian.Uint16(meta[offset+6:])), offset+8
+		}
+		if valueOffset+length > len(meta) {
+			return nil, "", "", fmt.Errorf("%w: truncated File Meta Information", ErrInvalidInstance)
+		}
+		value := strings.TrimRight(string(meta[valueOffset:valueOffset+length]), "\x00 ")
+		switch element {
+		case 0x0002:
+			classUID = value
+		case 0x0003:
+			instanceUID = value
+		}
+		offset = valueOffset + length
+	}
+	if instanceUID == "" {
+		return nil, "", "", fmt.Errorf("%w: missing Media Storage SOP Instance UID", ErrInvalidInstance)
+	}
+	return append(header, meta...), classUID, instanceUID, nil
+}
@@ -0,0 +1,163 @@
+package dicom_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/dicom"
+	"github.com/stretchr/testify/assert"
+)
+
+// part10 returns a minimal Part 10 stream with the given SOP Instance UID and payload size
+func part10(instanceUID string, payload int) []byte {
+	var meta bytes.Buffer
+	element := func(elem uint16, vr, value string) {
+		if len(value)%2 == 1 {
+			value += "\x00"
+		}
+		_ = binary.Write(&meta, binary.LittleEndian, []uint16{0x0002, elem})
+		meta.WriteString(vr)
+		_ = binary.Write(&meta, binary.LittleEndian, uint16(len(value)))
+		meta.WriteString(value)
+	}
+	element(0x0002, "UI", "1.2.840.10008.5.1.4.1.1.2")
+	element(0x0003, "UI", instanceUID)
+	element(0x0010, "UI", dicom.TransferSyntaxExplicitVRLittleEndian)
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 128))
+	buf.WriteString("DICM")
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{0x0002, 0x0000})
+	buf.WriteString("UL")
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(4))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(meta.Len()))
+	buf.Write(meta.Bytes())
+	buf.Write(bytes.Repeat([]byte{0x42}, payload))
+	return buf.Bytes()
+}
+
+func TestStore(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	client := dicomWebClient(t)
+
+	var mu sync.Mutex
+	var batches [][]string
+	muxDICOM.HandleFunc("/store/dicomweb/"+dicomWebOrgID+"/dicomWeb/studies", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "POST", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		assert.Nil(t, err)
+		assert.Equal(t, "multipart/related", mediaType)
+		assert.Equal(t, dicom.MediaTypeDICOM, params["type"])
+
+		var uids []string
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			data, _ := io.ReadAll(part)
+			// the UID sits at a fixed offset in the fixtures
+			uids = append(uids, strings.TrimRight(string(data[132+12+8+26+8:132+12+8+26+8+6]), "\x00"))
+		}
+		mu.Lock()
+		batches = append(batches, uids)
+		mu.Unlock()
+
+		response := dicom.Dataset{}
+		var referenced, failed []interface{}
+		for _, uid := range uids {
+			item := dicom.Dataset{}
+			item.Set(dicom.TagReferencedSOPInstanceUID, "UI", uid)
+			switch uid {
+			case "1.2.2":
+				item.Set(dicom.TagFailureReason, "US", float64(0xA700))
+				failed = append(failed, item)
+			case "1.2.3":
+				// silently dropped by the server
+			default:
+				item.Set(dicom.TagRetrieveURL, "UR", "https://wado/"+uid)
+				referenced = append(referenced, item)
+			}
+		}
+		response.Set(dicom.TagReferencedSOPSequence, "SQ", referenced...)
+		status := http.StatusOK
+		if len(failed) > 0 {
+			response.Set(dicom.TagFailedSOPSequence, "SQ", failed...)
+			status = http.StatusAccepted
+		}
+		w.Header().Set("Content-Type", dicom.MediaTypeDICOMJSON)
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(response)
+	})
+
+	client.STOW.MaxBatchSize = 2500
+	client.STOW.Workers = 2
+	unsized := io.MultiReader(bytes.NewReader(part10("1.2.4", 1000)))
+	results, err := client.STOW.Store(context.Background(),
+		bytes.NewReader(part10("1.2.1", 1000)),
+		bytes.NewReader(part10("1.2.2", 1000)),
+		bytes.NewReader(part10("1.2.3", 1000)),
+		unsized,
+		strings.NewReader("not dicom"),
+	)
+	assert.True(t, errors.Is(err, dicom.ErrStoreFailed))
+	if !assert.Len(t, results, 5) {
+		return
+	}
+	assert.Len(t, batches, 2)
+
+	assert.False(t, results[0].Failed())
+	assert.Equal(t, "1.2.1", results[0].SOPInstanceUID)
+	assert.Equal(t, "1.2.840.10008.5.1.4.1.1.2", results[0].SOPClassUID)
+	assert.Equal(t, "https://wado/1.2.1", results[0].RetrieveURL)
+
+	assert.True(t, errors.Is(results[1].Err, dicom.ErrInstanceRejected))
+	assert.Equal(t, 0xA700, results[1].FailureReason)
+	assert.True(t, errors.Is(results[2].Err, dicom.ErrMissingStoreResult))
+	assert.False(t, results[3].Failed())
+	assert.Equal(t, 3, results[3].Index)
+	assert.True(t, errors.Is(results[4].Err, dicom.ErrInvalidInstance))
+
+	results, err = client.STOW.Store(context.Background(), bytes.NewReader(part10("1.2.1", 10)))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestStoreConflict(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	client := dicomWebClient(t)
+
+	muxDICOM.HandleFunc("/store/dicomweb/"+dicomWebOrgID+"/dicomWeb/studies", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, `{"00081198": {"vr": "SQ", "Value": [{"00081155": {"vr": "UI", "Value": ["1.2.1"]}, "00081197": {"vr": "US", "Value": [272]}}]}}`)
+	})
+
+	results, err := client.STOW.Store(context.Background(), bytes.NewReader(part10("1.2.1", 10)))
+	assert.True(t, errors.Is(err, dicom.ErrStoreFailed))
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, 0x0110, results[0].FailureReason)
+	}
+
+	_, err = dicomClient.STOW.Store(context.Background(), bytes.NewReader(part10("1.2.1", 10)))
+	assert.True(t, errors.Is(err, dicom.ErrStoreFailed))
+}
@@ -0,0 +1,328 @@
+package dicom
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/philips-software/go-hsdp-api/internal"
+)
+
+// Transfer syntax UIDs for Accept negotiation
+const (
+	TransferSyntaxImplicitVRLittleEndian = "1.2.840.10008.1.2"
+	TransferSyntaxExplicitVRLittleEndian = "1.2.840.10008.1.2.1"
+	TransferSyntaxDeflatedExplicitVR     = "1.2.840.10008.1.2.1.99"
+	TransferSyntaxExplicitVRBigEndian    = "1.2.840.10008.1.2.2"
+	TransferSyntaxJPEGBaseline           = "1.2.840.10008.1.2.4.50"
+	TransferSyntaxJPEGLossless           = "1.2.840.10008.1.2.4.70"
+	TransferSyntaxJPEGLSLossless         = "1.2.840.10008.1.2.4.80"
+	TransferSyntaxJPEG2000Lossless       = "1.2.840.10008.1.2.4.90"
+	TransferSyntaxJPEG2000               = "1.2.840.10008.1.2.4.91"
+	TransferSyntaxRLELossless            = "1.2.840.10008.1.2.5"
+	// TransferSyntaxAny accepts the transfer syntax the instances are stored in
+	TransferSyntaxAny = "*"
+)
+
+const (
+	mediaTypeMultipartRelated = "multipart/related"
+	mediaTypeOctetStream      = "application/octet-stream"
+)
+
+// WADOService implements the WADO-RS retrieve transactions on GetWADOURL()
+type WADOService struct {
+	client *Client
+}
+
+// Reference identifies a study, series or instance. SeriesInstanceUID requires
+// StudyInstanceUID and SOPInstanceUID requires SeriesInstanceUID
+type Reference struct {
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	SOPInstanceUID    string
+}
+
+func (r Reference) path() (string, error) {
+	switch {
+	case r.StudyInstanceUID == "":
+		return "", ErrMissingStudyUID
+	case r.SeriesInstanceUID == "" && r.SOPInstanceUID != "":
+		return "", ErrMissingSeriesUID
+	}
+	p := "studies/" + url.PathEscape(r.StudyInstanceUID)
+	if r.SeriesInstanceUID != "" {
+		p += "/series/" + url.PathEscape(r.SeriesInstanceUID)
+	}
+	if r.SOPInstanceUID != "" {
+		p += "/instances/" + url.PathEscape(r.SOPInstanceUID)
+	}
+	return p, nil
+}
+
+// Part is a single body part of a multipart/related WADO-RS response. The part must be
+// consumed by the PartHandler before it returns as the response is streamed
+type Part struct {
+	io.Reader
+	// Index is the zero based position of the part in the response
+	Index           int
+	Header          textproto.MIMEHeader
+	MediaType       string
+	TransferSyntax  string
+	ContentLocation string
+}
+
+// PartHandler is called for each part of a retrieved study, series, instance or frame list
+type PartHandler func(part *Part) error
+
+// WriteTo returns a PartHandler which copies all parts to w in order
+func WriteTo(w io.Writer) PartHandler {
+	return func(part *Part) error {
+		_, err := io.Copy(w, part)
+		return err
+	}
+}
+
+// WriteFiles returns a PartHandler which writes each part to its own file in dir. Files are
+// named after the last segment of the Content-Location of the part or its index otherwise
+func WriteFiles(dir string) PartHandler {
+	return func(part *Part) error {
+		f, err := os.Create(filepath.Join(dir, part.fileName()))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, part)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+func (p *Part) fileName() string {
+	name := ""
+	if p.ContentLocation != "" {
+		if u, err := url.Parse(p.ContentLocation); err == nil {
+			name = path.Base(u.Path)
+		}
+	}
+	if name == "" || name == "." || name == "/" || name == ".." {
+		name = fmt.Sprintf("%05d", p.Index)
+	}
+	switch p.MediaType {
+	case MediaTypeDICOM:
+		name += ".dcm"
+	case "image/jpeg":
+		name += ".jpg"
+	case "image/png":
+		name += ".png"
+	case "image/jp2":
+		name += ".jp2"
+	case mediaTypeOctetStream:
+		name += ".raw"
+	}
+	return name
+}
+
+// WithAccept replaces the Accept header of the request with the given media ranges
+func WithAccept(mediaRanges ...string) OptionFunc {
+	return func(req *http.Request) error {
+		req.Header.Set("Accept", strings.Join(mediaRanges, ", "))
+		return nil
+	}
+}
+
+// WithTransferSyntax negotiates the transfer syntax of retrieved instances or frames. Each of
+// the media ranges already accepted by the request is offered in the given transfer syntaxes,
+// in order of preference
+func WithTransferSyntax(uids ...string) OptionFunc {
+	return func(req *http.Request) error {
+		if len(uids) == 0 {
+			return nil
+		}
+		var ranges []string
+		for _, mediaRange := range strings.Split(req.Header.Get("Accept"), ",") {
+			mediaRange = strings.TrimSpace(mediaRange)
+			if mediaRange == "" {
+				continue
+			}
+			for _, uid := range uids {
+				ranges = append(ranges, mediaRange+"; transfer-syntax="+uid)
+			}
+		}
+		req.Header.Set("Accept", strings.Join(ranges, ", "))
+		return nil
+	}
+}
+
+// Retrieve streams the instances of a study, series or single instance to handler
+func (w *WADOService) Retrieve(ref Reference, handler PartHandler, options ...OptionFunc) (*Response, error) {
+	p, err := ref.path()
+	if err != nil {
+		return nil, fmt.Errorf("Retrieve: %w", err)
+	}
+	return w.retrieve(p, `multipart/related; type="application/dicom"`, handler, options...)
+}
+
+// RetrieveFrames streams the pixel data of the given frames of an instance to handler.
+// Frame numbers start at 1
+func (w *WADOService) RetrieveFrames(ref Reference, frames []int, handler PartHandler, options ...OptionFunc) (*Response, error) {
+	if ref.SOPInstanceUID == "" {
+		return nil, fmt.Errorf("RetrieveFrames: %w", ErrMissingInstanceUID)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("RetrieveFrames: %w", ErrMissingFrames)
+	}
+	p, err := ref.path()
+	if err != nil {
+		return nil, fmt.Errorf("RetrieveFrames: %w", err)
+	}
+	return w.retrieve(p+"/frames/"+frameList(frames), `multipart/related; type="application/octet-stream"`, handler, options...)
+}
+
+// RetrieveMetadata returns the metadata of all instances of a study, series or single instance
+func (w *WADOService) RetrieveMetadata(ref Reference, options ...OptionFunc) ([]Dataset, *Response, error) {
+	p, err := ref.path()
+	if err != nil {
+		return nil, nil, fmt.Errorf("RetrieveMetadata: %w", err)
+	}
+	req, err := w.client.newDICOMWebRequest("GET", w.client.GetWADOURL(), p+"/metadata", nil, nil, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var metadata []Dataset
+	resp, err := w.client.do(req, &metadata)
+	if err != nil || resp == nil {
+		if resp == nil && err != nil {
+			err = fmt.Errorf("RetrieveMetadata: %w", ErrEmptyResult)
+		}
+		return nil, resp, err
+	}
+	return metadata, resp, nil
+}
+
+// RenderedOptions holds the query parameters of a rendered retrieve
+type RenderedOptions struct {
+	// Quality of lossy renditions between 1 and 100
+	Quality int `url:"quality,omitempty"`
+	// Viewport as vw,vh or vw,vh,sx,sy,sw,sh
+	Viewport string `url:"viewport,omitempty"`
+	// Window as center,width,function
+	Window string `url:"window,omitempty"`
+	// Annotation as patient, technique or both
+	Annotation string `url:"annotation,omitempty"`
+}
+
+// RetrieveRendered writes a consumer format rendition of the reference, or of a single frame
+// when frame is larger than 0, to writer. The media type defaults to image/jpeg and can be
+// changed with WithAccept
+func (w *WADOService) RetrieveRendered(ref Reference, frame int, writer io.Writer, opt *RenderedOptions, options ...OptionFunc) (*Response, error) {
+	p, err := ref.path()
+	if err != nil {
+		return nil, fmt.Errorf("RetrieveRendered: %w", err)
+	}
+	if frame > 0 {
+		if ref.SOPInstanceUID == "" {
+			return nil, fmt.Errorf("RetrieveRendered: %w", ErrMissingInstanceUID)
+		}
+		p += "/frames/" + strconv.Itoa(frame)
+	}
+	var params interface{}
+	if opt != nil {
+		params = opt
+	}
+	req, err := w.client.newDICOMWebRequest("GET", w.client.GetWADOURL(), p+"/rendered", nil, params,
+		append([]OptionFunc{WithAccept("image/jpeg")}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	return w.client.do(req, writer)
+}
+
+func frameList(frames []int) string {
+	list := make([]string, len(frames))
+	for i, frame := range frames {
+		list[i] = strconv.Itoa(frame)
+	}
+	return strings.Join(list, ",")
+}
+
+func (w *WADOService) retrieve(p, accept string, handler PartHandler, options ...OptionFunc) (*Response, error) {
+	req, err := w.client.newDICOMWebRequest("GET", w.client.GetWADOURL(), p, nil, nil,
+		append([]OptionFunc{WithAccept(accept)}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.client.iamClient.HttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	response := newResponse(resp)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if err := internal.CheckResponse(resp); err != nil {
+		return response, err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return response, nil
+	}
+	return response, readParts(resp, handler)
+}
+
+// readParts streams the parts of a multipart/related body to handler. A single part body
+// is passed on as is
+func readParts(resp *http.Response, handler PartHandler) error {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMultipart, err)
+	}
+	if mediaType != mediaTypeMultipartRelated {
+		part := &Part{
+			Reader:          resp.Body,
+			Header:          textproto.MIMEHeader(resp.Header),
+			MediaType:       mediaType,
+			TransferSyntax:  params["transfer-syntax"],
+			ContentLocation: resp.Header.Get("Content-Location"),
+		}
+		return handler(part)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return fmt.Errorf("%w: missing boundary", ErrInvalidMultipart)
+	}
+	reader := multipart.NewReader(resp.Body, boundary)
+	for index := 0; ; index++ {
+		raw, err := reader.NextRawPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMultipart, err)
+		}
+		part := &Part{
+			Reader:          raw,
+			Index:           index,
+			Header:          raw.Header,
+			MediaType:       params["type"],
+			ContentLocation: raw.Header.Get("Content-Location"),
+		}
+		if partType, partParams, err := mime.ParseMediaType(raw.Header.Get("Content-Type")); err == nil {
+			part.MediaType = partType
+			part.TransferSyntax = partParams["transfer-syntax"]
+		}
+		err = handler(part)
+		_ = raw.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package dicom_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/dicom"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeParts(t *testing.T, w http.ResponseWriter, partType string, parts map[string]string, order []string) {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", `multipart/related; type="`+partType+`"; boundary=`+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+	for _, location := range order {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", partType+"; transfer-syntax="+dicom.TransferSyntaxExplicitVRLittleEndian)
+		header.Set("Content-Location", location)
+		pw, err := mw.CreatePart(header)
+		if !assert.Nil(t, err) {
+			return
+		}
+		_, _ = io.WriteString(pw, parts[location])
+	}
+	_ = mw.Close()
+}
+
+func TestRetrieve(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	client := dicomWebClient(t)
+
+	root := "/store/dicomweb/" + dicomWebOrgID + "/dicomWeb/"
+	series := root + "studies/1.2.3/series/1.2.3.1"
+	parts := map[string]string{
+		series + "/instances/1.2.3.1.1": "DICM-one",
+		series + "/instances/1.2.3.1.2": "DICM-two",
+	}
+	muxDICOM.HandleFunc(series, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `multipart/related; type="application/dicom"; transfer-syntax=1.2.840.10008.1.2.4.90, `+
+			`multipart/related; type="application/dicom"; transfer-syntax=*`, r.Header.Get("Accept"))
+		writeParts(t, w, dicom.MediaTypeDICOM, parts, []string{series + "/instances/1.2.3.1.1", series + "/instances/1.2.3.1.2"})
+	})
+
+	dir := t.TempDir()
+	var syntaxes []string
+	handler := dicom.WriteFiles(dir)
+	ref := dicom.Reference{StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.1"}
+	_, err := client.WADO.Retrieve(ref, func(part *dicom.Part) error {
+		syntaxes = append(syntaxes, part.TransferSyntax)
+		return handler(part)
+	}, dicom.WithTransferSyntax(dicom.TransferSyntaxJPEG2000Lossless, dicom.TransferSyntaxAny))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{dicom.TransferSyntaxExplicitVRLittleEndian, dicom.TransferSyntaxExplicitVRLittleEndian}, syntaxes)
+	data, err := os.ReadFile(filepath.Join(dir, "1.2.3.1.2.dcm"))
+	assert.Nil(t, err)
+	assert.Equal(t, "DICM-two", string(data))
+
+	var buf bytes.Buffer
+	_, err = client.WADO.Retrieve(ref, dicom.WriteTo(&buf), dicom.WithTransferSyntax(dicom.TransferSyntaxJPEG2000Lossless, dicom.TransferSyntaxAny))
+	assert.Nil(t, err)
+	assert.Equal(t, "DICM-oneDICM-two", buf.String())
+
+	stop := errors.New("stop")
+	count := 0
+	_, err = client.WADO.Retrieve(ref, func(part *dicom.Part) error {
+		count++
+		return stop
+	}, dicom.WithTransferSyntax(dicom.TransferSyntaxJPEG2000Lossless, dicom.TransferSyntaxAny))
+	assert.True(t, errors.Is(err, stop))
+	assert.Equal(t, 1, count)
+
+	_, err = client.WADO.Retrieve(dicom.Reference{StudyInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3.1.1"}, dicom.WriteTo(&buf))
+	assert.True(t, errors.Is(err, dicom.ErrMissingSeriesUID))
+	_, err = client.WADO.Retrieve(dicom.Reference{}, dicom.WriteTo(&buf))
+	assert.True(t, errors.Is(err, dicom.ErrMissingStudyUID))
+}
+
+func TestRetrieveFramesMetadataAndRendered(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	client := dicomWebClient(t)
+
+	instance := "/store/dicomweb/" + dicomWebOrgID + "/dicomWeb/studies/1.2.3/series/1.2.3.1/instances/1.2.3.1.1"
+	muxDICOM.HandleFunc(instance+"/frames/1,3", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `multipart/related; type="application/octet-stream"`, r.Header.Get("Accept"))
+		writeParts(t, w, "application/octet-stream", map[string]string{"1": "frame-1", "3": "frame-3"}, []string{"1", "3"})
+	})
+	muxDICOM.HandleFunc(instance+"/metadata", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, dicom.MediaTypeDICOMJSON, r.Header.Get("Accept"))
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `[{"00080018": {"vr": "UI", "Value": ["1.2.3.1.1"]}, "00280008": {"vr": "IS", "Value": [3]}}]`)
+	})
+	muxDICOM.HandleFunc(instance+"/frames/2/rendered", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "image/png", r.Header.Get("Accept"))
+		assert.Equal(t, "64,64", r.URL.Query().Get("viewport"))
+		w.Header().Set("Content-Type", "image/png")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, "PNG")
+	})
+
+	ref := dicom.Reference{StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.1", SOPInstanceUID: "1.2.3.1.1"}
+	dir := t.TempDir()
+	_, err := client.WADO.RetrieveFrames(ref, []int{1, 3}, dicom.WriteFiles(dir))
+	if !assert.Nil(t, err) {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "3.raw"))
+	assert.Nil(t, err)
+	assert.Equal(t, "frame-3", string(data))
+
+	metadata, _, err := client.WADO.RetrieveMetadata(ref)
+	if assert.Nil(t, err) && assert.Len(t, metadata, 1) {
+		frames, _ := metadata[0].Int(dicom.TagNumberOfFrames)
+		assert.Equal(t, 3, frames)
+	}
+
+	var buf bytes.Buffer
+	_, err = client.WADO.RetrieveRendered(ref, 2, &buf, &dicom.RenderedOptions{Viewport: "64,64"}, dicom.WithAccept("image/png"))
+	assert.Nil(t, err)
+	assert.Equal(t, "PNG", buf.String())
+
+	_, err = client.WADO.RetrieveFrames(ref, nil, dicom.WriteTo(&buf))
+	assert.True(t, errors.Is(err, dicom.ErrMissingFrames))
+	_, err = client.WADO.RetrieveFrames(dicom.Reference{StudyInstanceUID: "1.2.3"}, []int{1}, dicom.WriteTo(&buf))
+	assert.True(t, errors.Is(err, dicom.ErrMissingInstanceUID))
+}
@@ -3,6 +3,7 @@ module github.com/philips-software/go-hsdp-api
 go 1.20
 
 require (
+	github.com/aws/aws-sdk-go-v2 v1.30.3
 	github.com/cenkalti/backoff/v4 v4.3.0
 	github.com/evanphx/json-patch/v5 v5.9.11
 	github.com/go-playground/validator/v10 v10.25.0
@@ -14,10 +15,12 @@ require (
 	github.com/philips-software/go-nih-signer v1.5.0
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/oauth2 v0.28.0
+	google.golang.org/protobuf v1.33.0
 )
 
 require (
 	bitbucket.org/creachadair/stringset v0.0.9 // indirect
+	github.com/aws/smithy-go v1.20.3 // indirect
 	github.com/coder/websocket v1.8.12 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.8 // indirect
@@ -35,6 +38,5 @@ require (
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
-	google.golang.org/protobuf v1.33.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
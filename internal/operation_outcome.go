@@ -6,10 +6,11 @@ type OperationOutcome struct {
 }
 
 type Issue struct {
-	Severity    string  `json:"severity"`
-	Code        string  `json:"code"`
-	Details     Details `json:"details"`
-	Diagnostics string  `json:"diagnostics"`
+	Severity    string   `json:"severity"`
+	Code        string   `json:"code"`
+	Details     Details  `json:"details"`
+	Diagnostics string   `json:"diagnostics"`
+	Expression  []string `json:"expression,omitempty"`
 }
 
 type Details struct {
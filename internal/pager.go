@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Pager pages through search results by following the next links of the result
+// bundles. Fetch returns a page with the bundle it was decoded from. It is called
+// with the options of the search, for pages after the first one followed by an
+// option replacing the query with the query of the next link
+type Pager[T any, O ~func(*http.Request) error] struct {
+	fetch     func(options ...O) ([]T, *Bundle, error)
+	options   []O
+	nextQuery string
+	page      []T
+	index     int
+	current   T
+	done      bool
+	err       error
+}
+
+// NewPager returns a Pager which fetches the pages using fetch
+func NewPager[T any, O ~func(*http.Request) error](fetch func(options ...O) ([]T, *Bundle, error), options ...O) *Pager[T, O] {
+	return &Pager[T, O]{fetch: fetch, options: options}
+}
+
+// Next advances to the next result, fetching the next page when needed.
+// It returns false when the results are exhausted or an error occurred
+func (p *Pager[T, O]) Next() bool {
+	for p.index >= len(p.page) {
+		if p.done || p.err != nil {
+			return false
+		}
+		options := p.options
+		if p.nextQuery != "" {
+			options = append(append([]O{}, p.options...), O(withRawQuery(p.nextQuery)))
+		}
+		page, bundle, err := p.fetch(options...)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.page, p.index = page, 0
+		p.done = true
+		if bundle == nil {
+			continue
+		}
+		if next := bundle.Link.Next(); next != nil {
+			u, err := url.Parse(next.URL)
+			if err != nil {
+				p.err = fmt.Errorf("next link: %w", err)
+				return false
+			}
+			// A next link pointing at the same page would never end
+			if u.RawQuery != "" && u.RawQuery != p.nextQuery && len(page) > 0 {
+				p.nextQuery = u.RawQuery
+				p.done = false
+			}
+		}
+	}
+	p.current = p.page[p.index]
+	p.index++
+	return true
+}
+
+// Current returns the current result
+func (p *Pager[T, O]) Current() T {
+	return p.current
+}
+
+// Err returns the error that stopped the paging, if any
+func (p *Pager[T, O]) Err() error {
+	return p.err
+}
+
+// withRawQuery replaces the query of the request, used to follow next links
+func withRawQuery(rawQuery string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		req.URL.RawQuery = rawQuery
+		return nil
+	}
+}
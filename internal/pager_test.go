@@ -0,0 +1,52 @@
+package internal_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+type optionFunc func(*http.Request) error
+
+func TestPager(t *testing.T) {
+	var queries []string
+	pages := map[string][]int{"": {1, 2}, "page=2": {3}}
+	next := map[string]string{"": "page=2", "page=2": "page=2"}
+	fetch := func(options ...optionFunc) ([]int, *internal.Bundle, error) {
+		req := &http.Request{URL: &url.URL{RawQuery: "page=1"}}
+		for _, option := range options {
+			_ = option(req)
+		}
+		query := req.URL.RawQuery
+		if query == "page=1" {
+			query = ""
+		}
+		queries = append(queries, query)
+		bundle := &internal.Bundle{}
+		if n, ok := next[query]; ok {
+			bundle.Link = internal.BundleLinks{{Relation: "next", URL: "https://example.com/Resource?" + n}}
+		}
+		return pages[query], bundle, nil
+	}
+
+	pager := internal.NewPager(fetch, optionFunc(func(*http.Request) error { return nil }))
+	var results []int
+	for pager.Next() {
+		results = append(results, pager.Current())
+	}
+	assert.Nil(t, pager.Err())
+	assert.Equal(t, []int{1, 2, 3}, results)
+	// The next link of the second page points at itself
+	assert.Equal(t, []string{"", "page=2"}, queries)
+
+	failed := errors.New("failed")
+	pager = internal.NewPager(func(options ...optionFunc) ([]int, *internal.Bundle, error) {
+		return nil, nil, failed
+	})
+	assert.False(t, pager.Next())
+	assert.Equal(t, failed, pager.Err())
+}
@@ -0,0 +1,299 @@
+// Package storetest provides an in-memory resource store for the tests of the
+// service clients. It serves the search, create, read, update and delete calls
+// of resource APIs, so tests that change resources can check the state left behind.
+package storetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Resource is a resource as kept by the store
+type Resource = map[string]interface{}
+
+// Action handles an operation on a resource, such as POST <type>/<id>/$setPolicy
+type Action func(w http.ResponseWriter, r *http.Request, id string)
+
+// Store keeps resources by type and serves them under a base path:
+//
+//	GET    <base>/<type>?<query>  searches
+//	POST   <base>/<type>          creates
+//	GET    <base>/<type>/<id>     reads
+//	PUT    <base>/<type>/<id>     updates
+//	DELETE <base>/<type>/<id>     deletes
+//	*      <base>/<type>/<id>/.../<action>
+type Store struct {
+	// IDField is the field holding the ID of resources, "id" by default
+	IDField string
+	// PageSize limits the resources of a search page, zero returns all of them
+	PageSize int
+	// Matches reports whether a resource matches a search query. By default
+	// query parameters are compared to the fields of the same name, with
+	// references and identifiers compared by ID
+	Matches func(resource Resource, query url.Values) bool
+	// Results encodes the found resources, by default as a FHIR Bundle.
+	// next is the URL of the next page or empty
+	Results func(resources []Resource, next string) interface{}
+	// UpdateStatus is the status of updates, by default 200 OK with the updated
+	// resource. A 204 No Content response has no body
+	UpdateStatus int
+	// Created is called with each created resource, to fill in the fields the server sets
+	Created func(resourceType string, resource Resource)
+	// Read is called with each resource returned by a search or read
+	Read func(resourceType string, resource Resource)
+	// Actions handles operations on resources by the last path element, e.g. "$scopes"
+	Actions map[string]Action
+
+	mu        sync.Mutex
+	base      string
+	resources map[string]map[string]Resource
+	order     map[string][]string
+	nextID    int
+}
+
+// New returns an empty store serving resources under base
+func New(base string) *Store {
+	return &Store{
+		base:      strings.TrimSuffix(base, "/") + "/",
+		resources: map[string]map[string]Resource{},
+		order:     map[string][]string{},
+	}
+}
+
+// Add stores resource, which is anything that marshals to a JSON object, and
+// returns its ID. Resources without an ID get one
+func (s *Store) Add(resourceType string, resource interface{}) string {
+	data, _ := json.Marshal(resource)
+	var r Resource
+	_ = json.Unmarshal(data, &r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(resourceType, r)
+}
+
+// Get decodes the resource with the given ID into v, which must be a pointer.
+// It returns false if there is none
+func (s *Store) Get(resourceType, id string, v interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resource, ok := s.resources[resourceType][id]
+	if !ok {
+		return false
+	}
+	// Start from the zero value, as decoding merges into maps and structs
+	target := reflect.ValueOf(v).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	data, _ := json.Marshal(resource)
+	return json.Unmarshal(data, v) == nil
+}
+
+// IDs returns the IDs of the stored resources of a type in the order they were added
+func (s *Store) IDs(resourceType string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.order[resourceType]...)
+}
+
+// Count returns the number of stored resources of a type
+func (s *Store) Count(resourceType string) int {
+	return len(s.IDs(resourceType))
+}
+
+func (s *Store) idField() string {
+	if s.IDField == "" {
+		return "id"
+	}
+	return s.IDField
+}
+
+func (s *Store) put(resourceType string, resource Resource) string {
+	id, _ := resource[s.idField()].(string)
+	if id == "" {
+		s.nextID++
+		id = "id-" + strconv.Itoa(s.nextID)
+		resource[s.idField()] = id
+	}
+	if s.resources[resourceType] == nil {
+		s.resources[resourceType] = map[string]Resource{}
+	}
+	if _, ok := s.resources[resourceType][id]; !ok {
+		s.order[resourceType] = append(s.order[resourceType], id)
+	}
+	s.resources[resourceType][id] = resource
+	return id
+}
+
+func (s *Store) remove(resourceType, id string) bool {
+	if _, ok := s.resources[resourceType][id]; !ok {
+		return false
+	}
+	delete(s.resources[resourceType], id)
+	var order []string
+	for _, i := range s.order[resourceType] {
+		if i != id {
+			order = append(order, i)
+		}
+	}
+	s.order[resourceType] = order
+	return true
+}
+
+// ServeHTTP serves the calls of the resources under the base path of the store
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, s.base), "/")
+	if len(parts) > 2 {
+		action, ok := s.Actions[parts[len(parts)-1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		action(w, r, parts[1])
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resourceType := parts[0]
+	status := http.StatusOK
+	var body interface{}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		status, body = http.StatusOK, s.search(r, resourceType)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var resource Resource
+		if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		delete(resource, s.idField())
+		id := s.put(resourceType, resource)
+		if s.Created != nil {
+			s.Created(resourceType, resource)
+		}
+		w.Header().Set("Location", s.base+resourceType+"/"+id)
+		status, body = http.StatusCreated, resource
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		resource, ok := s.resources[resourceType][parts[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if s.Read != nil {
+			s.Read(resourceType, resource)
+		}
+		body = resource
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		var resource Resource
+		if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, ok := s.resources[resourceType][parts[1]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resource[s.idField()] = parts[1]
+		s.put(resourceType, resource)
+		status, body = http.StatusOK, resource
+		if s.UpdateStatus == http.StatusNoContent {
+			status, body = http.StatusNoContent, nil
+		}
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		if !s.remove(resourceType, parts[1]) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		status = http.StatusNoContent
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+func (s *Store) search(r *http.Request, resourceType string) interface{} {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	query.Del("page")
+	matches := s.Matches
+	if matches == nil {
+		matches = s.matchesFields
+	}
+	found := []Resource{}
+	for _, id := range s.order[resourceType] {
+		resource := s.resources[resourceType][id]
+		if matches(resource, query) {
+			if s.Read != nil {
+				s.Read(resourceType, resource)
+			}
+			found = append(found, resource)
+		}
+	}
+	next := ""
+	if s.PageSize > 0 {
+		start, end := page*s.PageSize, (page+1)*s.PageSize
+		if end < len(found) {
+			query.Set("page", strconv.Itoa(page+1))
+			next = "http://" + r.Host + r.URL.Path + "?" + query.Encode()
+		} else {
+			end = len(found)
+		}
+		if start > end {
+			start = end
+		}
+		found = found[start:end]
+	}
+	if s.Results != nil {
+		return s.Results(found, next)
+	}
+	return bundle(found, next)
+}
+
+func (s *Store) matchesFields(resource Resource, query url.Values) bool {
+	for key, values := range query {
+		field := key
+		if key == "_id" {
+			field = s.idField()
+		}
+		if _, ok := resource[field]; !ok {
+			// Search parameters of fields the resource lacks are not checked
+			continue
+		}
+		var value string
+		switch v := resource[field].(type) {
+		case string:
+			value = v
+		case map[string]interface{}:
+			if reference, ok := v["reference"].(string); ok {
+				value = path.Base(reference)
+			} else if identifier, ok := v["value"].(string); ok {
+				value = identifier
+			}
+		}
+		if value != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func bundle(resources []Resource, next string) interface{} {
+	var entries []map[string]interface{}
+	for _, resource := range resources {
+		entries = append(entries, map[string]interface{}{"resource": resource})
+	}
+	bundle := map[string]interface{}{"resourceType": "Bundle", "type": "searchset", "entry": entries}
+	if next != "" {
+		bundle["link"] = []map[string]string{{"relation": "next", "url": next}}
+	}
+	return bundle
+}
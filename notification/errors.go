@@ -8,4 +8,12 @@ import (
 var (
 	ErrNotificationURLCannotBeEmpty = errors.New("base Notification URL cannot be empty")
 	ErrEmptyResult                  = errors.New("empty result")
+	ErrInvalidMessage               = errors.New("invalid notification message")
+	ErrInvalidSignature             = errors.New("invalid message signature")
+	ErrCertificateURLNotAllowed     = errors.New("signing certificate URL not allowed")
+	ErrTopicNotAllowed              = errors.New("topic not allowed")
+	ErrInvalidTopology              = errors.New("invalid topology")
+	ErrSubscriptionNotConfirmed     = errors.New("subscription not confirmed")
+	ErrMessageTooLarge              = errors.New("message too large")
+	ErrOutboxMessageNotFound        = errors.New("outbox message not found")
 )
@@ -0,0 +1,179 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxStatus is the delivery status of an outbox message
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	OutboxDead      OutboxStatus = "dead"
+)
+
+// OutboxMessage is a message stored for publishing. Its ID doubles as idempotency key
+type OutboxMessage struct {
+	ID            string
+	TopicID       string
+	Message       string
+	Status        OutboxStatus
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	DeliveredAt   time.Time
+}
+
+// OutboxStats summarizes the messages of an outbox that are not delivered
+type OutboxStats struct {
+	Pending int
+	Dead    int
+	// OldestPending is the creation time of the oldest pending message
+	OldestPending time.Time
+}
+
+// Outbox stores messages until a Relay publishes them, so they survive failures
+// of the service and of the process
+type Outbox interface {
+	// Enqueue validates and stores a message and returns its ID
+	Enqueue(ctx context.Context, request PublishRequest) (string, error)
+	// Due returns at most limit pending messages due at now, oldest first
+	Due(ctx context.Context, now time.Time, limit int) ([]OutboxMessage, error)
+	// MarkDelivered records the publication of a message
+	MarkDelivered(ctx context.Context, id string, at time.Time) error
+	// MarkFailed records a failed attempt and when to retry
+	MarkFailed(ctx context.Context, id string, failure string, retryAt time.Time) error
+	// MarkDead records a failed attempt after which the message is given up
+	MarkDead(ctx context.Context, id string, failure string) error
+	// Stats returns the number of undelivered messages
+	Stats(ctx context.Context) (OutboxStats, error)
+}
+
+func newOutboxMessage(request PublishRequest, now time.Time) (OutboxMessage, error) {
+	if err := ValidatePublishRequest(request); err != nil {
+		return OutboxMessage{}, err
+	}
+	return OutboxMessage{
+		ID:            uuid.NewString(),
+		TopicID:       request.TopicID,
+		Message:       request.Message,
+		Status:        OutboxPending,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// MemoryOutbox is an Outbox kept in memory. It does not survive restarts and is
+// meant for tests and for processes without a database
+type MemoryOutbox struct {
+	mu       sync.Mutex
+	messages map[string]*OutboxMessage
+	now      func() time.Time
+}
+
+// NewMemoryOutbox returns an empty MemoryOutbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{messages: map[string]*OutboxMessage{}, now: time.Now}
+}
+
+func (o *MemoryOutbox) Enqueue(_ context.Context, request PublishRequest) (string, error) {
+	msg, err := newOutboxMessage(request, o.now())
+	if err != nil {
+		return "", err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages[msg.ID] = &msg
+	return msg.ID, nil
+}
+
+func (o *MemoryOutbox) Due(_ context.Context, now time.Time, limit int) ([]OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var due []OutboxMessage
+	for _, msg := range o.messages {
+		if msg.Status == OutboxPending && !msg.NextAttemptAt.After(now) {
+			due = append(due, *msg)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Message returns a stored message
+func (o *MemoryOutbox) Message(id string) (*OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	msg, ok := o.messages[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrOutboxMessageNotFound)
+	}
+	found := *msg
+	return &found, nil
+}
+
+func (o *MemoryOutbox) update(id string, fn func(msg *OutboxMessage)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	msg, ok := o.messages[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", id, ErrOutboxMessageNotFound)
+	}
+	fn(msg)
+	return nil
+}
+
+func (o *MemoryOutbox) MarkDelivered(_ context.Context, id string, at time.Time) error {
+	return o.update(id, func(msg *OutboxMessage) {
+		msg.Status = OutboxDelivered
+		msg.Attempts++
+		msg.DeliveredAt = at
+	})
+}
+
+func (o *MemoryOutbox) MarkFailed(_ context.Context, id string, failure string, retryAt time.Time) error {
+	return o.update(id, func(msg *OutboxMessage) {
+		msg.Attempts++
+		msg.LastError = failure
+		msg.NextAttemptAt = retryAt
+	})
+}
+
+func (o *MemoryOutbox) MarkDead(_ context.Context, id string, failure string) error {
+	return o.update(id, func(msg *OutboxMessage) {
+		msg.Status = OutboxDead
+		msg.Attempts++
+		msg.LastError = failure
+	})
+}
+
+func (o *MemoryOutbox) Stats(_ context.Context) (OutboxStats, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var stats OutboxStats
+	for _, msg := range o.messages {
+		switch msg.Status {
+		case OutboxPending:
+			stats.Pending++
+			if stats.OldestPending.IsZero() || msg.CreatedAt.Before(stats.OldestPending) {
+				stats.OldestPending = msg.CreatedAt
+			}
+		case OutboxDead:
+			stats.Dead++
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,194 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultOutboxTable = "notification_outbox"
+
+// Execer executes statements. It is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// QuestionPlaceholder renders placeholders as used by MySQL and SQLite
+func QuestionPlaceholder(int) string {
+	return "?"
+}
+
+// DollarPlaceholder renders placeholders as used by PostgreSQL
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// SQLOutboxOptions configures a SQLOutbox
+type SQLOutboxOptions struct {
+	// Table defaults to notification_outbox
+	Table string
+	// Placeholder renders the n-th query argument. Defaults to QuestionPlaceholder
+	Placeholder func(n int) string
+}
+
+// SQLOutbox is an Outbox in a SQL database. Use EnqueueTx to store messages in the
+// transaction that commits the change they announce. Times are stored as Unix milliseconds
+type SQLOutbox struct {
+	db          *sql.DB
+	table       string
+	placeholder func(n int) string
+	now         func() time.Time
+}
+
+// NewSQLOutbox returns an Outbox stored in the table of db
+func NewSQLOutbox(db *sql.DB, options SQLOutboxOptions) *SQLOutbox {
+	if options.Table == "" {
+		options.Table = defaultOutboxTable
+	}
+	if options.Placeholder == nil {
+		options.Placeholder = QuestionPlaceholder
+	}
+	return &SQLOutbox{db: db, table: options.Table, placeholder: options.Placeholder, now: time.Now}
+}
+
+// query replaces the ? placeholders of query and the table name
+func (o *SQLOutbox) query(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range strings.ReplaceAll(query, "{table}", o.table) {
+		if r == '?' {
+			n++
+			b.WriteString(o.placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// CreateTable creates the outbox table when it does not exist
+func (o *SQLOutbox) CreateTable(ctx context.Context) error {
+	_, err := o.db.ExecContext(ctx, o.query(`CREATE TABLE IF NOT EXISTS {table} (
+	id VARCHAR(36) PRIMARY KEY,
+	topic_id VARCHAR(255) NOT NULL,
+	message TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INTEGER NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	next_attempt_at BIGINT NOT NULL,
+	delivered_at BIGINT NOT NULL
+)`))
+	return err
+}
+
+func (o *SQLOutbox) Enqueue(ctx context.Context, request PublishRequest) (string, error) {
+	return o.EnqueueTx(ctx, o.db, request)
+}
+
+// EnqueueTx validates and stores a message through tx, usually a *sql.Tx
+func (o *SQLOutbox) EnqueueTx(ctx context.Context, tx Execer, request PublishRequest) (string, error) {
+	msg, err := newOutboxMessage(request, o.now())
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, o.query(`INSERT INTO {table}
+(id, topic_id, message, status, attempts, last_error, created_at, next_attempt_at, delivered_at)
+VALUES (?, ?, ?, ?, 0, '', ?, ?, 0)`),
+		msg.ID, msg.TopicID, msg.Message, string(msg.Status), toMillis(msg.CreatedAt), toMillis(msg.NextAttemptAt))
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+func (o *SQLOutbox) Due(ctx context.Context, now time.Time, limit int) ([]OutboxMessage, error) {
+	rows, err := o.db.QueryContext(ctx, o.query(`SELECT id, topic_id, message, attempts, last_error, created_at, next_attempt_at
+FROM {table} WHERE status = ? AND next_attempt_at <= ? ORDER BY created_at LIMIT `+strconv.Itoa(limit)),
+		string(OutboxPending), toMillis(now))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var due []OutboxMessage
+	for rows.Next() {
+		msg := OutboxMessage{Status: OutboxPending}
+		var createdAt, nextAttemptAt int64
+		if err := rows.Scan(&msg.ID, &msg.TopicID, &msg.Message, &msg.Attempts, &msg.LastError, &createdAt, &nextAttemptAt); err != nil {
+			return nil, err
+		}
+		msg.CreatedAt = fromMillis(createdAt)
+		msg.NextAttemptAt = fromMillis(nextAttemptAt)
+		due = append(due, msg)
+	}
+	return due, rows.Err()
+}
+
+func (o *SQLOutbox) update(ctx context.Context, id, query string, args ...interface{}) error {
+	result, err := o.db.ExecContext(ctx, o.query(query), append(args, id)...)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", id, ErrOutboxMessageNotFound)
+	}
+	return nil
+}
+
+func (o *SQLOutbox) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	return o.update(ctx, id, `UPDATE {table} SET status = ?, attempts = attempts + 1, delivered_at = ? WHERE id = ?`,
+		string(OutboxDelivered), toMillis(at))
+}
+
+func (o *SQLOutbox) MarkFailed(ctx context.Context, id string, failure string, retryAt time.Time) error {
+	return o.update(ctx, id, `UPDATE {table} SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		failure, toMillis(retryAt))
+}
+
+func (o *SQLOutbox) MarkDead(ctx context.Context, id string, failure string) error {
+	return o.update(ctx, id, `UPDATE {table} SET status = ?, attempts = attempts + 1, last_error = ? WHERE id = ?`,
+		string(OutboxDead), failure)
+}
+
+func (o *SQLOutbox) Stats(ctx context.Context) (OutboxStats, error) {
+	rows, err := o.db.QueryContext(ctx, o.query(`SELECT status, COUNT(*), MIN(created_at) FROM {table} WHERE status <> ? GROUP BY status`),
+		string(OutboxDelivered))
+	if err != nil {
+		return OutboxStats{}, err
+	}
+	defer rows.Close()
+	var stats OutboxStats
+	for rows.Next() {
+		var status string
+		var count int
+		var oldest int64
+		if err := rows.Scan(&status, &count, &oldest); err != nil {
+			return OutboxStats{}, err
+		}
+		switch OutboxStatus(status) {
+		case OutboxPending:
+			stats.Pending = count
+			stats.OldestPending = fromMillis(oldest)
+		case OutboxDead:
+			stats.Dead = count
+		}
+	}
+	return stats, rows.Err()
+}
+
+func toMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
@@ -0,0 +1,264 @@
+package notification_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/notification"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePublisher struct {
+	fail      map[string]int
+	keys      []string
+	published []string
+}
+
+func (p *fakePublisher) Publish(request notification.PublishRequest, options ...notification.OptionFunc) (*notification.PublishResponse, *notification.Response, error) {
+	req, _ := http.NewRequest(http.MethodPost, "https://notification", nil)
+	for _, option := range options {
+		_ = option(req)
+	}
+	p.keys = append(p.keys, req.Header.Get("Idempotency-Key"))
+	if p.fail[request.Message] > 0 {
+		p.fail[request.Message]--
+		return nil, nil, errors.New("service unavailable")
+	}
+	p.published = append(p.published, request.Message)
+	return &notification.PublishResponse{TopicID: request.TopicID}, nil, nil
+}
+
+func TestRelay(t *testing.T) {
+	outbox := notification.NewMemoryOutbox()
+	ctx := context.Background()
+
+	_, err := outbox.Enqueue(ctx, notification.PublishRequest{TopicID: "topic", Message: strings.Repeat("x", notification.MaxMessageSize+1)})
+	assert.True(t, errors.Is(err, notification.ErrMessageTooLarge))
+	_, err = outbox.Enqueue(ctx, notification.PublishRequest{Message: "no topic"})
+	assert.True(t, errors.Is(err, notification.ErrInvalidMessage))
+
+	first, _ := outbox.Enqueue(ctx, notification.PublishRequest{TopicID: "topic", Message: "first"})
+	_, _ = outbox.Enqueue(ctx, notification.PublishRequest{TopicID: "topic", Message: "flaky"})
+	doomed, _ := outbox.Enqueue(ctx, notification.PublishRequest{TopicID: "topic", Message: "doomed"})
+
+	publisher := &fakePublisher{fail: map[string]int{"flaky": 1, "doomed": 100}}
+	var errs []error
+	relay := notification.NewRelay(outbox, publisher, notification.RelayOptions{
+		MaxAttempts: 3,
+		Backoff:     func(int) time.Duration { return 0 },
+		OnError:     func(err error) { errs = append(errs, err) },
+	})
+
+	n, err := relay.Flush(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	metrics := relay.Metrics()
+	assert.Equal(t, uint64(1), metrics.Published)
+	assert.Equal(t, uint64(2), metrics.Failures)
+	assert.Equal(t, 2, metrics.Pending)
+	assert.Greater(t, metrics.Lag, time.Duration(0))
+	assert.Equal(t, "service unavailable", metrics.LastError)
+
+	for i := 0; i < 3; i++ {
+		_, err = relay.Flush(ctx)
+		assert.Nil(t, err)
+	}
+	metrics = relay.Metrics()
+	assert.Equal(t, []string{"first", "flaky"}, publisher.published)
+	assert.Equal(t, uint64(2), metrics.Published)
+	assert.Equal(t, 0, metrics.Pending)
+	assert.Equal(t, 1, metrics.Dead)
+	assert.Equal(t, time.Duration(0), metrics.Lag)
+	assert.Len(t, errs, 4)
+
+	msg, err := outbox.Message(doomed)
+	if assert.Nil(t, err) {
+		assert.Equal(t, notification.OutboxDead, msg.Status)
+		assert.Equal(t, 3, msg.Attempts)
+	}
+	msg, _ = outbox.Message(first)
+	assert.Equal(t, notification.OutboxDelivered, msg.Status)
+	assert.Equal(t, first, publisher.keys[0])
+}
+
+func TestPublishIdempotencyKey(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxNotification.HandleFunc("/core/notification/Publish", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key-1", r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"_id":"published-1","topicId":"topic"}`)
+	})
+	resp, _, err := notificationClient.Publish(notification.PublishRequest{TopicID: "topic", Message: "hi"}, notification.WithIdempotencyKey("key-1"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, "published-1", resp.ID)
+	}
+}
+
+// outboxDriver is a database/sql driver understanding just the statements of SQLOutbox
+type outboxDriver struct {
+	mu      sync.Mutex
+	rows    map[string][]driver.Value
+	queries []string
+}
+
+type outboxConn struct{ d *outboxDriver }
+
+func (d *outboxDriver) Open(string) (driver.Conn, error) { return outboxConn{d}, nil }
+
+func (c outboxConn) Prepare(query string) (driver.Stmt, error) { return outboxStmt{c.d, query}, nil }
+func (c outboxConn) Close() error                              { return nil }
+func (c outboxConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c outboxConn) Commit() error                             { return nil }
+func (c outboxConn) Rollback() error                           { return nil }
+
+type outboxStmt struct {
+	d     *outboxDriver
+	query string
+}
+
+// Columns: id, topic_id, message, status, attempts, last_error, created_at, next_attempt_at, delivered_at
+func (s outboxStmt) Close() error  { return nil }
+func (s outboxStmt) NumInput() int { return -1 }
+
+func (s outboxStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		s.d.rows[args[0].(string)] = []driver.Value{args[0], args[1], args[2], args[3], int64(0), "", args[4], args[5], int64(0)}
+		return driver.RowsAffected(1), nil
+	}
+	row, ok := s.d.rows[args[len(args)-1].(string)]
+	if !ok {
+		return driver.RowsAffected(0), nil
+	}
+	row[4] = row[4].(int64) + 1
+	switch {
+	case strings.Contains(s.query, "delivered_at"):
+		row[3], row[8] = args[0], args[1]
+	case strings.Contains(s.query, "next_attempt_at"):
+		row[5], row[7] = args[0], args[1]
+	default:
+		row[3], row[5] = args[0], args[1]
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s outboxStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+	var all [][]driver.Value
+	for _, row := range s.d.rows {
+		all = append(all, row)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i][6].(int64) < all[j][6].(int64) })
+	rows := &outboxRows{}
+	if strings.HasPrefix(s.query, "SELECT status") {
+		rows.columns = []string{"status", "count", "min"}
+		groups := map[string][]driver.Value{}
+		for _, row := range all {
+			status := row[3].(string)
+			if status == args[0] {
+				continue
+			}
+			if group, ok := groups[status]; ok {
+				group[1] = group[1].(int64) + 1
+				continue
+			}
+			groups[status] = []driver.Value{status, int64(1), row[6]}
+			rows.values = append(rows.values, groups[status])
+		}
+		return rows, nil
+	}
+	rows.columns = []string{"id", "topic_id", "message", "attempts", "last_error", "created_at", "next_attempt_at"}
+	limit, _ := strconv.Atoi(s.query[strings.LastIndex(s.query, " ")+1:])
+	for _, row := range all {
+		if row[3] == args[0] && row[7].(int64) <= args[1].(int64) && len(rows.values) < limit {
+			rows.values = append(rows.values, []driver.Value{row[0], row[1], row[2], row[4], row[5], row[6], row[7]})
+		}
+	}
+	return rows, nil
+}
+
+type outboxRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *outboxRows) Columns() []string { return r.columns }
+func (r *outboxRows) Close() error      { return nil }
+func (r *outboxRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSQLOutbox(t *testing.T) {
+	fake := &outboxDriver{rows: map[string][]driver.Value{}}
+	sql.Register("outbox-test", fake)
+	db, err := sql.Open("outbox-test", "")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+	ctx := context.Background()
+	outbox := notification.NewSQLOutbox(db, notification.SQLOutboxOptions{Placeholder: notification.DollarPlaceholder})
+	assert.Nil(t, outbox.CreateTable(ctx))
+
+	tx, err := db.Begin()
+	if !assert.Nil(t, err) {
+		return
+	}
+	id, err := outbox.EnqueueTx(ctx, tx, notification.PublishRequest{TopicID: "topic", Message: "created"})
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+	_, err = outbox.Enqueue(ctx, notification.PublishRequest{TopicID: "topic", Message: strings.Repeat("x", notification.MaxMessageSize+1)})
+	assert.True(t, errors.Is(err, notification.ErrMessageTooLarge))
+	assert.Contains(t, fake.queries[1], "VALUES ($1, $2, $3, $4, 0, '', $5, $6, 0)")
+
+	due, err := outbox.Due(ctx, time.Now(), 10)
+	if !assert.Nil(t, err) || !assert.Len(t, due, 1) {
+		return
+	}
+	assert.Equal(t, id, due[0].ID)
+	assert.Equal(t, "created", due[0].Message)
+
+	assert.Nil(t, outbox.MarkFailed(ctx, id, "unavailable", time.Now().Add(time.Hour)))
+	due, _ = outbox.Due(ctx, time.Now(), 10)
+	assert.Len(t, due, 0)
+	stats, err := outbox.Stats(ctx)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, stats.Pending)
+		assert.False(t, stats.OldestPending.IsZero())
+	}
+
+	due, _ = outbox.Due(ctx, time.Now().Add(2*time.Hour), 10)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, 1, due[0].Attempts)
+		assert.Equal(t, "unavailable", due[0].LastError)
+	}
+	assert.Nil(t, outbox.MarkDelivered(ctx, id, time.Now()))
+	stats, _ = outbox.Stats(ctx)
+	assert.Equal(t, notification.OutboxStats{}, stats)
+	assert.True(t, errors.Is(outbox.MarkDead(ctx, "missing", "gone"), notification.ErrOutboxMessageNotFound))
+}
@@ -3,8 +3,17 @@ package notification
 import (
 	"fmt"
 	"io"
+	"net/http"
 )
 
+// MaxMessageSize is the maximum size in bytes of a published message
+const MaxMessageSize = 256 * 1024
+
+// Publisher publishes messages. It is implemented by Client
+type Publisher interface {
+	Publish(request PublishRequest, options ...OptionFunc) (*PublishResponse, *Response, error)
+}
+
 type PublishRequest struct {
 	TopicID string `json:"topicId"`
 	Message string `json:"message"`
@@ -16,12 +25,34 @@ type PublishResponse struct {
 	TopicID      string `json:"topicId"`
 }
 
+// WithIdempotencyKey sets the key by which repeated publishes of the same message can be recognized
+func WithIdempotencyKey(key string) OptionFunc {
+	return func(req *http.Request) error {
+		req.Header.Set("Idempotency-Key", key)
+		return nil
+	}
+}
+
+// ValidatePublishRequest checks a request against the limits of the service
+func ValidatePublishRequest(request PublishRequest) error {
+	if request.TopicID == "" {
+		return fmt.Errorf("missing topic ID: %w", ErrInvalidMessage)
+	}
+	if request.Message == "" {
+		return fmt.Errorf("empty message: %w", ErrInvalidMessage)
+	}
+	if len(request.Message) > MaxMessageSize {
+		return fmt.Errorf("message of %d bytes exceeds %d bytes: %w", len(request.Message), MaxMessageSize, ErrMessageTooLarge)
+	}
+	return nil
+}
+
 // Publish publishes a message to a topic
-func (c *Client) Publish(request PublishRequest) (*PublishResponse, *Response, error) {
+func (c *Client) Publish(request PublishRequest, options ...OptionFunc) (*PublishResponse, *Response, error) {
 	if err := c.validate.Struct(request); err != nil {
 		return nil, nil, err
 	}
-	req, err := c.newNotificationRequest("POST", "core/notification/Publish", request, nil)
+	req, err := c.newNotificationRequest("POST", "core/notification/Publish", request, options...)
 	if err != nil {
 		return nil, nil, err
 	}
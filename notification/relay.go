@@ -0,0 +1,173 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRelayBatchSize    = 100
+	defaultRelayPollInterval = time.Second
+	defaultRelayMaxAttempts  = 10
+	defaultRelayMinBackoff   = time.Second
+	defaultRelayMaxBackoff   = 5 * time.Minute
+)
+
+// RelayOptions configures a Relay. Zero values select the defaults
+type RelayOptions struct {
+	// BatchSize is the number of messages published per pass
+	BatchSize int
+	// PollInterval is the pause between passes that found nothing to publish
+	PollInterval time.Duration
+	// MaxAttempts is the number of failed attempts after which a message is dead
+	MaxAttempts int
+	// Backoff returns the delay before the next attempt. Defaults to exponential
+	// backoff from one second up to five minutes
+	Backoff func(attempts int) time.Duration
+	// OnError receives publish and outbox errors
+	OnError func(err error)
+}
+
+// RelayMetrics describes the progress of a Relay
+type RelayMetrics struct {
+	// Published is the number of messages published
+	Published uint64
+	// Failures is the number of failed publish attempts
+	Failures uint64
+	// Pending and Dead count the undelivered messages of the outbox
+	Pending int
+	Dead    int
+	// Lag is the age of the oldest pending message
+	Lag time.Duration
+	// LastError is the most recent publish failure
+	LastError string
+}
+
+// Relay publishes the messages of an Outbox. Each message is published with its ID as
+// idempotency key, so a message published again after a crash can be recognized. Run
+// a single relay per outbox
+type Relay struct {
+	outbox    Outbox
+	publisher Publisher
+	options   RelayOptions
+	now       func() time.Time
+
+	mu      sync.Mutex
+	metrics RelayMetrics
+}
+
+// NewRelay returns a Relay publishing the messages of outbox through publisher, usually a *Client
+func NewRelay(outbox Outbox, publisher Publisher, options RelayOptions) *Relay {
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultRelayBatchSize
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultRelayPollInterval
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaultRelayMaxAttempts
+	}
+	if options.Backoff == nil {
+		options.Backoff = exponentialBackoff
+	}
+	return &Relay{outbox: outbox, publisher: publisher, options: options, now: time.Now}
+}
+
+func exponentialBackoff(attempts int) time.Duration {
+	delay := defaultRelayMinBackoff
+	for i := 1; i < attempts && delay < defaultRelayMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > defaultRelayMaxBackoff {
+		delay = defaultRelayMaxBackoff
+	}
+	return delay
+}
+
+// Run publishes messages until ctx is done
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.Flush(ctx)
+		if err != nil {
+			r.report(err)
+		}
+		if n == r.options.BatchSize && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.options.PollInterval):
+		}
+	}
+}
+
+// Flush makes one pass over the due messages and returns how many it handled
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	now := r.now()
+	due, err := r.outbox.Due(ctx, now, r.options.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: %w", err)
+	}
+	for _, msg := range due {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if err := r.publish(ctx, msg); err != nil {
+			r.report(err)
+		}
+	}
+	stats, err := r.outbox.Stats(ctx)
+	if err != nil {
+		return len(due), fmt.Errorf("outbox: %w", err)
+	}
+	r.mu.Lock()
+	r.metrics.Pending = stats.Pending
+	r.metrics.Dead = stats.Dead
+	r.metrics.Lag = 0
+	if !stats.OldestPending.IsZero() {
+		r.metrics.Lag = r.now().Sub(stats.OldestPending)
+	}
+	r.mu.Unlock()
+	return len(due), nil
+}
+
+func (r *Relay) publish(ctx context.Context, msg OutboxMessage) error {
+	_, _, err := r.publisher.Publish(PublishRequest{TopicID: msg.TopicID, Message: msg.Message}, WithIdempotencyKey(msg.ID))
+	if err == nil {
+		r.mu.Lock()
+		r.metrics.Published++
+		r.mu.Unlock()
+		return r.outbox.MarkDelivered(ctx, msg.ID, r.now())
+	}
+	r.mu.Lock()
+	r.metrics.Failures++
+	r.metrics.LastError = err.Error()
+	r.mu.Unlock()
+	attempts := msg.Attempts + 1
+	if attempts >= r.options.MaxAttempts {
+		if markErr := r.outbox.MarkDead(ctx, msg.ID, err.Error()); markErr != nil {
+			return markErr
+		}
+		return fmt.Errorf("message %s dead after %d attempts: %w", msg.ID, attempts, err)
+	}
+	if markErr := r.outbox.MarkFailed(ctx, msg.ID, err.Error(), r.now().Add(r.options.Backoff(attempts))); markErr != nil {
+		return markErr
+	}
+	return fmt.Errorf("message %s: %w", msg.ID, err)
+}
+
+// Metrics returns the metrics as of the last pass
+func (r *Relay) Metrics() RelayMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.metrics
+}
+
+func (r *Relay) report(err error) {
+	if r.options.OnError != nil {
+		r.options.OnError(err)
+	}
+}
@@ -47,6 +47,37 @@ func (p *SubscriberService) CreateSubscriber(subscriber Subscriber) (*Subscriber
 	return &createdSubscriber, resp, nil
 }
 
+func (p *SubscriberService) UpdateSubscriber(subscriber Subscriber) (*Subscriber, *Response, error) {
+	if err := p.validate.Struct(subscriber); err != nil {
+		return nil, nil, err
+	}
+	req, err := p.client.newNotificationRequest("PUT", "core/notification/Subscriber/"+subscriber.ID, subscriber, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var updateResponse bytes.Buffer
+	resp, err := p.client.do(req, &updateResponse)
+	if (err != nil && err != io.EOF) || resp == nil {
+		if resp == nil && err != nil {
+			err = fmt.Errorf("UpdateSubscriber: %w", ErrEmptyResult)
+		}
+		return nil, resp, err
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return nil, resp, fmt.Errorf("update error: %v", updateResponse)
+	}
+	updated, resp, err := p.GetSubscribers(&GetOptions{
+		ID: &subscriber.ID,
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(updated) != 1 {
+		return nil, resp, fmt.Errorf("failed to retrieve updated Subscriber %s", subscriber.ID)
+	}
+	return &updated[0], resp, nil
+}
+
 func (p *SubscriberService) GetSubscribers(opt *GetOptions, options ...OptionFunc) ([]Subscriber, *Response, error) {
 	var subscribers []Subscriber
 
@@ -0,0 +1,536 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultConfirmPollInterval = 5 * time.Second
+
+// Topology declares the producers, topics, subscribers and subscriptions of an
+// organization. Producers and subscribers are identified by product and service name,
+// topics by name within their producer
+type Topology struct {
+	ManagingOrganizationID string
+	Producers              []ProducerSpec
+	Subscribers            []Subscriber
+}
+
+// ProducerSpec declares a producer and its topics. The producer ID is ignored
+type ProducerSpec struct {
+	Producer Producer
+	Topics   []TopicSpec
+}
+
+// TopicSpec declares a topic and its subscriptions. The topic and producer IDs are ignored
+type TopicSpec struct {
+	Topic         Topic
+	Subscriptions []SubscriptionSpec
+}
+
+// SubscriptionSpec subscribes a subscriber to a topic
+type SubscriptionSpec struct {
+	// Subscriber is the key of the subscriber, see SubscriberKey
+	Subscriber string
+	Endpoint   string
+}
+
+// ReconcileOptions configures Reconcile
+type ReconcileOptions struct {
+	// Prune deletes producers and subscribers of the organization, topics of declared
+	// producers and subscriptions to declared topics that are not in the topology
+	Prune bool
+	// DryRun only reports the changes
+	DryRun bool
+	// ConfirmTimeout waits for subscriptions to be confirmed when set
+	ConfirmTimeout time.Duration
+	// PollInterval is the interval of confirmation checks
+	PollInterval time.Duration
+}
+
+// ChangeAction is the kind of change a reconcile made
+type ChangeAction string
+
+const (
+	ChangeCreate   ChangeAction = "create"
+	ChangeUpdate   ChangeAction = "update"
+	ChangeRecreate ChangeAction = "recreate"
+	ChangeDelete   ChangeAction = "delete"
+)
+
+// Change is a change made by Reconcile. A recreate follows the delete of the
+// previous item and of the items that depend on it
+type Change struct {
+	Action ChangeAction
+	Kind   string
+	Key    string
+	ID     string
+}
+
+// ProducerKey returns the key identifying a producer in a Topology
+func ProducerKey(producer Producer) string {
+	return producer.ProducerProductName + "/" + producer.ProducerServiceName
+}
+
+// SubscriberKey returns the key identifying a subscriber in a Topology
+func SubscriberKey(subscriber Subscriber) string {
+	return subscriber.SubscriberProductName + "/" + subscriber.SubscriberServicename
+}
+
+// Confirmed reports whether the endpoint of the subscription confirmed it
+func (s Subscription) Confirmed() bool {
+	arn := strings.ToLower(strings.ReplaceAll(s.SubscriptionARN, " ", ""))
+	return arn != "" && arn != "pendingconfirmation"
+}
+
+type reconciler struct {
+	client  *Client
+	options ReconcileOptions
+	changes []Change
+
+	subscriptions []Subscription
+	deleted       map[string]bool
+	pending       []string
+}
+
+// Reconcile makes the producers, topics, subscribers and subscriptions match the topology.
+// It creates what is missing, updates changed topics and subscribers and recreates changed
+// producers and subscriptions, which cannot be updated. It returns the changes made
+func (c *Client) Reconcile(ctx context.Context, topology Topology, options ReconcileOptions) ([]Change, error) {
+	if err := validateTopology(topology); err != nil {
+		return nil, err
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultConfirmPollInterval
+	}
+	r := &reconciler{client: c, options: options, deleted: map[string]bool{}}
+	err := r.reconcile(ctx, topology)
+	if err == nil {
+		err = r.waitForConfirmation(ctx)
+	}
+	return r.changes, err
+}
+
+func validateTopology(topology Topology) error {
+	subscribers := map[string]bool{}
+	for _, subscriber := range topology.Subscribers {
+		key := SubscriberKey(subscriber)
+		if subscribers[key] {
+			return fmt.Errorf("duplicate subscriber %q: %w", key, ErrInvalidTopology)
+		}
+		subscribers[key] = true
+	}
+	producers := map[string]bool{}
+	for _, producer := range topology.Producers {
+		key := ProducerKey(producer.Producer)
+		if producers[key] {
+			return fmt.Errorf("duplicate producer %q: %w", key, ErrInvalidTopology)
+		}
+		producers[key] = true
+		topics := map[string]bool{}
+		for _, topic := range producer.Topics {
+			if topics[topic.Topic.Name] {
+				return fmt.Errorf("duplicate topic %q of producer %q: %w", topic.Topic.Name, key, ErrInvalidTopology)
+			}
+			topics[topic.Topic.Name] = true
+			for _, subscription := range topic.Subscriptions {
+				if subscription.Subscriber == "" || subscription.Endpoint == "" {
+					return fmt.Errorf("subscription to topic %q: %w", topic.Topic.Name, ErrInvalidTopology)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func ignoreEmpty[T any](items []T, _ *Response, err error) ([]T, error) {
+	if errors.Is(err, ErrEmptyResult) {
+		return nil, nil
+	}
+	return items, err
+}
+
+// apply records a change and makes it unless this is a dry run
+func (r *reconciler) apply(change Change, fn func() (string, error)) (string, error) {
+	if !r.options.DryRun {
+		id, err := fn()
+		if err != nil {
+			return "", fmt.Errorf("%s %s %q: %w", change.Action, change.Kind, change.Key, err)
+		}
+		if id != "" {
+			change.ID = id
+		}
+	}
+	r.changes = append(r.changes, change)
+	return change.ID, nil
+}
+
+func (r *reconciler) reconcile(ctx context.Context, topology Topology) error {
+	var org *string
+	if topology.ManagingOrganizationID != "" {
+		org = &topology.ManagingOrganizationID
+	}
+	subscribers, err := ignoreEmpty(r.client.Subscriber.GetSubscribers(&GetOptions{ManagedOrganizationID: org}))
+	if err != nil {
+		return err
+	}
+	producers, err := ignoreEmpty(r.client.Producer.GetProducers(&GetOptions{ManagedOrganizationID: org}))
+	if err != nil {
+		return err
+	}
+	r.subscriptions, err = ignoreEmpty(r.client.Subscription.GetSubscriptions(nil))
+	if err != nil {
+		return err
+	}
+
+	subscriberIDs := map[string]string{}
+	existingSubscribers := map[string]Subscriber{}
+	for _, subscriber := range subscribers {
+		subscriberIDs[SubscriberKey(subscriber)] = subscriber.ID
+		existingSubscribers[SubscriberKey(subscriber)] = subscriber
+	}
+	declaredSubscribers := map[string]bool{}
+	for _, want := range topology.Subscribers {
+		if want.ManagingOrganizationID == "" {
+			want.ManagingOrganizationID = topology.ManagingOrganizationID
+		}
+		key := SubscriberKey(want)
+		declaredSubscribers[key] = true
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id, err := r.reconcileSubscriber(key, want, existingSubscribers)
+		if err != nil {
+			return err
+		}
+		subscriberIDs[key] = id
+	}
+
+	existingProducers := map[string]Producer{}
+	for _, producer := range producers {
+		existingProducers[ProducerKey(producer)] = producer
+	}
+	declaredProducers := map[string]bool{}
+	for _, spec := range topology.Producers {
+		if spec.Producer.ManagingOrganizationID == "" {
+			spec.Producer.ManagingOrganizationID = topology.ManagingOrganizationID
+		}
+		key := ProducerKey(spec.Producer)
+		declaredProducers[key] = true
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.reconcileProducer(key, spec, existingProducers, subscriberIDs); err != nil {
+			return err
+		}
+	}
+
+	if !r.options.Prune {
+		return nil
+	}
+	for _, key := range sortedKeys(existingProducers) {
+		if declaredProducers[key] {
+			continue
+		}
+		if err := r.deleteProducer(key, existingProducers[key]); err != nil {
+			return err
+		}
+	}
+	for _, key := range sortedKeys(existingSubscribers) {
+		if declaredSubscribers[key] {
+			continue
+		}
+		subscriber := existingSubscribers[key]
+		for _, subscription := range r.subscriptions {
+			if subscription.SubscriberID == subscriber.ID {
+				if err := r.deleteSubscription(key, subscription); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := r.apply(Change{Action: ChangeDelete, Kind: "Subscriber", Key: key, ID: subscriber.ID}, func() (string, error) {
+			_, _, err := r.client.Subscriber.DeleteSubscriber(subscriber)
+			return "", err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) reconcileSubscriber(key string, want Subscriber, existing map[string]Subscriber) (string, error) {
+	have, ok := existing[key]
+	if !ok {
+		return r.apply(Change{Action: ChangeCreate, Kind: "Subscriber", Key: key}, func() (string, error) {
+			created, _, err := r.client.Subscriber.CreateSubscriber(want)
+			if err != nil {
+				return "", err
+			}
+			return created.ID, nil
+		})
+	}
+	want.ID = have.ID
+	if sameSubscriber(have, want) {
+		return have.ID, nil
+	}
+	return r.apply(Change{Action: ChangeUpdate, Kind: "Subscriber", Key: key, ID: have.ID}, func() (string, error) {
+		_, _, err := r.client.Subscriber.UpdateSubscriber(want)
+		return have.ID, err
+	})
+}
+
+func (r *reconciler) reconcileProducer(key string, spec ProducerSpec, existing map[string]Producer, subscriberIDs map[string]string) error {
+	want := spec.Producer
+	want.ID = ""
+	var topics []Topic
+	have, ok := existing[key]
+	switch {
+	case !ok:
+	case sameProducer(have, want):
+		want.ID = have.ID
+		var err error
+		topics, err = ignoreEmpty(r.client.Topic.GetTopics(&GetOptions{ProducerID: &have.ID}))
+		if err != nil {
+			return err
+		}
+	default:
+		if err := r.deleteProducer(key, have); err != nil {
+			return err
+		}
+	}
+	if want.ID == "" {
+		action := ChangeCreate
+		if ok {
+			action = ChangeRecreate
+		}
+		id, err := r.apply(Change{Action: action, Kind: "Producer", Key: key}, func() (string, error) {
+			created, _, err := r.client.Producer.CreateProducer(want)
+			if err != nil {
+				return "", err
+			}
+			return created.ID, nil
+		})
+		if err != nil {
+			return err
+		}
+		want.ID = id
+	}
+
+	existingTopics := map[string]Topic{}
+	for _, topic := range topics {
+		existingTopics[topic.Name] = topic
+	}
+	for _, topicSpec := range spec.Topics {
+		topicKey := key + "/" + topicSpec.Topic.Name
+		topic, err := r.reconcileTopic(topicKey, want.ID, topicSpec.Topic, existingTopics)
+		if err != nil {
+			return err
+		}
+		delete(existingTopics, topicSpec.Topic.Name)
+		if err := r.reconcileSubscriptions(topicKey, topic, topicSpec.Subscriptions, subscriberIDs); err != nil {
+			return err
+		}
+	}
+	if r.options.Prune {
+		for _, name := range sortedKeys(existingTopics) {
+			if err := r.deleteTopic(key+"/"+name, existingTopics[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) reconcileTopic(key, producerID string, want Topic, existing map[string]Topic) (Topic, error) {
+	want.ProducerID = producerID
+	have, ok := existing[want.Name]
+	if !ok {
+		id, err := r.apply(Change{Action: ChangeCreate, Kind: "Topic", Key: key}, func() (string, error) {
+			created, _, err := r.client.Topic.CreateTopic(want)
+			if err != nil {
+				return "", err
+			}
+			return created.ID, nil
+		})
+		want.ID = id
+		return want, err
+	}
+	want.ID = have.ID
+	if sameTopic(have, want) {
+		return have, nil
+	}
+	_, err := r.apply(Change{Action: ChangeUpdate, Kind: "Topic", Key: key, ID: have.ID}, func() (string, error) {
+		_, _, err := r.client.Topic.UpdateTopic(want)
+		return "", err
+	})
+	return want, err
+}
+
+func (r *reconciler) reconcileSubscriptions(topicKey string, topic Topic, specs []SubscriptionSpec, subscriberIDs map[string]string) error {
+	existing := map[string]Subscription{}
+	if topic.ID != "" {
+		for _, subscription := range r.subscriptions {
+			if subscription.TopicID == topic.ID {
+				existing[subscription.SubscriberID] = subscription
+			}
+		}
+	}
+	for _, spec := range specs {
+		key := topicKey + " -> " + spec.Subscriber
+		subscriberID, ok := subscriberIDs[spec.Subscriber]
+		if !ok {
+			return fmt.Errorf("subscription %q: unknown subscriber: %w", key, ErrInvalidTopology)
+		}
+		action := ChangeCreate
+		if have, ok := existing[subscriberID]; ok && subscriberID != "" {
+			delete(existing, subscriberID)
+			if have.SubscriptionEndpoint == spec.Endpoint {
+				if !have.Confirmed() {
+					r.pending = append(r.pending, have.ID)
+				}
+				continue
+			}
+			if err := r.deleteSubscription(key, have); err != nil {
+				return err
+			}
+			action = ChangeRecreate
+		}
+		id, err := r.apply(Change{Action: action, Kind: "Subscription", Key: key}, func() (string, error) {
+			created, _, err := r.client.Subscription.CreateSubscription(Subscription{
+				TopicID:              topic.ID,
+				SubscriberID:         subscriberID,
+				SubscriptionEndpoint: spec.Endpoint,
+			})
+			if err != nil {
+				return "", err
+			}
+			return created.ID, nil
+		})
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			r.pending = append(r.pending, id)
+		}
+	}
+	if r.options.Prune {
+		for _, subscriberID := range sortedKeys(existing) {
+			subscription := existing[subscriberID]
+			if err := r.deleteSubscription(topicKey+" -> "+subscription.SubscriberID, subscription); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) deleteSubscription(key string, subscription Subscription) error {
+	if r.deleted[subscription.ID] {
+		return nil
+	}
+	r.deleted[subscription.ID] = true
+	_, err := r.apply(Change{Action: ChangeDelete, Kind: "Subscription", Key: key, ID: subscription.ID}, func() (string, error) {
+		_, _, err := r.client.Subscription.DeleteSubscription(subscription)
+		return "", err
+	})
+	return err
+}
+
+func (r *reconciler) deleteTopic(key string, topic Topic) error {
+	for _, subscription := range r.subscriptions {
+		if subscription.TopicID == topic.ID {
+			if err := r.deleteSubscription(key+" -> "+subscription.SubscriberID, subscription); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := r.apply(Change{Action: ChangeDelete, Kind: "Topic", Key: key, ID: topic.ID}, func() (string, error) {
+		_, _, err := r.client.Topic.DeleteTopic(topic)
+		return "", err
+	})
+	return err
+}
+
+func (r *reconciler) deleteProducer(key string, producer Producer) error {
+	topics, err := ignoreEmpty(r.client.Topic.GetTopics(&GetOptions{ProducerID: &producer.ID}))
+	if err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		if err := r.deleteTopic(key+"/"+topic.Name, topic); err != nil {
+			return err
+		}
+	}
+	_, err = r.apply(Change{Action: ChangeDelete, Kind: "Producer", Key: key, ID: producer.ID}, func() (string, error) {
+		_, _, err := r.client.Producer.DeleteProducer(producer)
+		return "", err
+	})
+	return err
+}
+
+func (r *reconciler) waitForConfirmation(ctx context.Context) error {
+	if r.options.DryRun || r.options.ConfirmTimeout <= 0 || len(r.pending) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.options.ConfirmTimeout)
+	defer cancel()
+	ticker := time.NewTicker(r.options.PollInterval)
+	defer ticker.Stop()
+	pending := r.pending
+	for {
+		var unconfirmed []string
+		for _, id := range pending {
+			subscription, _, err := r.client.Subscription.GetSubscription(id)
+			if err != nil || !subscription.Confirmed() {
+				unconfirmed = append(unconfirmed, id)
+			}
+		}
+		if len(unconfirmed) == 0 {
+			return nil
+		}
+		pending = unconfirmed
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("subscriptions %s: %w", strings.Join(pending, ", "), ErrSubscriptionNotConfirmed)
+		case <-ticker.C:
+		}
+	}
+}
+
+func sortedKeys[T any](items map[string]T) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sameSubscriber(have, want Subscriber) bool {
+	have.ResourceType, want.ResourceType = "", ""
+	have.ManagingOrganization, want.ManagingOrganization = "", ""
+	return have == want
+}
+
+func sameProducer(have, want Producer) bool {
+	have.ID, want.ID = "", ""
+	have.ResourceType, want.ResourceType = "", ""
+	have.ManagingOrganization, want.ManagingOrganization = "", ""
+	return have == want
+}
+
+func sameTopic(have, want Topic) bool {
+	have.ResourceType, want.ResourceType = "", ""
+	if len(have.AllowedScopes) == 0 {
+		have.AllowedScopes = nil
+	}
+	if len(want.AllowedScopes) == 0 {
+		want.AllowedScopes = nil
+	}
+	return reflect.DeepEqual(have, want)
+}
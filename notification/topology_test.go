@@ -0,0 +1,153 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/internal/storetest"
+	"github.com/philips-software/go-hsdp-api/notification"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := storetest.New("/core/notification")
+	store.IDField = "_id"
+	store.UpdateStatus = http.StatusNoContent
+	store.Matches = func(item storetest.Resource, query url.Values) bool {
+		filters := map[string]string{
+			"_id":                    query.Get("_id"),
+			"producerId":             query.Get("producerId"),
+			"managingOrganizationId": query.Get("managedOrganizationId"),
+		}
+		for field, value := range filters {
+			if value != "" && item[field] != value {
+				return false
+			}
+		}
+		return true
+	}
+	store.Results = func(items []storetest.Resource, _ string) interface{} {
+		return map[string]interface{}{"total": len(items), "entry": items}
+	}
+	// Subscriptions get confirmed once read back
+	store.Read = func(kind string, item storetest.Resource) {
+		if kind != "Subscription" {
+			return
+		}
+		if item["subscriptionArn"] == nil {
+			item["subscriptionArn"] = "pending confirmation"
+			return
+		}
+		item["subscriptionArn"] = "arn:aws:sns:eu-west-1:123456789012:topic:" + item["_id"].(string)
+	}
+	muxNotification.Handle("/core/notification/", store)
+
+	producerID := store.Add("Producer", map[string]interface{}{
+		"managingOrganizationId": notificationOrgID, "producerProductName": "alerts", "producerServiceName": "engine",
+		"producerServiceInstanceName": "1", "producerServiceBaseUrl": "https://alerts", "producerServicePathUrl": "/engine",
+	})
+	topicID := store.Add("Topic", map[string]interface{}{"name": "critical", "producerId": producerID, "scope": "private"})
+	oldTopicID := store.Add("Topic", map[string]interface{}{"name": "obsolete", "producerId": producerID, "scope": "private"})
+	subscriberID := store.Add("Subscriber", map[string]interface{}{
+		"managingOrganizationId": notificationOrgID, "subscriberProductName": "pager", "subscriberServiceName": "duty",
+		"subscriberServiceBaseUrl": "https://pager", "subscriberServicePathUrl": "/old",
+	})
+	oldSubscriberID := store.Add("Subscriber", map[string]interface{}{
+		"managingOrganizationId": notificationOrgID, "subscriberProductName": "legacy", "subscriberServiceName": "mail",
+		"subscriberServiceBaseUrl": "https://legacy", "subscriberServicePathUrl": "/mail",
+	})
+	oldSubscriptionID := store.Add("Subscription", map[string]interface{}{
+		"topicId": topicID, "subscriberId": subscriberID, "subscriptionEndpoint": "https://pager/old", "subscriptionArn": "arn:old",
+	})
+	store.Add("Subscription", map[string]interface{}{
+		"topicId": oldTopicID, "subscriberId": oldSubscriberID, "subscriptionEndpoint": "https://legacy/mail", "subscriptionArn": "arn:legacy",
+	})
+
+	topology := notification.Topology{
+		ManagingOrganizationID: notificationOrgID,
+		Subscribers: []notification.Subscriber{
+			{SubscriberProductName: "pager", SubscriberServicename: "duty", SubscriberServiceBaseURL: "https://pager", SubscriberServicePathURL: "/duty"},
+			{SubscriberProductName: "audit", SubscriberServicename: "log", SubscriberServiceBaseURL: "https://audit", SubscriberServicePathURL: "/log"},
+		},
+		Producers: []notification.ProducerSpec{{
+			Producer: notification.Producer{
+				ProducerProductName: "alerts", ProducerServiceName: "engine", ProducerServiceInstanceName: "1",
+				ProducerServiceBaseURL: "https://alerts", ProducerServicePathURL: "/engine",
+			},
+			Topics: []notification.TopicSpec{
+				{
+					Topic:         notification.Topic{Name: "critical", Scope: "public"},
+					Subscriptions: []notification.SubscriptionSpec{{Subscriber: "pager/duty", Endpoint: "https://pager/duty"}},
+				},
+				{
+					Topic:         notification.Topic{Name: "info", Scope: "private"},
+					Subscriptions: []notification.SubscriptionSpec{{Subscriber: "audit/log", Endpoint: "https://audit/log"}},
+				},
+			},
+		}},
+	}
+	options := notification.ReconcileOptions{Prune: true, ConfirmTimeout: 5 * time.Second, PollInterval: 10 * time.Millisecond}
+
+	planned, err := notificationClient.Reconcile(context.Background(), topology, notification.ReconcileOptions{Prune: true, DryRun: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 2, store.Count("Topic"))
+
+	changes, err := notificationClient.Reconcile(context.Background(), topology, options)
+	if !assert.Nil(t, err) {
+		return
+	}
+	var summary []string
+	for _, change := range changes {
+		summary = append(summary, string(change.Action)+" "+change.Kind+" "+change.Key)
+	}
+	assert.Equal(t, []string{
+		"update Subscriber pager/duty",
+		"create Subscriber audit/log",
+		"update Topic alerts/engine/critical",
+		"delete Subscription alerts/engine/critical -> pager/duty",
+		"recreate Subscription alerts/engine/critical -> pager/duty",
+		"create Topic alerts/engine/info",
+		"create Subscription alerts/engine/info -> audit/log",
+		"delete Subscription alerts/engine/obsolete -> " + oldSubscriberID,
+		"delete Topic alerts/engine/obsolete",
+		"delete Subscriber legacy/mail",
+	}, summary)
+	assert.Len(t, planned, len(changes))
+
+	var topic, subscriber, gone storetest.Resource
+	if assert.True(t, store.Get("Topic", topicID, &topic)) {
+		assert.Equal(t, "public", topic["scope"])
+	}
+	if assert.True(t, store.Get("Subscriber", subscriberID, &subscriber)) {
+		assert.Equal(t, "/duty", subscriber["subscriberServicePathUrl"])
+	}
+	assert.False(t, store.Get("Subscription", oldSubscriptionID, &gone))
+	assert.False(t, store.Get("Topic", oldTopicID, &gone))
+	assert.False(t, store.Get("Subscriber", oldSubscriberID, &gone))
+	assert.Equal(t, 2, store.Count("Topic"))
+	assert.Equal(t, 2, store.Count("Subscriber"))
+	assert.Equal(t, 2, store.Count("Subscription"))
+	for _, id := range store.IDs("Subscription") {
+		var subscription storetest.Resource
+		store.Get("Subscription", id, &subscription)
+		assert.True(t, strings.HasPrefix(subscription["subscriptionArn"].(string), "arn:aws:sns"))
+	}
+
+	changes, err = notificationClient.Reconcile(context.Background(), topology, options)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	topology.Producers[0].Topics[0].Subscriptions[0].Subscriber = "unknown/subscriber"
+	_, err = notificationClient.Reconcile(context.Background(), topology, options)
+	assert.True(t, errors.Is(err, notification.ErrInvalidTopology))
+}
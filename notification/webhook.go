@@ -0,0 +1,283 @@
+package notification
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypeSubscriptionConfirmation = "SubscriptionConfirmation"
+	TypeNotification             = "Notification"
+	TypeUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+)
+
+const defaultMaxWebhookBodySize = 256 * 1024
+
+// DefaultCertHosts matches the hosts serving the certificates Amazon SNS signs messages with
+var DefaultCertHosts = []*regexp.Regexp{
+	regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`),
+}
+
+// Confirmer confirms subscriptions. It is implemented by SubscriptionService
+type Confirmer interface {
+	ConfirmSubscription(confirm ConfirmRequest) (*Subscription, *Response, error)
+}
+
+// WebhookOptions configures a Webhook
+type WebhookOptions struct {
+	// Endpoint is the subscription endpoint sent when confirming. It defaults to the URL of the request
+	Endpoint string
+	// CertHosts are the allowed hosts of signing certificate URLs. Defaults to DefaultCertHosts
+	CertHosts []*regexp.Regexp
+	// TopicARNs restricts the topics accepted. All topics are accepted when empty
+	TopicARNs []string
+	// HTTPClient downloads signing certificates
+	HTTPClient *http.Client
+	// MaxBodySize limits the size of requests
+	MaxBodySize int64
+	// OnUnsubscribe is called for unsubscribe confirmations
+	OnUnsubscribe func(ctx context.Context, event Event) error
+	// OnError receives the errors of rejected or failed requests
+	OnError func(err error)
+}
+
+// Webhook is an http.Handler for a subscription endpoint. It verifies the signature of
+// events, confirms subscriptions and dispatches notification messages to a handler
+type Webhook struct {
+	confirmer Confirmer
+	handler   func(ctx context.Context, event Event) error
+	options   WebhookOptions
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+// NewWebhook returns a Webhook which decodes notification messages into T before handing
+// them to handler. A string T receives the message as is
+func NewWebhook[T any](confirmer Confirmer, handler func(ctx context.Context, event Event, message T) error, options WebhookOptions) *Webhook {
+	if options.CertHosts == nil {
+		options.CertHosts = DefaultCertHosts
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = defaultMaxWebhookBodySize
+	}
+	return &Webhook{
+		confirmer: confirmer,
+		options:   options,
+		certs:     map[string]*x509.Certificate{},
+		handler: func(ctx context.Context, event Event) error {
+			var message T
+			if s, ok := any(&message).(*string); ok {
+				*s = event.Message
+			} else if err := json.Unmarshal([]byte(event.Message), &message); err != nil {
+				return fmt.Errorf("message %s: %v: %w", event.MessageID, err, ErrInvalidMessage)
+			}
+			return handler(ctx, event, message)
+		},
+	}
+}
+
+// signedEvent holds the fields of an event as received, which are signed verbatim
+type signedEvent struct {
+	Type         string  `json:"Type"`
+	MessageID    string  `json:"MessageId"`
+	Token        string  `json:"Token"`
+	TopicARN     string  `json:"TopicArn"`
+	Message      string  `json:"Message"`
+	Subject      *string `json:"Subject"`
+	SubscribeURL string  `json:"SubscribeURL"`
+	Timestamp    string  `json:"Timestamp"`
+}
+
+func (s signedEvent) stringToSign() string {
+	var b strings.Builder
+	add := func(key, value string) {
+		b.WriteString(key + "\n" + value + "\n")
+	}
+	add("Message", s.Message)
+	add("MessageId", s.MessageID)
+	if s.Type == TypeNotification {
+		if s.Subject != nil {
+			add("Subject", *s.Subject)
+		}
+	} else {
+		add("SubscribeURL", s.SubscribeURL)
+	}
+	add("Timestamp", s.Timestamp)
+	if s.Type != TypeNotification {
+		add("Token", s.Token)
+	}
+	add("TopicArn", s.TopicARN)
+	add("Type", s.Type)
+	return b.String()
+}
+
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	status, err := w.serve(r)
+	if err != nil && w.options.OnError != nil {
+		w.options.OnError(err)
+	}
+	rw.WriteHeader(status)
+}
+
+func (w *Webhook) serve(r *http.Request) (int, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, w.options.MaxBodySize+1))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if int64(len(body)) > w.options.MaxBodySize {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("body exceeds %d bytes: %w", w.options.MaxBodySize, ErrInvalidMessage)
+	}
+	var event Event
+	var signed signedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("%v: %w", err, ErrInvalidMessage)
+	}
+	_ = json.Unmarshal(body, &signed)
+	if err := w.verify(r.Context(), event, signed); err != nil {
+		return http.StatusForbidden, err
+	}
+	if len(w.options.TopicARNs) > 0 && !contains(w.options.TopicARNs, event.TopicARN) {
+		return http.StatusForbidden, fmt.Errorf("topic %q: %w", event.TopicARN, ErrTopicNotAllowed)
+	}
+
+	switch event.Type {
+	case TypeSubscriptionConfirmation:
+		endpoint := w.options.Endpoint
+		if endpoint == "" {
+			endpoint = requestURL(r)
+		}
+		_, _, err := w.confirmer.ConfirmSubscription(ConfirmRequest{
+			Token:    event.Token,
+			TopicARN: event.TopicARN,
+			Endpoint: endpoint,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("confirm subscription to %s: %w", event.TopicARN, err)
+		}
+	case TypeUnsubscribeConfirmation:
+		if w.options.OnUnsubscribe != nil {
+			if err := w.options.OnUnsubscribe(r.Context(), event); err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+	case TypeNotification:
+		if err := w.handler(r.Context(), event); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	default:
+		return http.StatusBadRequest, fmt.Errorf("type %q: %w", event.Type, ErrInvalidMessage)
+	}
+	return http.StatusOK, nil
+}
+
+func (w *Webhook) verify(ctx context.Context, event Event, signed signedEvent) error {
+	var algorithm x509.SignatureAlgorithm
+	switch event.SignatureVersion {
+	case "1":
+		algorithm = x509.SHA1WithRSA
+	case "2":
+		algorithm = x509.SHA256WithRSA
+	default:
+		return fmt.Errorf("signature version %q: %w", event.SignatureVersion, ErrInvalidSignature)
+	}
+	signature, err := base64.StdEncoding.DecodeString(event.Signature)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, ErrInvalidSignature)
+	}
+	cert, err := w.certificate(ctx, event.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	if err := cert.CheckSignature(algorithm, []byte(signed.stringToSign()), signature); err != nil {
+		return fmt.Errorf("message %s: %v: %w", event.MessageID, err, ErrInvalidSignature)
+	}
+	return nil
+}
+
+func (w *Webhook) certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	u, err := url.Parse(certURL)
+	if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Path, ".pem") || !w.allowedHost(u.Host) {
+		return nil, fmt.Errorf("%q: %w", certURL, ErrCertificateURLNotAllowed)
+	}
+	w.mu.Lock()
+	cert, ok := w.certs[certURL]
+	w.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.options.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", certURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: HTTP %d: %w", certURL, resp.StatusCode, ErrInvalidSignature)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, defaultMaxWebhookBodySize))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM certificate: %w", certURL, ErrInvalidSignature)
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v: %w", certURL, err, ErrInvalidSignature)
+	}
+	w.mu.Lock()
+	w.certs[certURL] = cert
+	w.mu.Unlock()
+	return cert, nil
+}
+
+func (w *Webhook) allowedHost(host string) bool {
+	for _, pattern := range w.options.CertHosts {
+		if pattern.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package notification_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/notification"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ notification.Confirmer = (*notification.SubscriptionService)(nil)
+
+type fakeConfirmer struct {
+	confirmed []notification.ConfirmRequest
+}
+
+func (f *fakeConfirmer) ConfirmSubscription(confirm notification.ConfirmRequest) (*notification.Subscription, *notification.Response, error) {
+	f.confirmed = append(f.confirmed, confirm)
+	return &notification.Subscription{}, nil, nil
+}
+
+type signer struct {
+	key     *rsa.PrivateKey
+	certURL string
+}
+
+func (s signer) sign(t *testing.T, fields map[string]string) string {
+	keys := []string{"Message", "MessageId", "Subject", "SubscribeURL", "Timestamp", "Token", "TopicArn", "Type"}
+	var b strings.Builder
+	for _, key := range keys {
+		value, ok := fields[key]
+		if !ok || (key == "Token" || key == "SubscribeURL") && fields["Type"] == notification.TypeNotification {
+			continue
+		}
+		b.WriteString(key + "\n" + value + "\n")
+	}
+	hash := sha256.Sum256([]byte(b.String()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	fields["SignatureVersion"] = "2"
+	fields["Signature"] = base64.StdEncoding.EncodeToString(signature)
+	fields["SigningCertURL"] = s.certURL
+	body, _ := json.Marshal(fields)
+	return string(body)
+}
+
+func TestWebhook(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err) {
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if !assert.Nil(t, err) {
+		return
+	}
+	certServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}))
+	defer certServer.Close()
+	certURL, _ := url.Parse(certServer.URL)
+	s := signer{key: key, certURL: certServer.URL + "/SimpleNotificationService-1.pem"}
+
+	type alert struct {
+		Severity string `json:"severity"`
+	}
+	var alerts []alert
+	var unsubscribed []string
+	var errs []error
+	confirmer := &fakeConfirmer{}
+	webhook := notification.NewWebhook(confirmer, func(ctx context.Context, event notification.Event, message alert) error {
+		assert.Equal(t, "Alert", event.Subject)
+		alerts = append(alerts, message)
+		return nil
+	}, notification.WebhookOptions{
+		Endpoint:   "https://example.com/hook",
+		CertHosts:  []*regexp.Regexp{regexp.MustCompile("^" + regexp.QuoteMeta(certURL.Host) + "$")},
+		TopicARNs:  []string{"arn:aws:sns:eu-west-1:123456789012:topic"},
+		HTTPClient: certServer.Client(),
+		OnUnsubscribe: func(ctx context.Context, event notification.Event) error {
+			unsubscribed = append(unsubscribed, event.TopicARN)
+			return nil
+		},
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		webhook.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body)))
+		return rec.Code
+	}
+	event := func(eventType string) map[string]string {
+		fields := map[string]string{
+			"Type":      eventType,
+			"MessageId": "message-1",
+			"TopicArn":  "arn:aws:sns:eu-west-1:123456789012:topic",
+			"Message":   `{"severity":"high"}`,
+			"Timestamp": "2022-04-25T21:49:25.710Z",
+		}
+		if eventType == notification.TypeNotification {
+			fields["Subject"] = "Alert"
+		} else {
+			fields["Token"] = "token-1"
+			fields["SubscribeURL"] = "https://sns.eu-west-1.amazonaws.com/?Action=ConfirmSubscription"
+		}
+		return fields
+	}
+
+	assert.Equal(t, http.StatusOK, post(s.sign(t, event(notification.TypeSubscriptionConfirmation))))
+	if assert.Len(t, confirmer.confirmed, 1) {
+		assert.Equal(t, notification.ConfirmRequest{
+			Token:    "token-1",
+			TopicARN: "arn:aws:sns:eu-west-1:123456789012:topic",
+			Endpoint: "https://example.com/hook",
+		}, confirmer.confirmed[0])
+	}
+
+	assert.Equal(t, http.StatusOK, post(s.sign(t, event(notification.TypeNotification))))
+	assert.Equal(t, []alert{{Severity: "high"}}, alerts)
+
+	assert.Equal(t, http.StatusOK, post(s.sign(t, event(notification.TypeUnsubscribeConfirmation))))
+	assert.Equal(t, []string{"arn:aws:sns:eu-west-1:123456789012:topic"}, unsubscribed)
+	assert.Empty(t, errs)
+
+	tampered := strings.Replace(s.sign(t, event(notification.TypeNotification)), "high", "low", 1)
+	assert.Equal(t, http.StatusForbidden, post(tampered))
+	assert.True(t, errors.Is(errs[len(errs)-1], notification.ErrInvalidSignature))
+
+	foreign := signer{key: key, certURL: "https://attacker.example.com/SimpleNotificationService-1.pem"}
+	assert.Equal(t, http.StatusForbidden, post(foreign.sign(t, event(notification.TypeNotification))))
+	assert.True(t, errors.Is(errs[len(errs)-1], notification.ErrCertificateURLNotAllowed))
+
+	other := event(notification.TypeNotification)
+	other["TopicArn"] = "arn:aws:sns:eu-west-1:123456789012:other"
+	assert.Equal(t, http.StatusForbidden, post(s.sign(t, other)))
+	assert.True(t, errors.Is(errs[len(errs)-1], notification.ErrTopicNotAllowed))
+	assert.Len(t, alerts, 1)
+}
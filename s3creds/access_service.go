@@ -1,7 +1,14 @@
 package s3creds
 
+import (
+	"sync"
+)
+
 type AccessService struct {
 	client *Client
+
+	cacheOnce sync.Once
+	cache     *accessCache
 }
 
 // GetAccessOptions describes the fields on which you can search for policies
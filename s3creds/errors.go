@@ -21,4 +21,5 @@ var (
 	ErrBaseURLCannotBeEmpty           = errors.New("credentials base URL cannot be empty")
 	ErrCouldNoReadResourceAfterCreate = errors.New("could not read resource after create")
 	ErrEmptyResult                    = errors.New("empty result")
+	ErrNoMatchingAccess               = errors.New("no access matching resource")
 )
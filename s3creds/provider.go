@@ -0,0 +1,253 @@
+package s3creds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	// DefaultRefreshWindow is how long before expiry cached access is refreshed
+	DefaultRefreshWindow = 5 * time.Minute
+	// CredentialsSource is the Source of the credentials returned by a CredentialsProvider
+	CredentialsSource = "HSDPS3Credentials"
+)
+
+var expiresLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+}
+
+// ExpiresAt returns the expiry of the credentials. Times without a zone are in UTC
+func (a *Access) ExpiresAt() (time.Time, error) {
+	for _, layout := range expiresLayouts {
+		if t, err := time.Parse(layout, a.Credentials.Expires); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: expires %q", ErrMalformedInputValue, a.Credentials.Expires)
+}
+
+// Prefixes returns the allowed resources with their trailing wildcard removed, for use
+// as key prefixes in the bucket
+func (a *Access) Prefixes() []string {
+	prefixes := make([]string, 0, len(a.Allowed.Resources))
+	for _, resource := range a.Allowed.Resources {
+		prefixes = append(prefixes, strings.TrimSuffix(resource, "*"))
+	}
+	return prefixes
+}
+
+// Allows reports whether the action, e.g. GET, is allowed
+func (a *Access) Allows(action string) bool {
+	for _, allowed := range a.Allowed.Actions {
+		if strings.EqualFold(allowed, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether one of the allowed resources equals the pattern or is a
+// wildcard covering it. An empty pattern is covered by any access
+func (a *Access) covers(pattern string) bool {
+	if pattern == "" {
+		return true
+	}
+	for _, resource := range a.Allowed.Resources {
+		if resource == pattern {
+			return true
+		}
+		if strings.HasSuffix(resource, "*") && strings.HasPrefix(pattern, strings.TrimSuffix(resource, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderOption configures a CredentialsProvider
+type ProviderOption func(*CredentialsProvider)
+
+// WithRefreshWindow sets how long before expiry the access is refreshed
+func WithRefreshWindow(window time.Duration) ProviderOption {
+	return func(p *CredentialsProvider) {
+		p.refreshWindow = window
+	}
+}
+
+// CredentialsProvider is an aws.CredentialsProvider backed by GetAccess. Providers of
+// the same AccessService share their cache, keyed by product key and resource pattern,
+// and concurrent refreshes of a key result in a single request
+type CredentialsProvider struct {
+	access        *AccessService
+	productKey    string
+	resource      string
+	refreshWindow time.Duration
+}
+
+var _ aws.CredentialsProvider = (*CredentialsProvider)(nil)
+
+type accessKey struct {
+	productKey string
+	resource   string
+}
+
+type accessEntry struct {
+	access  *Access
+	expires time.Time
+	// refreshing is closed when the running refresh completes
+	refreshing chan struct{}
+	err        error
+}
+
+type accessCache struct {
+	mu      sync.Mutex
+	entries map[accessKey]*accessEntry
+	now     func() time.Time
+}
+
+// CredentialsProvider returns a provider of credentials for the product key. The
+// resource pattern selects the Access whose allowed resources cover it, e.g.
+// "org-id/backups/*". An empty pattern selects the first Access returned
+func (c *AccessService) CredentialsProvider(productKey, resource string, options ...ProviderOption) *CredentialsProvider {
+	p := &CredentialsProvider{
+		access:        c,
+		productKey:    productKey,
+		resource:      resource,
+		refreshWindow: DefaultRefreshWindow,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+func (c *AccessService) accessCache() *accessCache {
+	c.cacheOnce.Do(func() {
+		c.cache = &accessCache{entries: make(map[accessKey]*accessEntry), now: time.Now}
+	})
+	return c.cache
+}
+
+// Retrieve returns the credentials, refreshing them when they expire within the refresh window
+func (p *CredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	access, expires, err := p.retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	return aws.Credentials{
+		AccessKeyID:     access.Credentials.AccessKey,
+		SecretAccessKey: access.Credentials.SecretKey,
+		SessionToken:    access.Credentials.SessionToken,
+		Source:          CredentialsSource,
+		CanExpire:       true,
+		Expires:         expires,
+	}, nil
+}
+
+// Access returns the cached Access, refreshing it like Retrieve does. It exposes the
+// bucket, allowed prefixes and actions of the credentials
+func (p *CredentialsProvider) Access(ctx context.Context) (*Access, error) {
+	access, _, err := p.retrieve(ctx)
+	return access, err
+}
+
+// Invalidate drops the cached access, e.g. after S3 rejected the credentials
+func (p *CredentialsProvider) Invalidate() {
+	cache := p.access.accessCache()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	key := accessKey{productKey: p.productKey, resource: p.resource}
+	if entry, ok := cache.entries[key]; ok && entry.refreshing == nil {
+		delete(cache.entries, key)
+	}
+}
+
+func (p *CredentialsProvider) retrieve(ctx context.Context) (*Access, time.Time, error) {
+	cache := p.access.accessCache()
+	key := accessKey{productKey: p.productKey, resource: p.resource}
+	for {
+		cache.mu.Lock()
+		entry := cache.entries[key]
+		if entry == nil {
+			entry = &accessEntry{}
+			cache.entries[key] = entry
+		}
+		if entry.refreshing == nil && entry.access != nil && cache.now().Add(p.refreshWindow).Before(entry.expires) {
+			access, expires := entry.access, entry.expires
+			cache.mu.Unlock()
+			return access, expires, nil
+		}
+		if entry.refreshing != nil {
+			// Wait for the refresh of another caller
+			refreshing := entry.refreshing
+			cache.mu.Unlock()
+			select {
+			case <-refreshing:
+			case <-ctx.Done():
+				return nil, time.Time{}, ctx.Err()
+			}
+			cache.mu.Lock()
+			access, expires, err := entry.access, entry.expires, entry.err
+			cache.mu.Unlock()
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			if access != nil {
+				return access, expires, nil
+			}
+			continue
+		}
+		refreshing := make(chan struct{})
+		entry.refreshing = refreshing
+		cache.mu.Unlock()
+
+		access, expires, err := p.fetch(ctx)
+
+		cache.mu.Lock()
+		entry.refreshing = nil
+		switch {
+		case err == nil:
+			entry.access, entry.expires, entry.err = access, expires, nil
+		case entry.access != nil && cache.now().Before(entry.expires):
+			// Keep using the access that is about to expire, the next call retries
+			access, expires, err = entry.access, entry.expires, nil
+			entry.err = nil
+		case ctx.Err() != nil:
+			// The context of this caller ended, waiters retry the refresh with their own
+			entry.access, entry.err = nil, nil
+		default:
+			entry.access, entry.err = nil, err
+		}
+		cache.mu.Unlock()
+		close(refreshing)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return access, expires, nil
+	}
+}
+
+func (p *CredentialsProvider) fetch(ctx context.Context) (*Access, time.Time, error) {
+	productKey := p.productKey
+	accesses, _, err := p.access.GetAccess(&GetAccessOptions{ProductKey: &productKey}, WithContext(ctx))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("s3creds: get access: %w", err)
+	}
+	for _, access := range accesses {
+		if access == nil || !access.covers(p.resource) {
+			continue
+		}
+		expires, err := access.ExpiresAt()
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("s3creds: %w", err)
+		}
+		return access, expires, nil
+	}
+	return nil, time.Time{}, fmt.Errorf("s3creds: resource %q: %w", p.resource, ErrNoMatchingAccess)
+}
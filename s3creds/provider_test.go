@@ -0,0 +1,160 @@
+package s3creds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsProvider(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	productKey := "430deb9e-01c8-4a3b-81dd-e2e46569cd5e"
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var calls int32
+	release := make(chan struct{})
+	var fail atomic.Bool
+
+	muxCreds.HandleFunc("/core/credentials/Access", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			<-release
+		}
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var accesses []Access
+		for _, resource := range []string{"org-1/logs/*", "org-1/backups/*"} {
+			var access Access
+			access.Allowed.Resources = []string{resource}
+			access.Allowed.Actions = []string{"GET", "PUT"}
+			access.Credentials.AccessKey = resource + "-key"
+			access.Credentials.SecretKey = "secret"
+			access.Credentials.SessionToken = "token"
+			access.Credentials.Bucket = "bucket"
+			access.Credentials.Expires = now.Add(time.Hour).Format("2006-01-02T15:04")
+			accesses = append(accesses, access)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(accesses)
+	})
+
+	cache := credsClient.Access.accessCache()
+	cache.now = func() time.Time { return now }
+
+	provider := credsClient.Access.CredentialsProvider(productKey, "org-1/backups/daily/*")
+
+	// Concurrent callers share a single request
+	var wg sync.WaitGroup
+	keys := make([]string, 10)
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			creds, err := provider.Retrieve(context.Background())
+			if assert.Nil(t, err) {
+				keys[i] = creds.AccessKeyID
+			}
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, key := range keys {
+		assert.Equal(t, "org-1/backups/*-key", key)
+	}
+
+	creds, err := provider.Retrieve(context.Background())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, creds.CanExpire)
+	assert.Equal(t, now.Add(time.Hour), creds.Expires)
+	assert.Equal(t, CredentialsSource, creds.Source)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	access, err := provider.Access(context.Background())
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"org-1/backups/"}, access.Prefixes())
+		assert.True(t, access.Allows("put"))
+		assert.False(t, access.Allows("DELETE"))
+		assert.Equal(t, "bucket", access.Credentials.Bucket)
+	}
+
+	// Providers of the same key share the cache
+	_, _ = credsClient.Access.CredentialsProvider(productKey, "org-1/backups/daily/*").Retrieve(context.Background())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Access is refreshed within the refresh window
+	now = now.Add(56 * time.Minute)
+	_, err = provider.Retrieve(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// A failed refresh keeps using access that has not expired yet
+	fail.Store(true)
+	now = now.Add(58 * time.Minute)
+	_, err = provider.Retrieve(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	now = now.Add(2 * time.Minute)
+	_, err = provider.Retrieve(context.Background())
+	assert.NotNil(t, err)
+
+	fail.Store(false)
+	_, err = credsClient.Access.CredentialsProvider(productKey, "org-2/*").Retrieve(context.Background())
+	assert.True(t, errors.Is(err, ErrNoMatchingAccess))
+}
+
+func TestCredentialsProviderCancelledRefresh(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	var calls int32
+	started := make(chan struct{})
+	muxCreds.HandleFunc("/core/credentials/Access", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		var access Access
+		access.Credentials.AccessKey = "key"
+		access.Credentials.Expires = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode([]Access{access})
+	})
+
+	provider := credsClient.Access.CredentialsProvider("product-key", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := provider.Retrieve(ctx)
+		done <- err
+	}()
+	<-started
+	waiter := make(chan error)
+	go func() {
+		_, err := provider.Retrieve(context.Background())
+		waiter <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	assert.NotNil(t, <-done)
+	// The waiter does not get the context error of the refreshing caller
+	assert.Nil(t, <-waiter)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,388 @@
+package s3creds
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/philips-software/go-hsdp-api/iam"
+)
+
+// Resource variables expanded by the Simulator
+const (
+	VariableManagingOrganization = "${managingOrganization}"
+	VariableUsername             = "${username}"
+	VariableUserID               = "${userId}"
+)
+
+// actionGroups lists the S3 actions covered by the composite policy actions
+var actionGroups = map[string][]string{
+	"GET":    {"GetObject", "HeadObject"},
+	"PUT":    {"PutObject", "CreateMultipartUpload", "UploadPart", "CompleteMultipartUpload", "AbortMultipartUpload"},
+	"LIST":   {"ListObjects", "ListObjectsV2", "ListParts", "ListMultipartUploads"},
+	"DELETE": {"DeleteObject", "DeleteObjects"},
+}
+
+// Membership is the membership of a principal in an organization
+type Membership struct {
+	OrganizationID string
+	Groups         []string
+}
+
+// Principal is the identity evaluated by a Simulator
+type Principal struct {
+	Username    string
+	UserID      string
+	Memberships []Membership
+}
+
+// PrincipalFromIntrospect returns the principal of an IAM introspect response
+func PrincipalFromIntrospect(resp *iam.IntrospectResponse) Principal {
+	principal := Principal{Username: resp.Username, UserID: resp.Sub}
+	for _, org := range resp.Organizations.OrganizationList {
+		principal.Memberships = append(principal.Memberships, Membership{
+			OrganizationID: org.OrganizationID,
+			Groups:         org.Groups,
+		})
+	}
+	return principal
+}
+
+// Grant is a policy allowing a request, through a membership of the principal
+type Grant struct {
+	Policy         *Policy
+	OrganizationID string
+	// Resource is the allowed resource pattern with its variables expanded
+	Resource string
+}
+
+// Decision is the outcome of evaluating a request
+type Decision struct {
+	Allowed bool
+	// Grants lists all policies allowing the request, in policy order
+	Grants []Grant
+}
+
+// Simulator evaluates requests against s3creds policies locally, so requests can be
+// checked before credentials are requested. A policy applies to a principal that is
+// a member of one of its groups in one of its managing organizations. It allows its
+// actions on keys matching one of its resources, in which * matches any sequence of
+// characters, including /, and variables are expanded for the principal. The composite
+// actions GET, PUT, LIST, DELETE, ALL_OBJECT and ALL_BUCKET cover the corresponding
+// S3 operations
+type Simulator struct {
+	Policies []Policy
+}
+
+// NewSimulator returns a simulator for the policies, e.g. from PolicyService.GetPolicy
+func NewSimulator(policies []*Policy) *Simulator {
+	s := &Simulator{}
+	for _, p := range policies {
+		if p != nil {
+			s.Policies = append(s.Policies, *p)
+		}
+	}
+	return s
+}
+
+// Evaluate returns whether the principal may perform the action on the resource, a key
+// in the bucket such as "org-id/reports/2024.csv"
+func (s *Simulator) Evaluate(principal Principal, action, resource string) Decision {
+	var decision Decision
+	for i := range s.Policies {
+		policy := &s.Policies[i]
+		if !actionAllowed(policy.Allowed.Actions, action) {
+			continue
+		}
+		for _, membership := range principal.Memberships {
+			if !contains(policy.Conditions.ManagingOrganizations, membership.OrganizationID) ||
+				!intersects(policy.Conditions.Groups, membership.Groups) {
+				continue
+			}
+			if pattern, ok := matchResources(policy.Allowed.Resources, principal, membership.OrganizationID, resource); ok {
+				decision.Allowed = true
+				decision.Grants = append(decision.Grants, Grant{
+					Policy:         policy,
+					OrganizationID: membership.OrganizationID,
+					Resource:       pattern,
+				})
+				break
+			}
+		}
+	}
+	return decision
+}
+
+// FindingKind classifies a lint finding
+type FindingKind string
+
+const (
+	// FindingUnreachable is a policy no principal or request can match
+	FindingUnreachable FindingKind = "unreachable"
+	// FindingRedundant is a policy whose access is fully granted by another policy
+	FindingRedundant FindingKind = "redundant"
+	// FindingOverlap is a pair of policies granting some of the same access
+	FindingOverlap FindingKind = "overlap"
+)
+
+// Finding is a problem found by Lint
+type Finding struct {
+	Kind    FindingKind
+	Policy  *Policy
+	Other   *Policy
+	Message string
+}
+
+// Lint reports policies that cannot match, policies made redundant by another and
+// pairs of policies that partially overlap. Variables in resources are compared literally
+func (s *Simulator) Lint() []Finding {
+	var findings []Finding
+	reachable := make([]bool, len(s.Policies))
+	for i := range s.Policies {
+		policy := &s.Policies[i]
+		var missing []string
+		if len(policy.Conditions.ManagingOrganizations) == 0 {
+			missing = append(missing, "managing organizations")
+		}
+		if len(policy.Conditions.Groups) == 0 {
+			missing = append(missing, "groups")
+		}
+		if len(policy.Allowed.Resources) == 0 {
+			missing = append(missing, "resources")
+		}
+		if len(policy.Allowed.Actions) == 0 {
+			missing = append(missing, "actions")
+		}
+		if len(missing) > 0 {
+			findings = append(findings, Finding{
+				Kind:    FindingUnreachable,
+				Policy:  policy,
+				Message: fmt.Sprintf("policy %d has no %s", policy.ID, strings.Join(missing, " or ")),
+			})
+			continue
+		}
+		reachable[i] = true
+	}
+	redundant := make([]bool, len(s.Policies))
+	for i := range s.Policies {
+		for j := range s.Policies {
+			if i == j || !reachable[i] || !reachable[j] || redundant[j] {
+				continue
+			}
+			// Of two identical policies only the later one is redundant
+			if subsumes(&s.Policies[j], &s.Policies[i]) && (i > j || !subsumes(&s.Policies[i], &s.Policies[j])) {
+				redundant[i] = true
+				findings = append(findings, Finding{
+					Kind:    FindingRedundant,
+					Policy:  &s.Policies[i],
+					Other:   &s.Policies[j],
+					Message: fmt.Sprintf("policy %d grants nothing beyond policy %d", s.Policies[i].ID, s.Policies[j].ID),
+				})
+				break
+			}
+		}
+	}
+	for i := range s.Policies {
+		for j := i + 1; j < len(s.Policies); j++ {
+			if !reachable[i] || !reachable[j] || redundant[i] || redundant[j] {
+				continue
+			}
+			if overlap(&s.Policies[i], &s.Policies[j]) {
+				findings = append(findings, Finding{
+					Kind:    FindingOverlap,
+					Policy:  &s.Policies[i],
+					Other:   &s.Policies[j],
+					Message: fmt.Sprintf("policies %d and %d grant some of the same access", s.Policies[i].ID, s.Policies[j].ID),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// subsumes reports whether policy a grants everything policy b grants
+func subsumes(a, b *Policy) bool {
+	if len(difference(b.Conditions.ManagingOrganizations, a.Conditions.ManagingOrganizations)) > 0 ||
+		len(difference(b.Conditions.Groups, a.Conditions.Groups)) > 0 {
+		return false
+	}
+	for _, action := range b.Allowed.Actions {
+		if !actionAllowed(a.Allowed.Actions, action) {
+			return false
+		}
+	}
+	for _, resource := range b.Allowed.Resources {
+		covered := false
+		for _, pattern := range a.Allowed.Resources {
+			if matchPattern(pattern, resource) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// overlap reports whether a principal and request exist which both policies allow
+func overlap(a, b *Policy) bool {
+	if !intersects(a.Conditions.ManagingOrganizations, b.Conditions.ManagingOrganizations) ||
+		!intersects(a.Conditions.Groups, b.Conditions.Groups) {
+		return false
+	}
+	sharedAction := false
+	for _, action := range b.Allowed.Actions {
+		for _, s3Action := range expandAction(action) {
+			if actionAllowed(a.Allowed.Actions, s3Action) {
+				sharedAction = true
+			}
+		}
+	}
+	if !sharedAction {
+		return false
+	}
+	for _, x := range a.Allowed.Resources {
+		for _, y := range b.Allowed.Resources {
+			if patternsIntersect(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// actionAllowed reports whether the policy actions cover the action, which is either
+// an S3 operation or a composite policy action
+func actionAllowed(allowed []string, action string) bool {
+	for _, a := range allowed {
+		switch {
+		case strings.EqualFold(a, action):
+			return true
+		case a == "ALL_OBJECT" && isObjectAction(action):
+			return true
+		case a == "ALL_BUCKET" && strings.Contains(action, "Bucket"):
+			return true
+		}
+		for _, s3Action := range actionGroups[a] {
+			if s3Action == action {
+				return true
+			}
+		}
+	}
+	// A composite action is allowed when all its operations are
+	if group, ok := actionGroups[action]; ok {
+		for _, s3Action := range group {
+			if !actionAllowed(allowed, s3Action) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isObjectAction(action string) bool {
+	if _, ok := actionGroups[action]; ok {
+		return true
+	}
+	return strings.Contains(action, "Object") || strings.Contains(action, "Multipart") ||
+		action == "UploadPart" || action == "UploadPartCopy" || action == "ListParts"
+}
+
+func expandAction(action string) []string {
+	if group, ok := actionGroups[action]; ok {
+		return group
+	}
+	return []string{action}
+}
+
+// matchResources returns the first resource pattern matching the resource after
+// expanding the variables for the principal in the organization
+func matchResources(patterns []string, principal Principal, organizationID, resource string) (string, bool) {
+	replacer := strings.NewReplacer(
+		VariableManagingOrganization, organizationID,
+		VariableUsername, principal.Username,
+		VariableUserID, principal.UserID,
+	)
+	for _, pattern := range patterns {
+		expanded := replacer.Replace(pattern)
+		if matchPattern(expanded, resource) {
+			return expanded, true
+		}
+	}
+	return "", false
+}
+
+// matchPattern matches name against a pattern in which * matches any sequence of
+// characters. A * in name is matched literally, so matchPattern(a, b) on two patterns
+// reports whether a matches everything b does
+func matchPattern(pattern, name string) bool {
+	px, nx := 0, 0
+	nextPx, nextNx := -1, -1
+	for px < len(pattern) || nx < len(name) {
+		if px < len(pattern) {
+			if pattern[px] == '*' {
+				nextPx, nextNx = px, nx+1
+				px++
+				continue
+			}
+			if nx < len(name) && pattern[px] == name[nx] {
+				px++
+				nx++
+				continue
+			}
+		}
+		if nextPx >= 0 && nextNx <= len(name) {
+			px, nx = nextPx, nextNx
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// patternsIntersect reports whether a name exists matching both patterns
+func patternsIntersect(a, b string) bool {
+	memo := make(map[[2]int]bool)
+	var walk func(i, j int) bool
+	walk = func(i, j int) bool {
+		key := [2]int{i, j}
+		if result, ok := memo[key]; ok {
+			return result
+		}
+		memo[key] = false
+		var result bool
+		switch {
+		case i == len(a) && j == len(b):
+			result = true
+		case i < len(a) && a[i] == '*':
+			result = walk(i+1, j) || (j < len(b) && walk(i, j+1))
+		case j < len(b) && b[j] == '*':
+			result = walk(i, j+1) || (i < len(a) && walk(i+1, j))
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result = walk(i+1, j+1)
+		}
+		memo[key] = result
+		return result
+	}
+	return walk(0, 0)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(a, b []string) bool {
+	for _, v := range a {
+		if contains(b, v) {
+			return true
+		}
+	}
+	return false
+}
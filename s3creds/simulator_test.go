@@ -0,0 +1,93 @@
+package s3creds
+
+import (
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func simulatorPolicy(id int, orgs, groups, resources, actions []string) *Policy {
+	p := &Policy{ID: id}
+	p.Conditions.ManagingOrganizations = orgs
+	p.Conditions.Groups = groups
+	p.Allowed.Resources = resources
+	p.Allowed.Actions = actions
+	return p
+}
+
+func TestSimulatorEvaluate(t *testing.T) {
+	simulator := NewSimulator([]*Policy{
+		simulatorPolicy(1, []string{"org-1", "org-2"}, []string{"readers"},
+			[]string{"${managingOrganization}/reports/*"}, []string{"GET", "LIST"}),
+		simulatorPolicy(2, []string{"org-1"}, []string{"writers"},
+			[]string{"${managingOrganization}/${username}/*"}, []string{"ALL_OBJECT"}),
+	})
+	var introspect iam.IntrospectResponse
+	introspect.Username = "ron"
+	introspect.Organizations.OrganizationList = append(introspect.Organizations.OrganizationList, struct {
+		OrganizationID       string   `json:"organizationId"`
+		Permissions          []string `json:"permissions"`
+		EffectivePermissions []string `json:"effectivePermissions"`
+		OrganizationName     string   `json:"organizationName"`
+		Groups               []string `json:"groups"`
+		Roles                []string `json:"roles"`
+	}{OrganizationID: "org-1", Groups: []string{"readers", "writers"}})
+	principal := PrincipalFromIntrospect(&introspect)
+	principal.Memberships = append(principal.Memberships, Membership{OrganizationID: "org-2", Groups: []string{"readers"}})
+
+	decision := simulator.Evaluate(principal, "GetObject", "org-2/reports/2024/q1.csv")
+	assert.True(t, decision.Allowed)
+	if assert.Len(t, decision.Grants, 1) {
+		assert.Equal(t, 1, decision.Grants[0].Policy.ID)
+		assert.Equal(t, "org-2", decision.Grants[0].OrganizationID)
+		assert.Equal(t, "org-2/reports/*", decision.Grants[0].Resource)
+	}
+	assert.True(t, simulator.Evaluate(principal, "LIST", "org-1/reports/").Allowed)
+	assert.False(t, simulator.Evaluate(principal, "PutObject", "org-2/reports/q1.csv").Allowed)
+	assert.False(t, simulator.Evaluate(principal, "GetObject", "org-3/reports/q1.csv").Allowed)
+
+	decision = simulator.Evaluate(principal, "PUT", "org-1/ron/notes.txt")
+	assert.True(t, decision.Allowed)
+	if assert.Len(t, decision.Grants, 1) {
+		assert.Equal(t, 2, decision.Grants[0].Policy.ID)
+	}
+	assert.False(t, simulator.Evaluate(principal, "PutObject", "org-1/leslie/notes.txt").Allowed)
+	assert.False(t, simulator.Evaluate(principal, "DeleteBucket", "org-1/ron/").Allowed)
+}
+
+func TestSimulatorLint(t *testing.T) {
+	simulator := NewSimulator([]*Policy{
+		simulatorPolicy(1, []string{"org-1"}, []string{"admins", "readers"}, []string{"org-1/*"}, []string{"ALL_OBJECT"}),
+		simulatorPolicy(2, []string{"org-1"}, []string{"readers"}, []string{"org-1/reports/*"}, []string{"GET"}),
+		simulatorPolicy(3, []string{"org-1"}, []string{}, []string{"org-1/*"}, []string{"GET"}),
+		simulatorPolicy(4, []string{"org-1"}, []string{"readers", "auditors"}, []string{"org-1/audit/*", "org-2/*"}, []string{"GET"}),
+		simulatorPolicy(5, []string{"org-1"}, []string{"auditors"}, []string{"org-2/*"}, []string{"GET", "ListBuckets"}),
+		simulatorPolicy(6, []string{"org-1"}, []string{"auditors"}, []string{"org-2/*"}, []string{"GET", "ListBuckets"}),
+	})
+	var result []string
+	for _, finding := range simulator.Lint() {
+		entry := string(finding.Kind) + " " + finding.Policy.StringID()
+		if finding.Other != nil {
+			entry += " " + finding.Other.StringID()
+		}
+		result = append(result, entry)
+	}
+	assert.Equal(t, []string{
+		"unreachable 3",
+		"redundant 2 1",
+		"redundant 6 5",
+		"overlap 1 4",
+		"overlap 4 5",
+	}, result)
+}
+
+func TestPatterns(t *testing.T) {
+	assert.True(t, matchPattern("org/*", "org/a/b"))
+	assert.True(t, matchPattern("org/*/data", "org/a/b/data"))
+	assert.False(t, matchPattern("org/*/data", "org/a/b/date"))
+	assert.True(t, matchPattern("org/*", "org/x/*"))
+	assert.False(t, matchPattern("org/x*", "org/*"))
+	assert.True(t, patternsIntersect("org/*/data", "org/a/*"))
+	assert.False(t, patternsIntersect("org/a/*", "org/b/*"))
+}
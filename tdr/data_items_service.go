@@ -1,9 +1,17 @@
 package tdr
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/philips-software/go-hsdp-api/internal"
 )
@@ -11,13 +19,19 @@ import (
 // DataItemsService provides operations on TDR data items
 type DataItemsService struct {
 	client *Client
+
+	mu      sync.Mutex
+	schemas map[string]*Schema
 }
 
 // GetDataItemOptions describes the fields on which you can search for data items
 type GetDataItemOptions struct {
-	Organization *string `url:"organization,omitempty"`
-	DataType     *string `url:"dataType,omitempty"`
-	Count        *int    `url:"_count,omitempty"`
+	Organization *string  `url:"organization,omitempty"`
+	DataType     *string  `url:"dataType,omitempty"`
+	Device       *string  `url:"device,omitempty"`
+	User         *string  `url:"user,omitempty"`
+	Timestamp    []string `url:"timestamp,omitempty"`
+	Count        *int     `url:"_count,omitempty"`
 }
 
 // KeyValue is backed by a string hash map
@@ -43,11 +57,22 @@ func DataSearch(kv KeyValue) OptionFunc {
 // Use the DataSearch OptionFunc to search in the data part. When using this the
 // DataType must be added as part of the options
 func (d *DataItemsService) GetDataItem(opt *GetDataItemOptions, options ...OptionFunc) ([]*DataItem, *Response, error) {
+	dataItems, bundle, resp, err := d.search(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	if bundle.Total == 0 {
+		return dataItems, resp, ErrEmptyResult
+	}
+	return dataItems, resp, err
+}
+
+func (d *DataItemsService) search(opt *GetDataItemOptions, options ...OptionFunc) ([]*DataItem, *internal.Bundle, *Response, error) {
 	var dataItems []*DataItem
 
 	req, err := d.client.newTDRRequest("GET", "store/tdr/DataItem", opt, options)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	req.Header.Set("Api-Version", APIVersion)
 
@@ -55,18 +80,344 @@ func (d *DataItemsService) GetDataItem(opt *GetDataItemOptions, options ...Optio
 
 	resp, err := d.client.Do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
-	}
-	if bundleResponse.Total == 0 {
-		return dataItems, resp, ErrEmptyResult
+		return nil, nil, resp, err
 	}
 	for _, e := range bundleResponse.Entry {
 		item := new(DataItem)
-		if err := json.Unmarshal(e.Resource, item); err == nil {
-			dataItems = append(dataItems, item)
-		} else {
-			return nil, resp, err
+		if err := json.Unmarshal(e.Resource, item); err != nil {
+			return nil, nil, resp, err
+		}
+		dataItems = append(dataItems, item)
+	}
+	return dataItems, &bundleResponse, resp, nil
+}
+
+// DataItemQuery is a typed search for data items of a data type
+type DataItemQuery struct {
+	Organization string
+	DataType     DataType
+	// Device and User restrict the results to a single device or user
+	Device *Device
+	User   *User
+	// From and To restrict the timestamps to [From, To). Zero values are unbounded
+	From time.Time
+	To   time.Time
+	// PageSize is the number of data items fetched per request
+	PageSize int
+	// Data searches in the data part of the data items
+	Data KeyValue
+}
+
+const timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+func (q DataItemQuery) options() (*GetDataItemOptions, error) {
+	if q.DataType.System == "" || q.DataType.Code == "" {
+		return nil, ErrMissingDataType
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return nil, ErrInvalidTimeRange
+	}
+	opt := &GetDataItemOptions{DataType: String(dataTypeParam(q.DataType))}
+	if q.Organization != "" {
+		opt.Organization = String(q.Organization)
+	}
+	if q.Device != nil {
+		opt.Device = String(q.Device.System + "|" + q.Device.Value)
+	}
+	if q.User != nil {
+		opt.User = String(q.User.System + "|" + q.User.Value)
+	}
+	if !q.From.IsZero() {
+		opt.Timestamp = append(opt.Timestamp, "ge"+q.From.UTC().Format(timestampLayout))
+	}
+	if !q.To.IsZero() {
+		opt.Timestamp = append(opt.Timestamp, "lt"+q.To.UTC().Format(timestampLayout))
+	}
+	if q.PageSize > 0 {
+		opt.Count = &q.PageSize
+	}
+	return opt, nil
+}
+
+// DataItemIterator pages through search results by following the next links of the bundles
+type DataItemIterator struct {
+	pager *internal.Pager[*DataItem, OptionFunc]
+}
+
+// Iterate returns an iterator over all data items matching the query
+func (d *DataItemsService) Iterate(query DataItemQuery, options ...OptionFunc) *DataItemIterator {
+	opt, err := query.options()
+	if len(query.Data) > 0 {
+		options = append(append([]OptionFunc{}, options...), DataSearch(query.Data))
+	}
+	return &DataItemIterator{pager: internal.NewPager(func(options ...OptionFunc) ([]*DataItem, *internal.Bundle, error) {
+		if err != nil {
+			return nil, nil, err
+		}
+		page, bundle, _, err := d.search(opt, options...)
+		return page, bundle, err
+	}, options...)}
+}
+
+// Next advances to the next data item, fetching the next page when needed.
+// It returns false when the results are exhausted or an error occurred
+func (it *DataItemIterator) Next() bool {
+	return it.pager.Next()
+}
+
+// DataItem returns the current data item
+func (it *DataItemIterator) DataItem() *DataItem {
+	return it.pager.Current()
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *DataItemIterator) Err() error {
+	return it.pager.Err()
+}
+
+// ItemStatus is the outcome of storing a data item
+type ItemStatus string
+
+const (
+	ItemCreated   ItemStatus = "created"
+	ItemDuplicate ItemStatus = "duplicate"
+	ItemInvalid   ItemStatus = "invalid"
+	ItemFailed    ItemStatus = "failed"
+)
+
+const defaultBatchSize = 100
+
+// ItemOutcome is the outcome of storing the data item at Index of a batch
+type ItemOutcome struct {
+	Index    int
+	Status   ItemStatus
+	Location string
+	Err      error
+}
+
+// BatchOptions configures CreateDataItems
+type BatchOptions struct {
+	// BatchSize is the number of data items per request
+	BatchSize int
+	// SkipValidation skips the validation against the contract schemas
+	SkipValidation bool
+}
+
+type dataItemBundleEntry struct {
+	Resource DataItem `json:"resource"`
+}
+
+type dataItemBundle struct {
+	ResourceType string                `json:"resourceType"`
+	Type         string                `json:"type"`
+	Entry        []dataItemBundleEntry `json:"entry"`
+}
+
+type dataItemBundleResponse struct {
+	ResourceType string `json:"resourceType"`
+	Type         string `json:"type"`
+	Entry        []struct {
+		Response struct {
+			Status   string          `json:"status"`
+			Location string          `json:"location"`
+			Outcome  json.RawMessage `json:"outcome"`
+		} `json:"response"`
+	} `json:"entry"`
+}
+
+func dataTypeParam(dataType DataType) string {
+	return dataType.System + "|" + dataType.Code
+}
+
+// Schema returns the parsed schema of the contract of the data type. Schemas are cached
+func (d *DataItemsService) Schema(organization string, dataType DataType, options ...OptionFunc) (*Schema, error) {
+	key := organization + "|" + dataTypeParam(dataType)
+	d.mu.Lock()
+	schema, ok := d.schemas[key]
+	d.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+	opt := &GetContractOptions{DataType: String(dataTypeParam(dataType))}
+	if organization != "" {
+		opt.Organization = &organization
+	}
+	contracts, _, err := d.client.Contracts.GetContract(opt, options...)
+	if errors.Is(err, ErrEmptyResult) || (err == nil && len(contracts) == 0) {
+		return nil, fmt.Errorf("%s: %w", dataTypeParam(dataType), ErrContractNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	schema, err = ParseSchema(contracts[0].Schema)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	if d.schemas == nil {
+		d.schemas = map[string]*Schema{}
+	}
+	d.schemas[key] = schema
+	d.mu.Unlock()
+	return schema, nil
+}
+
+// ValidateDataItem checks the data of the item against the schema of its contract
+func (d *DataItemsService) ValidateDataItem(item DataItem, options ...OptionFunc) error {
+	schema, err := d.Schema(item.Organization, item.DataType, options...)
+	if err != nil {
+		return err
+	}
+	return schema.Validate(item.Data)
+}
+
+// CreateDataItem validates a data item against the schema of its contract and stores it
+func (d *DataItemsService) CreateDataItem(item DataItem, options ...OptionFunc) (bool, *Response, error) {
+	if err := d.ValidateDataItem(item, options...); err != nil {
+		return false, nil, err
+	}
+	item.ResourceType = "DataItem"
+	req, err := d.client.newTDRRequest("POST", "store/tdr/DataItem", &item, options)
+	if err != nil {
+		return false, nil, err
+	}
+	req.Header.Set("Api-Version", APIVersion)
+
+	var createResponse bytes.Buffer
+	resp, err := d.client.Do(req, &createResponse)
+	if err != nil {
+		return false, resp, err
+	}
+	if resp.StatusCode() != http.StatusCreated {
+		return false, resp, fmt.Errorf("HTTP %d: %w", resp.StatusCode(), ErrOperationFailed)
+	}
+	if location := resp.Header.Get("Location"); location == "" {
+		return false, resp, ErrCouldNoReadResourceAfterCreate
+	}
+	return true, resp, nil
+}
+
+// DeduplicationKey identifies a data item by data type, device or user and timestamp.
+// Items without device and user have no key
+func DeduplicationKey(item DataItem) string {
+	var subject string
+	switch {
+	case item.Device.Value != "":
+		subject = "device|" + item.Device.System + "|" + item.Device.Value
+	case item.User.Value != "":
+		subject = "user|" + item.User.System + "|" + item.User.Value
+	default:
+		return ""
+	}
+	timestamp := item.Timestamp
+	if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+		timestamp = t.UTC().Format(time.RFC3339Nano)
+	}
+	return dataTypeParam(item.DataType) + "|" + subject + "|" + timestamp
+}
+
+// CreateDataItems validates and stores data items in batches. Items repeating the
+// deduplication key of an earlier valid item are skipped. It returns the outcome of every
+// item and ErrIngestionIncomplete when any item is invalid or failed to be stored
+func (d *DataItemsService) CreateDataItems(items []DataItem, batch BatchOptions, options ...OptionFunc) ([]ItemOutcome, error) {
+	if batch.BatchSize <= 0 {
+		batch.BatchSize = defaultBatchSize
+	}
+	outcomes := make([]ItemOutcome, len(items))
+	seen := map[string]bool{}
+	var pending []int
+	for i, item := range items {
+		outcomes[i].Index = i
+		key := DeduplicationKey(item)
+		if key != "" && seen[key] {
+			outcomes[i].Status = ItemDuplicate
+			continue
+		}
+		if !batch.SkipValidation {
+			if err := d.ValidateDataItem(item, options...); err != nil {
+				outcomes[i].Status = ItemInvalid
+				if !errors.Is(err, ErrSchemaValidation) {
+					outcomes[i].Status = ItemFailed
+				}
+				outcomes[i].Err = err
+				continue
+			}
+		}
+		// Only valid items claim their key, so a later valid repeat is still stored
+		if key != "" {
+			seen[key] = true
+		}
+		pending = append(pending, i)
+	}
+
+	for start := 0; start < len(pending); start += batch.BatchSize {
+		end := start + batch.BatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		d.createBatch(items, pending[start:end], outcomes, options)
+	}
+
+	var incomplete int
+	for _, outcome := range outcomes {
+		if outcome.Status == ItemInvalid || outcome.Status == ItemFailed {
+			incomplete++
+		}
+	}
+	if incomplete > 0 {
+		return outcomes, fmt.Errorf("%d of %d data items not stored: %w", incomplete, len(items), ErrIngestionIncomplete)
+	}
+	return outcomes, nil
+}
+
+func (d *DataItemsService) createBatch(items []DataItem, indexes []int, outcomes []ItemOutcome, options []OptionFunc) {
+	bundle := dataItemBundle{ResourceType: "Bundle", Type: "batch"}
+	for _, i := range indexes {
+		item := items[i]
+		item.ResourceType = "DataItem"
+		bundle.Entry = append(bundle.Entry, dataItemBundleEntry{Resource: item})
+	}
+	fail := func(err error) {
+		for _, i := range indexes {
+			outcomes[i].Status = ItemFailed
+			outcomes[i].Err = err
+		}
+	}
+	req, err := d.client.newTDRRequest("POST", "store/tdr/DataItem", &bundle, options)
+	if err != nil {
+		fail(err)
+		return
+	}
+	req.Header.Set("Api-Version", APIVersion)
+	var response dataItemBundleResponse
+	if _, err := d.client.Do(req, &response); err != nil && !errors.Is(err, io.EOF) {
+		fail(err)
+		return
+	}
+	if response.ResourceType != "Bundle" || response.Type != "batch-response" {
+		fail(fmt.Errorf("unexpected %s response: %w", response.ResourceType, ErrOperationFailed))
+		return
+	}
+	for n, i := range indexes {
+		if n >= len(response.Entry) {
+			outcomes[i].Status = ItemFailed
+			outcomes[i].Err = fmt.Errorf("no outcome reported: %w", ErrOperationFailed)
+			continue
+		}
+		entry := response.Entry[n].Response
+		outcomes[i].Location = entry.Location
+		status, _ := strconv.Atoi(strings.Fields(entry.Status + " 0")[0])
+		switch {
+		case status >= 200 && status < 300:
+			outcomes[i].Status = ItemCreated
+		case status == http.StatusConflict:
+			outcomes[i].Status = ItemDuplicate
+		case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+			outcomes[i].Status = ItemInvalid
+			outcomes[i].Err = fmt.Errorf("HTTP %d %s: %w", status, string(entry.Outcome), ErrSchemaValidation)
+		default:
+			outcomes[i].Status = ItemFailed
+			outcomes[i].Err = fmt.Errorf("HTTP %s %s: %w", entry.Status, string(entry.Outcome), ErrOperationFailed)
 		}
 	}
-	return dataItems, resp, err
 }
@@ -1,8 +1,11 @@
 package tdr
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -80,3 +83,116 @@ func TestGetDataItem(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(dataItems))
 }
+
+func TestCreateDataItems(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	contractRequests := 0
+	muxTDR.HandleFunc("/store/tdr/Contract", func(w http.ResponseWriter, r *http.Request) {
+		contractRequests++
+		assert.Equal(t, "TDROrg", r.URL.Query().Get("organization"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("dataType") != "system|heartRate" {
+			_, _ = io.WriteString(w, `{"type": "searchset", "total": 0, "entry": []}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+			"type": "searchset",
+			"total": 1,
+			"entry": [{"resource": {
+				"organization": "TDROrg",
+				"dataType": {"system": "system", "code": "heartRate"},
+				"schema": {"type": "object", "required": ["bpm"], "properties": {"bpm": {"type": "integer"}}}
+			}}]
+		}`)
+	})
+	var batches [][]DataItem
+	muxTDR.HandleFunc("/store/tdr/DataItem", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		var bundle dataItemBundle
+		if !assert.Nil(t, json.NewDecoder(r.Body).Decode(&bundle)) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if bundle.ResourceType == "DataItem" {
+			// Accepted without being stored
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if len(bundle.Entry) > 0 && bundle.Entry[0].Resource.Device.Value == "outcome" {
+			_, _ = io.WriteString(w, `{"resourceType": "OperationOutcome", "issue": [{"severity": "information", "code": "informational"}]}`)
+			return
+		}
+		assert.Equal(t, "Bundle", bundle.ResourceType)
+		assert.Equal(t, "batch", bundle.Type)
+		var items []DataItem
+		var entries []string
+		for _, e := range bundle.Entry {
+			items = append(items, e.Resource)
+			status := `"201 Created", "location": "DataItem/` + e.Resource.Timestamp + `"`
+			if e.Resource.Device.Value == "known" {
+				status = `"409 Conflict"`
+			}
+			if e.Resource.Device.Value == "broken" {
+				status = `"500 Internal Server Error"`
+			}
+			entries = append(entries, `{"response": {"status": `+status+`}}`)
+		}
+		batches = append(batches, items)
+		_, _ = io.WriteString(w, `{"resourceType": "Bundle", "type": "batch-response", "entry": [`+strings.Join(entries, ",")+`]}`)
+	})
+
+	heartRate := DataType{System: "system", Code: "heartRate"}
+	item := func(device, timestamp, data string) DataItem {
+		return DataItem{
+			Organization: "TDROrg",
+			DataType:     heartRate,
+			Device:       Device{System: "serial", Value: device},
+			Timestamp:    timestamp,
+			Data:         json.RawMessage(data),
+		}
+	}
+	items := []DataItem{
+		item("a", "2022-01-01T10:00:00Z", `{"bpm": 60}`),
+		item("a", "2022-01-01T11:00:00+01:00", `{"bpm": 61}`),
+		item("a", "2022-01-01T10:01:00Z", `{"bpm": "fast"}`),
+		item("a", "2022-01-01T10:01:00Z", `{"bpm": 59}`),
+		item("known", "2022-01-01T10:02:00Z", `{"bpm": 62}`),
+		item("broken", "2022-01-01T10:03:00Z", `{"bpm": 63}`),
+		item("b", "2022-01-01T10:04:00Z", `{"bpm": 64}`),
+		{Organization: "TDROrg", DataType: DataType{System: "system", Code: "unknown"}, Timestamp: "2022-01-01T10:05:00Z", Data: json.RawMessage(`{}`)},
+	}
+	outcomes, err := tdrClient.DataItems.CreateDataItems(items, BatchOptions{BatchSize: 2})
+	assert.True(t, errors.Is(err, ErrIngestionIncomplete))
+	if !assert.Len(t, outcomes, len(items)) {
+		return
+	}
+	statuses := make([]ItemStatus, len(outcomes))
+	for i, outcome := range outcomes {
+		assert.Equal(t, i, outcome.Index)
+		statuses[i] = outcome.Status
+	}
+	assert.Equal(t, []ItemStatus{ItemCreated, ItemDuplicate, ItemInvalid, ItemCreated, ItemDuplicate, ItemFailed, ItemCreated, ItemFailed}, statuses)
+	assert.Equal(t, "DataItem/2022-01-01T10:00:00Z", outcomes[0].Location)
+	assert.True(t, errors.Is(outcomes[2].Err, ErrSchemaValidation))
+	assert.True(t, errors.Is(outcomes[5].Err, ErrOperationFailed))
+	assert.True(t, errors.Is(outcomes[7].Err, ErrContractNotFound))
+	assert.Len(t, batches, 3)
+	assert.Equal(t, 2, contractRequests)
+
+	_, _, err = tdrClient.DataItems.CreateDataItem(item("c", "2022-01-01T10:06:00Z", `{}`))
+	assert.True(t, errors.Is(err, ErrSchemaValidation))
+	assert.Equal(t, 2, contractRequests)
+
+	// Only a batch-response bundle reports items as stored
+	outcomes, err = tdrClient.DataItems.CreateDataItems([]DataItem{item("outcome", "2022-01-01T10:07:00Z", `{"bpm": 65}`)}, BatchOptions{})
+	assert.True(t, errors.Is(err, ErrIngestionIncomplete))
+	if assert.Len(t, outcomes, 1) {
+		assert.Equal(t, ItemFailed, outcomes[0].Status)
+		assert.True(t, errors.Is(outcomes[0].Err, ErrOperationFailed))
+	}
+	ok, _, err := tdrClient.DataItems.CreateDataItem(item("c", "2022-01-01T10:08:00Z", `{"bpm": 66}`))
+	assert.False(t, ok)
+	assert.True(t, errors.Is(err, ErrOperationFailed))
+}
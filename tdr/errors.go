@@ -11,4 +11,12 @@ var (
 	ErrEmptyResult                    = errors.New("empty result")
 	ErrCouldNoReadResourceAfterCreate = errors.New("could not read resource after create")
 	ErrEmptyResults                   = errors.New("empty results")
+	ErrInvalidSchema                  = errors.New("invalid contract schema")
+	ErrSchemaValidation               = errors.New("data does not match the contract schema")
+	ErrContractNotFound               = errors.New("contract not found")
+	ErrIngestionIncomplete            = errors.New("not all data items were stored")
+	ErrOperationFailed                = errors.New("operation failed")
+	ErrMissingDataType                = errors.New("missing data type")
+	ErrInvalidTimeRange               = errors.New("time range ends before it starts")
+	ErrUnsupportedFormat              = errors.New("unsupported export format")
 )
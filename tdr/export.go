@@ -0,0 +1,342 @@
+package tdr
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ColumnType is the type of the values of an export column
+type ColumnType string
+
+const (
+	ColumnString  ColumnType = "string"
+	ColumnInteger ColumnType = "integer"
+	ColumnNumber  ColumnType = "number"
+	ColumnBoolean ColumnType = "boolean"
+)
+
+// Column is a column of an export. Data columns are named after the path of the
+// field in the data, e.g. data.reading.value
+type Column struct {
+	Name string
+	Type ColumnType
+
+	path []string
+}
+
+// ExportFormat is the file format of an export
+type ExportFormat string
+
+const (
+	ExportCSV        ExportFormat = "csv"
+	ExportJSONLines  ExportFormat = "jsonl"
+	ExportParquet    ExportFormat = "parquet"
+	defaultGroupSize              = 10000
+)
+
+// ExportOptions configures Export
+type ExportOptions struct {
+	Format ExportFormat
+	// RowGroupSize is the number of rows per Parquet row group
+	RowGroupSize int
+}
+
+var metadataColumns = []Column{
+	{Name: "id", Type: ColumnString},
+	{Name: "timestamp", Type: ColumnString},
+	{Name: "sequenceNumber", Type: ColumnInteger},
+	{Name: "organization", Type: ColumnString},
+	{Name: "dataType", Type: ColumnString},
+	{Name: "device", Type: ColumnString},
+	{Name: "user", Type: ColumnString},
+	{Name: "creationTimestamp", Type: ColumnString},
+}
+
+// ExportColumns returns the columns of an export of data items following schema.
+// Objects with properties are flattened into a column per property, other arrays
+// and objects are exported as JSON strings. Without schema the data is exported
+// as a single JSON string column
+func ExportColumns(schema *Schema) []Column {
+	columns := append([]Column{}, metadataColumns...)
+	if schema == nil || len(schema.Properties) == 0 {
+		return append(columns, Column{Name: "data", Type: ColumnString})
+	}
+	return appendDataColumns(columns, []string{"data"}, schema)
+}
+
+func appendDataColumns(columns []Column, path []string, schema *Schema) []Column {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property := schema.Properties[name]
+		propertyPath := append(append([]string{}, path...), name)
+		if len(property.Properties) > 0 {
+			columns = appendDataColumns(columns, propertyPath, property)
+			continue
+		}
+		columns = append(columns, Column{
+			Name: strings.Join(propertyPath, "."),
+			Type: columnType(property.Type),
+			path: propertyPath[1:],
+		})
+	}
+	return columns
+}
+
+func columnType(types SchemaTypes) ColumnType {
+	var nonNull []string
+	for _, t := range types {
+		if t != "null" {
+			nonNull = append(nonNull, t)
+		}
+	}
+	switch {
+	case len(nonNull) == 1 && nonNull[0] == "integer":
+		return ColumnInteger
+	case len(nonNull) == 1 && nonNull[0] == "number",
+		len(nonNull) == 2 && types.Has("integer") && types.Has("number"):
+		return ColumnNumber
+	case len(nonNull) == 1 && nonNull[0] == "boolean":
+		return ColumnBoolean
+	}
+	return ColumnString
+}
+
+// FlattenDataItem returns the values of item for columns. Values are nil, string,
+// int64, float64 or bool. Values not matching the type of their column are nil
+func FlattenDataItem(item *DataItem, columns []Column) []interface{} {
+	var data interface{}
+	if len(item.Data) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(item.Data))
+		decoder.UseNumber()
+		_ = decoder.Decode(&data)
+	}
+	row := make([]interface{}, len(columns))
+	for i, column := range columns {
+		switch column.Name {
+		case "id":
+			row[i] = optionalString(item.ID)
+		case "timestamp":
+			row[i] = optionalString(item.Timestamp)
+		case "sequenceNumber":
+			row[i] = int64(item.SequenceNumber)
+		case "organization":
+			row[i] = optionalString(item.Organization)
+		case "dataType":
+			row[i] = dataTypeParam(item.DataType)
+		case "device":
+			if item.Device.Value != "" {
+				row[i] = item.Device.System + "|" + item.Device.Value
+			}
+		case "user":
+			if item.User.Value != "" {
+				row[i] = item.User.System + "|" + item.User.Value
+			}
+		case "creationTimestamp":
+			row[i] = optionalString(item.CreationTimestamp)
+		case "data":
+			if len(item.Data) > 0 {
+				row[i] = string(item.Data)
+			}
+		default:
+			row[i] = columnValue(lookup(data, column.path), column.Type)
+		}
+	}
+	return row
+}
+
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func lookup(value interface{}, path []string) interface{} {
+	for _, name := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+func columnValue(value interface{}, columnType ColumnType) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch columnType {
+	case ColumnInteger:
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return i
+			}
+		}
+		return nil
+	case ColumnNumber:
+		if n, ok := value.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				return f
+			}
+		}
+		return nil
+	case ColumnBoolean:
+		if b, ok := value.(bool); ok {
+			return b
+		}
+		return nil
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+type rowWriter interface {
+	Write(row []interface{}) error
+	Close() error
+}
+
+func newRowWriter(w io.Writer, columns []Column, options ExportOptions) (rowWriter, error) {
+	switch options.Format {
+	case ExportCSV:
+		return newCSVWriter(w, columns)
+	case ExportJSONLines:
+		return &jsonLinesWriter{w: w, columns: columns}, nil
+	case ExportParquet:
+		if options.RowGroupSize <= 0 {
+			options.RowGroupSize = defaultGroupSize
+		}
+		return newParquetWriter(w, columns, options.RowGroupSize)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// Export streams the data items matching query to w. The data is flattened following
+// the schema of the contract of the data type. It returns the number of exported data items
+func (d *DataItemsService) Export(w io.Writer, query DataItemQuery, export ExportOptions, options ...OptionFunc) (int, error) {
+	if _, err := query.options(); err != nil {
+		return 0, err
+	}
+	schema, err := d.Schema(query.Organization, query.DataType, options...)
+	if err != nil && !errors.Is(err, ErrContractNotFound) {
+		return 0, err
+	}
+	columns := ExportColumns(schema)
+	writer, err := newRowWriter(w, columns, export)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	it := d.Iterate(query, options...)
+	for it.Next() {
+		if err := writer.Write(FlattenDataItem(it.DataItem(), columns)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		return count, err
+	}
+	return count, writer.Close()
+}
+
+// ExportFile exports the data items matching query to a file. When export.Format is
+// empty it follows the extension of path: .csv, .jsonl or .parquet
+func (d *DataItemsService) ExportFile(path string, query DataItemQuery, export ExportOptions, options ...OptionFunc) (int, error) {
+	if export.Format == "" {
+		export.Format = ExportFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	}
+	if _, err := newRowWriter(io.Discard, nil, export); err != nil {
+		return 0, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	count, err := d.Export(file, query, export, options...)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	writer := &csvWriter{w: csv.NewWriter(w)}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	return writer, writer.w.Write(header)
+}
+
+func (c *csvWriter) Write(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		switch v := value.(type) {
+		case string:
+			record[i] = v
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			record[i] = strconv.FormatBool(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonLinesWriter struct {
+	w       io.Writer
+	columns []Column
+	buf     bytes.Buffer
+}
+
+func (j *jsonLinesWriter) Write(row []interface{}) error {
+	j.buf.Reset()
+	j.buf.WriteByte('{')
+	for i, value := range row {
+		if i > 0 {
+			j.buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(j.columns[i].Name)
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		j.buf.Write(name)
+		j.buf.WriteByte(':')
+		j.buf.Write(encoded)
+	}
+	j.buf.WriteString("}\n")
+	_, err := j.w.Write(j.buf.Bytes())
+	return err
+}
+
+func (j *jsonLinesWriter) Close() error {
+	return nil
+}
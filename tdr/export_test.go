@@ -0,0 +1,145 @@
+package tdr
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func exportFixtures(t *testing.T) *[]string {
+	var queries []string
+	muxTDR.HandleFunc("/store/tdr/Contract", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+			"type": "searchset",
+			"total": 1,
+			"entry": [{"resource": {
+				"dataType": {"system": "system", "code": "vitals"},
+				"schema": {"type": "object", "properties": {
+					"bpm": {"type": "integer"},
+					"temperature": {"type": ["number", "null"]},
+					"resting": {"type": "boolean"},
+					"reading": {"type": "object", "properties": {"unit": {"type": "string"}, "tags": {"type": "array"}}}
+				}}
+			}}]
+		}`)
+	})
+	muxTDR.HandleFunc("/store/tdr/DataItem", func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("_startAt") == "" {
+			_, _ = io.WriteString(w, `{
+				"type": "searchset",
+				"total": 2,
+				"entry": [{"resource": {
+					"id": "1",
+					"timestamp": "2022-01-01T10:00:00.000Z",
+					"organization": "TDROrg",
+					"dataType": {"system": "system", "code": "vitals"},
+					"device": {"system": "serial", "value": "d1"},
+					"data": {"bpm": 60, "temperature": 36.6, "resting": true, "reading": {"unit": "bpm", "tags": ["a"]}}
+				}}],
+				"link": [{"relation": "next", "url": "https://elsewhere/store/tdr/DataItem?dataType=system%7Cvitals&_startAt=1"}]
+			}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+			"type": "searchset",
+			"total": 2,
+			"entry": [{"resource": {
+				"id": "2",
+				"timestamp": "2022-01-01T11:00:00.000Z",
+				"organization": "TDROrg",
+				"dataType": {"system": "system", "code": "vitals"},
+				"user": {"system": "iam", "value": "u1"},
+				"data": {"bpm": "n/a", "temperature": null}
+			}}]
+		}`)
+	})
+	return &queries
+}
+
+func TestIterateDataItems(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	queries := exportFixtures(t)
+
+	from := time.Date(2022, 1, 1, 11, 0, 0, 0, time.FixedZone("CET", 3600))
+	it := tdrClient.DataItems.Iterate(DataItemQuery{
+		Organization: "TDROrg",
+		DataType:     DataType{System: "system", Code: "vitals"},
+		Device:       &Device{System: "serial", Value: "d1"},
+		From:         from,
+		To:           from.Add(24 * time.Hour),
+		PageSize:     1,
+	})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.DataItem().ID)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"1", "2"}, ids)
+	if assert.Len(t, *queries, 2) {
+		assert.Equal(t, "_count=1&dataType=system%7Cvitals&device=serial%7Cd1&organization=TDROrg&timestamp=ge2022-01-01T10%3A00%3A00.000Z&timestamp=lt2022-01-02T10%3A00%3A00.000Z", (*queries)[0])
+		assert.Equal(t, "dataType=system%7Cvitals&_startAt=1", (*queries)[1])
+	}
+
+	it = tdrClient.DataItems.Iterate(DataItemQuery{DataType: DataType{System: "system", Code: "vitals"}, From: from, To: from})
+	assert.False(t, it.Next())
+	assert.True(t, errors.Is(it.Err(), ErrInvalidTimeRange))
+	it = tdrClient.DataItems.Iterate(DataItemQuery{})
+	assert.False(t, it.Next())
+	assert.True(t, errors.Is(it.Err(), ErrMissingDataType))
+}
+
+func TestExport(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+	_ = exportFixtures(t)
+	query := DataItemQuery{Organization: "TDROrg", DataType: DataType{System: "system", Code: "vitals"}}
+
+	var out bytes.Buffer
+	count, err := tdrClient.DataItems.Export(&out, query, ExportOptions{Format: ExportCSV})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, `id,timestamp,sequenceNumber,organization,dataType,device,user,creationTimestamp,data.bpm,data.reading.tags,data.reading.unit,data.resting,data.temperature
+1,2022-01-01T10:00:00.000Z,0,TDROrg,system|vitals,serial|d1,,,60,"[""a""]",bpm,true,36.6
+2,2022-01-01T11:00:00.000Z,0,TDROrg,system|vitals,,iam|u1,,,,,,
+`, out.String())
+
+	out.Reset()
+	_, err = tdrClient.DataItems.Export(&out, query, ExportOptions{Format: ExportJSONLines})
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, `{"id":"1","timestamp":"2022-01-01T10:00:00.000Z","sequenceNumber":0,"organization":"TDROrg","dataType":"system|vitals","device":"serial|d1","user":null,"creationTimestamp":null,"data.bpm":60,"data.reading.tags":"[\"a\"]","data.reading.unit":"bpm","data.resting":true,"data.temperature":36.6}`, lines[0])
+	}
+
+	path := filepath.Join(t.TempDir(), "vitals.parquet")
+	count, err = tdrClient.DataItems.ExportFile(path, query, ExportOptions{RowGroupSize: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	data, err := os.ReadFile(path)
+	if assert.Nil(t, err) && assert.Greater(t, len(data), 12) {
+		metadata, rows := readParquet(t, data)
+		assert.Len(t, metadata[4], 2)
+		assert.Equal(t, "data.reading.unit", field(metadata[2].([]interface{})[11], 4))
+		if assert.Len(t, rows, 2) {
+			assert.Equal(t, []interface{}{"1", "2022-01-01T10:00:00.000Z", int64(0), "TDROrg", "system|vitals", "serial|d1", nil, nil,
+				int64(60), `["a"]`, "bpm", true, 36.6}, rows[0])
+			assert.Equal(t, "iam|u1", rows[1][6])
+			assert.Nil(t, rows[1][8])
+		}
+	}
+
+	_, err = tdrClient.DataItems.ExportFile(filepath.Join(t.TempDir(), "vitals.xlsx"), query, ExportOptions{})
+	assert.True(t, errors.Is(err, ErrUnsupportedFormat))
+}
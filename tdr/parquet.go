@@ -0,0 +1,309 @@
+package tdr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+const parquetMagic = "PAR1"
+
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+	parquetUTF8     = 0
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetDataPage = 0
+)
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+type parquetChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+type parquetRowGroup struct {
+	chunks  []parquetChunk
+	numRows int64
+}
+
+// parquetWriter writes a Parquet file with optional columns, PLAIN encoded and
+// uncompressed, with a single data page per column chunk. Rows are buffered per
+// row group so the file is streamed to the writer group by group
+type parquetWriter struct {
+	w            io.Writer
+	offset       int64
+	columns      []Column
+	rowGroupSize int
+	rows         [][]interface{}
+	rowGroups    []parquetRowGroup
+	numRows      int64
+}
+
+func newParquetWriter(w io.Writer, columns []Column, rowGroupSize int) (*parquetWriter, error) {
+	p := &parquetWriter{w: w, columns: columns, rowGroupSize: rowGroupSize}
+	return p, p.write([]byte(parquetMagic))
+}
+
+func (p *parquetWriter) write(data []byte) error {
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *parquetWriter) Write(row []interface{}) error {
+	p.rows = append(p.rows, row)
+	if len(p.rows) >= p.rowGroupSize {
+		return p.flush()
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	footer := p.footer()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	if err := p.write(length[:]); err != nil {
+		return err
+	}
+	return p.write([]byte(parquetMagic))
+}
+
+func (p *parquetWriter) flush() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	group := parquetRowGroup{numRows: int64(len(p.rows))}
+	for i, column := range p.columns {
+		page := p.page(i, column.Type)
+		var header thriftWriter
+		header.begin()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5)
+		header.i32(1, int32(len(p.rows)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		chunk := parquetChunk{offset: p.offset, size: int64(header.Len() + len(page)), numValues: int64(len(p.rows))}
+		if err := p.write(header.Bytes()); err != nil {
+			return err
+		}
+		if err := p.write(page); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+	}
+	p.rowGroups = append(p.rowGroups, group)
+	p.numRows += group.numRows
+	p.rows = p.rows[:0]
+	return nil
+}
+
+// page encodes the definition levels and the values of column i of the buffered rows
+func (p *parquetWriter) page(i int, columnType ColumnType) []byte {
+	levels := make([]bool, len(p.rows))
+	var values bytes.Buffer
+	var booleans []bool
+	for r, row := range p.rows {
+		value := row[i]
+		if value == nil {
+			continue
+		}
+		levels[r] = true
+		switch v := value.(type) {
+		case string:
+			_ = binary.Write(&values, binary.LittleEndian, uint32(len(v)))
+			values.WriteString(v)
+		case int64:
+			_ = binary.Write(&values, binary.LittleEndian, v)
+		case float64:
+			_ = binary.Write(&values, binary.LittleEndian, math.Float64bits(v))
+		case bool:
+			booleans = append(booleans, v)
+		}
+	}
+	if columnType == ColumnBoolean {
+		values.Write(packBits(booleans))
+	}
+	// Definition levels as a single bit-packed run of the RLE/bit-packing hybrid
+	encodedLevels := binary.AppendUvarint(nil, uint64((len(levels)+7)/8)<<1|1)
+	encodedLevels = append(encodedLevels, packBits(levels)...)
+
+	var page bytes.Buffer
+	_ = binary.Write(&page, binary.LittleEndian, uint32(len(encodedLevels)))
+	page.Write(encodedLevels)
+	page.Write(values.Bytes())
+	return page.Bytes()
+}
+
+func packBits(bits []bool) []byte {
+	packed := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+func parquetType(columnType ColumnType) int32 {
+	switch columnType {
+	case ColumnInteger:
+		return parquetInt64
+	case ColumnNumber:
+		return parquetDouble
+	case ColumnBoolean:
+		return parquetBoolean
+	}
+	return parquetByteArray
+}
+
+// footer encodes the FileMetaData
+func (p *parquetWriter) footer() []byte {
+	var t thriftWriter
+	t.begin()
+	t.i32(1, 1)
+	t.list(2, thriftStruct, len(p.columns)+1)
+	t.begin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.end()
+	for _, column := range p.columns {
+		t.begin()
+		t.i32(1, parquetType(column.Type))
+		t.i32(3, parquetOptional)
+		t.binary(4, column.Name)
+		if column.Type == ColumnString {
+			t.i32(6, parquetUTF8)
+		}
+		t.end()
+	}
+	t.i64(3, p.numRows)
+	t.list(4, thriftStruct, len(p.rowGroups))
+	for _, group := range p.rowGroups {
+		var size int64
+		t.begin()
+		t.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			size += chunk.size
+			t.begin()
+			t.i64(2, chunk.offset)
+			t.structField(3)
+			t.i32(1, parquetType(p.columns[i].Type))
+			t.list(2, thriftI32, 2)
+			t.varint(zigzag(parquetPlain))
+			t.varint(zigzag(parquetRLE))
+			t.list(3, thriftBinary, 1)
+			t.varint(uint64(len(p.columns[i].Name)))
+			t.WriteString(p.columns[i].Name)
+			t.i32(4, 0)
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, size)
+		t.i64(3, group.numRows)
+		t.end()
+	}
+	t.binary(6, "go-hsdp-api")
+	t.end()
+	return t.Bytes()
+}
+
+// thriftWriter encodes structs with the Thrift compact protocol
+type thriftWriter struct {
+	bytes.Buffer
+	last []int16
+}
+
+func zigzag(n int64) uint64 {
+	return uint64(n<<1) ^ uint64(n>>63)
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) field(id int16, fieldType byte) {
+	last := t.last[len(t.last)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.WriteByte(fieldType)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last[len(t.last)-1] = id
+}
+
+// begin starts a struct, end writes its stop field
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+// list writes the header of a list field, the elements follow
+func (t *thriftWriter) list(id int16, elementType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.WriteByte(byte(size)<<4 | elementType)
+		return
+	}
+	t.WriteByte(0xf0 | elementType)
+	t.varint(uint64(size))
+}
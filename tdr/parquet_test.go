@@ -0,0 +1,228 @@
+package tdr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// thriftReader decodes Thrift compact protocol structs into maps of field ID to
+// value, independently of thriftWriter
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("invalid varint at %d", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(fieldType byte) interface{} {
+	switch fieldType {
+	case 1, 2:
+		return fieldType == 1
+	case 3:
+		return r.byte()
+	case 4, thriftI32, thriftI64:
+		return r.zigzag()
+	case 7:
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return v
+	case thriftBinary:
+		n := int(r.varint())
+		v := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return v
+	case thriftList, 10:
+		header := r.byte()
+		size, elementType := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(elementType)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d at %d", fieldType, r.pos))
+}
+
+func (r *thriftReader) structure() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		last = id
+		fields[id] = r.value(header & 0x0f)
+	}
+}
+
+func field(s interface{}, ids ...int16) interface{} {
+	for _, id := range ids {
+		s = s.(map[int16]interface{})[id]
+	}
+	return s
+}
+
+// readParquet decodes the rows of a file written by parquetWriter, following the
+// offsets in the footer
+func readParquet(t *testing.T, data []byte) (map[int16]interface{}, [][]interface{}) {
+	if !assert.Equal(t, parquetMagic, string(data[:4])) || !assert.Equal(t, parquetMagic, string(data[len(data)-4:])) {
+		t.FailNow()
+	}
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{data: data[len(data)-8-footerLength : len(data)-8]}
+	metadata := footer.structure()
+	assert.Equal(t, footerLength, footer.pos)
+
+	schema := metadata[2].([]interface{})
+	var rows [][]interface{}
+	for _, g := range metadata[4].([]interface{}) {
+		chunks := field(g, 1).([]interface{})
+		numRows := int(field(g, 3).(int64))
+		group := make([][]interface{}, numRows)
+		for r := range group {
+			group[r] = make([]interface{}, len(chunks))
+		}
+		var groupSize int64
+		for c, chunk := range chunks {
+			offset := field(chunk, 3, 9).(int64)
+			assert.Equal(t, offset, field(chunk, 2))
+			assert.Equal(t, int64(numRows), field(chunk, 3, 5))
+			header := &thriftReader{data: data, pos: int(offset)}
+			page := header.structure()
+			assert.Equal(t, int64(parquetDataPage), page[1])
+			assert.Equal(t, page[2], page[3])
+			pageLength := int(page[3].(int64))
+			assert.Equal(t, field(chunk, 3, 6), int64(header.pos)-offset+int64(pageLength))
+			assert.Equal(t, int64(numRows), field(page, 5, 1))
+			groupSize += field(chunk, 3, 6).(int64)
+
+			values := data[header.pos : header.pos+pageLength]
+			levelsLength := int(binary.LittleEndian.Uint32(values))
+			levels := &thriftReader{data: values[4 : 4+levelsLength]}
+			run := levels.varint()
+			assert.Equal(t, uint64(1), run&1, "bit-packed run")
+			assert.Equal(t, (numRows+7)/8, int(run>>1))
+			bits := levels.data[levels.pos:]
+			values = values[4+levelsLength:]
+
+			columnType := field(schema[c+1], 1).(int64)
+			var booleans int
+			for r := 0; r < numRows; r++ {
+				if bits[r/8]&(1<<(r%8)) == 0 {
+					continue
+				}
+				switch columnType {
+				case parquetByteArray:
+					n := int(binary.LittleEndian.Uint32(values))
+					group[r][c] = string(values[4 : 4+n])
+					values = values[4+n:]
+				case parquetInt64:
+					group[r][c] = int64(binary.LittleEndian.Uint64(values))
+					values = values[8:]
+				case parquetDouble:
+					group[r][c] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+					values = values[8:]
+				case parquetBoolean:
+					group[r][c] = values[booleans/8]&(1<<(booleans%8)) != 0
+					booleans++
+				}
+			}
+			if columnType == parquetBoolean {
+				values = values[(booleans+7)/8:]
+			}
+			assert.Len(t, values, 0, "trailing page bytes")
+		}
+		assert.Equal(t, groupSize, field(g, 2))
+		rows = append(rows, group...)
+	}
+	return metadata, rows
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: ColumnString},
+		{Name: "count", Type: ColumnInteger},
+		{Name: "value", Type: ColumnNumber},
+		{Name: "flag", Type: ColumnBoolean},
+	}
+	rows := [][]interface{}{
+		{"a", int64(1), 1.5, true},
+		{nil, int64(-2), nil, false},
+		{"ccc", nil, math.Pi, nil},
+		{"", int64(math.MaxInt64), -0.25, true},
+		{"e", int64(5), 2.0, true},
+	}
+	for i := 0; i < 10; i++ {
+		rows = append(rows, []interface{}{fmt.Sprintf("row-%d", i), int64(i), float64(i) / 2, i%3 == 0})
+	}
+
+	var out bytes.Buffer
+	writer, err := newParquetWriter(&out, columns, 4)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, row := range rows {
+		if !assert.Nil(t, writer.Write(row)) {
+			return
+		}
+	}
+	if !assert.Nil(t, writer.Close()) {
+		return
+	}
+
+	metadata, decoded := readParquet(t, out.Bytes())
+	assert.Equal(t, int64(1), metadata[1])
+	assert.Equal(t, int64(len(rows)), metadata[3])
+	assert.Len(t, metadata[4], 4)
+	assert.Equal(t, "go-hsdp-api", metadata[6])
+	schema := metadata[2].([]interface{})
+	if assert.Len(t, schema, len(columns)+1) {
+		assert.Equal(t, "schema", field(schema[0], 4))
+		assert.Equal(t, int64(len(columns)), field(schema[0], 5))
+		for i, column := range columns {
+			element := schema[i+1].(map[int16]interface{})
+			assert.Equal(t, column.Name, element[4])
+			assert.Equal(t, int64(parquetType(column.Type)), element[1])
+			assert.Equal(t, int64(parquetOptional), element[3])
+			if column.Type == ColumnString {
+				assert.Equal(t, int64(parquetUTF8), element[6])
+			} else {
+				assert.NotContains(t, element, int16(6))
+			}
+		}
+	}
+	assert.Equal(t, rows, decoded)
+}
@@ -0,0 +1,238 @@
+package tdr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is a JSON schema as used by TDR contracts. It supports the type, properties,
+// required, additionalProperties, items, enum, numeric range, length, pattern and
+// date-time format keywords. additionalProperties is either a boolean or a schema
+// the properties not listed in properties are validated against. Other keywords
+// are ignored
+type Schema struct {
+	Type                 SchemaTypes        `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+
+	pattern    *regexp.Regexp
+	additional *Schema
+}
+
+// SchemaTypes holds the allowed types of a schema, which JSON schema allows to be
+// a single type or a list of types
+type SchemaTypes []string
+
+func (t *SchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// Has reports whether the schema allows the type
+func (t SchemaTypes) Has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSchema parses the schema of a contract
+func ParseSchema(raw json.RawMessage) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidSchema)
+	}
+	if err := schema.compile(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %v: %w", s.Pattern, err, ErrInvalidSchema)
+		}
+		s.pattern = pattern
+	}
+	if additional := bytes.TrimSpace(s.AdditionalProperties); len(additional) > 0 && additional[0] == '{' {
+		s.additional = &Schema{}
+		if err := json.Unmarshal(additional, s.additional); err != nil {
+			return fmt.Errorf("additionalProperties: %v: %w", err, ErrInvalidSchema)
+		}
+		if err := s.additional.compile(); err != nil {
+			return err
+		}
+	}
+	for _, property := range s.Properties {
+		if err := property.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// ValidationError lists the violations of a schema
+type ValidationError struct {
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return "schema validation failed: " + strings.Join(e.Violations, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrSchemaValidation
+}
+
+// Validate checks a JSON document against the schema
+func (s *Schema) Validate(data json.RawMessage) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Violations: []string{"invalid JSON: " + err.Error()}}
+	}
+	var violations []string
+	s.validate("$", value, &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func (s *Schema) validate(path string, value interface{}, violations *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, path+": "+fmt.Sprintf(format, args...))
+	}
+	actual := jsonType(value)
+	if len(s.Type) > 0 && !s.Type.Has(actual) && !(actual == "integer" && s.Type.Has("number")) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), actual)
+		return
+	}
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		fail("value is not one of the allowed values")
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("%v is less than the minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("%v is greater than the maximum %v", v, *s.Maximum)
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("shorter than %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("longer than %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("does not match %q", s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				fail("not a date-time")
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("fewer than %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("more than %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(path+"."+name, v[name], violations)
+			} else if s.additional != nil {
+				s.additional.validate(path+"."+name, v[name], violations)
+			} else if string(s.AdditionalProperties) == "false" {
+				fail("property %q is not allowed", name)
+			}
+		}
+	}
+}
+
+func (s *Schema) inEnum(value interface{}) bool {
+	encoded, _ := json.Marshal(value)
+	for _, allowed := range s.Enum {
+		candidate, _ := json.Marshal(allowed)
+		if bytes.Equal(encoded, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package tdr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["value", "unit"],
+		"additionalProperties": false,
+		"properties": {
+			"value": {"type": "number", "minimum": 0, "maximum": 300},
+			"unit": {"type": "string", "enum": ["bpm", "mmHg"]},
+			"measured": {"type": "string", "format": "date-time"},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "pattern": "^[a-z]+$"}},
+			"note": {"type": ["string", "null"], "maxLength": 5}
+		}
+	}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, schema.Validate([]byte(`{"value": 72, "unit": "bpm", "measured": "2022-01-01T10:00:00Z", "tags": ["rest"], "note": null}`)))
+	assert.Nil(t, schema.Validate([]byte(`{"value": 72.5, "unit": "bpm"}`)))
+
+	err = schema.Validate([]byte(`{"value": 400, "unit": "kg", "measured": "yesterday", "tags": ["A", "b", "c"], "note": "too long", "extra": 1}`))
+	var validationErr *ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.True(t, errors.Is(err, ErrSchemaValidation))
+		assert.Equal(t, []string{
+			"$: property \"extra\" is not allowed",
+			"$.measured: not a date-time",
+			"$.note: longer than 5 characters",
+			"$.tags: more than 2 items",
+			"$.tags[0]: does not match \"^[a-z]+$\"",
+			"$.unit: value is not one of the allowed values",
+			"$.value: 400 is greater than the maximum 300",
+		}, validationErr.Violations)
+	}
+	err = schema.Validate([]byte(`{"value": "72"}`))
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Equal(t, []string{
+			"$: missing required property \"unit\"",
+			"$.value: expected number, got string",
+		}, validationErr.Violations)
+	}
+	assert.True(t, errors.Is(schema.Validate([]byte(`{`)), ErrSchemaValidation))
+
+	schema, err = ParseSchema([]byte(`{"type": "object", "additionalProperties": {"type": "integer", "minimum": 0}}`))
+	if assert.Nil(t, err) {
+		assert.Nil(t, schema.Validate([]byte(`{"a": 1, "b": 2}`)))
+		err = schema.Validate([]byte(`{"a": -1, "b": "two"}`))
+		if assert.True(t, errors.As(err, &validationErr)) {
+			assert.Equal(t, []string{
+				"$.a: -1 is less than the minimum 0",
+				"$.b: expected integer, got string",
+			}, validationErr.Violations)
+		}
+	}
+	_, err = ParseSchema([]byte(`{"additionalProperties": {"pattern": "("}}`))
+	assert.True(t, errors.Is(err, ErrInvalidSchema))
+	_, err = ParseSchema([]byte(`{"pattern": "("}`))
+	assert.True(t, errors.Is(err, ErrInvalidSchema))
+	_, err = ParseSchema([]byte(`[]`))
+	assert.True(t, errors.Is(err, ErrInvalidSchema))
+}